@@ -0,0 +1,252 @@
+// Package analyzer defines an Analyzer that checks the "db" key of struct field tags
+// and the usage of the pgtools package, catching mistakes that are otherwise silently ignored
+// at runtime, such as:
+//
+//   - malformed db tags, with unknown, empty, duplicated, or conflicting options.
+//   - duplicate column names within a struct.
+//   - unexported fields with a db tag.
+//   - calls to pgtools.Wildcard or pgtools.Fields with a type without any mappable fields.
+//
+// Use it with go vet through the pgtoolsvet command, as in:
+//
+//	go install github.com/henvic/pgtools/cmd/pgtoolsvet@latest
+//	go vet -vettool=$(which pgtoolsvet) ./...
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/henvic/pgtools/internal/structref"
+	"github.com/henvic/pgtools/internal/typeref"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const doc = `check db struct field tags and usage of the pgtools package
+
+Report malformed db tags, duplicate column names within a struct, unexported fields
+with a db tag, and calls to pgtools.Wildcard or pgtools.Fields with a type without
+any mappable fields.`
+
+// Analyzer checks the db struct field tags and the usage of the pgtools package.
+var Analyzer = &analysis.Analyzer{
+	Name:             "pgtools",
+	Doc:              doc,
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run,
+}
+
+const pgtoolsPath = "github.com/henvic/pgtools"
+
+// options supported by the db tag. The "type" option requires a value, as in type=numeric(10,2).
+var options = map[string]struct{}{
+	"json":      {},
+	"pk":        {},
+	"readonly":  {},
+	"generated": {},
+	"flatten":   {},
+	"composite": {},
+	"array":     {},
+	"type":      {},
+	"in":        {},
+	"ne":        {},
+	"gt":        {},
+	"gte":       {},
+	"lt":        {},
+	"lte":       {},
+	"like":      {},
+	"ilike":     {},
+}
+
+// operators used by pgtools.Where, of which a field can only use one.
+var operators = []string{"in", "ne", "gt", "gte", "lt", "lte", "like", "ilike"}
+
+// conflicts between options that define how a field is mapped.
+var conflicts = [][2]string{
+	{"json", "flatten"},
+	{"json", "composite"},
+	{"json", "array"},
+	{"flatten", "composite"},
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nodeFilter := []ast.Node{
+		(*ast.StructType)(nil),
+		(*ast.CallExpr)(nil),
+	}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.StructType:
+			checkStruct(pass, n)
+		case *ast.CallExpr:
+			checkCall(pass, n)
+		}
+	})
+	return nil, nil
+}
+
+// checkStruct checks the db tags of the fields of a struct.
+func checkStruct(pass *analysis.Pass, st *ast.StructType) {
+	type mapped struct {
+		field    string
+		operator bool
+	}
+	seen := map[string]mapped{}
+	for _, f := range st.Fields.List {
+		var dbTag string
+		if f.Tag != nil {
+			tag, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				continue
+			}
+			var ok bool
+			if dbTag, ok = reflect.StructTag(tag).Lookup("db"); ok {
+				checkTag(pass, f, dbTag)
+			}
+		}
+		name, opts, _ := strings.Cut(dbTag, ",")
+		if name == "-" {
+			continue
+		}
+		if len(f.Names) == 0 {
+			// Embedded fields are flattened, unless they have a column name.
+			if name != "" {
+				seen[name] = mapped{field: types.ExprString(f.Type)}
+			}
+			continue
+		}
+		operator := hasOperator(opts)
+		for _, ident := range f.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			column := name
+			if column == "" {
+				column = structref.ToSnakeCase(ident.Name)
+			}
+			prev, ok := seen[column]
+			if !ok {
+				seen[column] = mapped{field: ident.Name, operator: operator}
+				continue
+			}
+			// Filter structs used with pgtools.Where might use a column more than once,
+			// as in a range.
+			if !prev.operator && !operator {
+				pass.Reportf(ident.Pos(), "column %q of field %s is already mapped from field %s", column, ident.Name, prev.field)
+			}
+		}
+	}
+}
+
+// checkTag checks the db tag of a struct field.
+func checkTag(pass *analysis.Pass, f *ast.Field, dbTag string) {
+	name, opts, hasOpts := strings.Cut(dbTag, ",")
+	if name == "-" {
+		return
+	}
+	for _, ident := range f.Names {
+		if !ident.IsExported() {
+			pass.Reportf(ident.Pos(), "db struct tag on unexported field %s", ident.Name)
+		}
+	}
+	if hasOpts && opts == "" {
+		pass.Reportf(f.Tag.Pos(), "db struct tag has an empty option")
+		return
+	}
+	checkOptions(pass, f.Tag, structref.SplitOptions(opts))
+}
+
+// checkOptions of a db tag.
+func checkOptions(pass *analysis.Pass, tag *ast.BasicLit, opts []string) {
+	present := map[string]struct{}{}
+	for _, o := range opts {
+		key, value, hasValue := strings.Cut(o, "=")
+		if key == "" {
+			pass.Reportf(tag.Pos(), "db struct tag has an empty option")
+			continue
+		}
+		if _, ok := options[key]; !ok {
+			pass.Reportf(tag.Pos(), "db struct tag has unknown option %q", key)
+			continue
+		}
+		switch {
+		case key == "type" && (!hasValue || value == ""):
+			pass.Reportf(tag.Pos(), "db struct tag option type requires a value, as in type=text")
+		case key != "type" && hasValue:
+			pass.Reportf(tag.Pos(), "db struct tag option %s doesn't take a value", key)
+		}
+		if _, ok := present[key]; ok {
+			pass.Reportf(tag.Pos(), "db struct tag has duplicated option %s", key)
+		}
+		present[key] = struct{}{}
+	}
+	for _, c := range conflicts {
+		_, a := present[c[0]]
+		_, b := present[c[1]]
+		if a && b {
+			pass.Reportf(tag.Pos(), "db struct tag options %s and %s conflict", c[0], c[1])
+		}
+	}
+	var ops []string
+	for _, op := range operators {
+		if _, ok := present[op]; ok {
+			ops = append(ops, op)
+		}
+	}
+	if len(ops) > 1 {
+		pass.Reportf(tag.Pos(), "db struct tag has conflicting operators %s", strings.Join(ops, " and "))
+	}
+}
+
+func hasOperator(opts string) bool {
+	for _, op := range operators {
+		if typeref.HasOption(opts, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCall checks if pgtools.Wildcard and pgtools.Fields are called with a type with mappable fields.
+func checkCall(pass *analysis.Pass, call *ast.CallExpr) {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != pgtoolsPath {
+		return
+	}
+	if name := fn.Name(); (name != "Wildcard" && name != "Fields") || len(call.Args) == 0 {
+		return
+	}
+	t := pass.TypesInfo.TypeOf(call.Args[0])
+	if t == nil {
+		return
+	}
+	if b, ok := t.(*types.Basic); ok && b.Kind() == types.UntypedNil {
+		return
+	}
+	for {
+		ptr, ok := t.Underlying().(*types.Pointer)
+		if !ok {
+			break
+		}
+		t = ptr.Elem()
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Interface, *types.Map:
+		// The type is only known at runtime, or it's a map mapped using its keys.
+		return
+	case *types.Struct:
+		if len(typeref.Columns(u)) != 0 {
+			return
+		}
+	}
+	pass.Reportf(call.Args[0].Pos(), "pgtools.%s called with %s, which has no mappable fields",
+		fn.Name(), types.TypeString(t, types.RelativeTo(pass.Pkg)))
+}
@@ -0,0 +1,79 @@
+package a
+
+import (
+	"time"
+
+	"github.com/henvic/pgtools"
+)
+
+type Theme struct {
+	Color string
+}
+
+type User struct {
+	ID        string    `db:"id,pk,generated"`
+	Name      string    `db:"name"`
+	Theme     Theme     `db:"theme,json"`
+	Price     float64   `db:"price,type=numeric(10,2)"`
+	Tags      []string  `db:"tags,array"`
+	Address   Theme     `db:"address,composite"`
+	CreatedAt time.Time `db:"created_at,readonly"`
+	LastSeen  time.Time `db:"-"`
+	ignored   string
+	hidden    string `db:"-"`
+}
+
+type Malformed struct {
+	A string `db:"a,foo"`               // want `db struct tag has unknown option "foo"`
+	B string `db:"b,"`                  // want `db struct tag has an empty option`
+	C string `db:"c,type="`             // want `db struct tag option type requires a value, as in type=text`
+	D string `db:"d,type"`              // want `db struct tag option type requires a value, as in type=text`
+	E string `db:"e,pk=true"`           // want `db struct tag option pk doesn't take a value`
+	F string `db:"f,pk,pk"`             // want `db struct tag has duplicated option pk`
+	G Theme  `db:"g,json,composite"`    // want `db struct tag options json and composite conflict`
+	H Theme  `db:"h,flatten,composite"` // want `db struct tag options flatten and composite conflict`
+	I string `db:"i,gt,lt"`             // want `db struct tag has conflicting operators gt and lt`
+	J string `json:"j" db:"j,readonly" x:"y"`
+}
+
+type Unexported struct {
+	Name  string
+	email string `db:"email"` // want `db struct tag on unexported field email`
+}
+
+type Duplicated struct {
+	Name     string
+	FullName string `db:"name"` // want `column "name" of field FullName is already mapped from field Name`
+	Other    string `db:"name"` // want `column "name" of field Other is already mapped from field Name`
+	A, B     string `db:"ab"`   // want `column "ab" of field B is already mapped from field A`
+}
+
+type Filter struct {
+	Name          string    `db:"name,ilike"`
+	CreatedAfter  time.Time `db:"created_at,gte"`
+	CreatedBefore time.Time `db:"created_at,lt"`
+}
+
+type Empty struct {
+	ignored string
+	Skipped string `db:"-"`
+}
+
+func queries() {
+	_ = pgtools.Wildcard(User{})
+	_ = pgtools.Wildcard(&User{})
+	_ = pgtools.Wildcard(map[string]any{"id": 1})
+	_ = pgtools.Wildcard(nil)
+	_ = pgtools.Fields(Filter{})
+	var v any = User{}
+	_ = pgtools.Wildcard(v)
+	_ = pgtools.Wildcard(Empty{})    // want `pgtools.Wildcard called with Empty, which has no mappable fields`
+	_ = pgtools.Fields(&Empty{})     // want `pgtools.Fields called with Empty, which has no mappable fields`
+	_ = pgtools.Wildcard(struct{}{}) // want `pgtools.Wildcard called with struct\{\}, which has no mappable fields`
+	_ = pgtools.Wildcard(1)          // want `pgtools.Wildcard called with int, which has no mappable fields`
+	_, _ = pgtools.Where(Empty{})
+}
+
+func generic[T any](v T) string {
+	return pgtools.Wildcard(v)
+}
@@ -0,0 +1,9 @@
+package pgtools
+
+type Option func()
+
+func Wildcard(v any, opts ...Option) string { return "" }
+
+func Fields(v any, opts ...Option) []string { return nil }
+
+func Where(v any) (string, []any) { return "", nil }
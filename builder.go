@@ -0,0 +1,168 @@
+package pgtools
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Upsert returns an INSERT statement for the given table that updates the existing row
+// instead when a row with the same conflict columns already exists.
+//
+// The inserted columns are the same ones returned by Fields, except for the ones with
+// the "readonly" or "generated" options, and they're all updated on conflict, except for the
+// conflict columns themselves. If no conflict columns
+// are given, ON CONFLICT DO NOTHING is used, as PostgreSQL requires a conflict target
+// for DO UPDATE.
+//
+// Values are referenced positionally as $1, $2, etc. in the same order as the columns.
+//
+// If table is empty, the table name is resolved with the TableName function.
+//
+// v can also be a map[string]any, whose keys are used as the columns, as described by Fields.
+//
+// Like Wildcard, Upsert returns an empty string if v has no columns to insert.
+func Upsert(table string, v any, conflict ...string) string {
+	columns := insertableFields(v)
+	if len(columns) == 0 {
+		return ""
+	}
+	if table = resolveTable(table, v); table == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(table)
+	b.WriteString(" (")
+	writeIdentifiers(&b, columns)
+	b.WriteString(") VALUES (")
+	writePlaceholders(&b, 1, len(columns))
+	b.WriteString(")")
+	writeOnConflict(&b, columns, conflict)
+	return b.String()
+}
+
+// writeOnConflict writes the ON CONFLICT clause of Upsert, updating the columns except for the conflict ones.
+func writeOnConflict(b *strings.Builder, columns, conflict []string) {
+	if len(conflict) == 0 {
+		b.WriteString(" ON CONFLICT DO NOTHING")
+		return
+	}
+	b.WriteString(" ON CONFLICT (")
+	writeIdentifiers(b, conflict)
+	b.WriteString(")")
+
+	var set []string
+	for _, c := range columns {
+		if !contains(conflict, c) {
+			set = append(set, c)
+		}
+	}
+	if len(set) == 0 {
+		b.WriteString(" DO NOTHING")
+		return
+	}
+	b.WriteString(" DO UPDATE SET ")
+	for n, c := range set {
+		if n != 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(`"`)
+		b.WriteString(c)
+		b.WriteString(`" = EXCLUDED."`)
+		b.WriteString(c)
+		b.WriteString(`"`)
+	}
+}
+
+// Delete returns a DELETE statement for the given table matching the primary key of v.
+//
+// The primary key is defined by the struct fields with the "pk" option in the "db" key
+// of the field's tag, as in `db:"id,pk"`. For composite primary keys, tag each of its fields.
+// The values of the primary key fields are referenced positionally as $1, $2, etc. in the
+// order the fields are declared.
+//
+// If table is empty, the table name is resolved with the TableName function.
+//
+// To avoid deleting all rows of a table by mistake, Delete returns an empty string if v has no
+// primary key.
+func Delete(table string, v any) string {
+	pk := PrimaryKey(v)
+	if len(pk) == 0 {
+		return ""
+	}
+	if table = resolveTable(table, v); table == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("DELETE FROM ")
+	b.WriteString(table)
+	b.WriteString(" WHERE ")
+	writeConditions(&b, 1, pk)
+	return b.String()
+}
+
+// Returning appends a RETURNING clause with the Wildcard expression of v to the statement,
+// returning it alongside the columns of the clause, so the result can be scanned back into
+// the same struct type, as in:
+//
+//	sql, _ := pgtools.Returning(pgtools.Upsert("users", u, "id"), u)
+//	rows, err := db.Query(ctx, sql, pgtools.Values(u)...)
+//	// ...
+//	user, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[User])
+//
+// If v has no columns, the statement is returned unchanged.
+func Returning(sql string, v any) (string, []string) {
+	columns := Fields(v)
+	if len(columns) == 0 {
+		return sql, nil
+	}
+	return sql + " RETURNING " + Wildcard(v), columns
+}
+
+// writeConditions writes equality conditions for the columns joined by AND,
+// with positional parameters starting at $start.
+func writeConditions(b *strings.Builder, start int, columns []string) {
+	for n, c := range columns {
+		if n != 0 {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(`"`)
+		b.WriteString(c)
+		b.WriteString(`" = $`)
+		b.WriteString(strconv.Itoa(start + n))
+	}
+}
+
+// writeIdentifiers writes a comma-separated list of quoted identifiers.
+func writeIdentifiers(b *strings.Builder, identifiers []string) {
+	for n, s := range identifiers {
+		if n != 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(`"`)
+		b.WriteString(s)
+		b.WriteString(`"`)
+	}
+}
+
+// writePlaceholders writes a comma-separated list of n positional parameters starting at $start.
+func writePlaceholders(b *strings.Builder, start, n int) {
+	for i := 0; i < n; i++ {
+		if i != 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("$")
+		b.WriteString(strconv.Itoa(start + i))
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+package pgtools_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools"
+)
+
+func ExampleUpsert() {
+	sql := pgtools.Upsert("users", User{}, "id")
+	fmt.Println(sql)
+	// Output:
+	// INSERT INTO users ("username","full_name","email","id","theme") VALUES ($1,$2,$3,$4,$5) ON CONFLICT ("id") DO UPDATE SET "username" = EXCLUDED."username","full_name" = EXCLUDED."full_name","email" = EXCLUDED."email","theme" = EXCLUDED."theme"
+}
+
+func TestUpsert(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc     string
+		table    string
+		v        any
+		conflict []string
+		want     string
+	}{
+		{
+			desc:  "nil",
+			table: "nothing",
+			v:     nil,
+			want:  "",
+		},
+		{
+			desc:  "empty",
+			table: "nothing",
+			v:     emptyEmbed{},
+			want:  "",
+		},
+		{
+			desc:  "no conflict target",
+			table: "numbers",
+			v:     numericMock{},
+			want:  `INSERT INTO numbers ("number") VALUES ($1) ON CONFLICT DO NOTHING`,
+		},
+		{
+			desc:     "only conflict columns",
+			table:    "numbers",
+			v:        &numericMock{},
+			conflict: []string{"number"},
+			want:     `INSERT INTO numbers ("number") VALUES ($1) ON CONFLICT ("number") DO NOTHING`,
+		},
+		{
+			desc:     "composite conflict target",
+			table:    "public.mock",
+			v:        mock{},
+			conflict: []string{"automatic", "tagged"},
+			want:     `INSERT INTO public.mock ("automatic","tagged","one_two","CamelCase") VALUES ($1,$2,$3,$4) ON CONFLICT ("automatic","tagged") DO UPDATE SET "one_two" = EXCLUDED."one_two","CamelCase" = EXCLUDED."CamelCase"`,
+		},
+		{
+			desc:     "readonly",
+			table:    "documents",
+			v:        Document{},
+			conflict: []string{"id"},
+			want:     `INSERT INTO documents ("id","body") VALUES ($1,$2) ON CONFLICT ("id") DO UPDATE SET "body" = EXCLUDED."body"`,
+		},
+		{
+			desc:  "generated",
+			table: "comments",
+			v:     Comment{},
+			want:  `INSERT INTO comments ("body") VALUES ($1) ON CONFLICT DO NOTHING`,
+		},
+		{
+			desc:  "only readonly",
+			table: "documents",
+			v: struct {
+				SearchVector string `db:"search_vector,readonly"`
+			}{},
+			want: "",
+		},
+		{
+			desc:     "map",
+			table:    "users",
+			v:        map[string]any{"name": "Henrique", "id": 1},
+			conflict: []string{"id"},
+			want:     `INSERT INTO users ("id","name") VALUES ($1,$2) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+		},
+		{
+			desc: "map without table",
+			v:    map[string]any{"name": "Henrique"},
+			want: "",
+		},
+		{
+			desc:  "map with invalid key",
+			table: "users",
+			v:     map[string]any{`name") VALUES (1); --`: "Henrique"},
+			want:  "",
+		},
+		{
+			desc:     "embed",
+			table:    "embed",
+			v:        mockEmbed{},
+			conflict: []string{"before"},
+			want:     `INSERT INTO embed ("before","automatic","tagged","one_two","CamelCase","after") VALUES ($1,$2,$3,$4,$5,$6) ON CONFLICT ("before") DO UPDATE SET "automatic" = EXCLUDED."automatic","tagged" = EXCLUDED."tagged","one_two" = EXCLUDED."one_two","CamelCase" = EXCLUDED."CamelCase","after" = EXCLUDED."after"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := pgtools.Upsert(tc.table, tc.v, tc.conflict...); tc.want != got {
+				t.Errorf("expected statement to be %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+type Document struct {
+	ID           string `db:"id,pk"`
+	Body         string
+	SearchVector string `db:"search_vector,readonly"`
+}
+
+type Comment struct {
+	ID        int64 `db:"id,pk,generated"`
+	Body      string
+	CreatedAt time.Time `db:"created_at,generated"`
+}
+
+type Post struct {
+	ID      string `db:"id,pk"`
+	Title   string
+	Message string
+}
+
+func ExampleDelete() {
+	sql := pgtools.Delete("posts", Post{})
+	fmt.Println(sql)
+	// Output:
+	// DELETE FROM posts WHERE "id" = $1
+}
+
+func TestDelete(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc  string
+		table string
+		v     any
+		want  string
+	}{
+		{
+			desc:  "nil",
+			table: "nothing",
+			v:     nil,
+			want:  "",
+		},
+		{
+			desc:  "no primary key",
+			table: "users",
+			v:     User{},
+			want:  "",
+		},
+		{
+			desc:  "primary key",
+			table: "posts",
+			v:     &Post{},
+			want:  `DELETE FROM posts WHERE "id" = $1`,
+		},
+		{
+			desc:  "composite primary key",
+			table: "public.memberships",
+			v: struct {
+				Organization string `db:"org_id,pk"`
+				Role         string
+				User         string `db:"user_id,pk"`
+			}{},
+			want: `DELETE FROM public.memberships WHERE "org_id" = $1 AND "user_id" = $2`,
+		},
+		{
+			desc:  "embedded primary key",
+			table: "audit",
+			v: struct {
+				Post
+				Reviewer string `db:"reviewer,pk"`
+			}{},
+			want: `DELETE FROM audit WHERE "id" = $1 AND "reviewer" = $2`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := pgtools.Delete(tc.table, tc.v); tc.want != got {
+				t.Errorf("expected statement to be %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func ExampleReturning() {
+	sql, columns := pgtools.Returning(pgtools.Delete("posts", Post{}), Post{})
+	fmt.Println(sql)
+	fmt.Println(columns)
+	// Output:
+	// DELETE FROM posts WHERE "id" = $1 RETURNING "id","title","message"
+	// [id title message]
+}
+
+func TestReturning(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc        string
+		sql         string
+		v           any
+		want        string
+		wantColumns []string
+	}{
+		{
+			desc: "nil",
+			sql:  "DELETE FROM nothing",
+			v:    nil,
+			want: "DELETE FROM nothing",
+		},
+		{
+			desc: "empty",
+			sql:  "DELETE FROM nothing",
+			v:    emptyEmbed{},
+			want: "DELETE FROM nothing",
+		},
+		{
+			desc:        "upsert",
+			sql:         pgtools.Upsert("numbers", numericMock{}, "number"),
+			v:           &numericMock{},
+			want:        `INSERT INTO numbers ("number") VALUES ($1) ON CONFLICT ("number") DO NOTHING RETURNING "number"`,
+			wantColumns: []string{"number"},
+		},
+		{
+			desc:        "readonly",
+			sql:         pgtools.Upsert("documents", Document{}, "id"),
+			v:           Document{},
+			want:        `INSERT INTO documents ("id","body") VALUES ($1,$2) ON CONFLICT ("id") DO UPDATE SET "body" = EXCLUDED."body" RETURNING "id","body","search_vector"`,
+			wantColumns: []string{"id", "body", "search_vector"},
+		},
+		{
+			desc:        "generated",
+			sql:         pgtools.Upsert("comments", Comment{}),
+			v:           Comment{},
+			want:        `INSERT INTO comments ("body") VALUES ($1) ON CONFLICT DO NOTHING RETURNING "id","body","created_at"`,
+			wantColumns: []string{"id", "body", "created_at"},
+		},
+		{
+			desc:        "nested",
+			sql:         "DELETE FROM nested",
+			v:           struct{ Theme struct{ Color string } }{},
+			want:        `DELETE FROM nested RETURNING "theme.color" as "theme.color","theme"`,
+			wantColumns: []string{"theme.color", "theme"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, columns := pgtools.Returning(tc.sql, tc.v)
+			if tc.want != got {
+				t.Errorf("expected statement to be %v, got %v instead", tc.want, got)
+			}
+			if !reflect.DeepEqual(tc.wantColumns, columns) {
+				t.Errorf("expected columns to be %v, got %v instead", tc.wantColumns, columns)
+			}
+		})
+	}
+}
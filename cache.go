@@ -0,0 +1,126 @@
+package pgtools
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// cache of the mappings of struct types.
+//
+// Lookups use a sync.Map, so they don't contend with each other when queries are built
+// concurrently. To keep memory bounded, the least recently used struct types are evicted
+// using the CLOCK algorithm, an approximation of LRU that doesn't need to reorder a list,
+// and therefore acquire a lock, on every lookup.
+type cache struct {
+	cap int // Capacity.
+	m   sync.Map
+
+	mu   sync.Mutex // guards following
+	ring []*entry   // Entries in insertion order, replaced in place on eviction.
+	hand int        // Position of the next entry to consider for eviction.
+
+	hits, misses, evictions uint64 // Accessed atomically.
+}
+
+// entry of the cache.
+type entry struct {
+	m          *mapping
+	referenced uint32 // Set atomically when the entry is used, and cleared by the clock hand.
+}
+
+// newCache returns a cache with the given capacity.
+func newCache(capacity int) *cache {
+	return &cache{
+		cap:  capacity,
+		ring: make([]*entry, 0, capacity),
+	}
+}
+
+var wildcardsCache = newCache(1000) // Likely high enough for most applications, but low enough to mitigate a memory leak.
+
+// get the mapping for the struct type rv, creating and caching it if needed.
+func (c *cache) get(rv reflect.Type) *mapping {
+	if v, ok := c.m.Load(rv); ok {
+		e := v.(*entry)
+		// Avoid writing to memory shared between goroutines when possible.
+		if atomic.LoadUint32(&e.referenced) == 0 {
+			atomic.StoreUint32(&e.referenced, 1)
+		}
+		atomic.AddUint64(&c.hits, 1)
+		return e.m
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Another goroutine might have cached it while the lock was acquired.
+	if v, ok := c.m.Load(rv); ok {
+		atomic.AddUint64(&c.hits, 1)
+		return v.(*entry).m
+	}
+
+	// Get the columns, cache, and return it.
+	atomic.AddUint64(&c.misses, 1)
+	e := &entry{m: newMapping(rv)}
+	if len(c.ring) < c.cap {
+		c.ring = append(c.ring, e)
+		c.m.Store(rv, e)
+		return e.m
+	}
+
+	// Give a second chance to the entries used since the hand last passed by them.
+	for atomic.CompareAndSwapUint32(&c.ring[c.hand].referenced, 1, 0) {
+		c.hand = (c.hand + 1) % len(c.ring)
+	}
+	c.m.Delete(c.ring[c.hand].m.t)
+	atomic.AddUint64(&c.evictions, 1)
+	c.ring[c.hand] = e
+	c.hand = (c.hand + 1) % len(c.ring)
+	c.m.Store(rv, e)
+	return e.m
+}
+
+// getTypeMapping returns the mapping for the struct type rv, using the cache.
+func getTypeMapping(rv reflect.Type) *mapping {
+	return wildcardsCache.get(rv)
+}
+
+// CacheStats of the cache of the columns mapped from struct types,
+// used by functions such as Wildcard and Fields.
+type CacheStats struct {
+	// Hits is the number of times the mapping of a struct type was found in the cache.
+	Hits uint64
+
+	// Misses is the number of times the mapping of a struct type wasn't found in the cache.
+	Misses uint64
+
+	// Evictions is the number of times a struct type that wasn't recently used
+	// was removed from the cache to make room for another one.
+	Evictions uint64
+
+	// Size is the number of struct types in the cache.
+	Size int
+
+	// Capacity is the maximum number of struct types in the cache.
+	Capacity int
+}
+
+// Stats returns the statistics of the cache since the program started.
+//
+// Use it to verify your struct types fit in the cache: a growing number of evictions
+// means the cache is thrashing. To publish it with the expvar package, use:
+//
+//	expvar.Publish("pgtools", expvar.Func(func() any { return pgtools.Stats() }))
+func Stats() CacheStats {
+	c := wildcardsCache
+	c.mu.Lock()
+	size := len(c.ring)
+	c.mu.Unlock()
+	return CacheStats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+		Size:      size,
+		Capacity:  c.cap,
+	}
+}
@@ -1,8 +1,9 @@
 package pgtools
 
 import (
-	"container/list"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -13,12 +14,7 @@ func TestWildcardCache(t *testing.T) {
 	})
 
 	const maxCached = 3
-	wildcardsCache = &lru{
-		cap: maxCached,
-
-		m: map[reflect.Type]*list.Element{},
-		l: list.New(),
-	}
+	wildcardsCache = newCache(maxCached)
 
 	mocks := []struct {
 		v           any
@@ -96,14 +92,137 @@ func TestWildcardCache(t *testing.T) {
 		if orig != cached {
 			t.Errorf("wanted cached value %v, got %v instead", m.want, cached)
 		}
-		if wildcardsCache.l.Len() != len(wildcardsCache.m) {
-			t.Error("cache doubly linked list and map length should match")
+		n := cacheLen(wildcardsCache)
+		if len(wildcardsCache.ring) != n {
+			t.Error("cache ring and map length should match")
+		}
+		if n > maxCached {
+			t.Errorf("cache should contain %d once full, got %d instead", maxCached, n)
 		}
-		if len(wildcardsCache.m) > maxCached {
-			t.Errorf("cache should contain %d once full, got %d instead", maxCached, len(wildcardsCache.m))
+		if n != m.cachedItems {
+			t.Errorf("wanted %d cached items, found %d", m.cachedItems, n)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	old := wildcardsCache
+	t.Cleanup(func() {
+		wildcardsCache = old // Restore default caching.
+	})
+
+	wildcardsCache = newCache(2)
+	if got, want := Stats(), (CacheStats{Capacity: 2}); got != want {
+		t.Errorf("expected stats to be %+v, got %+v instead", want, got)
+	}
+
+	type a struct{ A string }
+	type b struct{ B string }
+	type c struct{ C string }
+	for _, v := range []any{a{}, a{}, &a{}, b{}, c{}, a{}, c{}} {
+		Wildcard(v)
+	}
+	want := CacheStats{
+		Hits:      4,
+		Misses:    3,
+		Evictions: 1,
+		Size:      2,
+		Capacity:  2,
+	}
+	if got := Stats(); got != want {
+		t.Errorf("expected stats to be %+v, got %+v instead", want, got)
+	}
+}
+
+// cacheLen returns the number of entries in the map of the cache.
+func cacheLen(c *cache) int {
+	var n int
+	c.m.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func TestCacheEviction(t *testing.T) {
+	old := wildcardsCache
+	t.Cleanup(func() {
+		wildcardsCache = old // Restore default caching.
+	})
+
+	const capacity = 64
+	wildcardsCache = newCache(capacity)
+	hot := reflect.TypeOf(struct{ Hot string }{})
+	for i := 0; i < 200; i++ {
+		typ := reflect.StructOf([]reflect.StructField{
+			{
+				Name: fmt.Sprintf("Field%d", i),
+				Type: reflect.TypeOf(""),
+			},
+		})
+		want := fmt.Sprintf(`"field%d"`, i)
+		if got := Wildcard(reflect.New(typ).Interface()); got != want {
+			t.Errorf("wanted %v, got %v instead", want, got)
 		}
-		if len(wildcardsCache.m) != m.cachedItems {
-			t.Errorf("wanted %d cached items, found %d", m.cachedItems, len(wildcardsCache.m))
+		// Keep using a type so it isn't evicted.
+		if got := Wildcard(reflect.New(hot).Interface()); got != `"hot"` {
+			t.Errorf("wanted %v, got %v instead", `"hot"`, got)
 		}
 	}
+	if _, ok := wildcardsCache.m.Load(hot); !ok {
+		t.Error("expected recently used type to be cached")
+	}
+	if n := cacheLen(wildcardsCache); n != len(wildcardsCache.ring) {
+		t.Errorf("cache ring and map length should match, got %d and %d instead", len(wildcardsCache.ring), n)
+	}
+	want := CacheStats{
+		Hits:      199,
+		Misses:    201,
+		Evictions: 201 - capacity,
+		Size:      capacity,
+		Capacity:  capacity,
+	}
+	if got := Stats(); got != want {
+		t.Errorf("expected stats to be %+v, got %+v instead", want, got)
+	}
+}
+
+func TestCacheConcurrency(t *testing.T) {
+	old := wildcardsCache
+	t.Cleanup(func() {
+		wildcardsCache = old // Restore default caching.
+	})
+
+	wildcardsCache = newCache(4)
+	types := make([]reflect.Type, 10)
+	for i := range types {
+		types[i] = reflect.StructOf([]reflect.StructField{
+			{
+				Name: fmt.Sprintf("Field%d", i),
+				Type: reflect.TypeOf(0),
+			},
+		})
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				n := (g + i) % len(types)
+				want := fmt.Sprintf(`"field%d"`, n)
+				if got := Wildcard(reflect.New(types[n]).Interface()); got != want {
+					t.Errorf("wanted %v, got %v instead", want, got)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	stats := Stats()
+	if stats.Hits+stats.Misses != 8000 {
+		t.Errorf("expected 8000 lookups, got %+v instead", stats)
+	}
+	if stats.Size != 4 || cacheLen(wildcardsCache) != 4 {
+		t.Errorf("expected cache to be full, got %+v instead", stats)
+	}
 }
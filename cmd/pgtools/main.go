@@ -0,0 +1,100 @@
+// Command pgtools contains tools to work with PostgreSQL databases and the pgtools package.
+//
+// The gen command connects to a database, and generates Go structs with db tags
+// mapping the columns of the given tables, as in:
+//
+//	pgtools gen -package=models -output=models.go users posts
+//
+// The connection string is set with the -database flag. If it's empty, the
+// PostgreSQL environment variables such as PGHOST and PGDATABASE are used.
+//
+// See the github.com/henvic/pgtools/gen package for details.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/henvic/pgtools/gen"
+	"github.com/henvic/pgtools/introspect"
+	"github.com/jackc/pgx/v5"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: pgtools <command> [flags]\n\nCommands:\n")
+	fmt.Fprintf(os.Stderr, "  gen    generate Go structs from database tables\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "gen":
+		err = genCommand(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "pgtools: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pgtools: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func genCommand(args []string) error {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	var (
+		database = fs.String("database", "", "connection string of the database")
+		pkg      = fs.String("package", "models", "package name of the generated file")
+		output   = fs.String("output", "", "output file name (default standard output)")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: pgtools gen [flags] table...\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, *database)
+	if err != nil {
+		return fmt.Errorf("cannot connect to database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	tables := make([]gen.Table, 0, fs.NArg())
+	for _, name := range fs.Args() {
+		columns, err := introspect.Columns(ctx, conn, name)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			return fmt.Errorf("table %q not found", name)
+		}
+		tables = append(tables, gen.Table{
+			Name:    name,
+			Columns: columns,
+		})
+	}
+	src, err := gen.Structs(*pkg, tables)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*output, src, 0o644) // #nosec G306
+}
@@ -0,0 +1,67 @@
+// Command pgtoolsgen generates code to query and scan Go structs without using reflection at runtime.
+//
+// Use it with go generate, as in:
+//
+//	//go:generate go run github.com/henvic/pgtools/cmd/pgtoolsgen -type=User,Post
+//
+// If the -type flag isn't used, code is generated for the struct types annotated with a
+// "//pgtools:generate" line in their doc comment.
+//
+// See the github.com/henvic/pgtools/gen package for details.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/henvic/pgtools/gen"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of struct type names")
+	output    = flag.String("output", "pgtools_gen.go", "output file name")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: pgtoolsgen [flags] [directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "pgtoolsgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	var types []string
+	if *typeNames != "" {
+		types = strings.Split(*typeNames, ",")
+	}
+	src, err := gen.Generate(gen.Config{
+		Dir:    dir,
+		Types:  types,
+		Output: filepath.Base(*output),
+	})
+	if err != nil {
+		return err
+	}
+	name := *output
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	return os.WriteFile(name, src, 0o644) // #nosec G306
+}
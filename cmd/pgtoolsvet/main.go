@@ -0,0 +1,17 @@
+// Command pgtoolsvet checks the db struct field tags and the usage of the pgtools package.
+//
+// Use it with go vet, as in:
+//
+//	go vet -vettool=$(which pgtoolsvet) ./...
+//
+// See the github.com/henvic/pgtools/analyzer package for details.
+package main
+
+import (
+	"github.com/henvic/pgtools/analyzer"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+func main() {
+	unitchecker.Main(analyzer.Analyzer)
+}
@@ -0,0 +1,196 @@
+package pgtools_test
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/henvic/pgtools"
+	"github.com/henvic/pgtools/pgxfake"
+	"github.com/jackc/pgx/v5"
+)
+
+func collectCopyFromSource(t *testing.T, src pgx.CopyFromSource) ([][]any, error) {
+	t.Helper()
+	var rows [][]any
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			return rows, err
+		}
+		rows = append(rows, values)
+	}
+	return rows, src.Err()
+}
+
+func TestCopyFromStructs(t *testing.T) {
+	t.Parallel()
+	docs := []Document{
+		{ID: "a", Body: "first", SearchVector: "ignored"},
+		{ID: "b", Body: "second"},
+	}
+	src, columns := pgtools.CopyFromStructs(docs)
+	if want := []string{"id", "body"}; !reflect.DeepEqual(want, columns) {
+		t.Errorf("expected columns to be %v, got %v instead", want, columns)
+	}
+	rows, err := collectCopyFromSource(t, src)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	want := [][]any{
+		{"a", "first"},
+		{"b", "second"},
+	}
+	if !reflect.DeepEqual(want, rows) {
+		t.Errorf("expected rows to be %v, got %v instead", want, rows)
+	}
+	columns[0] = "modified"
+	if _, got := pgtools.CopyFromStructs(docs); got[0] != "id" {
+		t.Errorf("expected cached columns to be unchanged, got %v instead", got)
+	}
+}
+
+func TestCopyFromStructsGenerated(t *testing.T) {
+	t.Parallel()
+	src, columns := pgtools.CopyFromStructs([]Comment{{ID: 1, Body: "first"}})
+	if want := []string{"body"}; !reflect.DeepEqual(want, columns) {
+		t.Errorf("expected columns to be %v, got %v instead", want, columns)
+	}
+	rows, err := collectCopyFromSource(t, src)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if want := [][]any{{"first"}}; !reflect.DeepEqual(want, rows) {
+		t.Errorf("expected rows to be %v, got %v instead", want, rows)
+	}
+}
+
+func TestCopyFromStructsPointers(t *testing.T) {
+	t.Parallel()
+	src, columns := pgtools.CopyFromStructs([]*numericMock{{Number: 1}, {Number: 2}})
+	if want := []string{"number"}; !reflect.DeepEqual(want, columns) {
+		t.Errorf("expected columns to be %v, got %v instead", want, columns)
+	}
+	rows, err := collectCopyFromSource(t, src)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if want := [][]any{{1}, {2}}; !reflect.DeepEqual(want, rows) {
+		t.Errorf("expected rows to be %v, got %v instead", want, rows)
+	}
+}
+
+func TestCopyFromStructsNilPointer(t *testing.T) {
+	t.Parallel()
+	src, _ := pgtools.CopyFromStructs([]*numericMock{{Number: 1}, nil})
+	_, err := collectCopyFromSource(t, src)
+	if want := "cannot copy row 1: nil value"; err == nil || err.Error() != want {
+		t.Errorf("expected error %q, got %v instead", want, err)
+	}
+}
+
+func TestCopyFromStructsInvalidJSON(t *testing.T) {
+	t.Parallel()
+	src, _ := pgtools.CopyFromStructs([]invalidJSONMock{{ID: "x"}})
+	_, err := collectCopyFromSource(t, src)
+	if want := "cannot copy row 0: json: unsupported type: func()"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error %q, got %v instead", want, err)
+	}
+}
+
+func TestCopyFromStructsNoColumns(t *testing.T) {
+	t.Parallel()
+	src, columns := pgtools.CopyFromStructs([]any{numericMock{}})
+	if columns != nil {
+		t.Errorf("expected no columns, got %v instead", columns)
+	}
+	if src.Next() {
+		t.Error("expected no rows")
+	}
+}
+
+func TestCopyInto(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	docs := []Document{{ID: "a", Body: "first"}, {ID: "b", Body: "second"}}
+	n, err := pgtools.CopyInto(ctx, db, "public.documents", docs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 rows to be copied, got %d instead", n)
+	}
+	calls := db.Calls()
+	if len(calls) != 1 || calls[0].SQL != `COPY "public"."documents"` {
+		t.Fatalf("expected rows to be copied into the table, got %+v instead", calls)
+	}
+	if want := []any{[]any{"a", "first"}, []any{"b", "second"}}; !reflect.DeepEqual(want, calls[0].Args) {
+		t.Errorf("expected rows to be %v, got %v instead", want, calls[0].Args)
+	}
+
+	if _, err := pgtools.CopyInto(ctx, db, "", []*custom{{ID: "c"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls := db.Calls(); len(calls) != 2 || calls[1].SQL != `COPY "custom_table"` {
+		t.Errorf("expected rows to be copied into the table of the element type, got %+v instead", calls)
+	}
+
+	if _, err := pgtools.CopyInto(ctx, db, "", []any{numericMock{}}); err == nil {
+		t.Error("expected error for missing table name, got nil instead")
+	}
+}
+
+func TestCopyUpsert(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	db.On(`INSERT INTO`, pgxfake.Response{Tag: "INSERT 0 2"})
+	db.On(`.`, pgxfake.Response{})
+	docs := []Document{{ID: "a", Body: "first"}, {ID: "b", Body: "second"}}
+	n, err := pgtools.CopyUpsert(ctx, db, "documents", docs, "id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 rows to be merged, got %d instead", n)
+	}
+	calls := db.Calls()
+	tmp := regexp.MustCompile(`pgtools_copy_\d+`).FindString(calls[1].SQL)
+	if tmp == "" {
+		t.Fatalf("expected temporary table name, got %q instead", calls[1].SQL)
+	}
+	var got []string
+	for _, c := range calls {
+		got = append(got, strings.ReplaceAll(c.Method+" "+c.SQL, tmp, "pgtools_copy_N"))
+	}
+	want := []string{
+		"Begin BEGIN",
+		`Exec CREATE TEMPORARY TABLE pgtools_copy_N ON COMMIT DROP AS SELECT "id","body" FROM documents WITH NO DATA`,
+		`CopyFrom COPY "pgtools_copy_N"`,
+		`Exec INSERT INTO documents ("id","body") SELECT "id","body" FROM pgtools_copy_N ON CONFLICT ("id") DO UPDATE SET "body" = EXCLUDED."body"`,
+		"Exec DROP TABLE pgtools_copy_N",
+		"Commit COMMIT",
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected calls to be %q, got %q instead", want, got)
+	}
+
+	// Calls on the same session use different temporary tables.
+	if _, err := pgtools.CopyUpsert(ctx, db, "documents", docs, "id"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls := db.Calls(); strings.Contains(calls[len(calls)-5].SQL, tmp) {
+		t.Errorf("expected temporary table name to be unique, got %q instead", calls[len(calls)-5].SQL)
+	}
+
+	// The table name is resolved from the element type, even for a slice of pointers.
+	if _, err := pgtools.CopyUpsert(ctx, db, "", []*custom{{ID: "c"}}, "id"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls := db.Calls(); !strings.HasPrefix(calls[len(calls)-3].SQL, "INSERT INTO custom_table ") {
+		t.Errorf("expected rows to be merged into the table of the element type, got %q instead", calls[len(calls)-3].SQL)
+	}
+}
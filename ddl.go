@@ -0,0 +1,169 @@
+package pgtools
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/henvic/pgtools/internal/structref"
+)
+
+// DDL returns a CREATE TABLE statement for the SQL table mapped from a given Go struct.
+// It's meant for prototyping and creating throwaway tables for tests, rather than for
+// replacing migrations.
+//
+// The PostgreSQL data type of each column is derived from the Go type of its field,
+// and can be set with the "type" option in the "db" key of the field's tag, as in
+// `db:"price,type=numeric(10,2)"`. Fields with the "json" option use jsonb, fields with
+// the "composite" option use the snake_case form of the struct type name, and types
+// without a known equivalent use text.
+//
+// Columns are NOT NULL, unless the field is a pointer, a slice without the "array" option,
+// a map, or a sql.Null type.
+// Fields with the "pk" option are used for the PRIMARY KEY constraint.
+// Columns of nested structs are ignored, and the field containing them is mapped to jsonb.
+//
+// If table is empty, the table name is resolved with the TableName function.
+// DDL returns an empty string if v has no columns.
+func DDL(v any, table string) string {
+	m := getMapping(v)
+	if m == nil || len(m.columns) == 0 {
+		return ""
+	}
+	if table = resolveTable(table, v); table == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("CREATE TABLE ")
+	b.WriteString(table)
+	b.WriteString(" (")
+	var n int
+	for _, c := range m.columns {
+		if isNested(m.columns, c) {
+			continue
+		}
+		if n != 0 {
+			b.WriteString(",")
+		}
+		n++
+		b.WriteString("\n\t\"")
+		b.WriteString(c.Name)
+		b.WriteString("\" ")
+		dataType, nullable := columnType(c)
+		b.WriteString(dataType)
+		if !nullable {
+			b.WriteString(" NOT NULL")
+		}
+	}
+	if len(m.pk) != 0 {
+		b.WriteString(",\n\tPRIMARY KEY (")
+		writeIdentifiers(&b, m.pk)
+		b.WriteString(")")
+	}
+	b.WriteString("\n);")
+	return b.String()
+}
+
+// isNested reports whether the column is mapped from a field of a nested struct,
+// rather than from the struct itself or from an embedded struct.
+func isNested(columns []structref.Column, c structref.Column) bool {
+	for _, p := range columns {
+		if len(p.Index) < len(c.Index) && reflect.DeepEqual(p.Index, c.Index[:len(p.Index)]) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	bytesType         = reflect.TypeOf([]byte(nil))
+	rawMessageType    = reflect.TypeOf(json.RawMessage(nil))
+	uuidType          = reflect.TypeOf([16]byte{})
+	nullableDataTypes = map[reflect.Type]string{
+		reflect.TypeOf(sql.NullString{}):  "text",
+		reflect.TypeOf(sql.NullBool{}):    "boolean",
+		reflect.TypeOf(sql.NullByte{}):    "smallint",
+		reflect.TypeOf(sql.NullInt16{}):   "smallint",
+		reflect.TypeOf(sql.NullInt32{}):   "integer",
+		reflect.TypeOf(sql.NullInt64{}):   "bigint",
+		reflect.TypeOf(sql.NullFloat64{}): "double precision",
+		reflect.TypeOf(sql.NullTime{}):    "timestamp with time zone",
+	}
+)
+
+// columnType returns the PostgreSQL data type for the column, and whether it's nullable.
+func columnType(c structref.Column) (dataType string, nullable bool) {
+	t := c.Type
+	if t.Kind() == reflect.Ptr {
+		t, nullable = t.Elem(), true
+	}
+	if dt, ok := nullableDataTypes[t]; ok {
+		dataType, nullable = dt, true
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		// Nil slices of array columns are encoded as empty arrays.
+		nullable = nullable || !c.HasOption("array")
+	case reflect.Map:
+		nullable = true
+	}
+	if dt, ok := c.OptionValue("type"); ok {
+		return dt, nullable
+	}
+	if dataType != "" {
+		return dataType, nullable
+	}
+	if c.HasOption("json") || t == rawMessageType {
+		return "jsonb", nullable
+	}
+	if c.HasOption("composite") && t.Kind() == reflect.Struct {
+		return structref.ToSnakeCase(t.Name()), nullable
+	}
+	return dataTypeOf(t), nullable
+}
+
+// dataTypeOf returns the PostgreSQL data type for a Go type.
+func dataTypeOf(t reflect.Type) string {
+	switch t {
+	case timeType:
+		return "timestamp with time zone"
+	case durationType:
+		return "interval"
+	case bytesType:
+		return "bytea"
+	case uuidType:
+		return "uuid"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "text"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "smallint"
+	case reflect.Int32, reflect.Uint16:
+		return "integer"
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "bigint"
+	case reflect.Uint, reflect.Uint64:
+		return "numeric(20)"
+	case reflect.Float32:
+		return "real"
+	case reflect.Float64:
+		return "double precision"
+	case reflect.Struct, reflect.Map:
+		return "jsonb"
+	case reflect.Slice, reflect.Array:
+		elem := t.Elem()
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		return dataTypeOf(elem) + "[]"
+	}
+	return "text"
+}
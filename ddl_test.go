@@ -0,0 +1,172 @@
+package pgtools_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools"
+)
+
+type Product struct {
+	ID          int64   `db:"id,pk"`
+	Name        string  `db:"name"`
+	Price       float64 `db:"price,type=numeric(10,2)"`
+	Description *string
+	Tags        []string
+	Attributes  map[string]string `db:"attributes,json"`
+	CreatedAt   time.Time
+}
+
+type PostalAddress struct {
+	Street string
+	City   string
+}
+
+func ExampleDDL() {
+	fmt.Println(pgtools.DDL(Product{}, ""))
+	// Output:
+	// CREATE TABLE products (
+	// 	"id" bigint NOT NULL,
+	// 	"name" text NOT NULL,
+	// 	"price" numeric(10,2) NOT NULL,
+	// 	"description" text,
+	// 	"tags" text[],
+	// 	"attributes" jsonb,
+	// 	"created_at" timestamp with time zone NOT NULL,
+	// 	PRIMARY KEY ("id")
+	// );
+}
+
+func TestDDL(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc  string
+		v     any
+		table string
+		want  string
+	}{
+		{
+			desc:  "nil",
+			v:     nil,
+			table: "nothing",
+			want:  "",
+		},
+		{
+			desc:  "empty",
+			v:     emptyEmbed{},
+			table: "nothing",
+			want:  "",
+		},
+		{
+			desc: "anonymous without table",
+			v:    struct{ ID string }{},
+			want: "",
+		},
+		{
+			desc:  "embed",
+			v:     mockEmbed{},
+			table: "embed",
+			want: `CREATE TABLE embed (
+	"before" bigint NOT NULL,
+	"automatic" text NOT NULL,
+	"tagged" text NOT NULL,
+	"one_two" text NOT NULL,
+	"CamelCase" text NOT NULL,
+	"after" text NOT NULL
+);`,
+		},
+		{
+			desc:  "nested",
+			v:     &HasPointerNestedMock{},
+			table: "nested",
+			want: `CREATE TABLE nested (
+	"id" text NOT NULL,
+	"name" text NOT NULL,
+	"code" text NOT NULL,
+	"is_active" boolean NOT NULL,
+	"theme" jsonb,
+	"created_at" timestamp with time zone NOT NULL,
+	"modified_at" timestamp with time zone NOT NULL
+);`,
+		},
+		{
+			desc:  "array",
+			table: "arrays",
+			v: struct {
+				Tags     []string  `db:"tags,array"`
+				Nullable *[]string `db:"nullable,array"`
+				Plain    []int64
+			}{},
+			want: `CREATE TABLE arrays (
+	"tags" text[] NOT NULL,
+	"nullable" text[],
+	"plain" bigint[]
+);`,
+		},
+		{
+			desc:  "composite",
+			table: "customers",
+			v: struct {
+				ID       string
+				Billing  PostalAddress  `db:"billing,composite"`
+				Shipping *PostalAddress `db:"shipping,composite"`
+				Home     PostalAddress  `db:"home,composite,type=home_address"`
+			}{},
+			want: `CREATE TABLE customers (
+	"id" text NOT NULL,
+	"billing" postal_address NOT NULL,
+	"shipping" postal_address,
+	"home" home_address NOT NULL
+);`,
+		},
+		{
+			desc: "types",
+			v: struct {
+				A int8
+				B int32
+				C uint
+				D float32
+				E []byte
+				F json.RawMessage
+				G time.Duration
+				H [16]byte
+				I sql.NullString
+				J sql.NullTime
+				K *sql.NullInt64
+				L []*int16
+				M complex64
+				N string `db:"n,pk,type=citext"`
+				O string `db:"o,pk"`
+			}{},
+			table: "types",
+			want: `CREATE TABLE types (
+	"a" smallint NOT NULL,
+	"b" integer NOT NULL,
+	"c" numeric(20) NOT NULL,
+	"d" real NOT NULL,
+	"e" bytea,
+	"f" jsonb,
+	"g" interval NOT NULL,
+	"h" uuid NOT NULL,
+	"i" text,
+	"j" timestamp with time zone,
+	"k" bigint,
+	"l" smallint[],
+	"m" text NOT NULL,
+	"n" citext NOT NULL,
+	"o" text NOT NULL,
+	PRIMARY KEY ("n","o")
+);`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := pgtools.DDL(tc.v, tc.table); tc.want != got {
+				t.Errorf("expected statement to be:\n%v\ngot:\n%v", tc.want, got)
+			}
+		})
+	}
+}
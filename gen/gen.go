@@ -0,0 +1,393 @@
+// Package gen generates code to query and scan Go structs without using reflection at runtime,
+// following the same rules of the "db" key of the struct field's tag used by the pgtools package.
+//
+// For each struct type, it generates a constant with the expression returned by pgtools.Wildcard,
+// and functions to scan a row into a value of the type, as in:
+//
+//	// UserColumns is the expression of the columns of User, as returned by pgtools.Wildcard.
+//	const UserColumns = "\"username\",\"full_name\",\"email\""
+//
+//	// ScanUser scans a row queried using UserColumns into a User.
+//	func ScanUser(row pgx.Row) (User, error)
+//
+//	// RowToUser is a pgx.RowToFunc to use with pgx.CollectRows and pgx.CollectOneRow
+//	// for rows queried using UserColumns.
+//	func RowToUser(row pgx.CollectableRow) (User, error)
+//
+// Use it with go generate through the pgtoolsgen command, as in:
+//
+//	//go:generate go run github.com/henvic/pgtools/cmd/pgtoolsgen -type=User
+//
+// In the other direction, Structs generates struct types mapping the columns of tables
+// described by the introspect package, as used by the pgtools gen command.
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/henvic/pgtools/internal/typeref"
+)
+
+// Annotation of the struct types to generate code for when no type is given.
+const Annotation = "//pgtools:generate"
+
+// Config of the generator.
+type Config struct {
+	// Dir of the package. If empty, the current directory is used.
+	Dir string
+
+	// Types to generate code for. If empty, the types annotated with a "//pgtools:generate"
+	// line in their doc comment are used.
+	Types []string
+
+	// Output is the name of the generated file, which is ignored when loading the package,
+	// so a previously generated version of it doesn't interfere with the new one.
+	Output string
+}
+
+// Generate returns the formatted source code of a file for the package in the given directory.
+func Generate(c Config) ([]byte, error) {
+	dir := c.Dir
+	if dir == "" {
+		dir = "."
+	}
+	pkg, files, typeErr, err := load(dir, c.Output)
+	if err != nil {
+		return nil, err
+	}
+	names := c.Types
+	if len(names) == 0 {
+		names = annotated(files)
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no types to generate code for")
+	}
+
+	g := &generator{
+		pkg:     pkg,
+		typeErr: typeErr,
+		imports: map[string]string{},
+	}
+	for _, name := range names {
+		if err := g.generate(name); err != nil {
+			return nil, err
+		}
+	}
+	return g.format()
+}
+
+// load parses and type-checks the package in dir, skipping the output file.
+//
+// Type errors unrelated to the struct types, such as the ones of a generated file that
+// became stale, shouldn't prevent generating code, so the first one is returned separately.
+func load(dir, output string) (pkg *types.Package, files []*ast.File, typeErr error, err error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot load package: %w", err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range bp.GoFiles {
+		if name == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error: func(err error) {
+			if typeErr == nil {
+				typeErr = err
+			}
+		},
+	}
+	pkg, _ = conf.Check(bp.ImportPath, fset, files, nil)
+	return pkg, files, typeErr, nil
+}
+
+// annotated returns the names of the types annotated for generating code.
+func annotated(files []*ast.File) []string {
+	var names []string
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gd.Specs) == 1 {
+					doc = gd.Doc
+				}
+				if hasAnnotation(doc) {
+					names = append(names, ts.Name.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+func hasAnnotation(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == Annotation {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcard returns the same expression as pgtools.Wildcard for the columns.
+func wildcard(columns []typeref.Column) string {
+	var b strings.Builder
+	for n, c := range columns {
+		if n != 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(`"`)
+		b.WriteString(c.Name)
+		b.WriteString(`"`)
+		if strings.ContainsRune(c.Name, '.') {
+			b.WriteString(` as "`)
+			b.WriteString(c.Name)
+			b.WriteString(`"`)
+		}
+	}
+	return b.String()
+}
+
+type generator struct {
+	pkg     *types.Package
+	typeErr error
+
+	structs []structData
+	imports map[string]string // Import path to package name.
+}
+
+// structData used by the template.
+type structData struct {
+	Name    string
+	Columns string
+	Allocs  []allocData
+	Dest    []string
+	JSON    []jsonData
+}
+
+type allocData struct {
+	Selector string
+	Type     string
+}
+
+type jsonData struct {
+	Var      string
+	Selector string
+	Type     string
+}
+
+// qualifier for the types of other packages, recording their imports.
+func (g *generator) qualifier(p *types.Package) string {
+	if p == g.pkg {
+		return ""
+	}
+	g.imports[p.Path()] = p.Name()
+	return p.Name()
+}
+
+// generate code for the struct type with the given name.
+func (g *generator) generate(name string) error {
+	obj := g.pkg.Scope().Lookup(name)
+	if obj == nil {
+		if g.typeErr != nil {
+			return fmt.Errorf("type %s not found: %w", name, g.typeErr)
+		}
+		return fmt.Errorf("type %s not found", name)
+	}
+	tn, ok := obj.(*types.TypeName)
+	if !ok {
+		return fmt.Errorf("%s is not a type", name)
+	}
+	if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() != 0 {
+		return fmt.Errorf("type %s is generic", name)
+	}
+	st, ok := tn.Type().Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("type %s is not a struct", name)
+	}
+	cols := typeref.Columns(st)
+	if len(cols) == 0 {
+		return fmt.Errorf("type %s has no columns", name)
+	}
+
+	data := structData{
+		Name:    name,
+		Columns: strconv.Quote(wildcard(cols)),
+	}
+	allocated := map[string]struct{}{}
+	for _, c := range cols {
+		if c.Type == types.Typ[types.Invalid] {
+			return fmt.Errorf("cannot resolve type of field %s.%s: %w", name, strings.Join(c.Path, "."), g.typeErr)
+		}
+		for _, a := range c.Allocs {
+			if _, ok := allocated[a.Selector]; ok {
+				continue
+			}
+			allocated[a.Selector] = struct{}{}
+			data.Allocs = append(data.Allocs, allocData{
+				Selector: a.Selector,
+				Type:     types.TypeString(a.Type, g.qualifier),
+			})
+		}
+		selector := strings.Join(c.Path, ".")
+		if !c.HasOption("json") {
+			data.Dest = append(data.Dest, "&v."+selector)
+			continue
+		}
+		v := "b" + strconv.Itoa(len(data.JSON))
+		data.Dest = append(data.Dest, "&"+v)
+		data.JSON = append(data.JSON, jsonData{
+			Var:      v,
+			Selector: selector,
+			Type:     types.TypeString(c.Type, g.qualifier),
+		})
+	}
+	g.imports["github.com/jackc/pgx/v5"] = "pgx"
+	if len(data.JSON) != 0 {
+		g.imports["encoding/json"] = "json"
+		g.imports["fmt"] = "fmt"
+	}
+	g.structs = append(g.structs, data)
+	return nil
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`// Code generated by pgtoolsgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+{{range .Structs}}
+// {{.Name}}Columns is the expression of the columns of {{.Name}}, as returned by pgtools.Wildcard.
+const {{.Name}}Columns = {{.Columns}}
+
+// Scan{{.Name}} scans a row queried using {{.Name}}Columns into a {{.Name}}.
+func Scan{{.Name}}(row pgx.Row) ({{.Name}}, error) {
+	var v {{.Name}}
+{{- range .Allocs}}
+	v.{{.Selector}} = new({{.Type}})
+{{- end}}
+{{- range .JSON}}
+	var {{.Var}} []byte
+{{- end}}
+	if err := row.Scan(
+{{- range .Dest}}
+		{{.}},
+{{- end}}
+	); err != nil {
+		return v, err
+	}
+{{- range .JSON}}
+	if {{.Var}} != nil {
+		if err := json.Unmarshal({{.Var}}, &v.{{.Selector}}); err != nil {
+			return v, fmt.Errorf("cannot unmarshal JSON into {{.Type}}: %w", err)
+		}
+	}
+{{- end}}
+	return v, nil
+}
+
+// RowTo{{.Name}} is a pgx.RowToFunc to use with pgx.CollectRows and pgx.CollectOneRow
+// for rows queried using {{.Name}}Columns.
+func RowTo{{.Name}}(row pgx.CollectableRow) ({{.Name}}, error) {
+	return Scan{{.Name}}(row)
+}
+{{end}}`))
+
+// format the generated code.
+func (g *generator) format() ([]byte, error) {
+	var b bytes.Buffer
+	if err := fileTemplate.Execute(&b, struct {
+		Package string
+		Imports []string
+		Structs []structData
+	}{
+		Package: g.pkg.Name(),
+		Imports: importSpecs(g.imports),
+		Structs: g.structs,
+	}); err != nil {
+		return nil, err
+	}
+	return formatSource(b.Bytes())
+}
+
+// importSpecs returns the import specs for a map of import paths to package names.
+func importSpecs(imports map[string]string) []string {
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	// Group the imports of the standard library before the other ones, like goimports does.
+	sort.SliceStable(paths, func(i, j int) bool {
+		return isStd(paths[i]) && !isStd(paths[j])
+	})
+	specs := make([]string, 0, len(paths)+1)
+	for n, path := range paths {
+		if n != 0 && isStd(paths[n-1]) && !isStd(path) {
+			specs = append(specs, "")
+		}
+		spec := strconv.Quote(path)
+		if name := imports[path]; name != pathName(path) {
+			spec = name + " " + spec
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+func formatSource(b []byte) ([]byte, error) {
+	src, err := format.Source(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot format generated code: %w", err)
+	}
+	return src, nil
+}
+
+// isStd reports whether the import path is of a package of the standard library.
+func isStd(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// pathName returns the name a package is imported as by default, ignoring major version suffixes.
+func pathName(path string) string {
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
+	}
+	return name
+}
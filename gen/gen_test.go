@@ -0,0 +1,109 @@
+package gen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/henvic/pgtools"
+	"github.com/henvic/pgtools/gen"
+	"github.com/henvic/pgtools/gen/internal/testmodels"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	const dir = "internal/testmodels"
+	got, err := gen.Generate(gen.Config{
+		Dir:    dir,
+		Output: "pgtools_gen.go",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, "pgtools_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(want) != string(got) {
+		t.Errorf("generated code is outdated, run go generate ./...:\n%s", got)
+	}
+}
+
+func TestGenerateTypes(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	src := `package models
+
+type Names []string
+
+type Page[T any] struct {
+	Items []T
+}
+
+type Empty struct{}
+
+type Book struct {
+	Title string
+	Shelf Shelf
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		desc    string
+		types   []string
+		wantErr string
+	}{
+		{
+			desc:    "no types",
+			wantErr: "no types to generate code for",
+		},
+		{
+			desc:    "not found",
+			types:   []string{"Missing"},
+			wantErr: "type Missing not found: " + filepath.Join(dir, "models.go") + ":13:8: undefined: Shelf",
+		},
+		{
+			desc:    "not a struct",
+			types:   []string{"Names"},
+			wantErr: "type Names is not a struct",
+		},
+		{
+			desc:    "generic",
+			types:   []string{"Page"},
+			wantErr: "type Page is generic",
+		},
+		{
+			desc:    "empty",
+			types:   []string{"Empty"},
+			wantErr: "type Empty has no columns",
+		},
+		{
+			desc:    "invalid field type",
+			types:   []string{"Book"},
+			wantErr: "cannot resolve type of field Book.Shelf: " + filepath.Join(dir, "models.go") + ":13:8: undefined: Shelf",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := gen.Generate(gen.Config{
+				Dir:   dir,
+				Types: tc.types,
+			})
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("expected error %q, got %v instead", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestColumns(t *testing.T) {
+	t.Parallel()
+	if want := pgtools.Wildcard(testmodels.User{}); testmodels.UserColumns != want {
+		t.Errorf("expected UserColumns to be %v, got %v instead", want, testmodels.UserColumns)
+	}
+	if want := pgtools.Wildcard(testmodels.Post{}); testmodels.PostColumns != want {
+		t.Errorf("expected PostColumns to be %v, got %v instead", want, testmodels.PostColumns)
+	}
+}
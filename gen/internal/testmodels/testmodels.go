@@ -0,0 +1,66 @@
+// Package testmodels contains struct types to test the code generated by the gen package.
+package testmodels
+
+import "time"
+
+//go:generate go run github.com/henvic/pgtools/cmd/pgtoolsgen
+
+// User model.
+//
+//pgtools:generate
+type User struct {
+	ID        int64 `db:"id,pk,generated"`
+	Username  string
+	FullName  string
+	Theme     Theme `db:"theme,json"`
+	Settings  *Settings
+	Tags      []string `db:"tags,array"`
+	Password  string   `db:"-"`
+	CreatedAt time.Time
+	base
+
+	secret string
+}
+
+// Theme of the user.
+type Theme struct {
+	PrimaryColor string
+	TextColor    string
+}
+
+// Settings of the user.
+type Settings struct {
+	Locale        string
+	Notifications *Notifications
+}
+
+// Notifications settings.
+type Notifications struct {
+	Email bool
+}
+
+type base struct {
+	Version int
+}
+
+// Post model.
+//
+//pgtools:generate
+type Post struct {
+	ID       string `db:"id,pk"`
+	Author   int64
+	Title    string
+	Location Location          `db:"location,composite"`
+	Metadata map[string]string `db:"metadata,json"`
+}
+
+// Location of a post.
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Filter isn't annotated.
+type Filter struct {
+	Author int64
+}
@@ -0,0 +1,72 @@
+package testmodels
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeRow returns the given values when scanned.
+type fakeRow []any
+
+func (r fakeRow) Scan(dest ...any) error {
+	for i, d := range dest {
+		if r[i] != nil {
+			reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r[i]))
+		}
+	}
+	return nil
+}
+
+func TestScanUser(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	row := fakeRow{
+		int64(1), "henvic", "Henrique Vicente", []byte(`{"PrimaryColor":"blue"}`),
+		"pt-BR", true, nil, nil,
+		[]string{"admin"}, now, 3,
+	}
+	got, err := ScanUser(row)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := User{
+		ID:       1,
+		Username: "henvic",
+		FullName: "Henrique Vicente",
+		Theme:    Theme{PrimaryColor: "blue"},
+		Settings: &Settings{
+			Locale:        "pt-BR",
+			Notifications: &Notifications{Email: true},
+		},
+		Tags:      []string{"admin"},
+		CreatedAt: now,
+		base:      base{Version: 3},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected user to be %+v, got %+v instead", want, got)
+	}
+}
+
+func TestScanPostInvalidJSON(t *testing.T) {
+	t.Parallel()
+	var rowTo pgx.RowToFunc[Post] = RowToPost
+	row := fakeRow{"a", int64(1), "title", Location{}, []byte(`[]`)}
+	_, err := rowTo(collectableRow{row})
+	want := "cannot unmarshal JSON into map[string]string: json: cannot unmarshal array into Go value of type map[string]string"
+	if err == nil || err.Error() != want {
+		t.Errorf("expected error %q, got %v instead", want, err)
+	}
+}
+
+// collectableRow implements pgx.CollectableRow for a fakeRow.
+type collectableRow struct {
+	fakeRow
+}
+
+func (collectableRow) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (collectableRow) Values() ([]any, error)                       { return nil, nil }
+func (collectableRow) RawValues() [][]byte                          { return nil }
@@ -0,0 +1,228 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/token"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/henvic/pgtools/internal/structref"
+	"github.com/henvic/pgtools/introspect"
+)
+
+// Table to generate a struct type for.
+type Table struct {
+	// Name of the table, which might be qualified with a schema, as in "public.users".
+	Name string
+
+	// Columns of the table, as returned by introspect.Columns.
+	Columns []introspect.Column
+}
+
+// Structs returns the formatted source code of a file of the given package declaring
+// a struct type for each table, with db tags mapping its fields to the columns of the table.
+//
+// The name of the struct type is the singular CamelCase form of the table name, as in
+// UserProfile for user_profiles. The "pk", "generated", "readonly", "json", and "array" options
+// are set for the columns they apply to, and nullable columns are mapped to pointers.
+func Structs(pkg string, tables []Table) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+	if len(tables) == 0 {
+		return nil, errors.New("no tables to generate code for")
+	}
+	imports := map[string]string{}
+	data := make([]tableData, 0, len(tables))
+	for _, t := range tables {
+		if len(t.Columns) == 0 {
+			return nil, fmt.Errorf("table %q has no columns", t.Name)
+		}
+		_, name, ok := strings.Cut(t.Name, ".")
+		if !ok {
+			name = t.Name
+		}
+		td := tableData{
+			Name:  goName(structref.Singularize(name)),
+			Table: t.Name,
+		}
+		// Set the table name explicitly when pgtools.TableName can't derive it from the type name.
+		if structref.Pluralize(structref.ToSnakeCase(td.Name)) != t.Name {
+			td.Tag = "`table:" + `"` + t.Name + `"` + "`"
+			imports["github.com/henvic/pgtools"] = "pgtools"
+		}
+		for _, c := range t.Columns {
+			typ, path := goType(c)
+			if path != "" {
+				imports[path] = pathName(path)
+			}
+			td.Fields = append(td.Fields, fieldData{
+				Name: goName(c.Name),
+				Type: typ,
+				Tag:  "`db:" + `"` + strings.Join(append([]string{c.Name}, columnOptions(c)...), ",") + `"` + "`",
+			})
+		}
+		data = append(data, td)
+	}
+
+	var b bytes.Buffer
+	if err := structsTemplate.Execute(&b, struct {
+		Package string
+		Imports []string
+		Tables  []tableData
+	}{
+		Package: pkg,
+		Imports: importSpecs(imports),
+		Tables:  data,
+	}); err != nil {
+		return nil, err
+	}
+	return formatSource(b.Bytes())
+}
+
+// tableData used by the template.
+type tableData struct {
+	Name   string
+	Table  string
+	Tag    string
+	Fields []fieldData
+}
+
+type fieldData struct {
+	Name string
+	Type string
+	Tag  string
+}
+
+var structsTemplate = template.Must(template.New("structs").Parse(`// Code generated by pgtools gen. DO NOT EDIT.
+
+package {{.Package}}
+{{if .Imports}}
+import (
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+{{end}}
+{{- range .Tables}}
+// {{.Name}} is mapped from the {{.Table}} table.
+type {{.Name}} struct {
+{{- if .Tag}}
+	pgtools.Table {{.Tag}}
+{{end}}
+{{- range .Fields}}
+	{{.Name}} {{.Type}} {{.Tag}}
+{{- end}}
+}
+{{end}}`))
+
+// columnOptions returns the options of the db tag for the column.
+func columnOptions(c introspect.Column) []string {
+	var opts []string
+	if c.PrimaryKey {
+		opts = append(opts, "pk")
+	}
+	switch {
+	case c.Generated:
+		opts = append(opts, "readonly")
+	case c.Identity, strings.HasPrefix(c.Default, "nextval("), c.PrimaryKey && c.Default != "":
+		// Serial columns, and primary keys such as uuid columns with a DEFAULT gen_random_uuid().
+		opts = append(opts, "generated")
+	}
+	switch {
+	case c.UDTName == "json" || c.UDTName == "jsonb":
+		opts = append(opts, "json")
+	case c.DataType == "ARRAY":
+		opts = append(opts, "array")
+	}
+	return opts
+}
+
+// goTypes of PostgreSQL data types by their udt_name, and the import path of their package.
+var goTypes = map[string][2]string{
+	"bool":        {"bool"},
+	"int2":        {"int16"},
+	"int4":        {"int32"},
+	"int8":        {"int64"},
+	"float4":      {"float32"},
+	"float8":      {"float64"},
+	"numeric":     {"pgtype.Numeric", "github.com/jackc/pgx/v5/pgtype"},
+	"text":        {"string"},
+	"varchar":     {"string"},
+	"bpchar":      {"string"},
+	"name":        {"string"},
+	"citext":      {"string"},
+	"uuid":        {"string"},
+	"bytea":       {"[]byte"},
+	"date":        {"time.Time", "time"},
+	"timestamp":   {"time.Time", "time"},
+	"timestamptz": {"time.Time", "time"},
+	"interval":    {"pgtype.Interval", "github.com/jackc/pgx/v5/pgtype"},
+	"inet":        {"netip.Prefix", "net/netip"},
+	"cidr":        {"netip.Prefix", "net/netip"},
+	"json":        {"json.RawMessage", "encoding/json"},
+	"jsonb":       {"json.RawMessage", "encoding/json"},
+}
+
+// goType returns the Go type for the column, and the import path of its package.
+func goType(c introspect.Column) (typ, path string) {
+	udt := c.UDTName
+	if c.DataType == "ARRAY" {
+		udt = strings.TrimPrefix(udt, "_")
+	}
+	t, ok := goTypes[udt]
+	switch {
+	case ok:
+		typ, path = t[0], t[1]
+	case c.DataType == "USER-DEFINED":
+		// Enum types are encoded as text.
+		typ = "string"
+	default:
+		typ = "any"
+	}
+	if c.DataType == "ARRAY" {
+		return "[]" + typ, path
+	}
+	if c.Nullable && typ != "any" && !strings.HasPrefix(typ, "[]") && typ != "json.RawMessage" {
+		typ = "*" + typ
+	}
+	return typ, path
+}
+
+// initialisms written in upper case in Go identifiers.
+var initialisms = map[string]struct{}{
+	"api":  {},
+	"html": {},
+	"http": {},
+	"id":   {},
+	"ip":   {},
+	"json": {},
+	"sql":  {},
+	"uri":  {},
+	"url":  {},
+	"uuid": {},
+}
+
+// goName returns the CamelCase form of a snake_case name, as in UserID for user_id.
+func goName(s string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if _, ok := initialisms[strings.ToLower(part)]; ok {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		runes := []rune(part)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
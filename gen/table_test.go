@@ -0,0 +1,112 @@
+package gen_test
+
+import (
+	"testing"
+
+	"github.com/henvic/pgtools/gen"
+	"github.com/henvic/pgtools/introspect"
+)
+
+func TestStructs(t *testing.T) {
+	t.Parallel()
+	tables := []gen.Table{
+		{
+			Name: "user_profiles",
+			Columns: []introspect.Column{
+				{Name: "id", DataType: "bigint", UDTName: "int8", PrimaryKey: true, Identity: true},
+				{Name: "user_id", DataType: "uuid", UDTName: "uuid"},
+				{Name: "full_name", DataType: "text", UDTName: "text"},
+				{Name: "bio", DataType: "text", UDTName: "text", Nullable: true},
+				{Name: "search", DataType: "tsvector", UDTName: "tsvector", Generated: true},
+				{Name: "settings", DataType: "jsonb", UDTName: "jsonb", Nullable: true},
+				{Name: "tags", DataType: "ARRAY", UDTName: "_text"},
+				{Name: "status", DataType: "USER-DEFINED", UDTName: "status_type"},
+				{Name: "balance", DataType: "numeric", UDTName: "numeric"},
+				{Name: "created_at", DataType: "timestamp with time zone", UDTName: "timestamptz", Default: "now()"},
+				{Name: "deleted_at", DataType: "timestamp with time zone", UDTName: "timestamptz", Nullable: true},
+			},
+		},
+		{
+			Name: "public.categories",
+			Columns: []introspect.Column{
+				{Name: "id", DataType: "uuid", UDTName: "uuid", PrimaryKey: true, Default: "gen_random_uuid()"},
+				{Name: "serial", DataType: "integer", UDTName: "int4", Default: "nextval('categories_serial_seq'::regclass)"},
+			},
+		},
+	}
+	got, err := gen.Structs("models", tables)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "// Code generated by pgtools gen. DO NOT EDIT.\n\n" + `package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/henvic/pgtools"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// UserProfile is mapped from the user_profiles table.
+type UserProfile struct {
+	ID        int64           ` + "`" + `db:"id,pk,generated"` + "`" + `
+	UserID    string          ` + "`" + `db:"user_id"` + "`" + `
+	FullName  string          ` + "`" + `db:"full_name"` + "`" + `
+	Bio       *string         ` + "`" + `db:"bio"` + "`" + `
+	Search    any             ` + "`" + `db:"search,readonly"` + "`" + `
+	Settings  json.RawMessage ` + "`" + `db:"settings,json"` + "`" + `
+	Tags      []string        ` + "`" + `db:"tags,array"` + "`" + `
+	Status    string          ` + "`" + `db:"status"` + "`" + `
+	Balance   pgtype.Numeric  ` + "`" + `db:"balance"` + "`" + `
+	CreatedAt time.Time       ` + "`" + `db:"created_at"` + "`" + `
+	DeletedAt *time.Time      ` + "`" + `db:"deleted_at"` + "`" + `
+}
+
+// Category is mapped from the public.categories table.
+type Category struct {
+	pgtools.Table ` + "`" + `table:"public.categories"` + "`" + `
+
+	ID     string ` + "`" + `db:"id,pk,generated"` + "`" + `
+	Serial int32  ` + "`" + `db:"serial,generated"` + "`" + `
+}
+`
+	if string(got) != want {
+		t.Errorf("expected generated code to be:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestStructsErrors(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc    string
+		pkg     string
+		tables  []gen.Table
+		wantErr string
+	}{
+		{
+			desc:    "invalid package",
+			pkg:     "my-models",
+			wantErr: `invalid package name "my-models"`,
+		},
+		{
+			desc:    "no tables",
+			pkg:     "models",
+			wantErr: "no tables to generate code for",
+		},
+		{
+			desc:    "no columns",
+			pkg:     "models",
+			tables:  []gen.Table{{Name: "users"}},
+			wantErr: `table "users" has no columns`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := gen.Structs(tc.pkg, tc.tables)
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("expected error to be %v, got %v instead", tc.wantErr, err)
+			}
+		})
+	}
+}
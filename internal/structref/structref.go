@@ -13,6 +13,7 @@ package structref
 import (
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -24,9 +25,115 @@ type toTraverse struct {
 	ColumnPrefix string
 }
 
+// Column mapped from a struct field.
+type Column struct {
+	// Name of the column.
+	Name string
+
+	// Index sequence of the struct field, as used by reflect.Value.FieldByIndex.
+	Index []int
+
+	// Type of the struct field.
+	Type reflect.Type
+
+	options tagOptions
+}
+
+// HasOption reports whether the db tag of the struct field contains the given option.
+func (c Column) HasOption(name string) bool {
+	return c.options.Contains(name)
+}
+
+// Options of the db tag of the struct field.
+// Commas inside parentheses don't separate options, as in type=numeric(10,2).
+func (c Column) Options() []string {
+	return SplitOptions(string(c.options))
+}
+
+// SplitOptions splits the options following the column name in a db tag.
+// Commas inside parentheses don't separate options, as in type=numeric(10,2).
+func SplitOptions(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var (
+		options []string
+		depth   int
+		start   int
+	)
+	for i, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case r == ',' && depth == 0:
+			options = append(options, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(options, s[start:])
+}
+
+// OptionValue returns the value of a key=value option of the db tag of the struct field.
+func (c Column) OptionValue(key string) (value string, ok bool) {
+	for _, o := range c.Options() {
+		if k, v, found := strings.Cut(o, "="); found && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
 // GetColumnToFieldIndexMap containing where columns should be mapped.
 func GetColumnToFieldIndexMap(structType reflect.Type) map[string][]int {
 	result := make(map[string][]int, structType.NumField())
+	for _, c := range getColumns(structType, true) {
+		result[c.Name] = c.Index
+	}
+	return result
+}
+
+// GetColumns returns the columns mapped from the fields of a struct, in the order the fields are declared.
+// Columns of nested structs are ordered right before the column of the field containing them.
+func GetColumns(structType reflect.Type) []Column {
+	return sortColumns(getColumns(structType, true))
+}
+
+// GetAllColumns is like GetColumns, but it doesn't skip fields mapped to a column name already in use,
+// as when a struct is used to filter data, and two fields set different conditions for the same column.
+func GetAllColumns(structType reflect.Type) []Column {
+	return sortColumns(getColumns(structType, false))
+}
+
+func sortColumns(columns []Column) []Column {
+	// Make the output stable with respect to the struct fields in order.
+	sort.SliceStable(columns, func(i, j int) bool {
+		a, b := columns[i].Index, columns[j].Index
+		// Go inwards each nested field until the end:
+		// indices a and b represent the path to the left and right fields being sorted.
+		for {
+			switch {
+			case len(a) == 0:
+				return false
+			case len(b) == 0:
+				return true
+			case a[0] < b[0]:
+				return true
+			case a[0] > b[0]:
+				return false
+			}
+			a, b = a[1:], b[1:]
+		}
+	})
+	return columns
+}
+
+// getColumns traverses the struct breadth-first, so that a column name is mapped
+// to the shallowest field using it when unique is set.
+func getColumns(structType reflect.Type, unique bool) []Column {
+	var result []Column
+	seen := map[string]struct{}{}
 	jsonColumns := map[string]struct{}{}
 	var queue []*toTraverse
 	queue = append(queue, &toTraverse{Type: structType, IndexPrefix: nil, ColumnPrefix: ""})
@@ -58,7 +165,7 @@ func GetColumnToFieldIndexMap(structType reflect.Type) map[string][]int {
 
 			columnPart := dbTag
 			if !dbTagPresent || columnPart == "" {
-				columnPart = toSnakeCase(field.Name)
+				columnPart = ToSnakeCase(field.Name)
 			}
 
 			childType := field.Type
@@ -76,9 +183,12 @@ func GetColumnToFieldIndexMap(structType reflect.Type) map[string][]int {
 
 			column := buildColumn(traversal.ColumnPrefix, columnPart)
 			if childType.Kind() == reflect.Struct {
-				if options.Contains("json") {
+				switch {
+				case options.Contains("json"):
 					jsonColumns[column] = struct{}{}
-				} else {
+				case options.Contains("composite") && !field.Anonymous:
+					// The struct is mapped to a single column of a composite type, so its fields aren't traversed.
+				default:
 					queue = append(queue, &toTraverse{
 						Type:         childType,
 						IndexPrefix:  index,
@@ -90,8 +200,14 @@ func GetColumnToFieldIndexMap(structType reflect.Type) map[string][]int {
 				_, self := jsonColumns[column]
 				_, parent := jsonColumns[traversal.ColumnPrefix]
 				if !self || !parent {
-					if _, exists := result[column]; !exists {
-						result[column] = index
+					if _, exists := seen[column]; !exists || !unique {
+						seen[column] = struct{}{}
+						result = append(result, Column{
+							Name:    column,
+							Index:   index,
+							Type:    field.Type,
+							options: options,
+						})
 					}
 				}
 			}
@@ -116,8 +232,35 @@ var (
 	matchAllCapRe   = regexp.MustCompile("([a-z0-9])([A-Z])")
 )
 
-func toSnakeCase(str string) string {
+// ToSnakeCase converts a Go identifier such as FullName to the snake_case form full_name.
+func ToSnakeCase(str string) string {
 	snake := matchFirstCapRe.ReplaceAllString(str, "${1}_${2}")
 	snake = matchAllCapRe.ReplaceAllString(snake, "${1}_${2}")
 	return strings.ToLower(snake)
 }
+
+// Pluralize an English noun using simple rules.
+func Pluralize(s string) string {
+	switch {
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "z"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	}
+	return s + "s"
+}
+
+// Singularize an English noun pluralized by Pluralize.
+func Singularize(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies") && len(s) > 3 && !strings.ContainsRune("aeiou", rune(s[len(s)-4])):
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(s, "ses"), strings.HasSuffix(s, "xes"), strings.HasSuffix(s, "zes"),
+		strings.HasSuffix(s, "ches"), strings.HasSuffix(s, "shes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "s") && !strings.HasSuffix(s, "ss"):
+		return s[:len(s)-1]
+	}
+	return s
+}
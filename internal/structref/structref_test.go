@@ -108,3 +108,148 @@ func TestGetColumnToFieldIndexMap(t *testing.T) {
 		})
 	}
 }
+
+func TestGetColumns(t *testing.T) {
+	type Embed struct {
+		Play bool
+	}
+	type Nested struct {
+		ID string
+	}
+	v := struct {
+		ID     string
+		Theme  Nested `db:"theme,json"`
+		Nested Nested
+		Embed
+		Last string `db:"last,other"`
+	}{}
+	got := GetColumns(reflect.TypeOf(v))
+	want := []struct {
+		name  string
+		index []int
+		json  bool
+	}{
+		{"id", []int{0}, false},
+		{"theme", []int{1}, true},
+		{"nested.id", []int{2, 0}, false},
+		{"nested", []int{2}, false},
+		{"play", []int{3, 0}, false},
+		{"last", []int{4}, false},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GetColumns() returned %d columns, want %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		c := got[i]
+		if c.Name != w.name || !reflect.DeepEqual(c.Index, w.index) || c.HasOption("json") != w.json {
+			t.Errorf("GetColumns()[%d] = %v (json: %v), want %v", i, c, c.HasOption("json"), w)
+		}
+	}
+	if !got[5].HasOption("other") {
+		t.Error("expected column last to have option other")
+	}
+	if opts := got[5].Options(); !reflect.DeepEqual(opts, []string{"other"}) {
+		t.Errorf("expected column last to have options [other], got %v instead", opts)
+	}
+	if opts := got[0].Options(); opts != nil {
+		t.Errorf("expected column id to have no options, got %v instead", opts)
+	}
+	if typ := got[1].Type; typ != reflect.TypeOf(Nested{}) {
+		t.Errorf("expected column theme to have type Nested, got %v instead", typ)
+	}
+}
+
+func TestGetColumnsNested(t *testing.T) {
+	type Address struct {
+		Street string
+		City   string
+	}
+	type Embed struct {
+		Play bool
+	}
+	v := struct {
+		Home     Address  `db:"home"`
+		Work     Address  `db:"work,flatten"`
+		Billing  Address  `db:"billing,composite"`
+		Shipping *Address `db:"shipping,composite"`
+		Embed    `db:",composite"`
+	}{}
+	var got []string
+	for _, c := range GetColumns(reflect.TypeOf(v)) {
+		got = append(got, c.Name)
+	}
+	want := []string{
+		"home.street", "home.city", "home",
+		"work.street", "work.city", "work",
+		"billing",
+		"shipping",
+		"play",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetColumns() = %v, want %v", got, want)
+	}
+}
+
+func TestGetAllColumns(t *testing.T) {
+	v := struct {
+		Min  int `db:"n,gte"`
+		Max  int `db:"n,lte"`
+		Name string
+	}{}
+	var got []string
+	for _, c := range GetAllColumns(reflect.TypeOf(v)) {
+		got = append(got, c.Name)
+	}
+	if want := []string{"n", "n", "name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAllColumns() = %v, want %v", got, want)
+	}
+	if got := GetColumns(reflect.TypeOf(v)); len(got) != 2 {
+		t.Errorf("GetColumns() = %v, want 2 columns", got)
+	}
+}
+
+func TestColumnOptions(t *testing.T) {
+	v := struct {
+		Price float64 `db:"price,type=numeric(10,2),readonly"`
+		Other string  `db:"other,type=text"`
+		None  string
+	}{}
+	columns := GetColumns(reflect.TypeOf(v))
+	if got, want := columns[0].Options(), []string{"type=numeric(10,2)", "readonly"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Options() = %v, want %v", got, want)
+	}
+	if got, ok := columns[0].OptionValue("type"); !ok || got != "numeric(10,2)" {
+		t.Errorf("OptionValue() = %v, %v, want numeric(10,2), true", got, ok)
+	}
+	if got, ok := columns[1].OptionValue("type"); !ok || got != "text" {
+		t.Errorf("OptionValue() = %v, %v, want text, true", got, ok)
+	}
+	if got, ok := columns[2].OptionValue("type"); ok || got != "" {
+		t.Errorf("OptionValue() = %v, %v, want empty", got, ok)
+	}
+}
+
+func TestSingularize(t *testing.T) {
+	tests := []struct {
+		singular string
+		plural   string
+	}{
+		{"user", "users"},
+		{"user_profile", "user_profiles"},
+		{"category", "categories"},
+		{"day", "days"},
+		{"address", "addresses"},
+		{"status", "statuses"},
+		{"box", "boxes"},
+		{"match", "matches"},
+		{"wish", "wishes"},
+	}
+	for _, tt := range tests {
+		if got := Pluralize(tt.singular); got != tt.plural {
+			t.Errorf("Pluralize(%q) = %q, want %q", tt.singular, got, tt.plural)
+		}
+		if got := Singularize(tt.plural); got != tt.singular {
+			t.Errorf("Singularize(%q) = %q, want %q", tt.plural, got, tt.singular)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package typeref provides the columns for a struct type using go/types,
+// following the same rules of the structref package for code analysis and generation.
+package typeref
+
+import (
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/henvic/pgtools/internal/structref"
+)
+
+// Column mapped from a struct field, following the same rules as pgtools.Fields.
+type Column struct {
+	Name    string
+	Index   []int    // Index sequence of the struct field, used for sorting.
+	Path    []string // Names of the fields to select the struct field.
+	Allocs  []Alloc  // Pointers to nested structs to allocate before scanning.
+	Type    types.Type
+	Options string
+}
+
+// HasOption reports whether the column has the given option.
+func (c Column) HasOption(name string) bool {
+	return HasOption(c.Options, name)
+}
+
+// Alloc is a pointer to a nested struct.
+type Alloc struct {
+	Selector string
+	Type     types.Type // Type of the struct pointed to.
+}
+
+type toTraverse struct {
+	st           *types.Struct
+	indexPrefix  []int
+	pathPrefix   []string
+	allocs       []Alloc
+	columnPrefix string
+}
+
+// Columns of the struct, traversed breadth-first like pgtools.Fields does.
+func Columns(st *types.Struct) []Column {
+	var result []Column
+	seen := map[string]struct{}{}
+	jsonColumns := map[string]struct{}{}
+	queue := []*toTraverse{{st: st}}
+	for len(queue) > 0 {
+		traversal := queue[0]
+		queue = queue[1:]
+		for i := 0; i < traversal.st.NumFields(); i++ {
+			field := traversal.st.Field(i)
+			if !field.Exported() && !field.Embedded() {
+				// Field is unexported, skip it.
+				continue
+			}
+
+			dbTag, dbTagPresent := reflect.StructTag(traversal.st.Tag(i)).Lookup("db")
+			var options string
+			if dbTagPresent {
+				dbTag, options, _ = strings.Cut(dbTag, ",")
+			}
+			if dbTag == "-" {
+				// Field is ignored, skip it.
+				continue
+			}
+
+			index := append(append([]int(nil), traversal.indexPrefix...), i)
+			path := append(append([]string(nil), traversal.pathPrefix...), field.Name())
+
+			columnPart := dbTag
+			if !dbTagPresent || columnPart == "" {
+				columnPart = structref.ToSnakeCase(field.Name())
+			}
+
+			childType, pointer := field.Type(), false
+			if ptr, ok := childType.Underlying().(*types.Pointer); ok {
+				childType, pointer = ptr.Elem(), true
+			}
+			child, isStruct := childType.Underlying().(*types.Struct)
+			if isStruct && field.Embedded() {
+				columnPart = dbTag
+			}
+
+			name := buildColumn(traversal.columnPrefix, columnPart)
+			if isStruct {
+				switch {
+				case HasOption(options, "json"):
+					jsonColumns[name] = struct{}{}
+				case HasOption(options, "composite") && !field.Embedded():
+					// The struct is mapped to a single column of a composite type.
+				default:
+					allocs := traversal.allocs
+					if pointer {
+						allocs = append(append([]Alloc(nil), allocs...), Alloc{
+							Selector: strings.Join(path, "."),
+							Type:     childType,
+						})
+					}
+					queue = append(queue, &toTraverse{
+						st:           child,
+						indexPrefix:  index,
+						pathPrefix:   path,
+						allocs:       allocs,
+						columnPrefix: name,
+					})
+				}
+			}
+			if field.Embedded() {
+				continue
+			}
+			_, self := jsonColumns[name]
+			_, parent := jsonColumns[traversal.columnPrefix]
+			if self && parent {
+				continue
+			}
+			if _, exists := seen[name]; exists {
+				continue
+			}
+			seen[name] = struct{}{}
+			result = append(result, Column{
+				Name:    name,
+				Index:   index,
+				Path:    path,
+				Allocs:  traversal.allocs,
+				Type:    field.Type(),
+				Options: options,
+			})
+		}
+	}
+
+	// Make the output stable with respect to the struct fields in order,
+	// with columns of nested structs right before the column of the field containing them.
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i].Index, result[j].Index
+		for {
+			switch {
+			case len(a) == 0:
+				return false
+			case len(b) == 0:
+				return true
+			case a[0] < b[0]:
+				return true
+			case a[0] > b[0]:
+				return false
+			}
+			a, b = a[1:], b[1:]
+		}
+	})
+	return result
+}
+
+func buildColumn(parts ...string) string {
+	var notEmptyParts []string
+	for _, p := range parts {
+		if p != "" {
+			notEmptyParts = append(notEmptyParts, p)
+		}
+	}
+	return strings.Join(notEmptyParts, ".")
+}
+
+// hasOption reports whether the comma-separated options contain the given option.
+func HasOption(options, name string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package introspect_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools/introspect"
+	"github.com/henvic/pgtools/sqltest"
+)
+
+var force = flag.Bool("force", false, "Force cleaning the database before starting")
+
+func checkPostgres(t testing.TB) {
+	if os.Getenv("INTEGRATION_TESTDB") != "true" {
+		t.Skip("Skipping tests that require database connection")
+	}
+}
+
+type Post struct {
+	ID         string `db:"id,pk"`
+	Name       string
+	Message    string
+	CreatedAt  time.Time
+	ModifiedAt time.Time
+}
+
+func TestValidate(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("../sqltest/example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_introspect_",
+	})
+	pool := migration.Setup(ctx, "")
+
+	columns, err := introspect.Columns(ctx, pool, "public.posts")
+	if err != nil {
+		t.Fatalf("cannot get columns: %v", err)
+	}
+	want := []introspect.Column{
+		{Name: "id", DataType: "text", UDTName: "text", PrimaryKey: true},
+		{Name: "name", DataType: "text", UDTName: "text"},
+		{Name: "message", DataType: "text", UDTName: "text"},
+		{Name: "created_at", DataType: "timestamp with time zone", UDTName: "timestamptz", Default: "now()"},
+		{Name: "modified_at", DataType: "timestamp with time zone", UDTName: "timestamptz", Default: "now()"},
+	}
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("got columns %+v, want %+v", columns, want)
+	}
+
+	if err := introspect.Validate(ctx, pool, Post{}, "posts"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	migration.ValidateSchema(ctx, &Post{}, "posts")
+
+	type drifted struct {
+		ID      int
+		Name    string
+		Deleted bool
+	}
+	err = introspect.Validate(ctx, pool, drifted{}, "posts")
+	var me *introspect.MismatchError
+	if !errors.As(err, &me) {
+		t.Fatalf("expected *introspect.MismatchError, got %v instead", err)
+	}
+	wantProblems := []string{
+		`column "id" has type text, which is incompatible with int`,
+		`column "deleted" is missing`,
+	}
+	if !reflect.DeepEqual(me.Problems, wantProblems) {
+		t.Errorf("got problems %q, want %q", me.Problems, wantProblems)
+	}
+
+	if err := introspect.Validate(ctx, pool, Post{}, "posts", introspect.Strict()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	type partial struct {
+		ID      string `db:"id,pk"`
+		Name    *string
+		Message string
+	}
+	err = introspect.Validate(ctx, pool, partial{}, "posts", introspect.Strict())
+	if !errors.As(err, &me) {
+		t.Fatalf("expected *introspect.MismatchError, got %v instead", err)
+	}
+	wantProblems = []string{
+		`column "name" is NOT NULL without a default value, but *string can hold NULL: use a non-pointer type, or drop NOT NULL from the column`,
+		`column "created_at" of type timestamp with time zone isn't mapped from any field`,
+		`column "modified_at" of type timestamp with time zone isn't mapped from any field`,
+	}
+	if !reflect.DeepEqual(me.Problems, wantProblems) {
+		t.Errorf("got problems %q, want %q", me.Problems, wantProblems)
+	}
+
+	if err := introspect.Validate(ctx, pool, Post{}, "unknown"); err == nil || err.Error() != `table "unknown" not found` {
+		t.Errorf("expected table not found error, got %v instead", err)
+	}
+}
@@ -0,0 +1,299 @@
+// Package introspect inspects the schema of a PostgreSQL database,
+// and validates it against the mapping of Go structs used by pgtools.
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/henvic/pgtools"
+	"github.com/jackc/pgx/v5"
+)
+
+// Querier is satisfied by *pgx.Conn, *pgxpool.Pool, and pgx.Tx.
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Column of a table, as described by information_schema.columns.
+type Column struct {
+	// Name of the column.
+	Name string
+
+	// DataType of the column, such as "text", "ARRAY", or "USER-DEFINED".
+	DataType string
+
+	// UDTName is the name of the underlying data type, such as "int4" or "_text" (an array of text).
+	UDTName string
+
+	// Nullable is true if the column accepts NULL values.
+	Nullable bool
+
+	// Default expression of the column, such as "now()", or empty if there's none.
+	Default string
+
+	// PrimaryKey is true if the column is part of the primary key of the table.
+	PrimaryKey bool
+
+	// Identity is true for identity columns, whose value is generated on insert.
+	Identity bool
+
+	// Generated is true for generated columns, whose value is computed from other columns.
+	Generated bool
+}
+
+// Columns returns the columns of a table in the order they're defined.
+// The table name might be qualified with a schema, as in "public.users".
+// Otherwise, the current schema is used.
+func Columns(ctx context.Context, db Querier, table string) ([]Column, error) {
+	var schema *string
+	if s, t, ok := strings.Cut(table, "."); ok {
+		schema, table = &s, t
+	}
+	rows, err := db.Query(ctx, `SELECT c.column_name, c.data_type, c.udt_name, c.is_nullable = 'YES',
+			COALESCE(c.column_default, ''),
+			EXISTS(SELECT 1 FROM information_schema.table_constraints tc
+				JOIN information_schema.key_column_usage kcu
+				ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+				WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema
+				AND tc.table_name = c.table_name AND kcu.column_name = c.column_name),
+			c.is_identity = 'YES', c.is_generated = 'ALWAYS'
+		FROM information_schema.columns c
+		WHERE c.table_schema = COALESCE($1, current_schema()) AND c.table_name = $2
+		ORDER BY c.ordinal_position`, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query columns: %w", err)
+	}
+	defer rows.Close()
+	var columns []Column
+	for rows.Next() {
+		var c Column
+		if err := rows.Scan(&c.Name, &c.DataType, &c.UDTName, &c.Nullable, &c.Default, &c.PrimaryKey, &c.Identity, &c.Generated); err != nil {
+			return nil, fmt.Errorf("cannot scan column: %w", err)
+		}
+		columns = append(columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("cannot query columns: %w", err)
+	}
+	return columns, nil
+}
+
+// MismatchError is returned when a Go struct doesn't match a table.
+type MismatchError struct {
+	Type     reflect.Type
+	Table    string
+	Problems []string
+}
+
+func (e *MismatchError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v doesn't match table %q:", e.Type, e.Table)
+	for _, p := range e.Problems {
+		b.WriteString("\n\t- ")
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+// Validate checks if every column mapped from the fields of a given Go struct
+// exists in the table with a compatible data type.
+//
+// Columns of nested structs are ignored, as they don't exist in a table.
+// If there's a mismatch, a *MismatchError describing every problem found is returned.
+// Use the Strict option to check the other way around too.
+func Validate(ctx context.Context, db Querier, v any, table string, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	columns, err := Columns(ctx, db, table)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("table %q not found", table)
+	}
+	if problems := compare(pgtools.Metadata(v), columns, o.strict); len(problems) != 0 {
+		return &MismatchError{
+			Type:     reflect.Indirect(reflect.ValueOf(v)).Type(),
+			Table:    table,
+			Problems: problems,
+		}
+	}
+	return nil
+}
+
+// Option for Validate.
+type Option func(*options)
+
+type options struct {
+	strict bool
+}
+
+// Strict makes Validate also check that every column of the table is mapped from a field,
+// and that the nullability of each column matches the type of its field:
+// a nullable column requires a field that can hold NULL, such as a pointer or a sql.Null type,
+// and a NOT NULL column without a default value requires a field that cannot.
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// compare the mapped columns to the columns of the table.
+// If strict is true, unmapped columns and the nullability of the columns are checked too.
+func compare(mapped []pgtools.Column, columns []Column, strict bool) (problems []string) {
+	table := make(map[string]Column, len(columns))
+	for _, c := range columns {
+		table[c.Name] = c
+	}
+	fields := make(map[string]pgtools.Column, len(mapped))
+	for _, mc := range mapped {
+		if isNested(mapped, mc) {
+			continue
+		}
+		fields[mc.Name] = mc
+		c, ok := table[mc.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("column %q is missing", mc.Name))
+			continue
+		}
+		if !compatible(mc, c) {
+			problems = append(problems, fmt.Sprintf("column %q has type %s, which is incompatible with %v", c.Name, typeName(c), mc.Type))
+		}
+	}
+	if !strict {
+		return problems
+	}
+	for _, c := range columns {
+		mc, ok := fields[c.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("column %q of type %s isn't mapped from any field", c.Name, typeName(c)))
+			continue
+		}
+		switch {
+		case c.Nullable && !nullable(mc):
+			problems = append(problems, fmt.Sprintf("column %q is nullable, but %v cannot hold NULL: use a pointer or a sql.Null type, or add NOT NULL to the column", c.Name, mc.Type))
+		case !c.Nullable && nullable(mc) && c.Default == "" && !c.Identity && !c.Generated:
+			problems = append(problems, fmt.Sprintf("column %q is NOT NULL without a default value, but %v can hold NULL: use a non-pointer type, or drop NOT NULL from the column", c.Name, mc.Type))
+		}
+	}
+	return problems
+}
+
+// nullable reports whether the Go type of the mapped column can hold NULL.
+// Types handling their own encoding, such as sql.NullString, are considered nullable.
+func nullable(mc pgtools.Column) bool {
+	t := mc.Type
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return true
+	case reflect.Slice:
+		// Nil slices of array columns are encoded as empty arrays.
+		return !mc.HasOption("array")
+	}
+	return reflect.PtrTo(t).Implements(scannerType)
+}
+
+// isNested reports whether the column is mapped from a field of a nested struct.
+func isNested(columns []pgtools.Column, c pgtools.Column) bool {
+	for _, p := range columns {
+		if len(p.Index) < len(c.Index) && reflect.DeepEqual(p.Index, c.Index[:len(p.Index)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName returns a human-readable name for the data type of the column.
+func typeName(c Column) string {
+	switch c.DataType {
+	case "ARRAY":
+		return strings.TrimPrefix(c.UDTName, "_") + "[]"
+	case "USER-DEFINED":
+		return c.UDTName
+	}
+	return c.DataType
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	bytesType      = reflect.TypeOf([]byte(nil))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// scannerType and valuerType are implemented by types handling their own encoding.
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+var (
+	jsonTypes    = []string{"json", "jsonb"}
+	textTypes    = []string{"text", "character varying", "character", "name", "citext", "uuid", "inet", "cidr", "macaddr", "xml", "json", "jsonb", "USER-DEFINED"}
+	integerTypes = []string{"smallint", "integer", "bigint", "numeric"}
+	floatTypes   = []string{"real", "double precision", "numeric"}
+	timeTypes    = []string{"timestamp with time zone", "timestamp without time zone", "date"}
+)
+
+// compatible reports whether the Go type of the mapped column can be used with the column.
+// Types it doesn't know about are considered compatible.
+func compatible(mc pgtools.Column, c Column) bool {
+	if mc.HasOption("json") {
+		return contains(jsonTypes, c.DataType)
+	}
+	if mc.HasOption("array") {
+		return c.DataType == "ARRAY"
+	}
+	if mc.HasOption("composite") {
+		return c.DataType == "USER-DEFINED"
+	}
+	t := mc.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(scannerType) || t.Implements(valuerType) {
+		return true
+	}
+	switch t {
+	case timeType:
+		return contains(timeTypes, c.DataType)
+	case durationType:
+		return c.DataType == "interval" || contains(integerTypes, c.DataType)
+	case bytesType, rawMessageType:
+		return c.DataType == "bytea" || contains(jsonTypes, c.DataType)
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return contains(textTypes, c.DataType)
+	case reflect.Bool:
+		return c.DataType == "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return contains(integerTypes, c.DataType)
+	case reflect.Float32, reflect.Float64:
+		return contains(floatTypes, c.DataType)
+	case reflect.Slice:
+		return c.DataType == "ARRAY" || contains(jsonTypes, c.DataType)
+	case reflect.Map:
+		return contains(jsonTypes, c.DataType) || c.UDTName == "hstore"
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
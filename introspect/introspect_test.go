@@ -0,0 +1,117 @@
+package introspect
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools"
+)
+
+func TestCompare(t *testing.T) {
+	type Theme struct {
+		Color string
+	}
+	v := struct {
+		ID             string `db:"id,pk"`
+		Name           string
+		Count          int
+		Ratio          float64
+		Active         bool
+		Theme          Theme `db:"theme,json"`
+		Nested         Theme
+		Tags           []string
+		Raw            json.RawMessage
+		Note           sql.NullString
+		CreatedAt      *time.Time
+		Missing        string
+		Wrong          int
+		WrongJSON      Theme `db:"wrong_json,json"`
+		Composite      Theme `db:"composite,composite"`
+		WrongComposite Theme `db:"wrong_composite,composite"`
+		Array          []int `db:"array,array"`
+		WrongArray     []int `db:"wrong_array,array"`
+	}{}
+	columns := []Column{
+		{Name: "id", DataType: "text"},
+		{Name: "name", DataType: "character varying"},
+		{Name: "count", DataType: "bigint"},
+		{Name: "ratio", DataType: "numeric"},
+		{Name: "active", DataType: "boolean"},
+		{Name: "theme", DataType: "jsonb"},
+		{Name: "nested", DataType: "USER-DEFINED", UDTName: "theme"},
+		{Name: "tags", DataType: "ARRAY", UDTName: "_text"},
+		{Name: "raw", DataType: "json"},
+		{Name: "note", DataType: "integer"},
+		{Name: "created_at", DataType: "timestamp with time zone", Nullable: true},
+		{Name: "wrong", DataType: "ARRAY", UDTName: "_int4"},
+		{Name: "wrong_json", DataType: "text"},
+		{Name: "composite", DataType: "USER-DEFINED", UDTName: "theme"},
+		{Name: "wrong_composite", DataType: "jsonb"},
+		{Name: "array", DataType: "ARRAY", UDTName: "_int8"},
+		{Name: "wrong_array", DataType: "jsonb"},
+		{Name: "extra", DataType: "text"},
+	}
+	got := compare(pgtools.Metadata(v), columns, false)
+	want := []string{
+		`column "missing" is missing`,
+		`column "wrong" has type int4[], which is incompatible with int`,
+		`column "wrong_json" has type text, which is incompatible with introspect.Theme`,
+		`column "wrong_composite" has type jsonb, which is incompatible with introspect.Theme`,
+		`column "wrong_array" has type jsonb, which is incompatible with []int`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compare() = %q, want %q", got, want)
+	}
+}
+
+func TestMismatchError(t *testing.T) {
+	err := &MismatchError{
+		Type:     reflect.TypeOf(Column{}),
+		Table:    "public.columns",
+		Problems: []string{"column \"a\" is missing", "column \"b\" is missing"},
+	}
+	want := `introspect.Column doesn't match table "public.columns":
+	- column "a" is missing
+	- column "b" is missing`
+	if got := err.Error(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompareStrict(t *testing.T) {
+	v := struct {
+		ID        int64 `db:"id,pk"`
+		Name      string
+		Nickname  *string
+		Bio       string
+		Tags      []string `db:"tags,array"`
+		Note      sql.NullString
+		Avatar    *string
+		CreatedAt *time.Time
+		Missing   string
+	}{}
+	columns := []Column{
+		{Name: "id", DataType: "bigint", Identity: true},
+		{Name: "name", DataType: "text"},
+		{Name: "nickname", DataType: "text", Nullable: true},
+		{Name: "bio", DataType: "text", Nullable: true},
+		{Name: "tags", DataType: "ARRAY", UDTName: "_text"},
+		{Name: "note", DataType: "text", Nullable: true},
+		{Name: "avatar", DataType: "text"},
+		{Name: "created_at", DataType: "timestamp with time zone", Default: "now()"},
+		{Name: "deleted_at", DataType: "timestamp with time zone", Nullable: true},
+	}
+	got := compare(pgtools.Metadata(v), columns, true)
+	want := []string{
+		`column "missing" is missing`,
+		`column "bio" is nullable, but string cannot hold NULL: use a pointer or a sql.Null type, or add NOT NULL to the column`,
+		`column "avatar" is NOT NULL without a default value, but *string can hold NULL: use a non-pointer type, or drop NOT NULL from the column`,
+		`column "deleted_at" of type timestamp with time zone isn't mapped from any field`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compare() = %q, want %q", got, want)
+	}
+}
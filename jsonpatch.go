@@ -0,0 +1,189 @@
+package pgtools
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPatch returns an assignment for an UPDATE statement that partially updates a
+// jsonb column with the keys of patch, and the arguments referenced positionally by
+// it as $1, $2, etc., as in:
+//
+//	sql, args := pgtools.JSONPatch("theme", map[string]any{"primary_color": "red"})
+//	// sql: "theme" = COALESCE("theme", '{}'::jsonb) || $1::jsonb
+//
+// The operators used only exist for jsonb, so a json column must be converted to jsonb first.
+//
+// The patch can be a map with string keys or a sparse struct, where only non-zero fields are used.
+// Struct fields are named after their json tags, and fields of embedded structs are promoted,
+// so a patch can use the same type of a field with the "json" option.
+// Unlike encoding/json, tag options such as omitempty and string are ignored, as zero fields are always skipped,
+// and if fields of embedded structs at the same depth have the same name, the first one is used instead of none.
+//
+// Top-level keys are merged with the || operator, and nested objects are merged with
+// jsonb_set recursively, so keys that aren't in the patch are kept at every level.
+//
+// JSONPatch returns an empty string if the patch is empty.
+func JSONPatch(column string, patch any) (string, []any) {
+	entries := jsonEntries(reflect.ValueOf(patch))
+	if len(entries) == 0 {
+		return "", nil
+	}
+	quoted := `"` + column + `"`
+	var args []any
+	expr := jsonMerge("COALESCE("+quoted+", '{}'::jsonb)", quoted, nil, entries, &args)
+	return quoted + " = " + expr, args
+}
+
+// jsonEntry is a key of a JSON object with either a value or nested entries.
+type jsonEntry struct {
+	key    string
+	value  any
+	nested []jsonEntry
+}
+
+// jsonMerge returns an expression merging the entries into target.
+// The column and path are used to reference the current value of nested objects.
+func jsonMerge(target, column string, path []string, entries []jsonEntry, args *[]any) string {
+	scalars := map[string]any{}
+	for _, e := range entries {
+		if e.nested == nil {
+			scalars[e.key] = e.value
+		}
+	}
+	if len(scalars) != 0 {
+		b, err := json.Marshal(scalars)
+		if err != nil {
+			*args = append(*args, invalidValue{err})
+		} else {
+			*args = append(*args, b)
+		}
+		target += " || $" + strconv.Itoa(len(*args)) + "::jsonb"
+	}
+	for _, e := range entries {
+		if e.nested == nil {
+			continue
+		}
+		p := append(append([]string(nil), path...), e.key)
+		*args = append(*args, p)
+		current := "COALESCE(" + column + " #> $" + strconv.Itoa(len(*args)) + "::text[], '{}'::jsonb)"
+		sub := jsonMerge(current, column, p, e.nested, args)
+		*args = append(*args, []string{e.key})
+		target = "jsonb_set(" + target + ", $" + strconv.Itoa(len(*args)) + "::text[], " + sub + ")"
+	}
+	return target
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// jsonEntries returns the entries of a map with string keys or a struct.
+// It returns nil if v isn't an object, or if it's empty.
+func jsonEntries(v reflect.Value) []jsonEntry {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !isJSONObject(v) {
+		return nil
+	}
+	var entries []jsonEntry
+	add := func(key string, fv reflect.Value) {
+		for fv.Kind() == reflect.Interface && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		e := jsonEntry{key: key}
+		if nested := jsonEntries(fv); nested != nil {
+			e.nested = nested
+		} else if fv.IsValid() {
+			e.value = fv.Interface()
+		}
+		if e.nested != nil || !isJSONObject(reflect.Indirect(fv)) {
+			entries = append(entries, e)
+		}
+	}
+	if v.Kind() == reflect.Map {
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		for _, k := range keys {
+			add(k.String(), v.MapIndex(k))
+		}
+		return entries
+	}
+	t := v.Type()
+	names := map[string]bool{}
+	var embedded []reflect.Value
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, tagged := f.Name, false
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name, tagged = tagName, true
+			}
+		}
+		fv := v.Field(i)
+		if f.Anonymous && !tagged && indirectType(f.Type).Kind() == reflect.Struct {
+			embedded = append(embedded, fv)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		names[name] = true
+		if !fv.IsZero() {
+			add(name, fv)
+		}
+	}
+	// Promote the fields of embedded structs, as encoding/json does.
+	for _, fv := range embedded {
+		for _, e := range jsonEntries(fv) {
+			if !names[e.key] {
+				names[e.key] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries
+}
+
+// indirectType returns the type pointed to by t, or t if it isn't a pointer.
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// isJSONObject reports whether v is a map with string keys or a struct
+// that is marshaled as an object by encoding/json.
+func isJSONObject(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PtrTo(t).Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String
+	case reflect.Struct:
+		return true
+	}
+	return false
+}
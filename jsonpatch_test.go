@@ -0,0 +1,148 @@
+package pgtools_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools"
+)
+
+func ExampleJSONPatch() {
+	set, args := pgtools.JSONPatch("theme", Theme{PrimaryColor: "red"})
+	fmt.Println("UPDATE users SET " + set + " WHERE id = $2")
+	fmt.Printf("%s\n", args...)
+	// Output:
+	// UPDATE users SET "theme" = COALESCE("theme", '{}'::jsonb) || $1::jsonb WHERE id = $2
+	// {"PrimaryColor":"red"}
+}
+
+type jsonPatchMock struct {
+	Name     string            `json:"name"`
+	Ignored  string            `json:"-"`
+	Count    *int              `json:"count,omitempty"`
+	When     time.Time         `json:"when"`
+	Settings *jsonPatchNested  `json:"settings"`
+	Labels   map[string]string `json:"labels"`
+}
+
+type jsonPatchNested struct {
+	Enabled bool
+	Deep    map[string]any `json:"deep"`
+}
+
+type jsonPatchEmbed struct {
+	jsonPatchBase
+	*jsonPatchNested
+	Name string `json:"name"`
+}
+
+type jsonPatchBase struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+func TestJSONPatch(t *testing.T) {
+	t.Parallel()
+	zero := 0
+	when := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	testCases := []struct {
+		desc     string
+		column   string
+		patch    any
+		want     string
+		wantArgs []any
+	}{
+		{
+			desc:   "nil",
+			column: "theme",
+			patch:  nil,
+			want:   "",
+		},
+		{
+			desc:   "not an object",
+			column: "theme",
+			patch:  "x",
+			want:   "",
+		},
+		{
+			desc:   "empty map",
+			column: "theme",
+			patch:  map[string]any{},
+			want:   "",
+		},
+		{
+			desc:   "zero struct",
+			column: "theme",
+			patch:  &Theme{},
+			want:   "",
+		},
+		{
+			desc:     "map",
+			column:   "settings",
+			patch:    map[string]any{"b": 2, "a": "x", "empty": map[string]any{}},
+			want:     `"settings" = COALESCE("settings", '{}'::jsonb) || $1::jsonb`,
+			wantArgs: []any{[]byte(`{"a":"x","b":2}`)},
+		},
+		{
+			desc:   "nested",
+			column: "data",
+			patch: jsonPatchMock{
+				Name:    "henvic",
+				Ignored: "ignored",
+				Count:   &zero,
+				When:    when,
+				Settings: &jsonPatchNested{
+					Enabled: true,
+					Deep:    map[string]any{"level": 3},
+				},
+				Labels: map[string]string{"env": "test"},
+			},
+			want: `"data" = jsonb_set(jsonb_set(COALESCE("data", '{}'::jsonb) || $1::jsonb, $7::text[], ` +
+				`jsonb_set(COALESCE("data" #> $2::text[], '{}'::jsonb) || $3::jsonb, $6::text[], COALESCE("data" #> $4::text[], '{}'::jsonb) || $5::jsonb)` +
+				`), $10::text[], COALESCE("data" #> $8::text[], '{}'::jsonb) || $9::jsonb)`,
+			wantArgs: []any{
+				[]byte(`{"count":0,"name":"henvic","when":"2023-01-02T03:04:05Z"}`),
+				[]string{"settings"},
+				[]byte(`{"Enabled":true}`),
+				[]string{"settings", "deep"},
+				[]byte(`{"level":3}`),
+				[]string{"deep"},
+				[]string{"settings"},
+				[]string{"labels"},
+				[]byte(`{"env":"test"}`),
+				[]string{"labels"},
+			},
+		},
+		{
+			desc:   "embedded",
+			column: "data",
+			patch: jsonPatchEmbed{
+				jsonPatchBase:   jsonPatchBase{Name: "shadowed", Color: "red"},
+				jsonPatchNested: &jsonPatchNested{Enabled: true},
+				Name:            "henvic",
+			},
+			want:     `"data" = COALESCE("data", '{}'::jsonb) || $1::jsonb`,
+			wantArgs: []any{[]byte(`{"Enabled":true,"color":"red","name":"henvic"}`)},
+		},
+		{
+			desc:     "nil embedded pointer",
+			column:   "data",
+			patch:    jsonPatchEmbed{Name: "henvic"},
+			want:     `"data" = COALESCE("data", '{}'::jsonb) || $1::jsonb`,
+			wantArgs: []any{[]byte(`{"name":"henvic"}`)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, args := pgtools.JSONPatch(tc.column, tc.patch)
+			if tc.want != got {
+				t.Errorf("expected assignment to be:\n%v\ngot:\n%v", tc.want, got)
+			}
+			if tc.wantArgs != nil && !reflect.DeepEqual(tc.wantArgs, args) {
+				t.Errorf("expected args to be %q, got %q instead", tc.wantArgs, args)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package pgtools
+
+import "sort"
+
+// maxIdentifierLength is the maximum length of an identifier in PostgreSQL (NAMEDATALEN - 1).
+const maxIdentifierLength = 63
+
+// mapColumns returns the sorted keys of a map used in place of a struct, so that columns are
+// listed in a stable order.
+//
+// As keys might come from user input, mapColumns returns nil if any of them isn't a valid identifier.
+func mapColumns(m map[string]any) []string {
+	columns := make([]string, 0, len(m))
+	for k := range m {
+		if !validIdentifier(k) {
+			return nil
+		}
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// validIdentifier reports whether s is an unqualified identifier that can be used as a column name
+// without quoting issues. The rules are stricter than PostgreSQL's: only ASCII letters, digits,
+// underscores, and dollar signs are accepted, and s must start with a letter or an underscore.
+func validIdentifier(s string) bool {
+	if s == "" || len(s) > maxIdentifierLength {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r == '$' || r >= '0' && r <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// insertableFields returns the columns inserted by builders such as Upsert.
+func insertableFields(v any) []string {
+	if mv, ok := v.(map[string]any); ok {
+		return mapColumns(mv)
+	}
+	if m := getMapping(v); m != nil {
+		return m.insertableFields
+	}
+	return nil
+}
+
+// mapValues returns the values of the map in the same order as mapColumns.
+func mapValues(m map[string]any) []any {
+	columns := mapColumns(m)
+	if columns == nil {
+		return nil
+	}
+	values := make([]any, 0, len(columns))
+	for _, c := range columns {
+		values = append(values, m[c])
+	}
+	return values
+}
@@ -0,0 +1,73 @@
+package pgtools
+
+import "reflect"
+
+// Column describes a SQL table column mapped from a Go struct field.
+type Column struct {
+	// Name of the column.
+	Name string
+
+	// Index sequence of the struct field, as used by reflect.Value.FieldByIndex.
+	Index []int
+
+	// Type of the struct field.
+	Type reflect.Type
+
+	// Options of the "db" key in the struct field's tag, such as "json" or "pk".
+	Options []string
+}
+
+// HasOption reports whether the column has the given option.
+func (c Column) HasOption(name string) bool {
+	return contains(c.Options, name)
+}
+
+// Metadata returns a description of the columns of a SQL table mapped from a given Go struct
+// using the same rules as Fields, and in the same order.
+//
+// It's useful to introspect the mapping without parsing the struct tags again.
+func Metadata(v any) []Column {
+	m := getMapping(v)
+	if m == nil {
+		return nil
+	}
+	columns := make([]Column, 0, len(m.columns))
+	for _, c := range m.columns {
+		columns = append(columns, Column{
+			Name:    c.Name,
+			Index:   append([]int(nil), c.Index...),
+			Type:    c.Type,
+			Options: c.Options(),
+		})
+	}
+	return columns
+}
+
+// PrimaryKey returns the columns of the primary key of the SQL table mapped from a given Go struct.
+// The primary key is defined by the struct fields with the "pk" option, as in `db:"id,pk"`.
+func PrimaryKey(v any) []string {
+	m := getMapping(v)
+	if m == nil {
+		return nil
+	}
+	return m.pk
+}
+
+// FieldIndexes returns a map of the columns of a SQL table mapped from a given Go struct
+// to the index sequence of their struct fields, as used by reflect.Value.FieldByIndex,
+// using the same rules as Fields.
+//
+// It's useful to build custom scanners, or to cache the destination of each column,
+// without deriving the rules of the "db" key in the struct field's tag again.
+// The returned map can be modified by the caller.
+func FieldIndexes(v any) map[string][]int {
+	m := getMapping(v)
+	if m == nil {
+		return nil
+	}
+	indexes := make(map[string][]int, len(m.columns))
+	for _, c := range m.columns {
+		indexes[c.Name] = append([]int(nil), c.Index...)
+	}
+	return indexes
+}
@@ -0,0 +1,213 @@
+package pgtools_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/henvic/pgtools"
+)
+
+func ExampleMetadata() {
+	for _, c := range pgtools.Metadata(Document{}) {
+		fmt.Println(c.Name, c.Type, c.Options)
+	}
+	// Output:
+	// id string [pk]
+	// body string []
+	// search_vector string [readonly]
+}
+
+func ExamplePrimaryKey() {
+	fmt.Println(pgtools.PrimaryKey(Post{}))
+	// Output:
+	// [id]
+}
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc string
+		v    any
+		want []pgtools.Column
+	}{
+		{
+			desc: "nil",
+			v:    nil,
+			want: nil,
+		},
+		{
+			desc: "empty",
+			v:    emptyEmbed{},
+			want: []pgtools.Column{},
+		},
+		{
+			desc: "embed",
+			v:    &pointerEmbedMock{},
+			want: []pgtools.Column{
+				{
+					Name:  "id",
+					Index: []int{0},
+					Type:  reflect.TypeOf(""),
+				},
+				{
+					Name:  "number",
+					Index: []int{1, 0},
+					Type:  reflect.TypeOf(0),
+				},
+			},
+		},
+		{
+			desc: "options",
+			v:    themeImplicit{},
+			want: []pgtools.Column{
+				{
+					Name:  "id",
+					Index: []int{0},
+					Type:  reflect.TypeOf(""),
+				},
+				{
+					Name:    "xyz",
+					Index:   []int{1},
+					Type:    reflect.TypeOf(Theme{}),
+					Options: []string{"json"},
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := pgtools.Metadata(tc.v); !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("expected metadata to be %+v, got %+v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMetadataCopy(t *testing.T) {
+	t.Parallel()
+	type copyMock struct {
+		ID   string `db:"id,pk"`
+		Name string
+	}
+	m := pgtools.Metadata(copyMock{})
+	m[0].Index[0] = 10
+	m[0].Options[0] = "modified"
+	got := pgtools.Metadata(copyMock{})
+	if got[0].Index[0] != 0 || !got[0].HasOption("pk") {
+		t.Errorf("expected metadata to be a copy, got %+v instead", got[0])
+	}
+}
+
+func TestPrimaryKey(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc string
+		v    any
+		want []string
+	}{
+		{
+			desc: "nil",
+			v:    nil,
+			want: nil,
+		},
+		{
+			desc: "none",
+			v:    User{},
+			want: nil,
+		},
+		{
+			desc: "single",
+			v:    &Post{},
+			want: []string{"id"},
+		},
+		{
+			desc: "composite",
+			v: struct {
+				Organization string `db:"org_id,pk"`
+				Role         string
+				User         string `db:"user_id,readonly,pk"`
+			}{},
+			want: []string{"org_id", "user_id"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := pgtools.PrimaryKey(tc.v); !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("expected primary key to be %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func ExampleFieldIndexes() {
+	v := reflect.ValueOf(pointerEmbedMock{
+		ID:          "x",
+		numericMock: &numericMock{Number: 7},
+	})
+	indexes := pgtools.FieldIndexes(v.Interface())
+	fmt.Println(v.FieldByIndex(indexes["id"]), v.FieldByIndex(indexes["number"]))
+	// Output:
+	// x 7
+}
+
+func TestFieldIndexes(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc string
+		v    any
+		want map[string][]int
+	}{
+		{
+			desc: "nil",
+			v:    nil,
+			want: nil,
+		},
+		{
+			desc: "empty",
+			v:    emptyEmbed{},
+			want: map[string][]int{},
+		},
+		{
+			desc: "embed",
+			v:    &mockEmbed{},
+			want: map[string][]int{
+				"before":    {0},
+				"automatic": {1, 0},
+				"tagged":    {1, 1},
+				"one_two":   {1, 2},
+				"CamelCase": {1, 3},
+				"after":     {2},
+			},
+		},
+		{
+			desc: "nested",
+			v:    struct{ Theme struct{ Color string } }{},
+			want: map[string][]int{
+				"theme.color": {0, 0},
+				"theme":       {0},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := pgtools.FieldIndexes(tc.v); !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("expected field indexes to be %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFieldIndexesCopy(t *testing.T) {
+	t.Parallel()
+	indexes := pgtools.FieldIndexes(mockEmbed{})
+	indexes["automatic"][0] = 9
+	delete(indexes, "before")
+	got := pgtools.FieldIndexes(mockEmbed{})
+	if want := []int{1, 0}; !reflect.DeepEqual(want, got["automatic"]) {
+		t.Errorf("expected field index to be %v, got %v instead", want, got["automatic"])
+	}
+	if _, ok := got["before"]; !ok {
+		t.Error("expected column before to be mapped")
+	}
+}
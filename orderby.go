@@ -0,0 +1,58 @@
+package pgtools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSort is returned by OrderBy when the sort expression is invalid.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// OrderBy returns an ORDER BY clause for a comma-separated sort expression, such as one received
+// as a parameter of an HTTP request. Prefix a column with "-" to sort it in descending order, as in:
+//
+//	sql, err := pgtools.OrderBy(Post{}, "-created_at,name")
+//	// sql: ORDER BY "created_at" DESC, "name" ASC
+//
+// Columns are validated against the columns mapped from v, as returned by Fields,
+// so the expression can't be used to inject SQL. Otherwise, an error wrapping
+// ErrInvalidSort is returned.
+//
+// OrderBy returns an empty string if the sort expression is empty.
+func OrderBy(v any, sort string) (string, error) {
+	if strings.TrimSpace(sort) == "" {
+		return "", nil
+	}
+	columns := fields(v)
+	var (
+		b    strings.Builder
+		used []string
+	)
+	b.WriteString("ORDER BY ")
+	for n, s := range strings.Split(sort, ",") {
+		s = strings.TrimSpace(s)
+		direction := " ASC"
+		switch {
+		case strings.HasPrefix(s, "-"):
+			s, direction = s[1:], " DESC"
+		case strings.HasPrefix(s, "+"):
+			s = s[1:]
+		}
+		if !contains(columns, s) {
+			return "", fmt.Errorf("%w: unknown column %q", ErrInvalidSort, s)
+		}
+		if contains(used, s) {
+			return "", fmt.Errorf("%w: duplicated column %q", ErrInvalidSort, s)
+		}
+		used = append(used, s)
+		if n != 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(`"`)
+		b.WriteString(s)
+		b.WriteString(`"`)
+		b.WriteString(direction)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,96 @@
+package pgtools_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/henvic/pgtools"
+)
+
+func ExampleOrderBy() {
+	orderBy, err := pgtools.OrderBy(Product{}, "-created_at,name")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("SELECT " + pgtools.Wildcard(Product{}, pgtools.Only("id", "name")) + " FROM products " + orderBy)
+	// Output:
+	// SELECT "id","name" FROM products ORDER BY "created_at" DESC, "name" ASC
+}
+
+func TestOrderBy(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc    string
+		v       any
+		sort    string
+		want    string
+		wantErr string
+	}{
+		{
+			desc: "empty",
+			v:    Product{},
+			sort: " ",
+			want: "",
+		},
+		{
+			desc: "single",
+			v:    Product{},
+			sort: "name",
+			want: `ORDER BY "name" ASC`,
+		},
+		{
+			desc: "multiple",
+			v:    &Product{},
+			sort: " -price, +name ,id",
+			want: `ORDER BY "price" DESC, "name" ASC, "id" ASC`,
+		},
+		{
+			desc:    "nil",
+			v:       nil,
+			sort:    "name",
+			wantErr: `invalid sort: unknown column "name"`,
+		},
+		{
+			desc:    "unknown column",
+			v:       Product{},
+			sort:    "name,password",
+			wantErr: `invalid sort: unknown column "password"`,
+		},
+		{
+			desc:    "injection",
+			v:       Product{},
+			sort:    `name"; DROP TABLE products; --`,
+			wantErr: `invalid sort: unknown column "name\"; DROP TABLE products; --"`,
+		},
+		{
+			desc:    "duplicated column",
+			v:       Product{},
+			sort:    "name,-name",
+			wantErr: `invalid sort: duplicated column "name"`,
+		},
+		{
+			desc:    "empty column",
+			v:       Product{},
+			sort:    "name,",
+			wantErr: `invalid sort: unknown column ""`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := pgtools.OrderBy(tc.v, tc.sort)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr || !errors.Is(err, pgtools.ErrInvalidSort) {
+					t.Errorf("expected error %q, got %v instead", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.want != got {
+				t.Errorf("expected clause to be %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
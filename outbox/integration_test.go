@@ -0,0 +1,99 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools/outbox"
+	"github.com/henvic/pgtools/pgxtx"
+	"github.com/henvic/pgtools/sqltest"
+	"github.com/jackc/pgx/v5"
+)
+
+var force = flag.Bool("force", false, "Force cleaning the database before starting")
+
+func checkPostgres(t testing.TB) {
+	if os.Getenv("INTEGRATION_TESTDB") != "true" {
+		t.Skip("Skipping tests that require database connection")
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("../sqltest/example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_outbox_",
+	})
+	pool := migration.Setup(ctx, "")
+	if _, err := pool.Exec(ctx, outbox.Schema); err != nil {
+		t.Fatalf("cannot create outbox table: %v", err)
+	}
+
+	errRollback := errors.New("rollback")
+	for _, id := range []string{"committed", "rolled_back"} {
+		id := id
+		err := pgxtx.WithTx(ctx, pool, func(ctx context.Context, tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ($1, 'name', 'photo', 'url')", id); err != nil {
+				return err
+			}
+			if err := outbox.Enqueue(ctx, tx, outbox.Message{
+				Topic:   "media_created",
+				Key:     id,
+				Payload: []byte(id),
+				Headers: map[string]string{"trace_id": "abc"},
+			}); err != nil {
+				return err
+			}
+			if id == "rolled_back" {
+				return errRollback
+			}
+			return nil
+		})
+		want := errRollback
+		if id == "committed" {
+			want = nil
+		}
+		if !errors.Is(err, want) {
+			t.Fatalf("expected error to be %v, got %v instead", want, err)
+		}
+	}
+
+	var handled []outbox.Message
+	fail := true
+	relay := outbox.NewRelay(pool, func(ctx context.Context, m outbox.Message) error {
+		handled = append(handled, m)
+		if fail {
+			fail = false
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}, outbox.Options{Backoff: func(int) time.Duration { return 0 }})
+
+	// The first attempt fails, and the message is delivered again.
+	for i, want := range []int{0, 1, 0} {
+		delivered, err := relay.Process(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %v instead", err)
+		}
+		if delivered != want {
+			t.Errorf("expected %d messages delivered on attempt %d, got %d instead", want, i+1, delivered)
+		}
+	}
+	if len(handled) != 2 {
+		t.Fatalf("expected message to be handled twice, got %+v instead", handled)
+	}
+	m := handled[1]
+	if m.Key != "committed" || string(m.Payload) != "committed" || m.Headers["trace_id"] != "abc" {
+		t.Errorf("expected committed message, got %+v instead", m)
+	}
+	if m.Attempts != 1 || m.LastError != "broker unavailable" {
+		t.Errorf("expected message to record the failed attempt, got %+v instead", m)
+	}
+}
@@ -0,0 +1,216 @@
+// Package outbox implements the transactional outbox pattern, so that services can publish events
+// if and only if the business transaction producing them commits.
+//
+// Messages are written to the outbox table in the same transaction as the changes they describe:
+//
+//	err := pgxtx.WithTx(ctx, pool, func(ctx context.Context, tx pgx.Tx) error {
+//		if _, err := tx.Exec(ctx, "INSERT INTO orders (id) VALUES ($1)", id); err != nil {
+//			return err
+//		}
+//		return outbox.Enqueue(ctx, tx, outbox.Message{Topic: "order_created", Key: id, Payload: payload})
+//	})
+//
+// A Relay polls the table, and calls a Handler to deliver them, such as by publishing them to a message broker:
+//
+//	relay := outbox.NewRelay(pool, publish, outbox.Options{})
+//	go relay.Run(ctx)
+//
+// Delivery is at least once: a message is deleted only after it's handled successfully,
+// and handled again if the Relay fails before deleting it.
+// Relays running in multiple processes share the work, as each message is locked while handled.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/henvic/pgtools"
+	"github.com/henvic/pgtools/pgxretry"
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/henvic/pgtools/pgxtx"
+	"github.com/jackc/pgx/v5"
+)
+
+// Schema of the outbox table, to be added to the migrations of the service.
+const Schema = `CREATE TABLE outbox (
+	id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	topic text NOT NULL,
+	key text NOT NULL DEFAULT '',
+	payload bytea NOT NULL,
+	headers jsonb NOT NULL DEFAULT '{}',
+	attempts integer NOT NULL DEFAULT 0,
+	last_error text NOT NULL DEFAULT '',
+	created_at timestamptz NOT NULL DEFAULT now(),
+	available_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX outbox_available_at_id_idx ON outbox (available_at, id);
+`
+
+// Message in the outbox.
+type Message struct {
+	// ID of the message, set once it's enqueued.
+	ID int64 `db:"id,pk,generated"`
+
+	// Topic of the message, such as the name of the event.
+	Topic string `db:"topic"`
+
+	// Key of the message, such as the ID of the entity the event is about, used by brokers for partitioning.
+	Key string `db:"key"`
+
+	// Payload of the message.
+	Payload []byte `db:"payload"`
+
+	// Headers of the message, such as a trace ID.
+	Headers map[string]string `db:"headers,json"`
+
+	// Attempts to deliver the message that failed.
+	Attempts int `db:"attempts,generated"`
+
+	// LastError returned by the Handler when delivering the message.
+	LastError string `db:"last_error,generated"`
+
+	// CreatedAt is the time the message was enqueued.
+	CreatedAt time.Time `db:"created_at,generated"`
+}
+
+// Enqueue the message in the outbox.
+// Call it with the transaction of the business changes, so that the message is only delivered if it commits.
+func Enqueue(ctx context.Context, tx pgxtx.Querier, m Message) error {
+	if m.Topic == "" {
+		return errors.New("missing message topic")
+	}
+	headers := m.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	if m.Payload == nil {
+		m.Payload = []byte{}
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO outbox (topic, key, payload, headers) VALUES ($1, $2, $3, $4)",
+		m.Topic, m.Key, m.Payload, headers); err != nil {
+		return fmt.Errorf("cannot enqueue message: %w", err)
+	}
+	return nil
+}
+
+// Handler delivers a message.
+// If it returns an error, the message is delivered again later.
+// As the message is locked while it's handled, it should return promptly.
+type Handler func(ctx context.Context, m Message) error
+
+// Options for the Relay.
+type Options struct {
+	// BatchSize is the maximum number of messages handled in each transaction.
+	// If zero, it's 100.
+	BatchSize int
+
+	// PollInterval is the time to wait before checking for new messages once there are none to deliver.
+	// If zero, it's one second.
+	PollInterval time.Duration
+
+	// Backoff returns the time to wait before delivering a message again after it failed the given number of times.
+	// If nil, pgxretry.ExponentialBackoff(time.Second, time.Hour) is used.
+	Backoff func(attempts int) time.Duration
+
+	// OnError is called with the errors of the Relay, including the ones returned by the Handler.
+	OnError func(err error)
+}
+
+// Relay delivering the messages in the outbox.
+type Relay struct {
+	db      pgxs.PGX
+	handler Handler
+	o       Options
+}
+
+// NewRelay of the messages in the outbox of db, delivering them with the handler.
+func NewRelay(db pgxs.PGX, handler Handler, o Options) *Relay {
+	if o.BatchSize == 0 {
+		o.BatchSize = 100
+	}
+	if o.PollInterval == 0 {
+		o.PollInterval = time.Second
+	}
+	if o.Backoff == nil {
+		o.Backoff = pgxretry.ExponentialBackoff(time.Second, time.Hour)
+	}
+	return &Relay{db: db, handler: handler, o: o}
+}
+
+// Run delivers the messages until ctx is canceled.
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		n, _, err := r.process(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.onError(err)
+		}
+		// Keep going without waiting while there might be more messages available.
+		if err == nil && n == r.o.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.o.PollInterval):
+		}
+	}
+}
+
+// Process a batch of the messages available, returning how many were delivered.
+// Messages locked by other Relays are skipped.
+func (r *Relay) Process(ctx context.Context) (int, error) {
+	_, delivered, err := r.process(ctx)
+	return delivered, err
+}
+
+// selectMessages available for delivery, locking them.
+var selectMessages = "SELECT " + pgtools.Wildcard(Message{}) +
+	" FROM outbox WHERE available_at <= now() ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED"
+
+// process a batch of messages, returning how many were handled and delivered.
+func (r *Relay) process(ctx context.Context) (handled, delivered int, err error) {
+	err = pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, selectMessages, r.o.BatchSize)
+		if err != nil {
+			return err
+		}
+		messages, err := pgx.CollectRows(rows, pgx.RowToStructByName[Message])
+		if err != nil {
+			return err
+		}
+		var ids []int64
+		for _, m := range messages {
+			if err := r.handler(ctx, m); err != nil {
+				r.onError(fmt.Errorf("cannot deliver message %d: %w", m.ID, err))
+				delay := r.o.Backoff(m.Attempts + 1)
+				if _, err := tx.Exec(ctx, `UPDATE outbox SET attempts = attempts + 1, last_error = $2,
+					available_at = now() + $3 * interval '1 millisecond' WHERE id = $1`,
+					m.ID, err.Error(), delay.Milliseconds()); err != nil {
+					return err
+				}
+				continue
+			}
+			ids = append(ids, m.ID)
+		}
+		if len(ids) != 0 {
+			if _, err := tx.Exec(ctx, "DELETE FROM outbox WHERE id = ANY($1)", ids); err != nil {
+				return err
+			}
+		}
+		handled, delivered = len(messages), len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot process outbox: %w", err)
+	}
+	return handled, delivered, nil
+}
+
+func (r *Relay) onError(err error) {
+	if r.o.OnError != nil {
+		r.o.OnError(err)
+	}
+}
@@ -0,0 +1,113 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools/outbox"
+	"github.com/henvic/pgtools/pgxfake"
+)
+
+func TestEnqueue(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	db.On(`INSERT INTO outbox`, pgxfake.Response{Tag: "INSERT 0 1"})
+
+	if err := outbox.Enqueue(ctx, db, outbox.Message{Topic: "order_created", Key: "1", Payload: []byte(`{}`)}); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if err := outbox.Enqueue(ctx, db, outbox.Message{Key: "1"}); err == nil {
+		t.Error("expected error for missing topic, got nil instead")
+	}
+	calls := db.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 call, got %+v instead", calls)
+	}
+	if want := []any{"order_created", "1", []byte(`{}`), map[string]string{}}; !reflect.DeepEqual(calls[0].Args, want) {
+		t.Errorf("expected args to be %v, got %v instead", want, calls[0].Args)
+	}
+}
+
+// messages response with the given IDs.
+func messages(ids ...int64) pgxfake.Response {
+	r := pgxfake.Response{
+		Columns: []string{"id", "topic", "key", "payload", "headers", "attempts", "last_error", "created_at"},
+	}
+	for _, id := range ids {
+		r.Rows = append(r.Rows, []any{id, "order_created", fmt.Sprint(id), []byte(`{}`), map[string]string{}, 0, "", time.Now()})
+	}
+	return r
+}
+
+func TestRelayProcess(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	db.On(`SELECT .* FROM outbox .* FOR UPDATE SKIP LOCKED`, messages(1, 2, 3))
+	db.On(`UPDATE outbox`, pgxfake.Response{Tag: "UPDATE 1"})
+	db.On(`DELETE FROM outbox`, pgxfake.Response{Tag: "DELETE 2"})
+
+	errBroker := errors.New("broker unavailable")
+	var handled []string
+	var errs []error
+	relay := outbox.NewRelay(db, func(ctx context.Context, m outbox.Message) error {
+		handled = append(handled, m.Key)
+		if m.ID == 2 {
+			return errBroker
+		}
+		return nil
+	}, outbox.Options{
+		Backoff: func(attempts int) time.Duration { return time.Duration(attempts) * time.Minute },
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+	delivered, err := relay.Process(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	if delivered != 2 {
+		t.Errorf("expected 2 messages to be delivered, got %d instead", delivered)
+	}
+	if want := []string{"1", "2", "3"}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("expected handled messages to be %v, got %v instead", want, handled)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], errBroker) {
+		t.Errorf("expected error to be %v, got %v instead", errBroker, errs)
+	}
+
+	calls := db.Calls()
+	if len(calls) != 5 {
+		t.Fatalf("expected 5 calls, got %+v instead", calls)
+	}
+	if want := []any{int64(2), errBroker.Error(), int64(60000)}; !reflect.DeepEqual(calls[2].Args, want) {
+		t.Errorf("expected failed message to be rescheduled with %v, got %v instead", want, calls[2].Args)
+	}
+	if want := []any{[]int64{1, 3}}; !reflect.DeepEqual(calls[3].Args, want) {
+		t.Errorf("expected delivered messages to be deleted with %v, got %v instead", want, calls[3].Args)
+	}
+	if calls[4].Method != "Commit" {
+		t.Errorf("expected transaction to be committed, got %+v instead", calls[4])
+	}
+}
+
+func TestRelayProcessError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	errQuery := errors.New("relation \"outbox\" does not exist")
+	db.On(`FROM outbox`, pgxfake.Response{Err: errQuery})
+	relay := outbox.NewRelay(db, func(ctx context.Context, m outbox.Message) error {
+		t.Error("expected handler not to be called")
+		return nil
+	}, outbox.Options{})
+	if _, err := relay.Process(ctx); !errors.Is(err, errQuery) {
+		t.Errorf("expected error to be %v, got %v instead", errQuery, err)
+	}
+	if calls := db.Calls(); calls[len(calls)-1].Method != "Rollback" {
+		t.Errorf("expected transaction to be rolled back, got %+v instead", calls)
+	}
+}
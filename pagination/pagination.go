@@ -0,0 +1,231 @@
+// Package pagination implements keyset (seek) pagination for structs mapped with pgtools.
+//
+// Unlike OFFSET pagination, keyset pagination doesn't need to scan and skip the rows of
+// previous pages, so it performs well with large tables, as long as there's an index matching
+// the sort order. Pages are requested using opaque cursors encoding the keys of the last row
+// of the previous page.
+//
+// Key columns must be NOT NULL, and the last one must be unique (such as a primary key)
+// to guarantee a stable order.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/henvic/pgtools"
+)
+
+// ErrInvalidCursor is returned when a cursor can't be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Key column used to sort rows.
+type Key struct {
+	Column string
+	Desc   bool
+
+	index []int
+	typ   reflect.Type
+}
+
+// Keyset pagination for a struct type.
+type Keyset struct {
+	typ  reflect.Type
+	keys []Key
+}
+
+// New returns a Keyset for paginating rows mapped to v, sorted by the given columns.
+// Prefix a column with "-" to sort it in descending order, as in:
+//
+//	keyset, err := pagination.New(Post{}, "-created_at", "-id")
+func New(v any, columns ...string) (*Keyset, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("pagination requires at least one key column")
+	}
+	if v == nil {
+		return nil, errors.New("pagination requires a struct")
+	}
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	metadata := pgtools.Metadata(v)
+	k := &Keyset{
+		typ: t,
+	}
+	for _, c := range columns {
+		key := Key{Column: c}
+		if strings.HasPrefix(c, "-") {
+			key = Key{Column: c[1:], Desc: true}
+		}
+		for _, mc := range metadata {
+			if mc.Name == key.Column {
+				key.index, key.typ = mc.Index, mc.Type
+				break
+			}
+		}
+		if key.index == nil {
+			return nil, fmt.Errorf("column %q not found in %v", key.Column, k.typ)
+		}
+		k.keys = append(k.keys, key)
+	}
+	return k, nil
+}
+
+// Keys used to sort rows.
+func (k *Keyset) Keys() []Key {
+	return append([]Key(nil), k.keys...)
+}
+
+// OrderBy returns the ORDER BY clause for the keyset, as in:
+//
+//	ORDER BY "created_at" DESC, "id" DESC
+func (k *Keyset) OrderBy() string {
+	var b strings.Builder
+	b.WriteString("ORDER BY ")
+	for n, key := range k.keys {
+		if n != 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(`"`)
+		b.WriteString(key.Column)
+		b.WriteString(`"`)
+		if key.Desc {
+			b.WriteString(" DESC")
+		} else {
+			b.WriteString(" ASC")
+		}
+	}
+	return b.String()
+}
+
+// Where returns the WHERE clause to seek the rows after the given cursor,
+// and the arguments referenced positionally by it as $1, $2, etc., as in:
+//
+//	WHERE ("created_at","id") < ($1,$2)
+//
+// If all keys are sorted in the same direction, a row comparison is used.
+// Otherwise, the condition is expanded for each key.
+//
+// For the first page, use an empty cursor, and Where returns an empty clause.
+func (k *Keyset) Where(cursor string) (string, []any, error) {
+	if cursor == "" {
+		return "", nil, nil
+	}
+	args, err := k.Decode(cursor)
+	if err != nil {
+		return "", nil, err
+	}
+	var b strings.Builder
+	b.WriteString("WHERE ")
+	if k.sameDirection() {
+		op := ">"
+		if k.keys[0].Desc {
+			op = "<"
+		}
+		b.WriteString("(")
+		for n, key := range k.keys {
+			if n != 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(`"`)
+			b.WriteString(key.Column)
+			b.WriteString(`"`)
+		}
+		b.WriteString(") ")
+		b.WriteString(op)
+		b.WriteString(" (")
+		for n := range k.keys {
+			if n != 0 {
+				b.WriteString(",")
+			}
+			b.WriteString("$")
+			b.WriteString(strconv.Itoa(n + 1))
+		}
+		b.WriteString(")")
+		return b.String(), args, nil
+	}
+
+	// (a > $1) OR (a = $1 AND b < $2) OR ...
+	for i, key := range k.keys {
+		if i != 0 {
+			b.WriteString(" OR ")
+		}
+		b.WriteString("(")
+		for j, prev := range k.keys[:i] {
+			b.WriteString(`"`)
+			b.WriteString(prev.Column)
+			b.WriteString(`" = $`)
+			b.WriteString(strconv.Itoa(j + 1))
+			b.WriteString(" AND ")
+		}
+		op := " > $"
+		if key.Desc {
+			op = " < $"
+		}
+		b.WriteString(`"`)
+		b.WriteString(key.Column)
+		b.WriteString(`"`)
+		b.WriteString(op)
+		b.WriteString(strconv.Itoa(i + 1))
+		b.WriteString(")")
+	}
+	return b.String(), args, nil
+}
+
+func (k *Keyset) sameDirection() bool {
+	for _, key := range k.keys[1:] {
+		if key.Desc != k.keys[0].Desc {
+			return false
+		}
+	}
+	return true
+}
+
+// Cursor returns an opaque cursor for the given row, which should be the last row of a page.
+func (k *Keyset) Cursor(row any) (string, error) {
+	rv := reflect.Indirect(reflect.ValueOf(row))
+	if !rv.IsValid() || rv.Type() != k.typ {
+		return "", fmt.Errorf("cannot create cursor: row must be a %v", k.typ)
+	}
+	values := make([]any, 0, len(k.keys))
+	for _, key := range k.keys {
+		f, err := rv.FieldByIndexErr(key.index)
+		if err != nil {
+			return "", fmt.Errorf("cannot create cursor: %w", err)
+		}
+		values = append(values, f.Interface())
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("cannot create cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Decode a cursor into the values of its keys, to use as query arguments.
+// Values are decoded into the types of the struct fields they were obtained from.
+func (k *Keyset) Decode(cursor string) ([]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil || len(raw) != len(k.keys) {
+		return nil, ErrInvalidCursor
+	}
+	args := make([]any, 0, len(k.keys))
+	for i, key := range k.keys {
+		v := reflect.New(key.typ)
+		if err := json.Unmarshal(raw[i], v.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		args = append(args, v.Elem().Interface())
+	}
+	return args, nil
+}
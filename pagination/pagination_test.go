@@ -0,0 +1,173 @@
+package pagination_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools"
+	"github.com/henvic/pgtools/pagination"
+)
+
+type Post struct {
+	ID        int64 `db:"id,pk"`
+	Title     string
+	CreatedAt time.Time
+}
+
+func Example() {
+	keyset, err := pagination.New(Post{}, "-created_at", "-id")
+	if err != nil {
+		panic(err)
+	}
+	// First page:
+	fmt.Println("SELECT " + pgtools.Wildcard(Post{}) + " FROM posts " + keyset.OrderBy() + " LIMIT 10")
+
+	// After scanning the rows, create a cursor for the next page with the last one:
+	last := Post{ID: 42, CreatedAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}
+	cursor, err := keyset.Cursor(last)
+	if err != nil {
+		panic(err)
+	}
+
+	// Next page:
+	where, args, err := keyset.Where(cursor)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("SELECT " + pgtools.Wildcard(Post{}) + " FROM posts " + where + " " + keyset.OrderBy() + " LIMIT 10")
+	fmt.Println(args...)
+	// Output:
+	// SELECT "id","title","created_at" FROM posts ORDER BY "created_at" DESC, "id" DESC LIMIT 10
+	// SELECT "id","title","created_at" FROM posts WHERE ("created_at","id") < ($1,$2) ORDER BY "created_at" DESC, "id" DESC LIMIT 10
+	// 2023-01-02 03:04:05 +0000 UTC 42
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc    string
+		v       any
+		columns []string
+		want    []pagination.Key
+		wantErr string
+	}{
+		{
+			desc:    "nil",
+			v:       nil,
+			columns: []string{"id"},
+			wantErr: "pagination requires a struct",
+		},
+		{
+			desc:    "no columns",
+			v:       Post{},
+			wantErr: "pagination requires at least one key column",
+		},
+		{
+			desc:    "unknown column",
+			v:       &Post{},
+			columns: []string{"id", "-unknown"},
+			wantErr: `column "unknown" not found in pagination_test.Post`,
+		},
+		{
+			desc:    "keys",
+			v:       &Post{},
+			columns: []string{"title", "-id"},
+			want: []pagination.Key{
+				{Column: "title"},
+				{Column: "id", Desc: true},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			k, err := pagination.New(tc.v, tc.columns...)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("expected error %q, got %v instead", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			keys := k.Keys()
+			if len(keys) != len(tc.want) {
+				t.Fatalf("expected %d keys, got %d instead", len(tc.want), len(keys))
+			}
+			for i, key := range keys {
+				if key.Column != tc.want[i].Column || key.Desc != tc.want[i].Desc {
+					t.Errorf("expected key %d to be %+v, got %+v instead", i, tc.want[i], key)
+				}
+			}
+		})
+	}
+}
+
+func TestWhereMixedDirections(t *testing.T) {
+	t.Parallel()
+	k, err := pagination.New(Post{}, "title", "-created_at", "id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := k.OrderBy(), `ORDER BY "title" ASC, "created_at" DESC, "id" ASC`; got != want {
+		t.Errorf("expected %v, got %v instead", want, got)
+	}
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	cursor, err := k.Cursor(&Post{ID: 7, Title: "hello", CreatedAt: created})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	where, args, err := k.Where(cursor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `WHERE ("title" > $1) OR ("title" = $1 AND "created_at" < $2) OR ("title" = $1 AND "created_at" = $2 AND "id" > $3)`
+	if where != want {
+		t.Errorf("expected %v, got %v instead", want, where)
+	}
+	if wantArgs := []any{"hello", created, int64(7)}; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("expected args %#v, got %#v instead", wantArgs, args)
+	}
+}
+
+func TestWhereFirstPage(t *testing.T) {
+	t.Parallel()
+	k, err := pagination.New(Post{}, "id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	where, args, err := k.Where("")
+	if where != "" || args != nil || err != nil {
+		t.Errorf("expected empty clause, got %q, %v, %v instead", where, args, err)
+	}
+}
+
+func TestInvalidCursor(t *testing.T) {
+	t.Parallel()
+	k, err := pagination.New(Post{}, "id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, cursor := range []string{"!", "bm90IGpzb24", "WzEsMl0", "WyJ4Il0"} {
+		if _, _, err := k.Where(cursor); !errors.Is(err, pagination.ErrInvalidCursor) {
+			t.Errorf("expected invalid cursor error for %q, got %v instead", cursor, err)
+		}
+	}
+}
+
+func TestCursorWrongType(t *testing.T) {
+	t.Parallel()
+	k, err := pagination.New(Post{}, "id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := k.Cursor(struct{ ID int64 }{}); err == nil {
+		t.Error("expected error creating cursor for the wrong type")
+	}
+	if _, err := k.Cursor(nil); err == nil {
+		t.Error("expected error creating cursor for nil")
+	}
+}
@@ -0,0 +1,146 @@
+// Package pgerr translates the PostgreSQL errors returned by pgx so that application code
+// can handle them without matching SQLSTATE codes or error messages, as in:
+//
+//	_, err := db.Exec(ctx, "INSERT INTO users (id, email) VALUES ($1, $2)", id, email)
+//	if pgerr.IsUniqueViolation(err, "users_email_key") {
+//		return ErrEmailTaken
+//	}
+//
+// The functions look for a *pgconn.PgError in the chain of wrapped errors, so they work with errors
+// wrapped with fmt.Errorf and %w.
+//
+// Reference: https://www.postgresql.org/docs/current/errcodes-appendix.html
+package pgerr
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes of commonly handled PostgreSQL errors.
+const (
+	NotNullViolation     = "23502"
+	ForeignKeyViolation  = "23503"
+	UniqueViolation      = "23505"
+	CheckViolation       = "23514"
+	ExclusionViolation   = "23P01"
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+	UndefinedTable       = "42P01"
+	LockNotAvailable     = "55P03"
+	QueryCanceled        = "57014"
+)
+
+// Class of integrity constraint violations, the first two characters of their SQLSTATE codes.
+const IntegrityConstraintViolation = "23"
+
+// As returns the PostgreSQL error wrapped by err, if any.
+func As(err error) (*pgconn.PgError, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr, true
+	}
+	return nil, false
+}
+
+// Code returns the SQLSTATE code of the PostgreSQL error wrapped by err, or an empty string.
+func Code(err error) string {
+	if pgErr, ok := As(err); ok {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// ConstraintName returns the name of the constraint violated, or an empty string.
+func ConstraintName(err error) string {
+	if pgErr, ok := As(err); ok {
+		return pgErr.ConstraintName
+	}
+	return ""
+}
+
+// IsConstraintViolation reports whether err is an integrity constraint violation of any kind.
+// If constraints are given, the name of the violated constraint must be one of them.
+func IsConstraintViolation(err error, constraints ...string) bool {
+	pgErr, ok := As(err)
+	return ok && len(pgErr.Code) == 5 && pgErr.Code[:2] == IntegrityConstraintViolation &&
+		matchConstraint(pgErr, constraints)
+}
+
+// IsUniqueViolation reports whether err is a unique violation (23505).
+// If constraints are given, the name of the violated constraint must be one of them.
+func IsUniqueViolation(err error, constraints ...string) bool {
+	return isConstraint(err, UniqueViolation, constraints)
+}
+
+// IsForeignKeyViolation reports whether err is a foreign key violation (23503).
+// If constraints are given, the name of the violated constraint must be one of them.
+func IsForeignKeyViolation(err error, constraints ...string) bool {
+	return isConstraint(err, ForeignKeyViolation, constraints)
+}
+
+// IsCheckViolation reports whether err is a check constraint violation (23514).
+// If constraints are given, the name of the violated constraint must be one of them.
+func IsCheckViolation(err error, constraints ...string) bool {
+	return isConstraint(err, CheckViolation, constraints)
+}
+
+// IsExclusionViolation reports whether err is an exclusion constraint violation (23P01).
+// If constraints are given, the name of the violated constraint must be one of them.
+func IsExclusionViolation(err error, constraints ...string) bool {
+	return isConstraint(err, ExclusionViolation, constraints)
+}
+
+// IsNotNullViolation reports whether err is a not-null constraint violation (23502).
+// If columns are given, the name of the column must be one of them.
+func IsNotNullViolation(err error, columns ...string) bool {
+	pgErr, ok := As(err)
+	return ok && pgErr.Code == NotNullViolation && (len(columns) == 0 || contains(columns, pgErr.ColumnName))
+}
+
+// IsSerializationFailure reports whether err is a serialization failure (40001),
+// meaning the transaction can be retried.
+func IsSerializationFailure(err error) bool {
+	return Code(err) == SerializationFailure
+}
+
+// IsDeadlock reports whether err is a deadlock detected by the server (40P01),
+// meaning the transaction can be retried.
+func IsDeadlock(err error) bool {
+	return Code(err) == DeadlockDetected
+}
+
+// IsLockNotAvailable reports whether err is a failure to acquire a lock with NOWAIT or lock_timeout (55P03).
+func IsLockNotAvailable(err error) bool {
+	return Code(err) == LockNotAvailable
+}
+
+// IsQueryCanceled reports whether err is a statement canceled by the server (57014),
+// such as when statement_timeout is exceeded.
+func IsQueryCanceled(err error) bool {
+	return Code(err) == QueryCanceled
+}
+
+// IsUndefinedTable reports whether err is a reference to a table that doesn't exist (42P01).
+func IsUndefinedTable(err error) bool {
+	return Code(err) == UndefinedTable
+}
+
+func isConstraint(err error, code string, constraints []string) bool {
+	pgErr, ok := As(err)
+	return ok && pgErr.Code == code && matchConstraint(pgErr, constraints)
+}
+
+func matchConstraint(pgErr *pgconn.PgError, constraints []string) bool {
+	return len(constraints) == 0 || contains(constraints, pgErr.ConstraintName)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
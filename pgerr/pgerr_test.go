@@ -0,0 +1,128 @@
+package pgerr_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/henvic/pgtools/pgerr"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestCode(t *testing.T) {
+	t.Parallel()
+	err := fmt.Errorf("cannot create user: %w", &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"})
+	if got := pgerr.Code(err); got != pgerr.UniqueViolation {
+		t.Errorf("expected code to be %q, got %q instead", pgerr.UniqueViolation, got)
+	}
+	if got := pgerr.ConstraintName(err); got != "users_email_key" {
+		t.Errorf("expected constraint name to be %q, got %q instead", "users_email_key", got)
+	}
+	other := errors.New("connection refused")
+	if got := pgerr.Code(other); got != "" {
+		t.Errorf("expected no code, got %q instead", got)
+	}
+	if got := pgerr.ConstraintName(other); got != "" {
+		t.Errorf("expected no constraint name, got %q instead", got)
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	t.Parallel()
+	unique := fmt.Errorf("cannot create user: %w", &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"})
+	testCases := []struct {
+		desc string
+		got  bool
+		want bool
+	}{
+		{
+			desc: "unique violation",
+			got:  pgerr.IsUniqueViolation(unique),
+			want: true,
+		},
+		{
+			desc: "unique violation of constraint",
+			got:  pgerr.IsUniqueViolation(unique, "users_pkey", "users_email_key"),
+			want: true,
+		},
+		{
+			desc: "unique violation of another constraint",
+			got:  pgerr.IsUniqueViolation(unique, "users_pkey"),
+		},
+		{
+			desc: "constraint violation",
+			got:  pgerr.IsConstraintViolation(unique, "users_email_key"),
+			want: true,
+		},
+		{
+			desc: "foreign key violation",
+			got:  pgerr.IsForeignKeyViolation(&pgconn.PgError{Code: "23503", ConstraintName: "posts_user_id_fkey"}, "posts_user_id_fkey"),
+			want: true,
+		},
+		{
+			desc: "unique violation is not a foreign key violation",
+			got:  pgerr.IsForeignKeyViolation(unique),
+		},
+		{
+			desc: "check violation",
+			got:  pgerr.IsCheckViolation(&pgconn.PgError{Code: "23514", ConstraintName: "users_age_check"}),
+			want: true,
+		},
+		{
+			desc: "exclusion violation",
+			got:  pgerr.IsExclusionViolation(&pgconn.PgError{Code: "23P01", ConstraintName: "bookings_room_during_excl"}),
+			want: true,
+		},
+		{
+			desc: "not-null violation of column",
+			got:  pgerr.IsNotNullViolation(&pgconn.PgError{Code: "23502", ColumnName: "email"}, "email"),
+			want: true,
+		},
+		{
+			desc: "not-null violation of another column",
+			got:  pgerr.IsNotNullViolation(&pgconn.PgError{Code: "23502", ColumnName: "email"}, "name"),
+		},
+		{
+			desc: "serialization failure",
+			got:  pgerr.IsSerializationFailure(&pgconn.PgError{Code: "40001"}),
+			want: true,
+		},
+		{
+			desc: "deadlock",
+			got:  pgerr.IsDeadlock(&pgconn.PgError{Code: "40P01"}),
+			want: true,
+		},
+		{
+			desc: "lock not available",
+			got:  pgerr.IsLockNotAvailable(&pgconn.PgError{Code: "55P03"}),
+			want: true,
+		},
+		{
+			desc: "query canceled",
+			got:  pgerr.IsQueryCanceled(&pgconn.PgError{Code: "57014"}),
+			want: true,
+		},
+		{
+			desc: "undefined table",
+			got:  pgerr.IsUndefinedTable(&pgconn.PgError{Code: "42P01"}),
+			want: true,
+		},
+		{
+			desc: "serialization failure is not a constraint violation",
+			got:  pgerr.IsConstraintViolation(&pgconn.PgError{Code: "40001"}),
+		},
+		{
+			desc: "not a PostgreSQL error",
+			got:  pgerr.IsUniqueViolation(errors.New("duplicate key")),
+		},
+		{
+			desc: "nil",
+			got:  pgerr.IsSerializationFailure(nil),
+		},
+	}
+	for _, tc := range testCases {
+		if tc.got != tc.want {
+			t.Errorf("%s: expected %v, got %v instead", tc.desc, tc.want, tc.got)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package pglock_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools/pglock"
+	"github.com/henvic/pgtools/sqltest"
+)
+
+var force = flag.Bool("force", false, "Force cleaning the database before starting")
+
+func checkPostgres(t testing.TB) {
+	if os.Getenv("INTEGRATION_TESTDB") != "true" {
+		t.Skip("Skipping tests that require database connection")
+	}
+}
+
+func TestMutex(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("../sqltest/example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_pglock_",
+	})
+	pool := migration.Setup(ctx, "")
+
+	key := pglock.Key("billing")
+	m1 := pglock.New(pool, key, pglock.Options{})
+	m2 := pglock.New(pool, key, pglock.Options{})
+	if err := m1.Lock(ctx); err != nil {
+		t.Fatalf("cannot lock: %v", err)
+	}
+	if locked, err := m2.TryLock(ctx); err != nil || locked {
+		t.Errorf("expected lock to be unavailable, got %v, %v instead", locked, err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := m2.Lock(timeout); err == nil {
+		t.Error("expected lock to time out, got nil instead")
+	}
+	if err := m1.Unlock(ctx); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if err := m1.Unlock(ctx); !errors.Is(err, pglock.ErrNotLocked) {
+		t.Errorf("expected error to be %v, got %v instead", pglock.ErrNotLocked, err)
+	}
+	if locked, err := m2.TryLock(ctx); err != nil || !locked {
+		t.Errorf("expected lock to be acquired, got %v, %v instead", locked, err)
+	}
+	if err := m2.Unlock(ctx); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+}
+
+func TestMutexLost(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("../sqltest/example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_pglock_",
+	})
+	pool := migration.Setup(ctx, "")
+
+	m := pglock.New(pool, pglock.Key("reports"), pglock.Options{CheckInterval: 10 * time.Millisecond})
+	if err := m.Lock(ctx); err != nil {
+		t.Fatalf("cannot lock: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_locks
+		WHERE locktype = 'advisory' AND database = (SELECT oid FROM pg_database WHERE datname = current_database())`); err != nil {
+		t.Fatalf("cannot terminate connection holding the lock: %v", err)
+	}
+	select {
+	case <-m.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected lock to be lost")
+	}
+	if err := m.Unlock(ctx); !errors.Is(err, pglock.ErrLockLost) {
+		t.Errorf("expected error to be %v, got %v instead", pglock.ErrLockLost, err)
+	}
+}
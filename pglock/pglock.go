@@ -0,0 +1,222 @@
+// Package pglock coordinates processes with PostgreSQL advisory locks.
+//
+// A Mutex holds a session-level advisory lock on a dedicated connection acquired from a pool:
+//
+//	m := pglock.New(pool, pglock.Key("billing"), pglock.Options{})
+//	if err := m.Lock(ctx); err != nil {
+//		return err
+//	}
+//	defer m.Unlock(ctx)
+//
+// PostgreSQL releases the lock if the connection is lost, so the Mutex checks the connection periodically,
+// and closes the channel returned by Lost once it's gone, so that work protected by the lock can be stopped.
+//
+// Use LockTx and TryLockTx for transaction-level advisory locks, released once the transaction ends.
+//
+// Reference: https://www.postgresql.org/docs/current/explicit-locking.html#ADVISORY-LOCKS
+package pglock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/henvic/pgtools/pgxtx"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrNotLocked is returned by Unlock when the Mutex isn't locked.
+var ErrNotLocked = errors.New("advisory lock not held")
+
+// ErrLockLost is returned by Unlock when the connection holding the lock was lost, releasing it before Unlock was called.
+var ErrLockLost = errors.New("advisory lock lost with its connection")
+
+// Key returns the key of the advisory lock for the name, so that locks can be identified by strings.
+func Key(name string) int64 {
+	sum := sha256.Sum256([]byte(name))
+	return int64(binary.BigEndian.Uint64(sum[:8]))
+}
+
+// Acquirer of dedicated connections, such as *pgxpool.Pool.
+type Acquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// Validate if *pgxpool.Pool implements the Acquirer interface.
+var _ Acquirer = (*pgxpool.Pool)(nil)
+
+// Options for the Mutex.
+type Options struct {
+	// CheckInterval is the interval between checks of the connection holding the lock.
+	// If zero, it's 10 seconds. If negative, the connection isn't checked.
+	CheckInterval time.Duration
+}
+
+// Mutex is a session-level advisory lock.
+// Like sync.Mutex, it's held by one goroutine at a time, but also by one Mutex with the same key across processes.
+type Mutex struct {
+	db  Acquirer
+	key int64
+	o   Options
+
+	// sem is held while the Mutex is locked, serializing the goroutines of the process.
+	sem chan struct{}
+
+	conn *pgxpool.Conn
+	lost chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New Mutex for the advisory lock with the key, acquiring connections from db.
+func New(db Acquirer, key int64, o Options) *Mutex {
+	if o.CheckInterval == 0 {
+		o.CheckInterval = 10 * time.Second
+	}
+	return &Mutex{
+		db:  db,
+		key: key,
+		o:   o,
+		sem: make(chan struct{}, 1),
+	}
+}
+
+// Lock waits until the lock is acquired, or until ctx is done.
+func (m *Mutex) Lock(ctx context.Context) error {
+	select {
+	case m.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		<-m.sem
+		return err
+	}
+	// If ctx is done while waiting, pgx closes the connection, so the server doesn't hold the lock.
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", m.key); err != nil {
+		conn.Release()
+		<-m.sem
+		return fmt.Errorf("cannot acquire lock: %w", err)
+	}
+	m.hold(conn)
+	return nil
+}
+
+// TryLock acquires the lock if it's available, without waiting, reporting whether it succeeded.
+func (m *Mutex) TryLock(ctx context.Context) (bool, error) {
+	select {
+	case m.sem <- struct{}{}:
+	default:
+		return false, nil
+	}
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		<-m.sem
+		return false, err
+	}
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", m.key).Scan(&locked); err != nil || !locked {
+		conn.Release()
+		<-m.sem
+		if err != nil {
+			return false, fmt.Errorf("cannot acquire lock: %w", err)
+		}
+		return false, nil
+	}
+	m.hold(conn)
+	return true, nil
+}
+
+// hold the lock on the connection, checking it periodically.
+func (m *Mutex) hold(conn *pgxpool.Conn) {
+	m.conn = conn
+	m.lost = make(chan struct{})
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.check(conn, m.lost, m.stop, m.done)
+}
+
+func (m *Mutex) check(conn *pgxpool.Conn, lost, stop, done chan struct{}) {
+	defer close(done)
+	if m.o.CheckInterval < 0 {
+		<-stop
+		return
+	}
+	ticker := time.NewTicker(m.o.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), m.o.CheckInterval)
+		err := conn.Ping(ctx)
+		cancel()
+		if err != nil {
+			close(lost)
+			return
+		}
+	}
+}
+
+// Lost returns a channel closed once the connection holding the lock is found to be lost.
+// It returns nil if the Mutex isn't locked.
+//
+// It must be called by the goroutine holding the lock.
+func (m *Mutex) Lost() <-chan struct{} {
+	return m.lost
+}
+
+// Unlock releases the lock, and the connection back to the pool.
+// If the connection was lost, it returns ErrLockLost, as the lock might have been acquired by another process meanwhile.
+func (m *Mutex) Unlock(ctx context.Context) error {
+	if m.conn == nil {
+		return ErrNotLocked
+	}
+	close(m.stop)
+	<-m.done
+	conn, lost := m.conn, m.lost
+	m.conn, m.lost, m.stop, m.done = nil, nil, nil, nil
+	defer func() { <-m.sem }()
+	defer conn.Release()
+
+	select {
+	case <-lost:
+		return ErrLockLost
+	default:
+	}
+	var unlocked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_advisory_unlock($1)", m.key).Scan(&unlocked); err != nil {
+		// Closing the connection releases the lock, in case it's still held.
+		_ = conn.Conn().Close(context.Background())
+		return fmt.Errorf("cannot release lock: %w", err)
+	}
+	if !unlocked {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// LockTx acquires a transaction-level advisory lock with the key, waiting until it's available or ctx is done.
+// The lock is released once the transaction ends.
+func LockTx(ctx context.Context, tx pgxtx.Querier, key int64) error {
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", key); err != nil {
+		return fmt.Errorf("cannot acquire lock: %w", err)
+	}
+	return nil
+}
+
+// TryLockTx acquires a transaction-level advisory lock with the key if it's available, without waiting,
+// reporting whether it succeeded. The lock is released once the transaction ends.
+func TryLockTx(ctx context.Context, tx pgxtx.Querier, key int64) (bool, error) {
+	var locked bool
+	if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", key).Scan(&locked); err != nil {
+		return false, fmt.Errorf("cannot acquire lock: %w", err)
+	}
+	return locked, nil
+}
@@ -0,0 +1,80 @@
+package pglock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/henvic/pgtools/pglock"
+	"github.com/henvic/pgtools/pgxfake"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestKey(t *testing.T) {
+	t.Parallel()
+	if pglock.Key("billing") != pglock.Key("billing") {
+		t.Error("expected key to be the same for the same name")
+	}
+	if pglock.Key("billing") == pglock.Key("reports") {
+		t.Error("expected keys to be different for different names")
+	}
+}
+
+func TestLockTx(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	db.On(`pg_advisory_xact_lock`, pgxfake.Response{Tag: "SELECT 1"})
+	key := pglock.Key("billing")
+	err := pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		return pglock.LockTx(ctx, tx, key)
+	})
+	if err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	calls := db.Calls()
+	if len(calls) != 3 || calls[1].SQL != "SELECT pg_advisory_xact_lock($1)" || calls[1].Args[0] != key {
+		t.Errorf("expected lock to be acquired in the transaction, got %+v instead", calls)
+	}
+}
+
+func TestTryLockTx(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	testCases := []struct {
+		desc     string
+		response pgxfake.Response
+		want     bool
+		wantErr  bool
+	}{
+		{
+			desc:     "locked",
+			response: pgxfake.Response{Columns: []string{"pg_try_advisory_xact_lock"}, Rows: [][]any{{true}}},
+			want:     true,
+		},
+		{
+			desc:     "unavailable",
+			response: pgxfake.Response{Columns: []string{"pg_try_advisory_xact_lock"}, Rows: [][]any{{false}}},
+		},
+		{
+			desc:     "error",
+			response: pgxfake.Response{Err: errors.New("connection reset")},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			db := pgxfake.New()
+			db.On(`pg_try_advisory_xact_lock`, tc.response)
+			locked, err := pglock.TryLockTx(ctx, db, pglock.Key("billing"))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error to be %v, got %v instead", tc.wantErr, err)
+			}
+			if locked != tc.want {
+				t.Errorf("expected locked to be %v, got %v instead", tc.want, locked)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package pgnotify_test
+
+import (
+	"context"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools/pgnotify"
+	"github.com/henvic/pgtools/sqltest"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var force = flag.Bool("force", false, "Force cleaning the database before starting")
+
+func checkPostgres(t testing.TB) {
+	if os.Getenv("INTEGRATION_TESTDB") != "true" {
+		t.Skip("Skipping tests that require database connection")
+	}
+}
+
+func TestListener(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("../sqltest/example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_pgnotify_",
+	})
+	pool := migration.Setup(ctx, "")
+
+	connected := make(chan struct{}, 1)
+	l := pgnotify.NewListener(pool, []string{"media_created"}, pgnotify.Options{
+		ReconnectDelay: 10 * time.Millisecond,
+		OnConnect: func(ctx context.Context) {
+			connected <- struct{}{}
+		},
+	})
+	sub := l.Subscribe()
+	stopped := make(chan struct{})
+	go func() {
+		l.Run(ctx)
+		close(stopped)
+	}()
+
+	receive := func(payload string) {
+		t.Helper()
+		if err := pgnotify.Notify(ctx, pool, "media_created", payload); err != nil {
+			t.Fatalf("cannot notify: %v", err)
+		}
+		var n *pgconn.Notification
+		select {
+		case n = <-sub.C:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected notification, got none")
+		}
+		if n.Channel != "media_created" || n.Payload != payload {
+			t.Errorf("expected notification on media_created with payload %q, got %+v instead", payload, n)
+		}
+	}
+
+	<-connected
+	receive("1")
+
+	// Terminate the connection of the listener, which reconnects and listens again.
+	if _, err := pool.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+		WHERE datname = current_database() AND query LIKE 'LISTEN%'`); err != nil {
+		t.Fatalf("cannot terminate listener connection: %v", err)
+	}
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected listener to reconnect")
+	}
+	receive("2")
+
+	cancel()
+	<-stopped
+	if _, ok := <-sub.C; ok {
+		t.Error("expected subscription to be closed once the listener stops")
+	}
+}
@@ -0,0 +1,254 @@
+package pgnotify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Options for the Listener.
+type Options struct {
+	// ReconnectDelay is the time to wait before reconnecting after the connection is lost.
+	// If zero, it's one second.
+	ReconnectDelay time.Duration
+
+	// BatchInterval is the time to gather notifications before delivering them to the subscribers.
+	// Notifications with the same channel and payload received in the interval are delivered only once.
+	// If zero, notifications are delivered as soon as they're received.
+	BatchInterval time.Duration
+
+	// BufferSize of the channel of each subscription.
+	// If zero, it's 64.
+	BufferSize int
+
+	// OnConnect is called once the channels are listened to, after connecting and after reconnecting.
+	OnConnect func(ctx context.Context)
+
+	// OnError is called with the errors that make the Listener reconnect.
+	OnError func(err error)
+}
+
+// Listener of notifications on a dedicated connection.
+type Listener struct {
+	db       pgxs.Listener
+	channels []string
+	o        Options
+
+	mu            sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+	stopped       bool
+}
+
+// NewListener of notifications on the channels, using a connection acquired from db.
+// Call Run to start listening.
+func NewListener(db pgxs.Listener, channels []string, o Options) *Listener {
+	if o.ReconnectDelay == 0 {
+		o.ReconnectDelay = time.Second
+	}
+	if o.BufferSize == 0 {
+		o.BufferSize = 64
+	}
+	return &Listener{
+		db:            db,
+		channels:      channels,
+		o:             o,
+		subscriptions: map[*Subscription]struct{}{},
+	}
+}
+
+// Subscription to notifications delivered by a Listener.
+type Subscription struct {
+	// C receives the notifications, and is closed once the subscription is closed or the Listener stops.
+	C <-chan *pgconn.Notification
+
+	l        *Listener
+	c        chan *pgconn.Notification
+	channels map[string]bool
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Subscribe to the notifications on the channels, or on all channels of the Listener if none is given.
+//
+// Delivery waits for the subscribers to receive the notifications once their buffers are full,
+// so read them promptly, and close subscriptions that aren't needed anymore.
+func (l *Listener) Subscribe(channels ...string) *Subscription {
+	c := make(chan *pgconn.Notification, l.o.BufferSize)
+	s := &Subscription{
+		C:        c,
+		l:        l,
+		c:        c,
+		channels: map[string]bool{},
+		done:     make(chan struct{}),
+	}
+	for _, channel := range channels {
+		s.channels[channel] = true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopped {
+		close(s.c)
+		return s
+	}
+	l.subscriptions[s] = struct{}{}
+	return s
+}
+
+// Close the subscription.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		// Closing done first releases a delivery waiting on this subscription, so the lock can be acquired.
+		close(s.done)
+		s.l.mu.Lock()
+		defer s.l.mu.Unlock()
+		if _, ok := s.l.subscriptions[s]; ok {
+			delete(s.l.subscriptions, s)
+			close(s.c)
+		}
+	})
+}
+
+// Run listens to the notifications until ctx is canceled, reconnecting whenever the connection is lost.
+// Once it returns, the subscriptions are closed, and the Listener cannot be run again.
+func (l *Listener) Run(ctx context.Context) {
+	defer l.stop()
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if l.o.OnError != nil {
+			l.o.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(l.o.ReconnectDelay):
+		}
+	}
+}
+
+func (l *Listener) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopped = true
+	for s := range l.subscriptions {
+		delete(l.subscriptions, s)
+		close(s.c)
+	}
+}
+
+// listen to the channels on a new connection, delivering the notifications until the connection fails.
+func (l *Listener) listen(ctx context.Context) error {
+	pc, err := l.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection is taken from the pool and closed once done, so that it doesn't go back to the pool still listening.
+	conn := pc.Hijack()
+	defer conn.Close(context.Background())
+
+	for _, channel := range l.channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+	}
+	if l.o.OnConnect != nil {
+		l.o.OnConnect(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	notifications := make(chan *pgconn.Notification)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(notifications)
+		for {
+			n, err := conn.WaitForNotification(ctx)
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case notifications <- n:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+	}()
+	l.dispatch(ctx, notifications)
+	// Wait for the connection to be free before closing it.
+	cancel()
+	for range notifications {
+	}
+	return <-errc
+}
+
+// dispatch the notifications received to the subscribers, batching them if needed.
+func (l *Listener) dispatch(ctx context.Context, notifications <-chan *pgconn.Notification) {
+	var (
+		batch []*pgconn.Notification
+		timer *time.Timer
+		tick  <-chan time.Time
+	)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		select {
+		case n, ok := <-notifications:
+			if !ok {
+				l.deliver(ctx, batch)
+				return
+			}
+			if l.o.BatchInterval <= 0 {
+				l.deliver(ctx, []*pgconn.Notification{n})
+				continue
+			}
+			batch = append(batch, n)
+			if tick == nil {
+				timer = time.NewTimer(l.o.BatchInterval)
+				tick = timer.C
+			}
+		case <-tick:
+			l.deliver(ctx, batch)
+			batch, timer, tick = nil, nil, nil
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deliver the notifications to the subscribers, skipping duplicates.
+func (l *Listener) deliver(ctx context.Context, batch []*pgconn.Notification) {
+	type key struct {
+		channel, payload string
+	}
+	seen := map[key]bool{}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, n := range batch {
+		k := key{n.Channel, n.Payload}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		for s := range l.subscriptions {
+			if len(s.channels) != 0 && !s.channels[n.Channel] {
+				continue
+			}
+			select {
+			case s.c <- n:
+			case <-s.done:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package pgnotify
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// received notifications until the channel is closed.
+func received(c <-chan *pgconn.Notification) string {
+	var got []string
+	for n := range c {
+		got = append(got, n.Channel+":"+n.Payload)
+	}
+	return fmt.Sprint(got)
+}
+
+func TestDispatch(t *testing.T) {
+	t.Parallel()
+	l := NewListener(nil, []string{"media_created", "media_deleted"}, Options{})
+	all := l.Subscribe()
+	created := l.Subscribe("media_created")
+
+	notifications := make(chan *pgconn.Notification)
+	go func() {
+		notifications <- &pgconn.Notification{Channel: "media_created", Payload: "1"}
+		notifications <- &pgconn.Notification{Channel: "media_deleted", Payload: "2"}
+		notifications <- &pgconn.Notification{Channel: "media_created", Payload: "3"}
+		close(notifications)
+	}()
+	l.dispatch(context.Background(), notifications)
+	l.stop()
+
+	if want, got := "[media_created:1 media_deleted:2 media_created:3]", received(all.C); got != want {
+		t.Errorf("expected notifications to be %v, got %v instead", want, got)
+	}
+	if want, got := "[media_created:1 media_created:3]", received(created.C); got != want {
+		t.Errorf("expected notifications to be %v, got %v instead", want, got)
+	}
+}
+
+func TestDispatchBatch(t *testing.T) {
+	t.Parallel()
+	l := NewListener(nil, []string{"media_created"}, Options{BatchInterval: time.Hour})
+	sub := l.Subscribe()
+
+	notifications := make(chan *pgconn.Notification)
+	go func() {
+		for _, payload := range []string{"1", "2", "1", "1"} {
+			notifications <- &pgconn.Notification{Channel: "media_created", Payload: payload}
+		}
+		if len(sub.C) != 0 {
+			t.Error("expected notifications to be delivered once the batch is done")
+		}
+		close(notifications)
+	}()
+	l.dispatch(context.Background(), notifications)
+	l.stop()
+
+	if want, got := "[media_created:1 media_created:2]", received(sub.C); got != want {
+		t.Errorf("expected notifications to be %v, got %v instead", want, got)
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	t.Parallel()
+	l := NewListener(nil, []string{"media_created"}, Options{BufferSize: 1})
+	slow := l.Subscribe()
+	sub := l.Subscribe()
+
+	notifications := make(chan *pgconn.Notification)
+	done := make(chan struct{})
+	go func() {
+		l.dispatch(context.Background(), notifications)
+		close(done)
+	}()
+	notifications <- &pgconn.Notification{Channel: "media_created", Payload: "1"}
+	if n := <-sub.C; n.Payload != "1" {
+		t.Errorf("expected payload to be %q, got %q instead", "1", n.Payload)
+	}
+	// The buffer of the slow subscription is full, so the second notification is delivered once it's closed.
+	notifications <- &pgconn.Notification{Channel: "media_created", Payload: "2"}
+	slow.Close()
+	if n := <-sub.C; n.Payload != "2" {
+		t.Errorf("expected payload to be %q, got %q instead", "2", n.Payload)
+	}
+	close(notifications)
+	<-done
+
+	if want, got := "[media_created:1]", received(slow.C); got != want {
+		t.Errorf("expected notifications to be %v, got %v instead", want, got)
+	}
+	l.stop()
+	if _, ok := <-sub.C; ok {
+		t.Error("expected subscription to be closed once the listener stops")
+	}
+	if _, ok := <-l.Subscribe().C; ok {
+		t.Error("expected subscription to a stopped listener to be closed")
+	}
+}
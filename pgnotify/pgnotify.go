@@ -0,0 +1,49 @@
+// Package pgnotify implements publish/subscribe with PostgreSQL LISTEN/NOTIFY.
+//
+// A Listener keeps a dedicated connection listening to a set of channels, reconnecting and listening again
+// after it's lost, and fans out the notifications to Go subscribers:
+//
+//	l := pgnotify.NewListener(pool, []string{"media_created"}, pgnotify.Options{})
+//	sub := l.Subscribe("media_created")
+//	go l.Run(ctx)
+//	for n := range sub.C {
+//		// ...
+//	}
+//
+// Use Notify to send notifications:
+//
+//	err := pgnotify.Notify(ctx, pool, "media_created", id)
+//
+// Notifications are sent when the transaction that sends them commits, and aren't queued for listeners
+// that are disconnected, so notifications sent while a Listener reconnects are lost.
+// Use Options.OnConnect to catch up with changes made in the meantime, if needed.
+//
+// Reference: https://www.postgresql.org/docs/current/sql-notify.html
+package pgnotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/henvic/pgtools/pgxtx"
+)
+
+// MaxPayloadSize is the maximum size of a payload in bytes accepted by a PostgreSQL server built with the default BLCKSZ.
+const MaxPayloadSize = 7999
+
+// ErrPayloadTooLarge is returned by Notify when the payload is larger than MaxPayloadSize.
+var ErrPayloadTooLarge = errors.New("payload too large")
+
+// Notify sends a notification with the payload on the channel.
+// If db is a transaction, the notification is only sent once it commits.
+func Notify(ctx context.Context, db pgxtx.Querier, channel, payload string) error {
+	if channel == "" {
+		return errors.New("missing channel")
+	}
+	if len(payload) > MaxPayloadSize {
+		return fmt.Errorf("%w: %d bytes, maximum is %d", ErrPayloadTooLarge, len(payload), MaxPayloadSize)
+	}
+	_, err := db.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
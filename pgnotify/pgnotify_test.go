@@ -0,0 +1,35 @@
+package pgnotify_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/henvic/pgtools/pgnotify"
+	"github.com/henvic/pgtools/pgxfake"
+)
+
+func TestNotify(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	db.On(`pg_notify`, pgxfake.Response{Tag: "SELECT 1"})
+
+	if err := pgnotify.Notify(ctx, db, "media_created", "1"); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if err := pgnotify.Notify(ctx, db, "media_created", strings.Repeat("x", pgnotify.MaxPayloadSize+1)); !errors.Is(err, pgnotify.ErrPayloadTooLarge) {
+		t.Errorf("expected error to be %v, got %v instead", pgnotify.ErrPayloadTooLarge, err)
+	}
+	if err := pgnotify.Notify(ctx, db, "", "1"); err == nil {
+		t.Error("expected error for missing channel, got nil instead")
+	}
+	calls := db.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 call, got %+v instead", calls)
+	}
+	if want := []any{"media_created", "1"}; len(calls[0].Args) != 2 || calls[0].Args[0] != want[0] || calls[0].Args[1] != want[1] {
+		t.Errorf("expected args to be %v, got %v instead", want, calls[0].Args)
+	}
+}
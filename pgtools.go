@@ -3,9 +3,7 @@
 package pgtools
 
 import (
-	"container/list"
 	"reflect"
-	"sort"
 	"strings"
 	"sync"
 
@@ -26,14 +24,25 @@ import (
 // and for performance reasons too by reducing the number of places where
 // a wildcard (*) is used for convenience in SELECT queries.
 //
+// Use the Omit and Only options to select a subset of the columns.
+//
 // See example for usage.
 // It was first envisioned to use with github.com/georgysavva/scany, but you can
 // use it without it too. Since pg v5, you might want to use pgx.CollectOneRow and pgx.CollectRows.
 //
 // If you're curious about doing this "in the other direction", see
 // https://github.com/golang/pkgsite/blob/2d3ade3c90634f9afed7aa772e53a62bb433447a/internal/database/reflect.go#L20-L46
-func Wildcard(v any) string {
-	elems := Fields(v)
+func Wildcard(v any, opts ...Option) string {
+	if len(opts) == 0 {
+		if m := getMapping(v); m != nil {
+			return m.wildcard
+		}
+	}
+	return wildcard(fields(v, opts...))
+}
+
+// wildcard returns the expression for the given columns.
+func wildcard(elems []string) string {
 	// Logic below based on strings.Join, but avoids column ambiguity.
 	if len(elems) == 0 {
 		return ""
@@ -63,28 +72,143 @@ func Wildcard(v any) string {
 	return b.String()
 }
 
-// lru is the least recently used caching for the Fields function.
-type lru struct {
-	cap int // Capacity.
+// Fields returns column names for a SQL table that can be queried by a given Go struct.
+// Only use this function to list fields on a struct.
+//
+// To avoid ambiguity issues, it's important to use the Wildcard function instead of
+// calling strings.Join(pgtools.Field(v), ", ") to generate the query expression.
+//
+// Use the Omit and Only options to select a subset of the columns.
+//
+// The columns of each struct type are cached, and a copy is returned,
+// so the caller can modify it.
+//
+// For dynamic cases where a struct isn't available, such as the handler of a PATCH
+// request, v can also be a map[string]any. Its keys are used as the columns,
+// sorted alphabetically. As keys might come from user input, Fields returns nil if any
+// of them isn't a valid identifier containing only letters, digits, underscores, and
+// dollar signs, or if it's longer than 63 characters.
+func Fields(v any, opts ...Option) []string {
+	columns := fields(v, opts...)
+	if _, ok := v.(map[string]any); ok || len(opts) != 0 || columns == nil {
+		return columns
+	}
+	// Return a defensive copy of the cached columns.
+	return append(make([]string, 0, len(columns)), columns...)
+}
+
+// InsertFields returns the columns of a given Go struct in the same order as Values,
+// so they can be zipped with the values to build a query.
+//
+// Unlike Fields, it skips fields with the "readonly" option, such as generated columns,
+// as they cannot be written, and fields with the "generated" option, such as identity columns,
+// as their values are set by the database on insert.
+//
+// A copy of the cached columns is returned, so the caller can modify it.
+// If v is a map[string]any, it returns the same columns as Fields.
+func InsertFields(v any) []string {
+	columns := insertableFields(v)
+	if columns == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(columns)), columns...)
+}
 
-	mu sync.Mutex // guards following
-	m  map[reflect.Type]*list.Element
-	l  *list.List
+// fields is like Fields, but it returns the cached columns of a struct type
+// without copying them if no options are used, so they must not be modified.
+func fields(v any, opts ...Option) []string {
+	var fields []string
+	if mv, ok := v.(map[string]any); ok {
+		fields = mapColumns(mv)
+	} else if m := getMapping(v); m != nil {
+		fields = m.fields
+	}
+	if len(opts) == 0 || fields == nil {
+		return fields
+	}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var columns []string
+	for _, c := range fields {
+		if o.selected(c) {
+			columns = append(columns, c)
+		}
+	}
+	return columns
 }
 
-var wildcardsCache = &lru{
-	cap: 1000, // Likely high enough for most applications, but low enough to mitigate a memory leak.
+// Option for selecting the columns used by Fields and Wildcard.
+type Option func(*options)
 
-	m: map[reflect.Type]*list.Element{},
-	l: list.New(),
+type options struct {
+	omit []string
+
+	restrict bool // restrict to only.
+	only     []string
 }
 
-// Fields returns column names for a SQL table that can be queried by a given Go struct.
-// Only use this function to list fields on a struct.
-//
-// To avoid ambiguity issues, it's important to use the Wildcard function instead of
-// calling strings.Join(pgtools.Field(v), ", ") to generate the query expression.
-func Fields(v any) []string {
+// Omit the given columns.
+func Omit(columns ...string) Option {
+	return func(o *options) {
+		o.omit = append(o.omit, columns...)
+	}
+}
+
+// Only use the given columns.
+// The columns are still listed in the order of the struct fields.
+// If used multiple times, any of the given columns is used.
+func Only(columns ...string) Option {
+	return func(o *options) {
+		o.restrict = true
+		o.only = append(o.only, columns...)
+	}
+}
+
+// selected reports whether a column is selected by the options.
+func (o options) selected(column string) bool {
+	if o.restrict && !contains(o.only, column) {
+		return false
+	}
+	return !contains(o.omit, column)
+}
+
+// mapping of a struct type to the columns of a SQL table.
+type mapping struct {
+	t        reflect.Type
+	columns  []structref.Column
+	fields   []string
+	wildcard string // Expression returned by Wildcard without options.
+
+	// writable columns, excluding the ones with the "readonly" option.
+	writable       []structref.Column
+	writableFields []string
+
+	// insertable columns, excluding the writable ones with the "generated" option.
+	insertable       []structref.Column
+	insertableFields []string
+
+	pk    []string // Primary key columns.
+	table string   // Table name set by the Table marker, or derived from the type name.
+
+	byName map[string]structref.Column
+
+	filterOnce sync.Once
+	filter     []structref.Column // Lazily loaded by filterColumns.
+}
+
+// filterColumns returns the columns for using the struct as a filter.
+func (m *mapping) filterColumns() []structref.Column {
+	m.filterOnce.Do(func() {
+		m.filter = structref.GetAllColumns(m.t)
+	})
+	return m.filter
+}
+
+// getMapping returns the mapping for the struct type of v, using the LRU cache.
+// It returns nil if v isn't a struct or a pointer to a struct.
+func getMapping(v any) *mapping {
 	// Get the right type.
 	if v == nil {
 		return nil
@@ -95,73 +219,34 @@ func Fields(v any) []string {
 	} else {
 		rv = reflect.Indirect(reflect.ValueOf(v)).Type()
 	}
-	wildcardsCache.mu.Lock()
-	defer wildcardsCache.mu.Unlock()
-
-	// field exists to maintain a reference to the struct in the linked list.
-	type field struct {
-		t reflect.Type
-		v []string
-	}
-	// Keep the map and linked list of the LRU cache up-to-date.
-	if cache, ok := wildcardsCache.m[rv]; ok {
-		wildcardsCache.l.MoveToFront(cache)
-		return cache.Value.(field).v
-	}
-
-	// If we don't have the data cached yet, continue.
-	if wildcardsCache.l.Len() == wildcardsCache.cap {
-		oldest := wildcardsCache.l.Back()
-		wildcardsCache.l.Remove(oldest)
-		delete(wildcardsCache.m, oldest.Value.(field).t)
+	if rv.Kind() != reflect.Struct {
+		return nil
 	}
-
-	// Get the columns, cache, and return it.
-	columns := fields(rv)
-	wildcardsCache.m[rv] = wildcardsCache.l.PushFront(field{
-		t: rv,
-		v: columns,
-	})
-	return columns
+	return getTypeMapping(rv)
 }
 
-func fields(rv reflect.Type) []string {
-	// Column is used to make it possible to sort the columns by index.
-	type column struct {
-		indices []int
-		name    string
-	}
-
-	var cs []column
-	for name, i := range structref.GetColumnToFieldIndexMap(rv) {
-		cs = append(cs, column{
-			indices: i,
-			name:    name,
-		})
-	}
-	// Make fields output stable with respect to the struct fields in order.
-	sort.SliceStable(cs, func(i, j int) bool {
-		a, b := cs[i].indices, cs[j].indices
-		// Go inwards each nested field until the end:
-		// indices a and b represent the path to the left and right fields being sorted.
-		for {
-			switch {
-			case len(a) == 0:
-				return false
-			case len(b) == 0:
-				return true
-			case a[0] < b[0]:
-				return true
-			case a[0] > b[0]:
-				return false
+func newMapping(rv reflect.Type) *mapping {
+	m := &mapping{
+		t:       rv,
+		columns: structref.GetColumns(rv),
+	}
+	m.table = tableName(rv)
+	m.byName = make(map[string]structref.Column, len(m.columns))
+	for _, c := range m.columns {
+		m.byName[c.Name] = c
+		m.fields = append(m.fields, c.Name)
+		if !c.HasOption("readonly") {
+			m.writable = append(m.writable, c)
+			m.writableFields = append(m.writableFields, c.Name)
+			if !c.HasOption("generated") {
+				m.insertable = append(m.insertable, c)
+				m.insertableFields = append(m.insertableFields, c.Name)
 			}
-			a, b = a[1:], b[1:]
 		}
-	})
-
-	var columns []string
-	for _, column := range cs {
-		columns = append(columns, column.name)
+		if c.HasOption("pk") {
+			m.pk = append(m.pk, c.Name)
+		}
 	}
-	return columns
+	m.wildcard = wildcard(m.fields)
+	return m
 }
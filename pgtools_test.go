@@ -2,6 +2,7 @@ package pgtools_test
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -244,6 +245,11 @@ func TestWildcard(t *testing.T) {
 			desc: "nil",
 			want: "",
 		},
+		{
+			v:    "not a struct",
+			desc: "string",
+			want: "",
+		},
 		{
 			v:    &HasNestedMock{},
 			desc: "HasNestedMock",
@@ -270,6 +276,22 @@ func TestWildcard(t *testing.T) {
 			desc: "implicit",
 			want: `"id","xyz"`,
 		},
+		{
+			v: struct {
+				ID    string
+				Theme Theme `db:"theme,flatten"`
+			}{},
+			desc: "flatten",
+			want: `"id","theme.primary_color" as "theme.primary_color","theme.secondary_color" as "theme.secondary_color","theme.text_color" as "theme.text_color","theme.text_uppercase" as "theme.text_uppercase","theme.font_family_headings" as "theme.font_family_headings","theme.font_family_body" as "theme.font_family_body","theme.font_family_default" as "theme.font_family_default","theme"`,
+		},
+		{
+			v: struct {
+				ID    string
+				Theme *Theme `db:"theme,composite"`
+			}{},
+			desc: "composite",
+			want: `"id","theme"`,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.desc, func(t *testing.T) {
@@ -286,6 +308,14 @@ func BenchmarkWildcard(b *testing.B) {
 	}
 }
 
+func BenchmarkWildcardParallel(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pgtools.Wildcard(mock{})
+		}
+	})
+}
+
 func BenchmarkWildcardAsync(b *testing.B) {
 	var w sync.WaitGroup
 	w.Add(b.N)
@@ -297,3 +327,238 @@ func BenchmarkWildcardAsync(b *testing.B) {
 	}
 	w.Wait()
 }
+
+func ExampleOmit() {
+	sql := "SELECT " + pgtools.Wildcard(User{}, pgtools.Omit("theme")) + " WHERE id = $1"
+	fmt.Println(sql)
+	// Output:
+	// SELECT "username","full_name","email","id" WHERE id = $1
+}
+
+func ExampleOnly() {
+	sql := "SELECT " + pgtools.Wildcard(User{}, pgtools.Only("id", "username")) + " WHERE id = $1"
+	fmt.Println(sql)
+	// Output:
+	// SELECT "username","id" WHERE id = $1
+}
+
+func TestFieldsOptions(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc string
+		v    any
+		opts []pgtools.Option
+		want []string
+	}{
+		{
+			desc: "nil",
+			v:    nil,
+			opts: []pgtools.Option{pgtools.Omit("id")},
+			want: nil,
+		},
+		{
+			desc: "no options",
+			v:    mock{},
+			want: []string{"automatic", "tagged", "one_two", "CamelCase"},
+		},
+		{
+			desc: "omit",
+			v:    mock{},
+			opts: []pgtools.Option{pgtools.Omit("tagged", "CamelCase")},
+			want: []string{"automatic", "one_two"},
+		},
+		{
+			desc: "omit unknown",
+			v:    mock{},
+			opts: []pgtools.Option{pgtools.Omit("unknown")},
+			want: []string{"automatic", "tagged", "one_two", "CamelCase"},
+		},
+		{
+			desc: "only",
+			v:    &mock{},
+			opts: []pgtools.Option{pgtools.Only("CamelCase", "automatic")},
+			want: []string{"automatic", "CamelCase"},
+		},
+		{
+			desc: "only multiple times",
+			v:    &mock{},
+			opts: []pgtools.Option{pgtools.Only("CamelCase"), pgtools.Only("tagged")},
+			want: []string{"tagged", "CamelCase"},
+		},
+		{
+			desc: "only and omit",
+			v:    mock{},
+			opts: []pgtools.Option{pgtools.Omit("tagged"), pgtools.Only("tagged", "one_two")},
+			want: []string{"one_two"},
+		},
+		{
+			desc: "only nothing",
+			v:    mock{},
+			opts: []pgtools.Option{pgtools.Only()},
+			want: nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := pgtools.Fields(tc.v, tc.opts...); !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("expected fields to be %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func ExampleFields_map() {
+	patch := map[string]any{
+		"name":  "Henrique",
+		"email": "henvic@example.com",
+	}
+	fmt.Println(pgtools.Fields(patch))
+	fmt.Println(pgtools.Values(patch))
+	// Output:
+	// [email name]
+	// [henvic@example.com Henrique]
+}
+
+func TestFieldsMap(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc string
+		v    map[string]any
+		opts []pgtools.Option
+		want []string
+	}{
+		{
+			desc: "nil",
+			v:    nil,
+			want: []string{},
+		},
+		{
+			desc: "sorted",
+			v:    map[string]any{"b": 1, "a": 2, "CamelCase": 3, "_c$1": 4},
+			want: []string{"CamelCase", "_c$1", "a", "b"},
+		},
+		{
+			desc: "options",
+			v:    map[string]any{"b": 1, "a": 2, "c": 3},
+			opts: []pgtools.Option{pgtools.Omit("a")},
+			want: []string{"b", "c"},
+		},
+		{
+			desc: "quote",
+			v:    map[string]any{"a": 1, `b" = 1; --`: 2},
+			want: nil,
+		},
+		{
+			desc: "dot",
+			v:    map[string]any{"theme.color": 1},
+			want: nil,
+		},
+		{
+			desc: "empty key",
+			v:    map[string]any{"": 1},
+			want: nil,
+		},
+		{
+			desc: "starts with digit",
+			v:    map[string]any{"1a": 1},
+			want: nil,
+		},
+		{
+			desc: "non-ASCII",
+			v:    map[string]any{"ação": 1},
+			want: nil,
+		},
+		{
+			desc: "too long",
+			v:    map[string]any{strings.Repeat("a", 64): 1},
+			want: nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := pgtools.Fields(tc.v, tc.opts...); !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("expected fields to be %#v, got %#v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFieldsCopy(t *testing.T) {
+	t.Parallel()
+	fields := pgtools.Fields(mock{})
+	fields[0] = "modified"
+	if got := pgtools.Fields(mock{}); got[0] != "automatic" {
+		t.Errorf("expected cached fields to be unchanged, got %v instead", got)
+	}
+	if got := pgtools.Wildcard(mock{}); got != `"automatic","tagged","one_two","CamelCase"` {
+		t.Errorf("expected cached expression to be unchanged, got %v instead", got)
+	}
+}
+
+func TestInsertFields(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		v    any
+		desc string
+		want []string
+	}{
+		{
+			v:    nil,
+			desc: "nil",
+			want: nil,
+		},
+		{
+			v:    mock{},
+			desc: "mock",
+			want: []string{"automatic", "tagged", "one_two", "CamelCase"},
+		},
+		{
+			v:    Document{},
+			desc: "readonly",
+			want: []string{"id", "body"},
+		},
+		{
+			v:    Comment{},
+			desc: "generated",
+			want: []string{"body"},
+		},
+		{
+			v:    map[string]any{"b": "x", "a": 1},
+			desc: "map",
+			want: []string{"a", "b"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := pgtools.InsertFields(tc.v)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected fields to be %#v, got %#v instead", tc.want, got)
+			}
+			if values := pgtools.Values(tc.v); len(values) != len(got) {
+				t.Errorf("expected fields to match values %#v, got %#v instead", values, got)
+			}
+		})
+	}
+	fields := pgtools.InsertFields(Document{})
+	fields[0] = "modified"
+	if got := pgtools.InsertFields(Document{}); got[0] != "id" {
+		t.Errorf("expected cached fields to be unchanged, got %v instead", got)
+	}
+}
+
+func TestFieldsAllocs(t *testing.T) {
+	v := &User{}
+	pgtools.Fields(v) // Warm up the cache.
+	if allocs := testing.AllocsPerRun(100, func() { pgtools.Wildcard(v) }); allocs != 0 {
+		t.Errorf("expected Wildcard to not allocate, got %v allocations instead", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { pgtools.Fields(v) }); allocs != 1 {
+		t.Errorf("expected Fields to allocate once, got %v allocations instead", allocs)
+	}
+}
+
+func BenchmarkFields(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		pgtools.Fields(User{})
+	}
+}
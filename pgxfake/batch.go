@@ -0,0 +1,60 @@
+package pgxfake
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// batchResults returning the responses programmed with OnBatch in order.
+type batchResults struct {
+	responses []Response
+	n         int
+	err       error
+	closed    bool
+}
+
+// next response of the batch.
+func (b *batchResults) next() Response {
+	switch {
+	case b.err != nil:
+		return Response{Err: b.err}
+	case b.closed:
+		return Response{Err: fmt.Errorf("pgxfake: batch already closed")}
+	case b.n >= len(b.responses):
+		return Response{Err: fmt.Errorf("%w for query %d of batch", ErrNoResponse, b.n+1)}
+	}
+	b.n++
+	return b.responses[b.n-1]
+}
+
+func (b *batchResults) Exec() (pgconn.CommandTag, error) {
+	r := b.next()
+	if r.Err != nil {
+		return pgconn.CommandTag{}, r.Err
+	}
+	return pgconn.NewCommandTag(r.Tag), nil
+}
+
+func (b *batchResults) Query() (pgx.Rows, error) {
+	return newRows(b.next()), nil
+}
+
+func (b *batchResults) QueryRow() pgx.Row {
+	return &row{rows: newRows(b.next())}
+}
+
+// Close the batch, returning the first error of the remaining responses, as pgx does.
+func (b *batchResults) Close() error {
+	if b.closed {
+		return b.err
+	}
+	for b.err == nil && b.n < len(b.responses) {
+		if r := b.next(); r.Err != nil {
+			b.err = r.Err
+		}
+	}
+	b.closed = true
+	return b.err
+}
@@ -0,0 +1,194 @@
+// Package pgxfake implements the pgxs.PGX interface in memory with programmable responses,
+// so that business logic packages can be unit tested without a database, as in:
+//
+//	db := pgxfake.New()
+//	db.On(`SELECT id, name FROM users WHERE id = \$1`, pgxfake.Response{
+//		Columns: []string{"id", "name"},
+//		Rows:    [][]any{{"1", "Alice"}},
+//	})
+//	db.On(`INSERT INTO users`, pgxfake.Response{Err: errors.New("unique violation")})
+//	s := &Service{Postgres: db}
+//
+// Statements are matched against the regular expressions of the responses in the order they were added,
+// and every call is recorded, so that it can be checked with Calls. Transactions are matched against
+// BEGIN, COMMIT, and ROLLBACK, and succeed unless a matching response has an error.
+//
+// For tests of the SQL itself, use a real database with the sqltest package instead.
+package pgxfake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrNoResponse is returned for statements that don't match any response.
+var ErrNoResponse = errors.New("pgxfake: no response")
+
+// Response to the statements matching a pattern.
+type Response struct {
+	// Columns returned by Query and QueryRow.
+	Columns []string
+
+	// Rows returned by Query and QueryRow, with values in the order of Columns.
+	// Values are scanned by assigning them to the destinations, so they must have compatible types,
+	// such as string for *string or *sql.NullString.
+	Rows [][]any
+
+	// Tag returned by Exec, such as "INSERT 0 1".
+	Tag string
+
+	// Err returned by the call, or by Rows.Err and Row.Scan with Query and QueryRow.
+	Err error
+}
+
+// Call to the fake database.
+type Call struct {
+	// Method called, such as Exec, Query, Begin, or Commit.
+	Method string
+
+	// SQL of the statement, or the table name for CopyFrom.
+	SQL string
+
+	// Args of the statement, or the rows for CopyFrom.
+	Args []any
+}
+
+type expectation struct {
+	pattern  *regexp.Regexp
+	response Response
+}
+
+// DB is a fake database implementing the pgxs.PGX interface.
+// It's safe for concurrent use.
+type DB struct {
+	mu           sync.Mutex
+	expectations []expectation
+	batches      [][]Response
+	calls        []Call
+}
+
+// Validate if DB implements the PGX interface.
+var _ pgxs.PGX = (*DB)(nil)
+
+// New fake database.
+func New() *DB {
+	return &DB{}
+}
+
+// On statements matching the regular expression pattern, return the response.
+// It panics if the pattern is invalid.
+func (db *DB) On(pattern string, r Response) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.expectations = append(db.expectations, expectation{
+		pattern:  regexp.MustCompile(pattern),
+		response: r,
+	})
+}
+
+// OnBatch returns the responses to the queries of the next batch sent with SendBatch, in order.
+// Batches are programmed separately from On, as the statements of a batch can't be read with pgx v5.3.
+func (db *DB) OnBatch(responses ...Response) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.batches = append(db.batches, responses)
+}
+
+// Calls made to the database, including calls made inside transactions.
+func (db *DB) Calls() []Call {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]Call(nil), db.calls...)
+}
+
+// Reset the responses and recorded calls.
+func (db *DB) Reset() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.expectations, db.batches, db.calls = nil, nil, nil
+}
+
+// record the call, and return the response for the statement.
+func (db *DB) record(method, sql string, args []any) Response {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.calls = append(db.calls, Call{Method: method, SQL: sql, Args: args})
+	for _, e := range db.expectations {
+		if e.pattern.MatchString(sql) {
+			return e.response
+		}
+	}
+	return Response{Err: fmt.Errorf("%w for %q", ErrNoResponse, sql)}
+}
+
+// Begin starts a fake transaction.
+func (db *DB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return db.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// BeginTx starts a fake transaction. The options are ignored.
+func (db *DB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	if r := db.record("Begin", "BEGIN", nil); r.Err != nil && !errors.Is(r.Err, ErrNoResponse) {
+		return nil, r.Err
+	}
+	return &tx{db: db}, nil
+}
+
+// CopyFrom reads the rows from rowSrc, and returns how many there are.
+// Responses are matched against "COPY <table>".
+func (db *DB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	var rows []any
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return 0, err
+		}
+		rows = append(rows, values)
+	}
+	if err := rowSrc.Err(); err != nil {
+		return 0, err
+	}
+	r := db.record("CopyFrom", "COPY "+tableName.Sanitize(), rows)
+	if r.Err != nil && !errors.Is(r.Err, ErrNoResponse) {
+		return 0, r.Err
+	}
+	return int64(len(rows)), nil
+}
+
+// Exec returns the Tag or Err of the response.
+func (db *DB) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	r := db.record("Exec", sql, arguments)
+	if r.Err != nil {
+		return pgconn.CommandTag{}, r.Err
+	}
+	return pgconn.NewCommandTag(r.Tag), nil
+}
+
+// Query returns the Rows of the response.
+func (db *DB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return newRows(db.record("Query", sql, args)), nil
+}
+
+// QueryRow returns the first of the Rows of the response, or pgx.ErrNoRows if there are none.
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &row{rows: newRows(db.record("QueryRow", sql, args))}
+}
+
+// SendBatch returns the responses programmed with OnBatch.
+func (db *DB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.calls = append(db.calls, Call{Method: "SendBatch", Args: []any{b.Len()}})
+	var responses []Response
+	if len(db.batches) != 0 {
+		responses, db.batches = db.batches[0], db.batches[1:]
+	}
+	return &batchResults{responses: responses}
+}
@@ -0,0 +1,165 @@
+package pgxfake_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/henvic/pgtools/pgxfake"
+	"github.com/jackc/pgx/v5"
+)
+
+type user struct {
+	ID    string
+	Name  string
+	Email *string
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	email := "alice@example.com"
+	db.On(`SELECT id, name, email FROM users`, pgxfake.Response{
+		Columns: []string{"id", "name", "email"},
+		Rows: [][]any{
+			{"1", "Alice", email},
+			{"2", "Bob", nil},
+		},
+	})
+	rows, err := db.Query(ctx, "SELECT id, name, email FROM users ORDER BY id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	got, err := pgx.CollectRows(rows, pgx.RowToStructByName[user])
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	want := []user{
+		{ID: "1", Name: "Alice", Email: &email},
+		{ID: "2", Name: "Bob"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected users to be %+v, got %+v instead", want, got)
+	}
+}
+
+func TestQueryRow(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	db.On(`SELECT count\(\*\) FROM users`, pgxfake.Response{
+		Columns: []string{"count"},
+		Rows:    [][]any{{int64(2)}},
+	})
+	db.On(`SELECT name FROM users`, pgxfake.Response{
+		Columns: []string{"name"},
+	})
+
+	var count int
+	if err := db.QueryRow(ctx, "SELECT count(*) FROM users").Scan(&count); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if count != 2 {
+		t.Errorf("expected count to be 2, got %d instead", count)
+	}
+	var name sql.NullString
+	if err := db.QueryRow(ctx, "SELECT name FROM users WHERE id = $1", "3").Scan(&name); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected error to be %v, got %v instead", pgx.ErrNoRows, err)
+	}
+	if err := db.QueryRow(ctx, "SELECT email FROM users").Scan(&name); !errors.Is(err, pgxfake.ErrNoResponse) {
+		t.Errorf("expected error to be %v, got %v instead", pgxfake.ErrNoResponse, err)
+	}
+	var wrong string
+	if err := db.QueryRow(ctx, "SELECT count(*) FROM users").Scan(&wrong); err == nil {
+		t.Error("expected error scanning integer into string, got nil instead")
+	}
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	errUnique := errors.New("unique violation")
+	db.On(`INSERT INTO users .* VALUES \('1'`, pgxfake.Response{Err: errUnique})
+	db.On(`INSERT INTO users`, pgxfake.Response{Tag: "INSERT 0 1"})
+
+	tag, err := db.Exec(ctx, "INSERT INTO users (id) VALUES ('2')")
+	if err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if tag.RowsAffected() != 1 {
+		t.Errorf("expected rows affected to be 1, got %d instead", tag.RowsAffected())
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO users (id) VALUES ('1')"); !errors.Is(err, errUnique) {
+		t.Errorf("expected error to be %v, got %v instead", errUnique, err)
+	}
+}
+
+func TestTx(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	db.On(`UPDATE users`, pgxfake.Response{Tag: "UPDATE 1"})
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	if _, err := tx.Exec(ctx, "UPDATE users SET name = $1", "Carol"); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if err := tx.Rollback(ctx); !errors.Is(err, pgx.ErrTxClosed) {
+		t.Errorf("expected error to be %v, got %v instead", pgx.ErrTxClosed, err)
+	}
+	want := []pgxfake.Call{
+		{Method: "Begin", SQL: "BEGIN"},
+		{Method: "Exec", SQL: "UPDATE users SET name = $1", Args: []any{"Carol"}},
+		{Method: "Commit", SQL: "COMMIT"},
+	}
+	if got := db.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected calls to be %+v, got %+v instead", want, got)
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	t.Parallel()
+	db := pgxfake.New()
+	n, err := db.CopyFrom(context.Background(), pgx.Identifier{"users"}, []string{"id", "name"}, pgx.CopyFromRows([][]any{
+		{"1", "Alice"},
+		{"2", "Bob"},
+	}))
+	if err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 rows to be copied, got %d instead", n)
+	}
+	if calls := db.Calls(); len(calls) != 1 || calls[0].SQL != `COPY "users"` || len(calls[0].Args) != 2 {
+		t.Errorf("expected CopyFrom call with 2 rows, got %+v instead", calls)
+	}
+}
+
+func TestSendBatch(t *testing.T) {
+	t.Parallel()
+	db := pgxfake.New()
+	errConflict := errors.New("conflict")
+	db.OnBatch(
+		pgxfake.Response{Tag: "INSERT 0 1"},
+		pgxfake.Response{Err: errConflict},
+	)
+	b := &pgx.Batch{}
+	b.Queue("INSERT INTO users (id) VALUES ($1)", "1")
+	b.Queue("INSERT INTO users (id) VALUES ($1)", "2")
+	br := db.SendBatch(context.Background(), b)
+	if _, err := br.Exec(); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if err := br.Close(); !errors.Is(err, errConflict) {
+		t.Errorf("expected error to be %v, got %v instead", errConflict, err)
+	}
+}
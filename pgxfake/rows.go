@@ -0,0 +1,177 @@
+package pgxfake
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// rows of a response.
+type rows struct {
+	columns []string
+	values  [][]any
+	n       int // Index of the current row, starting at 1.
+	err     error
+	closed  bool
+}
+
+func newRows(r Response) *rows {
+	return &rows{
+		columns: r.Columns,
+		values:  r.Rows,
+		err:     r.Err,
+	}
+}
+
+func (r *rows) Close() {
+	r.closed = true
+}
+
+func (r *rows) Err() error {
+	return r.err
+}
+
+func (r *rows) CommandTag() pgconn.CommandTag {
+	return pgconn.NewCommandTag(fmt.Sprintf("SELECT %d", len(r.values)))
+}
+
+func (r *rows) FieldDescriptions() []pgconn.FieldDescription {
+	fields := make([]pgconn.FieldDescription, 0, len(r.columns))
+	for _, c := range r.columns {
+		fields = append(fields, pgconn.FieldDescription{Name: c})
+	}
+	return fields
+}
+
+func (r *rows) Next() bool {
+	if r.closed || r.err != nil || r.n >= len(r.values) {
+		r.closed = true
+		return false
+	}
+	r.n++
+	return true
+}
+
+func (r *rows) Scan(dest ...any) error {
+	// Row scanners, such as the ones used by pgx.RowToStructByName, scan the row themselves.
+	if len(dest) == 1 {
+		if rs, ok := dest[0].(pgx.RowScanner); ok {
+			return rs.ScanRow(r)
+		}
+	}
+	values, err := r.Values()
+	if err != nil {
+		return err
+	}
+	if len(dest) != len(values) {
+		err := fmt.Errorf("pgxfake: number of field descriptions must equal number of destinations, got %d and %d", len(values), len(dest))
+		r.err = err
+		return err
+	}
+	for n, d := range dest {
+		if err := assign(d, values[n]); err != nil {
+			err = fmt.Errorf("can't scan into dest[%d]: %w", n, err)
+			r.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *rows) Values() ([]any, error) {
+	if r.n == 0 || r.n > len(r.values) {
+		return nil, fmt.Errorf("pgxfake: no current row")
+	}
+	return r.values[r.n-1], nil
+}
+
+// RawValues aren't supported, as the values aren't encoded.
+func (r *rows) RawValues() [][]byte {
+	return nil
+}
+
+// Conn returns nil, as there's no connection.
+func (r *rows) Conn() *pgx.Conn {
+	return nil
+}
+
+// row returned by QueryRow.
+type row struct {
+	rows *rows
+}
+
+func (r *row) Scan(dest ...any) error {
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	return r.rows.Scan(dest...)
+}
+
+// assign the value to the destination, which must be a pointer to a compatible type or a sql.Scanner.
+// NULL (nil) can only be assigned to destinations that can hold it, such as pointers.
+func assign(dest, v any) error {
+	if s, ok := dest.(sql.Scanner); ok {
+		return s.Scan(v)
+	}
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Pointer || d.IsNil() {
+		return fmt.Errorf("pgxfake: destination must be a non-nil pointer, got %T", dest)
+	}
+	d = d.Elem()
+	if v == nil {
+		switch d.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			d.SetZero()
+			return nil
+		}
+		return fmt.Errorf("pgxfake: cannot scan NULL into %T", dest)
+	}
+	value := reflect.ValueOf(v)
+	if d.Kind() == reflect.Pointer && !value.Type().AssignableTo(d.Type()) {
+		// Allocate the pointer, as for a nullable column that isn't NULL.
+		p := reflect.New(d.Type().Elem())
+		if err := assign(p.Interface(), v); err != nil {
+			return err
+		}
+		d.Set(p)
+		return nil
+	}
+	switch {
+	case value.Type().AssignableTo(d.Type()):
+		d.Set(value)
+	case convertible(value, d):
+		d.Set(value.Convert(d.Type()))
+	default:
+		return fmt.Errorf("pgxfake: cannot scan %T into %T", v, dest)
+	}
+	return nil
+}
+
+// convertible reports whether the value can be converted to the type of the destination
+// without changing its meaning, as from int to int64, but not from int to string.
+func convertible(value, d reflect.Value) bool {
+	if !value.Type().ConvertibleTo(d.Type()) {
+		return false
+	}
+	return kindClass(value.Kind()) == kindClass(d.Kind())
+}
+
+// kindClass groups kinds that can be converted to each other.
+func kindClass(k reflect.Kind) string {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	default:
+		return k.String()
+	}
+}
@@ -0,0 +1,94 @@
+package pgxfake
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// tx is a fake transaction recording its calls on the database.
+type tx struct {
+	db     *DB
+	closed bool
+}
+
+func (t *tx) Begin(ctx context.Context) (pgx.Tx, error) {
+	if t.closed {
+		return nil, pgx.ErrTxClosed
+	}
+	return t.db.Begin(ctx)
+}
+
+func (t *tx) Commit(ctx context.Context) error {
+	return t.end("Commit", "COMMIT")
+}
+
+func (t *tx) Rollback(ctx context.Context) error {
+	return t.end("Rollback", "ROLLBACK")
+}
+
+// end the transaction, as with Commit and Rollback.
+func (t *tx) end(method, sql string) error {
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+	t.closed = true
+	if r := t.db.record(method, sql, nil); r.Err != nil && !errors.Is(r.Err, ErrNoResponse) {
+		return r.Err
+	}
+	return nil
+}
+
+func (t *tx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if t.closed {
+		return 0, pgx.ErrTxClosed
+	}
+	return t.db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (t *tx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if t.closed {
+		return &batchResults{err: pgx.ErrTxClosed}
+	}
+	return t.db.SendBatch(ctx, b)
+}
+
+// LargeObjects aren't supported.
+func (t *tx) LargeObjects() pgx.LargeObjects {
+	return pgx.LargeObjects{}
+}
+
+func (t *tx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	if t.closed {
+		return nil, pgx.ErrTxClosed
+	}
+	return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
+}
+
+func (t *tx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	if t.closed {
+		return pgconn.CommandTag{}, pgx.ErrTxClosed
+	}
+	return t.db.Exec(ctx, sql, arguments...)
+}
+
+func (t *tx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if t.closed {
+		return nil, pgx.ErrTxClosed
+	}
+	return t.db.Query(ctx, sql, args...)
+}
+
+func (t *tx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if t.closed {
+		return &row{rows: &rows{err: pgx.ErrTxClosed}}
+	}
+	return t.db.QueryRow(ctx, sql, args...)
+}
+
+// Conn returns nil, as there's no connection.
+func (t *tx) Conn() *pgx.Conn {
+	return nil
+}
@@ -0,0 +1,239 @@
+package pgxotel
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DB creating a span for each call to another implementation of the PGX interface,
+// and for each transaction, from Begin until Commit or Rollback.
+type DB struct {
+	db     pgxs.PGX
+	tracer trace.Tracer
+}
+
+// Validate if DB implements the PGX interface.
+var _ pgxs.PGX = (*DB)(nil)
+
+// New DB tracing the calls to db.
+func New(db pgxs.PGX, o Options) *DB {
+	return &DB{db: db, tracer: o.tracer()}
+}
+
+// Begin a transaction, starting its span.
+func (db *DB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return db.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// BeginTx starts a transaction, starting its span.
+func (db *DB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	_, span := db.tracer.Start(ctx, "transaction", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		dbSystemKey.String("postgresql"),
+	))
+	tx, err := db.db.BeginTx(ctx, txOptions)
+	if err != nil {
+		setError(span, err)
+		span.End()
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, tracer: db.tracer, span: span}, nil
+}
+
+func (db *DB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return copyFrom(ctx, db.tracer, db.db, tableName, columnNames, rowSrc)
+}
+
+func (db *DB) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return exec(ctx, db.tracer, db.db, sql, arguments...)
+}
+
+// Query ends its span when the rows are closed, either explicitly or by reading all of them.
+func (db *DB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return query(ctx, db.tracer, db.db, sql, args...)
+}
+
+// QueryRow ends its span when the row is scanned.
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return queryRow(ctx, db.tracer, db.db, sql, args...)
+}
+
+// SendBatch ends its span when the results are closed.
+func (db *DB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return sendBatch(ctx, db.tracer, db.db, b)
+}
+
+// querier is the subset of PGX shared by DB and transactions.
+type querier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func copyFrom(ctx context.Context, tracer trace.Tracer, q querier, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	ctx, span := startSpan(ctx, tracer, "COPY "+tableName.Sanitize())
+	n, err := q.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	endSpan(span, n, err)
+	return n, err
+}
+
+func exec(ctx context.Context, tracer trace.Tracer, q querier, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	ctx, span := startSpan(ctx, tracer, sql)
+	tag, err := q.Exec(ctx, sql, arguments...)
+	endSpan(span, tag.RowsAffected(), err)
+	return tag, err
+}
+
+func query(ctx context.Context, tracer trace.Tracer, q querier, sql string, args ...any) (pgx.Rows, error) {
+	ctx, span := startSpan(ctx, tracer, sql)
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		endSpan(span, 0, err)
+		return rows, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func queryRow(ctx context.Context, tracer trace.Tracer, q querier, sql string, args ...any) pgx.Row {
+	ctx, span := startSpan(ctx, tracer, sql)
+	return &tracedRow{row: q.QueryRow(ctx, sql, args...), span: span}
+}
+
+func sendBatch(ctx context.Context, tracer trace.Tracer, q querier, b *pgx.Batch) pgx.BatchResults {
+	ctx, span := tracer.Start(ctx, "batch", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		dbSystemKey.String("postgresql"),
+		dbBatchSizeKey.Int(b.Len()),
+	))
+	return &tracedBatchResults{BatchResults: q.SendBatch(ctx, b), span: span}
+}
+
+// tracedTx ending the span of the transaction when it's committed or rolled back.
+type tracedTx struct {
+	pgx.Tx
+	tracer trace.Tracer
+	span   trace.Span
+	once   sync.Once
+}
+
+// end the span of the transaction.
+func (t *tracedTx) end(rolledBack bool, err error) {
+	t.once.Do(func() {
+		t.span.SetAttributes(rolledBackKey.Bool(rolledBack))
+		if err != nil {
+			setError(t.span, err)
+		}
+		t.span.End()
+	})
+}
+
+func (t *tracedTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	_, span := t.tracer.Start(ctx, "savepoint", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		dbSystemKey.String("postgresql"),
+	))
+	tx, err := t.Tx.Begin(ctx)
+	if err != nil {
+		setError(span, err)
+		span.End()
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, tracer: t.tracer, span: span}, nil
+}
+
+func (t *tracedTx) Commit(ctx context.Context) error {
+	err := t.Tx.Commit(ctx)
+	t.end(false, err)
+	return err
+}
+
+func (t *tracedTx) Rollback(ctx context.Context) error {
+	err := t.Tx.Rollback(ctx)
+	// Rolling back a transaction that was already committed is a no-op, as with a deferred Rollback.
+	if !errors.Is(err, pgx.ErrTxClosed) {
+		t.end(true, err)
+	}
+	return err
+}
+
+func (t *tracedTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return copyFrom(ctx, t.tracer, t.Tx, tableName, columnNames, rowSrc)
+}
+
+func (t *tracedTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return sendBatch(ctx, t.tracer, t.Tx, b)
+}
+
+func (t *tracedTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return exec(ctx, t.tracer, t.Tx, sql, arguments...)
+}
+
+func (t *tracedTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return query(ctx, t.tracer, t.Tx, sql, args...)
+}
+
+func (t *tracedTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return queryRow(ctx, t.tracer, t.Tx, sql, args...)
+}
+
+// tracedRows ending the span when the rows are closed.
+type tracedRows struct {
+	pgx.Rows
+	span trace.Span
+	once sync.Once
+}
+
+func (r *tracedRows) Close() {
+	r.Rows.Close()
+	r.once.Do(func() {
+		endSpan(r.span, r.Rows.CommandTag().RowsAffected(), r.Rows.Err())
+	})
+}
+
+func (r *tracedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	// Rows are closed automatically once they're read.
+	r.Close()
+	return false
+}
+
+// tracedRow ending the span when the row is scanned.
+type tracedRow struct {
+	row  pgx.Row
+	span trace.Span
+}
+
+func (r *tracedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	var rows int64
+	if err == nil {
+		rows = 1
+	}
+	endSpan(r.span, rows, err)
+	return err
+}
+
+// tracedBatchResults ending the span when the results are closed.
+type tracedBatchResults struct {
+	pgx.BatchResults
+	span trace.Span
+	once sync.Once
+}
+
+func (b *tracedBatchResults) Close() error {
+	err := b.BatchResults.Close()
+	b.once.Do(func() {
+		if err != nil {
+			setError(b.span, err)
+		}
+		b.span.End()
+	})
+	return err
+}
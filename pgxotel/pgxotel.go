@@ -0,0 +1,101 @@
+// Package pgxotel creates OpenTelemetry spans for the statements and transactions executed with pgx.
+//
+// To trace every statement executed on a pool, install the Tracer on its configuration:
+//
+//	config.ConnConfig.Tracer = pgxotel.NewTracer(pgxotel.Options{})
+//
+// To also trace transactions, or to trace another implementation of the pgxs.PGX interface,
+// wrap it with New instead:
+//
+//	db := pgxotel.New(pool, pgxotel.Options{})
+//	s := &Service{Postgres: db}
+//
+// Don't use both for the same pool, as the statements would be traced twice.
+package pgxotel
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName of the tracer.
+const instrumentationName = "github.com/henvic/pgtools/pgxotel"
+
+// Attributes of the spans, following the OpenTelemetry semantic conventions for databases where there's one.
+const (
+	dbSystemKey       = attribute.Key("db.system")
+	dbNameKey         = attribute.Key("db.name")
+	dbStatementKey    = attribute.Key("db.statement")
+	dbOperationKey    = attribute.Key("db.operation")
+	dbRowsAffectedKey = attribute.Key("db.rows_affected")
+	dbSQLStateKey     = attribute.Key("db.postgresql.sqlstate")
+	dbBatchSizeKey    = attribute.Key("db.postgresql.batch_size")
+	rolledBackKey     = attribute.Key("db.postgresql.rolled_back")
+)
+
+// Options for tracing.
+type Options struct {
+	// TracerProvider creating the spans. If nil, the global provider is used.
+	TracerProvider trace.TracerProvider
+}
+
+// tracer for the options.
+func (o Options) tracer() trace.Tracer {
+	tp := o.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(instrumentationName)
+}
+
+// startSpan for the statement.
+func startSpan(ctx context.Context, tracer trace.Tracer, sql string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	op := operation(sql)
+	name := op
+	if name == "" {
+		name = "postgresql"
+	}
+	attrs = append(attrs,
+		dbSystemKey.String("postgresql"),
+		dbStatementKey.String(sql),
+		dbOperationKey.String(op),
+	)
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan with the number of rows affected by the statement, or its error.
+func endSpan(span trace.Span, rows int64, err error) {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) {
+		span.SetAttributes(dbRowsAffectedKey.Int64(rows))
+	} else {
+		setError(span, err)
+	}
+	span.End()
+}
+
+// setError on the span, including the SQLSTATE code of PostgreSQL errors.
+func setError(span trace.Span, err error) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		span.SetAttributes(dbSQLStateKey.String(pgErr.Code))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// operation of the statement, such as SELECT or INSERT.
+func operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
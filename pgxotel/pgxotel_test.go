@@ -0,0 +1,134 @@
+package pgxotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/henvic/pgtools/pgxfake"
+	"github.com/henvic/pgtools/pgxotel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// attr returns the value of the attribute of the span.
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestDB(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	fake := pgxfake.New()
+	fake.On(`UPDATE users`, pgxfake.Response{Tag: "UPDATE 2"})
+	fake.On(`SELECT id FROM users`, pgxfake.Response{
+		Columns: []string{"id"},
+		Rows:    [][]any{{"1"}, {"2"}, {"3"}},
+	})
+	fake.On(`INSERT INTO users`, pgxfake.Response{Err: &pgconn.PgError{Code: "23505", Message: "duplicate key"}})
+	db := pgxotel.New(fake, pgxotel.Options{TracerProvider: tp})
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	if _, err := tx.Exec(ctx, "UPDATE users SET active = $1", true); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if err := tx.Rollback(ctx); !errors.Is(err, pgx.ErrTxClosed) {
+		t.Errorf("expected error to be %v, got %v instead", pgx.ErrTxClosed, err)
+	}
+	rows, err := db.Query(ctx, "SELECT id FROM users")
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	if _, err := pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO users (id) VALUES ($1)", "1"); err == nil {
+		t.Error("expected error, got nil instead")
+	}
+
+	spans := recorder.Ended()
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.Name())
+	}
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 spans, got %v instead", names)
+	}
+	update, transaction, query, insert := spans[0], spans[1], spans[2], spans[3]
+	if update.Name() != "UPDATE" || attr(update, "db.statement").AsString() != "UPDATE users SET active = $1" || attr(update, "db.rows_affected").AsInt64() != 2 {
+		t.Errorf("unexpected UPDATE span: %v %v", update.Name(), update.Attributes())
+	}
+	if transaction.Name() != "transaction" || attr(transaction, "db.postgresql.rolled_back").AsBool() || transaction.Status().Code != codes.Unset {
+		t.Errorf("unexpected transaction span: %v %v", transaction.Name(), transaction.Attributes())
+	}
+	if query.Name() != "SELECT" || attr(query, "db.rows_affected").AsInt64() != 3 {
+		t.Errorf("unexpected SELECT span: %v %v", query.Name(), query.Attributes())
+	}
+	if insert.Status().Code != codes.Error || attr(insert, "db.postgresql.sqlstate").AsString() != "23505" {
+		t.Errorf("unexpected INSERT span: %v %v %v", insert.Name(), insert.Status(), insert.Attributes())
+	}
+}
+
+func TestQueryRow(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	fake := pgxfake.New()
+	fake.On(`SELECT name FROM users`, pgxfake.Response{Columns: []string{"name"}})
+	db := pgxotel.New(fake, pgxotel.Options{TracerProvider: tp})
+
+	var name string
+	if err := db.QueryRow(ctx, "SELECT name FROM users WHERE id = $1", "1").Scan(&name); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected error to be %v, got %v instead", pgx.ErrNoRows, err)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d instead", len(spans))
+	}
+	// No rows isn't an error of the statement.
+	if got := spans[0].Status().Code; got != codes.Unset {
+		t.Errorf("expected status to be %v, got %v instead", codes.Unset, got)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := pgxotel.NewTracer(pgxotel.Options{TracerProvider: tp})
+
+	ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "delete from users"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{
+		CommandTag: pgconn.NewCommandTag("DELETE 0"),
+		Err:        errors.New("permission denied"),
+	})
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d instead", len(spans))
+	}
+	if got := spans[0].Name(); got != "DELETE" {
+		t.Errorf("expected span name to be DELETE, got %q instead", got)
+	}
+	if got := spans[0].Status(); got.Code != codes.Error || got.Description != "permission denied" {
+		t.Errorf("expected status to be an error, got %+v instead", got)
+	}
+}
@@ -0,0 +1,87 @@
+package pgxotel
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer creating a span for each statement, batch, and copy executed on a connection.
+// It implements pgx.QueryTracer, pgx.BatchTracer, and pgx.CopyFromTracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// Validate if Tracer implements the pgx tracer interfaces.
+var (
+	_ pgx.QueryTracer    = (*Tracer)(nil)
+	_ pgx.BatchTracer    = (*Tracer)(nil)
+	_ pgx.CopyFromTracer = (*Tracer)(nil)
+)
+
+// NewTracer to install on the configuration of a connection or pool.
+func NewTracer(o Options) *Tracer {
+	return &Tracer{tracer: o.tracer()}
+}
+
+// database the connection is connected to.
+func database(conn *pgx.Conn) string {
+	if conn == nil {
+		return ""
+	}
+	return conn.Config().Database
+}
+
+// TraceQueryStart starts the span of the statement.
+func (t *Tracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = startSpan(ctx, t.tracer, data.SQL, dbNameKey.String(database(conn)))
+	return ctx
+}
+
+// TraceQueryEnd ends the span of the statement.
+func (t *Tracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.CommandTag.RowsAffected(), data.Err)
+}
+
+// TraceBatchStart starts the span of the batch.
+func (t *Tracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "batch", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		dbSystemKey.String("postgresql"),
+		dbNameKey.String(database(conn)),
+		dbBatchSizeKey.Int(data.Batch.Len()),
+	))
+	return ctx
+}
+
+// TraceBatchQuery records the statement of the batch as an event of its span.
+func (t *Tracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("query", trace.WithAttributes(
+		dbStatementKey.String(data.SQL),
+		dbRowsAffectedKey.Int64(data.CommandTag.RowsAffected()),
+	))
+	if data.Err != nil {
+		span.RecordError(data.Err, trace.WithAttributes(dbStatementKey.String(data.SQL)))
+	}
+}
+
+// TraceBatchEnd ends the span of the batch.
+func (t *Tracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		setError(span, data.Err)
+	}
+	span.End()
+}
+
+// TraceCopyFromStart starts the span of the copy.
+func (t *Tracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	ctx, _ = startSpan(ctx, t.tracer, "COPY "+data.TableName.Sanitize(), dbNameKey.String(database(conn)))
+	return ctx
+}
+
+// TraceCopyFromEnd ends the span of the copy.
+func (t *Tracer) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.CommandTag.RowsAffected(), data.Err)
+}
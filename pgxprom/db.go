@@ -0,0 +1,217 @@
+package pgxprom
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DB measuring the calls to another implementation of the PGX interface, including the calls made inside transactions.
+type DB struct {
+	db      pgxs.PGX
+	metrics *Metrics
+}
+
+// Validate if DB implements the PGX interface.
+var _ pgxs.PGX = (*DB)(nil)
+
+// New DB measuring the calls to db.
+func New(db pgxs.PGX, metrics *Metrics) *DB {
+	return &DB{db: db, metrics: metrics}
+}
+
+// Begin a transaction measuring its calls.
+func (db *DB) Begin(ctx context.Context) (pgx.Tx, error) {
+	finish := db.metrics.start(ctx, "Begin")
+	tx, err := db.db.Begin(ctx)
+	finish(err)
+	if err != nil {
+		return nil, err
+	}
+	return &measuredTx{Tx: tx, metrics: db.metrics}, nil
+}
+
+// BeginTx starts a transaction measuring its calls.
+func (db *DB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	finish := db.metrics.start(ctx, "Begin")
+	tx, err := db.db.BeginTx(ctx, txOptions)
+	finish(err)
+	if err != nil {
+		return nil, err
+	}
+	return &measuredTx{Tx: tx, metrics: db.metrics}, nil
+}
+
+func (db *DB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return copyFrom(ctx, db.metrics, db.db, tableName, columnNames, rowSrc)
+}
+
+func (db *DB) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return exec(ctx, db.metrics, db.db, sql, arguments...)
+}
+
+// Query is measured until the rows are closed, either explicitly or by reading all of them.
+func (db *DB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return query(ctx, db.metrics, db.db, sql, args...)
+}
+
+// QueryRow is measured until the row is scanned.
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return queryRow(ctx, db.metrics, db.db, sql, args...)
+}
+
+// SendBatch is measured until the results are closed.
+func (db *DB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return sendBatch(ctx, db.metrics, db.db, b)
+}
+
+// querier is the subset of PGX shared by DB and transactions.
+type querier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func copyFrom(ctx context.Context, m *Metrics, q querier, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	finish := m.start(ctx, "CopyFrom")
+	n, err := q.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	finish(err)
+	return n, err
+}
+
+func exec(ctx context.Context, m *Metrics, q querier, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	finish := m.start(ctx, "Exec")
+	tag, err := q.Exec(ctx, sql, arguments...)
+	finish(err)
+	return tag, err
+}
+
+func query(ctx context.Context, m *Metrics, q querier, sql string, args ...any) (pgx.Rows, error) {
+	finish := m.start(ctx, "Query")
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		finish(err)
+		return rows, err
+	}
+	return &measuredRows{Rows: rows, finish: finish}, nil
+}
+
+func queryRow(ctx context.Context, m *Metrics, q querier, sql string, args ...any) pgx.Row {
+	finish := m.start(ctx, "QueryRow")
+	return &measuredRow{row: q.QueryRow(ctx, sql, args...), finish: finish}
+}
+
+func sendBatch(ctx context.Context, m *Metrics, q querier, b *pgx.Batch) pgx.BatchResults {
+	finish := m.start(ctx, "SendBatch")
+	return &measuredBatchResults{BatchResults: q.SendBatch(ctx, b), finish: finish}
+}
+
+// measuredTx measuring the calls made in a transaction.
+type measuredTx struct {
+	pgx.Tx
+	metrics *Metrics
+}
+
+func (t *measuredTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	finish := t.metrics.start(ctx, "Begin")
+	tx, err := t.Tx.Begin(ctx)
+	finish(err)
+	if err != nil {
+		return nil, err
+	}
+	return &measuredTx{Tx: tx, metrics: t.metrics}, nil
+}
+
+func (t *measuredTx) Commit(ctx context.Context) error {
+	finish := t.metrics.start(ctx, "Commit")
+	err := t.Tx.Commit(ctx)
+	finish(err)
+	return err
+}
+
+// Rollback is measured unless the transaction was already closed, as with a deferred Rollback after Commit.
+func (t *measuredTx) Rollback(ctx context.Context) error {
+	finish := t.metrics.start(ctx, "Rollback")
+	err := t.Tx.Rollback(ctx)
+	if errors.Is(err, pgx.ErrTxClosed) {
+		err = nil
+	}
+	finish(err)
+	return err
+}
+
+func (t *measuredTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return copyFrom(ctx, t.metrics, t.Tx, tableName, columnNames, rowSrc)
+}
+
+func (t *measuredTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return sendBatch(ctx, t.metrics, t.Tx, b)
+}
+
+func (t *measuredTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return exec(ctx, t.metrics, t.Tx, sql, arguments...)
+}
+
+func (t *measuredTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return query(ctx, t.metrics, t.Tx, sql, args...)
+}
+
+func (t *measuredTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return queryRow(ctx, t.metrics, t.Tx, sql, args...)
+}
+
+// measuredRows finishing the call when the rows are closed.
+type measuredRows struct {
+	pgx.Rows
+	finish func(err error)
+	once   sync.Once
+}
+
+func (r *measuredRows) Close() {
+	r.Rows.Close()
+	r.once.Do(func() {
+		r.finish(r.Rows.Err())
+	})
+}
+
+func (r *measuredRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	// Rows are closed automatically once they're read.
+	r.Close()
+	return false
+}
+
+// measuredRow finishing the call when the row is scanned.
+type measuredRow struct {
+	row    pgx.Row
+	finish func(err error)
+}
+
+func (r *measuredRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	r.finish(err)
+	return err
+}
+
+// measuredBatchResults finishing the call when the results are closed.
+type measuredBatchResults struct {
+	pgx.BatchResults
+	finish func(err error)
+	once   sync.Once
+}
+
+func (b *measuredBatchResults) Close() error {
+	err := b.BatchResults.Close()
+	b.once.Do(func() {
+		b.finish(err)
+	})
+	return err
+}
@@ -0,0 +1,132 @@
+// Package pgxprom exports Prometheus metrics for the calls made through the pgxs.PGX interface,
+// delegating them to another implementation, such as a *pgxpool.Pool, as in:
+//
+//	metrics := pgxprom.NewMetrics(pgxprom.Options{Namespace: "myservice"})
+//	prometheus.MustRegister(metrics)
+//	s := &Service{Postgres: pgxprom.New(pool, metrics)}
+//
+// The metrics are labeled by the operation set on the context with WithOperation, so that
+// the calls of each use case can be told apart without instrumenting every call site:
+//
+//	ctx = pgxprom.WithOperation(ctx, "get_user")
+package pgxprom
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Options for the metrics.
+type Options struct {
+	// Namespace and Subsystem prefixing the names of the metrics.
+	Namespace string
+	Subsystem string
+
+	// Buckets of the latency histogram, in seconds. If nil, prometheus.DefBuckets is used.
+	Buckets []float64
+}
+
+// Metrics of the calls, implementing prometheus.Collector:
+//
+//   - pgx_call_duration_seconds: histogram of the latency of the calls, labeled by operation and method.
+//   - pgx_call_errors_total: counter of the errors, labeled by operation, method, and SQLSTATE code.
+//   - pgx_calls_in_flight: gauge of the calls in progress, labeled by operation.
+//
+// Queries are in progress until their rows are closed.
+type Metrics struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+	inFlight *prometheus.GaugeVec
+}
+
+// Validate if Metrics implements the prometheus.Collector interface.
+var _ prometheus.Collector = (*Metrics)(nil)
+
+// NewMetrics to register with a prometheus.Registerer.
+func NewMetrics(o Options) *Metrics {
+	return &Metrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.Namespace,
+			Subsystem: o.Subsystem,
+			Name:      "pgx_call_duration_seconds",
+			Help:      "Latency of the calls to PostgreSQL.",
+			Buckets:   o.Buckets,
+		}, []string{"operation", "method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.Namespace,
+			Subsystem: o.Subsystem,
+			Name:      "pgx_call_errors_total",
+			Help:      "Errors of the calls to PostgreSQL by SQLSTATE code.",
+		}, []string{"operation", "method", "sqlstate"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: o.Namespace,
+			Subsystem: o.Subsystem,
+			Name:      "pgx_calls_in_flight",
+			Help:      "Calls to PostgreSQL in progress.",
+		}, []string{"operation"}),
+	}
+}
+
+// Describe the metrics.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.duration.Describe(ch)
+	m.errors.Describe(ch)
+	m.inFlight.Describe(ch)
+}
+
+// Collect the metrics.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.duration.Collect(ch)
+	m.errors.Collect(ch)
+	m.inFlight.Collect(ch)
+}
+
+// start measuring a call, returning a function to finish it with its error.
+func (m *Metrics) start(ctx context.Context, method string) func(err error) {
+	op := Operation(ctx)
+	inFlight := m.inFlight.WithLabelValues(op)
+	inFlight.Inc()
+	start := time.Now()
+	return func(err error) {
+		inFlight.Dec()
+		m.duration.WithLabelValues(op, method).Observe(time.Since(start).Seconds())
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			m.errors.WithLabelValues(op, method, sqlState(err)).Inc()
+		}
+	}
+}
+
+// sqlState of the error, or a description of it for errors that don't come from PostgreSQL.
+func sqlState(err error) string {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pgErr):
+		return pgErr.Code
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "other"
+	}
+}
+
+type operationKey struct{}
+
+// WithOperation returns a copy of the context with the operation name used to label the metrics of the calls made with it.
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+// Operation set on the context with WithOperation, or "unknown".
+func Operation(ctx context.Context) string {
+	if op, ok := ctx.Value(operationKey{}).(string); ok {
+		return op
+	}
+	return "unknown"
+}
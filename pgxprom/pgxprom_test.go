@@ -0,0 +1,128 @@
+package pgxprom_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/henvic/pgtools/pgxfake"
+	"github.com/henvic/pgtools/pgxprom"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gathered metrics, keyed by their labels.
+type gathered struct {
+	counts   map[string]uint64  // Observations by operation and method.
+	errors   map[string]float64 // Errors by operation, method, and SQLSTATE code.
+	inFlight map[string]float64 // Calls in flight by operation.
+}
+
+func gather(t *testing.T, reg *prometheus.Registry) gathered {
+	t.Helper()
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("cannot gather metrics: %v", err)
+	}
+	g := gathered{
+		counts:   map[string]uint64{},
+		errors:   map[string]float64{},
+		inFlight: map[string]float64{},
+	}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			switch mf.GetName() {
+			case "test_pgx_call_duration_seconds":
+				g.counts[labels["operation"]+" "+labels["method"]] += m.GetHistogram().GetSampleCount()
+			case "test_pgx_call_errors_total":
+				g.errors[labels["operation"]+" "+labels["method"]+" "+labels["sqlstate"]] += m.GetCounter().GetValue()
+			case "test_pgx_calls_in_flight":
+				g.inFlight[labels["operation"]] += m.GetGauge().GetValue()
+			}
+		}
+	}
+	return g
+}
+
+func TestDB(t *testing.T) {
+	t.Parallel()
+	fake := pgxfake.New()
+	fake.On(`SELECT id FROM users`, pgxfake.Response{
+		Columns: []string{"id"},
+		Rows:    [][]any{{"1"}},
+	})
+	fake.On(`INSERT INTO users`, pgxfake.Response{Err: &pgconn.PgError{Code: "23505"}})
+	fake.On(`UPDATE users`, pgxfake.Response{Err: context.DeadlineExceeded})
+	metrics := pgxprom.NewMetrics(pgxprom.Options{Namespace: "test"})
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(metrics); err != nil {
+		t.Fatalf("cannot register metrics: %v", err)
+	}
+	db := pgxprom.New(fake, metrics)
+	ctx := pgxprom.WithOperation(context.Background(), "create_user")
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO users (id) VALUES ($1)", "1"); err == nil {
+		t.Error("expected error, got nil instead")
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	rows, err := db.Query(ctx, "SELECT id FROM users")
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	if got := gather(t, reg).inFlight["create_user"]; got != 1 {
+		t.Errorf("expected 1 call in flight until the rows are closed, got %v instead", got)
+	}
+	if _, err := pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	var id string
+	if err := db.QueryRow(ctx, "SELECT id FROM users WHERE id = $1", "2").Scan(&id); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if _, err := db.Exec(context.Background(), "UPDATE users SET active = true"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to be %v, got %v instead", context.DeadlineExceeded, err)
+	}
+
+	got := gather(t, reg)
+	wantCounts := map[string]uint64{
+		"create_user Begin":    1,
+		"create_user Exec":     1,
+		"create_user Rollback": 1,
+		"create_user Query":    1,
+		"create_user QueryRow": 1,
+		"unknown Exec":         1,
+	}
+	for k, want := range wantCounts {
+		if got.counts[k] != want {
+			t.Errorf("expected %d observations of %q, got %d instead", want, k, got.counts[k])
+		}
+	}
+	wantErrs := map[string]float64{
+		"create_user Exec 23505": 1,
+		"unknown Exec timeout":   1,
+	}
+	if len(got.errors) != len(wantErrs) {
+		t.Errorf("expected errors to be %v, got %v instead", wantErrs, got.errors)
+	}
+	for k, want := range wantErrs {
+		if got.errors[k] != want {
+			t.Errorf("expected %v errors of %q, got %v instead", want, k, got.errors[k])
+		}
+	}
+	for op, v := range got.inFlight {
+		if v != 0 {
+			t.Errorf("expected no calls in flight for %q, got %v instead", op, v)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package pgxretry implements the pgxs.PGX interface by delegating the calls to another implementation,
+// such as a *pgxpool.Pool, and retrying them on transient errors, such as serialization failures (40001),
+// deadlocks (40P01), and connection resets, as in:
+//
+//	db := pgxretry.New(pool, pgxretry.Options{MaxAttempts: 5})
+//	s := &Service{Postgres: db}
+//
+// Statements inside a transaction can't be retried individually, as the error aborts the transaction.
+// To retry a whole transaction, use BeginFunc.
+package pgxretry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"syscall"
+	"time"
+
+	"github.com/henvic/pgtools/pgerr"
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Options for retrying.
+type Options struct {
+	// MaxAttempts of a call, including the first one. If zero, 3 is used.
+	MaxAttempts int
+
+	// Backoff returns how long to wait before the given retry, starting at 1.
+	// If nil, ExponentialBackoff(10*time.Millisecond, time.Second) is used.
+	Backoff func(retry int) time.Duration
+
+	// Retryable reports whether a call that failed with the error should be retried.
+	// If nil, IsRetryable is used.
+	Retryable func(err error) bool
+}
+
+// DB retrying the calls to another implementation of the PGX interface.
+// It's safe for concurrent use if the underlying implementation is.
+type DB struct {
+	db pgxs.PGX
+	o  Options
+}
+
+// Validate if DB implements the PGX interface.
+var _ pgxs.PGX = (*DB)(nil)
+
+// New DB retrying the calls to db.
+func New(db pgxs.PGX, o Options) *DB {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 3
+	}
+	if o.Backoff == nil {
+		o.Backoff = ExponentialBackoff(10*time.Millisecond, time.Second)
+	}
+	if o.Retryable == nil {
+		o.Retryable = IsRetryable
+	}
+	return &DB{db: db, o: o}
+}
+
+// IsRetryable reports whether the error is a serialization failure (40001), a deadlock (40P01),
+// a connection reset, or another error that happened before any data was sent to the server.
+//
+// Retrying after a connection reset is only safe for idempotent statements, as it's unknown whether
+// the server got the statement. Use Options.Retryable to change it.
+func IsRetryable(err error) bool {
+	if _, ok := pgerr.As(err); ok {
+		return pgerr.IsSerializationFailure(err) || pgerr.IsDeadlock(err)
+	}
+	var safe interface{ SafeToRetry() bool }
+	if errors.As(err, &safe) && safe.SafeToRetry() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET)
+}
+
+// ExponentialBackoff doubles the wait starting at base for each retry, up to maxDelay, with random jitter of up to half of it.
+func ExponentialBackoff(base, maxDelay time.Duration) func(retry int) time.Duration {
+	return func(retry int) time.Duration {
+		d := base
+		for i := 1; i < retry && d < maxDelay; i++ {
+			d *= 2
+		}
+		if d > maxDelay {
+			d = maxDelay
+		}
+		return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+}
+
+// do the call until it succeeds, fails with an error that isn't retryable, or runs out of attempts.
+func (db *DB) do(ctx context.Context, call func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= db.o.MaxAttempts || !db.o.Retryable(err) {
+			return err
+		}
+		t := time.NewTimer(db.o.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// Begin a transaction, retrying if it fails to start.
+func (db *DB) Begin(ctx context.Context) (pgx.Tx, error) {
+	var tx pgx.Tx
+	err := db.do(ctx, func() (err error) {
+		tx, err = db.db.Begin(ctx)
+		return err
+	})
+	return tx, err
+}
+
+// BeginTx starts a transaction, retrying if it fails to start.
+func (db *DB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	var tx pgx.Tx
+	err := db.do(ctx, func() (err error) {
+		tx, err = db.db.BeginTx(ctx, txOptions)
+		return err
+	})
+	return tx, err
+}
+
+// BeginFunc starts a transaction and calls fn with it, committing it if fn succeeds, and rolling it back otherwise.
+// The whole transaction is retried if it fails with a retryable error, so fn must be safe to call again.
+func (db *DB) BeginFunc(ctx context.Context, txOptions pgx.TxOptions, fn func(pgx.Tx) error) error {
+	return db.do(ctx, func() error {
+		return pgx.BeginTxFunc(ctx, db.db, txOptions, fn)
+	})
+}
+
+// CopyFrom isn't retried, as the rows of rowSrc can't be read again.
+func (db *DB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return db.db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+// Exec the statement, retrying on transient errors.
+func (db *DB) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := db.do(ctx, func() (err error) {
+		tag, err = db.db.Exec(ctx, sql, arguments...)
+		return err
+	})
+	return tag, err
+}
+
+// Query is retried if it fails before returning the rows.
+// Errors reading the rows aren't retried, as some of them might have been read already.
+func (db *DB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := db.do(ctx, func() (err error) {
+		rows, err = db.db.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow is retried when the row is scanned.
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &row{
+		db:  db,
+		ctx: ctx,
+		query: func() pgx.Row {
+			return db.db.QueryRow(ctx, sql, args...)
+		},
+	}
+}
+
+// SendBatch isn't retried, as the errors are only known when the results are read.
+func (db *DB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return db.db.SendBatch(ctx, b)
+}
+
+// row querying again if scanning fails with a retryable error.
+type row struct {
+	db    *DB
+	ctx   context.Context
+	query func() pgx.Row
+}
+
+func (r *row) Scan(dest ...any) error {
+	return r.db.do(r.ctx, func() error {
+		return r.query().Scan(dest...)
+	})
+}
@@ -0,0 +1,193 @@
+package pgxretry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools/pgxfake"
+	"github.com/henvic/pgtools/pgxretry"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// flaky database failing the first calls to Exec with err.
+type flaky struct {
+	*pgxfake.DB
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flaky) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return pgconn.CommandTag{}, f.err
+	}
+	return f.DB.Exec(ctx, sql, arguments...)
+}
+
+func noBackoff(int) time.Duration {
+	return 0
+}
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc string
+		err  error
+		want bool
+	}{
+		{
+			desc: "serialization failure",
+			err:  &pgconn.PgError{Code: "40001"},
+			want: true,
+		},
+		{
+			desc: "deadlock",
+			err:  fmt.Errorf("cannot update: %w", &pgconn.PgError{Code: "40P01"}),
+			want: true,
+		},
+		{
+			desc: "unique violation",
+			err:  &pgconn.PgError{Code: "23505"},
+		},
+		{
+			desc: "connection reset",
+			err:  fmt.Errorf("read: %w", syscall.ECONNRESET),
+			want: true,
+		},
+		{
+			desc: "context canceled",
+			err:  context.Canceled,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			if got := pgxretry.IsRetryable(tc.err); got != tc.want {
+				t.Errorf("expected IsRetryable to be %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc     string
+		failures int
+		err      error
+		calls    int
+		wantErr  bool
+	}{
+		{
+			desc:     "success",
+			failures: 2,
+			err:      &pgconn.PgError{Code: "40001"},
+			calls:    3,
+		},
+		{
+			desc:     "max attempts",
+			failures: 3,
+			err:      &pgconn.PgError{Code: "40P01"},
+			calls:    3,
+			wantErr:  true,
+		},
+		{
+			desc:     "not retryable",
+			failures: 1,
+			err:      &pgconn.PgError{Code: "23505"},
+			calls:    1,
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			f := &flaky{DB: pgxfake.New(), failures: tc.failures, err: tc.err}
+			f.On(`UPDATE accounts`, pgxfake.Response{Tag: "UPDATE 1"})
+			db := pgxretry.New(f, pgxretry.Options{MaxAttempts: 3, Backoff: noBackoff})
+			tag, err := db.Exec(context.Background(), "UPDATE accounts SET balance = balance - 1")
+			if tc.wantErr != (err != nil) {
+				t.Errorf("expected error to be %v, got %v instead", tc.wantErr, err)
+			}
+			if tc.wantErr && !errors.Is(err, tc.err) {
+				t.Errorf("expected error to be %v, got %v instead", tc.err, err)
+			}
+			if !tc.wantErr && tag.String() != "UPDATE 1" {
+				t.Errorf("expected tag to be UPDATE 1, got %q instead", tag)
+			}
+			if f.calls != tc.calls {
+				t.Errorf("expected %d calls, got %d instead", tc.calls, f.calls)
+			}
+		})
+	}
+}
+
+func TestBeginFunc(t *testing.T) {
+	t.Parallel()
+	fake := pgxfake.New()
+	db := pgxretry.New(fake, pgxretry.Options{Backoff: noBackoff})
+	var attempts int
+	err := db.BeginFunc(context.Background(), pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx pgx.Tx) error {
+		attempts++
+		if attempts < 2 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d instead", attempts)
+	}
+	var methods []string
+	for _, c := range fake.Calls() {
+		methods = append(methods, c.Method)
+	}
+	want := "[Begin Rollback Begin Commit]"
+	if got := fmt.Sprint(methods); got != want {
+		t.Errorf("expected calls to be %v, got %v instead", want, got)
+	}
+}
+
+func TestContextDone(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f := &flaky{DB: pgxfake.New(), failures: 5, err: &pgconn.PgError{Code: "40001"}}
+	db := pgxretry.New(f, pgxretry.Options{MaxAttempts: 5, Backoff: func(int) time.Duration { return time.Hour }})
+	if _, err := db.Exec(ctx, "UPDATE accounts SET balance = 0"); err == nil {
+		t.Error("expected error, got nil instead")
+	}
+	if f.calls != 1 {
+		t.Errorf("expected 1 call, got %d instead", f.calls)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	t.Parallel()
+	backoff := pgxretry.ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	testCases := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 50 * time.Millisecond},
+		{10, 50 * time.Millisecond},
+	}
+	for _, tc := range testCases {
+		if d := backoff(tc.retry); d < tc.max/2 || d > tc.max {
+			t.Errorf("expected backoff for retry %d to be between %v and %v, got %v instead", tc.retry, tc.max/2, tc.max, d)
+		}
+	}
+}
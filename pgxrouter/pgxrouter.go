@@ -0,0 +1,348 @@
+// Package pgxrouter implements the pgxs.PGX interface by sending writes to a primary database
+// and reads to replicas, as in:
+//
+//	router := pgxrouter.New(primary, []pgxs.PGX{replica1, replica2}, pgxrouter.Options{
+//		HealthCheckInterval: 5 * time.Second,
+//	})
+//	defer router.Close()
+//	s := &Service{Postgres: router}
+//
+// Calls are sent to the primary unless they're made through the ReadOnly view, or, with the AutoRoute option,
+// unless they're queries that are read-only, as in:
+//
+//	rows, err := router.ReadOnly().Query(ctx, "SELECT id, name FROM users")
+//
+// Replicas are chosen in turn among the healthy ones, and the primary is used if none are.
+// As replicas might lag behind the primary, don't read from them data that must reflect a write just made.
+package pgxrouter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Options for routing.
+type Options struct {
+	// AutoRoute sends the queries made with Query and QueryRow that are read-only, according to IsReadOnly, to the replicas.
+	// Otherwise, only calls made through the ReadOnly view are sent to them.
+	AutoRoute bool
+
+	// HealthCheckInterval between checks of the replicas. Unhealthy replicas aren't used until they pass a check.
+	// If zero, replicas are only marked unhealthy when a call to them fails with a connection error, and never checked again.
+	HealthCheckInterval time.Duration
+
+	// HealthCheck of a replica. If nil, a replica is healthy if it can execute "SELECT 1".
+	HealthCheck func(ctx context.Context, db pgxs.PGX) error
+}
+
+// replica database and its health.
+type replica struct {
+	db        pgxs.PGX
+	unhealthy atomic.Bool
+}
+
+// Router sending writes to the primary database and reads to the replicas.
+// It's safe for concurrent use.
+type Router struct {
+	primary  pgxs.PGX
+	replicas []*replica
+	next     atomic.Uint64 // Used to choose the replicas in turn.
+	o        Options
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Validate if Router implements the PGX interface.
+var _ pgxs.PGX = (*Router)(nil)
+
+// New router, starting to check the health of the replicas if HealthCheckInterval is set.
+// Call Close to stop checking them.
+func New(primary pgxs.PGX, replicas []pgxs.PGX, o Options) *Router {
+	if o.HealthCheck == nil {
+		o.HealthCheck = ping
+	}
+	r := &Router{
+		primary: primary,
+		o:       o,
+	}
+	for _, db := range replicas {
+		r.replicas = append(r.replicas, &replica{db: db})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	if o.HealthCheckInterval > 0 && len(r.replicas) != 0 {
+		r.wg.Add(1)
+		go r.checkHealth(ctx)
+	}
+	return r
+}
+
+// Close stops checking the health of the replicas. It doesn't close the databases.
+func (r *Router) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// ping the database.
+func ping(ctx context.Context, db pgxs.PGX) error {
+	_, err := db.Exec(ctx, "SELECT 1")
+	return err
+}
+
+// checkHealth of the replicas periodically, until the context is canceled.
+func (r *Router) checkHealth(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.o.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.CheckHealth(ctx)
+		}
+	}
+}
+
+// CheckHealth of the replicas now, instead of waiting for the next periodic check.
+func (r *Router) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, rep := range r.replicas {
+		wg.Add(1)
+		go func(rep *replica) {
+			defer wg.Done()
+			ctx := ctx
+			if r.o.HealthCheckInterval > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, r.o.HealthCheckInterval)
+				defer cancel()
+			}
+			rep.unhealthy.Store(r.o.HealthCheck(ctx, rep.db) != nil)
+		}(rep)
+	}
+	wg.Wait()
+}
+
+// Healthy returns how many replicas are healthy.
+func (r *Router) Healthy() int {
+	var n int
+	for _, rep := range r.replicas {
+		if !rep.unhealthy.Load() {
+			n++
+		}
+	}
+	return n
+}
+
+// replica to send a read to, or nil if none is healthy.
+func (r *Router) replica() *replica {
+	n := len(r.replicas)
+	if n == 0 {
+		return nil
+	}
+	start := r.next.Add(1)
+	for i := 0; i < n; i++ {
+		if rep := r.replicas[(start+uint64(i))%uint64(n)]; !rep.unhealthy.Load() {
+			return rep
+		}
+	}
+	return nil
+}
+
+// connectionError reports whether the error means the replica can't be reached, so that the read can be sent to the primary.
+func connectionError(err error) bool {
+	var pgErr *pgconn.PgError
+	if err == nil || errors.As(err, &pgErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	var safe interface{ SafeToRetry() bool }
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		(errors.As(err, &safe) && safe.SafeToRetry())
+}
+
+// read calls fn with a healthy replica, falling back to the primary if there's none or if the replica can't be reached.
+func (r *Router) read(fn func(db pgxs.PGX) error) error {
+	rep := r.replica()
+	if rep == nil {
+		return fn(r.primary)
+	}
+	err := fn(rep.db)
+	if !connectionError(err) {
+		return err
+	}
+	rep.unhealthy.Store(true)
+	return fn(r.primary)
+}
+
+// readOnlyPattern matches the statements that start as read-only queries.
+var readOnlyPattern = regexp.MustCompile(`(?is)^\s*(SELECT|SHOW|VALUES|TABLE)\b`)
+
+// writePattern matches clauses that make a query that starts as read-only write or lock rows.
+var writePattern = regexp.MustCompile(`(?i)\b(INTO|FOR\s+(UPDATE|SHARE|NO\s+KEY\s+UPDATE|KEY\s+SHARE)|NEXTVAL|SETVAL|PG_ADVISORY_\w*LOCK\w*)\b`)
+
+// IsReadOnly reports whether the statement is a read-only query, conservatively:
+// it must start with SELECT, SHOW, VALUES, or TABLE, and not lock rows, create a table with SELECT INTO,
+// or call functions known to write, such as nextval. Statements calling other functions that write aren't detected.
+func IsReadOnly(sql string) bool {
+	return readOnlyPattern.MatchString(sql) && !writePattern.MatchString(stripLiterals(sql))
+}
+
+// stripLiterals removes the string literals and quoted identifiers of the statement, so that their contents aren't matched.
+func stripLiterals(sql string) string {
+	var sb strings.Builder
+	var quote rune
+	for _, c := range sql {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// Begin a transaction on the primary.
+func (r *Router) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.primary.Begin(ctx)
+}
+
+// BeginTx starts a transaction on the primary, or on a replica if it's read-only and AutoRoute is set.
+func (r *Router) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	if !r.o.AutoRoute || txOptions.AccessMode != pgx.ReadOnly {
+		return r.primary.BeginTx(ctx, txOptions)
+	}
+	return r.ReadOnly().BeginTx(ctx, txOptions)
+}
+
+// CopyFrom on the primary.
+func (r *Router) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return r.primary.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+// Exec on the primary.
+func (r *Router) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return r.primary.Exec(ctx, sql, arguments...)
+}
+
+// Query on the primary, or on a replica if it's read-only and AutoRoute is set.
+func (r *Router) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if !r.o.AutoRoute || !IsReadOnly(sql) {
+		return r.primary.Query(ctx, sql, args...)
+	}
+	return r.ReadOnly().Query(ctx, sql, args...)
+}
+
+// QueryRow on the primary, or on a replica if it's read-only and AutoRoute is set.
+func (r *Router) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if !r.o.AutoRoute || !IsReadOnly(sql) {
+		return r.primary.QueryRow(ctx, sql, args...)
+	}
+	return r.ReadOnly().QueryRow(ctx, sql, args...)
+}
+
+// SendBatch on the primary.
+func (r *Router) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return r.primary.SendBatch(ctx, b)
+}
+
+// ReadOnly view of the router, sending all calls to the replicas.
+func (r *Router) ReadOnly() pgxs.PGX {
+	return readOnly{r}
+}
+
+// readOnly view sending all calls to the replicas.
+type readOnly struct {
+	r *Router
+}
+
+// Begin a read-only transaction on a replica.
+func (ro readOnly) Begin(ctx context.Context) (pgx.Tx, error) {
+	return ro.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// BeginTx starts a read-only transaction on a replica.
+func (ro readOnly) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	txOptions.AccessMode = pgx.ReadOnly
+	var tx pgx.Tx
+	err := ro.r.read(func(db pgxs.PGX) (err error) {
+		tx, err = db.BeginTx(ctx, txOptions)
+		return err
+	})
+	return tx, err
+}
+
+// CopyFrom on a replica, which fails unless the table is temporary.
+func (ro readOnly) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	rep := ro.r.replica()
+	if rep == nil {
+		return ro.r.primary.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	}
+	return rep.db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (ro readOnly) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := ro.r.read(func(db pgxs.PGX) (err error) {
+		tag, err = db.Exec(ctx, sql, arguments...)
+		return err
+	})
+	return tag, err
+}
+
+func (ro readOnly) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := ro.r.read(func(db pgxs.PGX) (err error) {
+		rows, err = db.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow on a replica, falling back to the primary when the row is scanned if the replica can't be reached.
+func (ro readOnly) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &row{
+		r: ro.r,
+		query: func(db pgxs.PGX) pgx.Row {
+			return db.QueryRow(ctx, sql, args...)
+		},
+	}
+}
+
+// SendBatch on a replica.
+func (ro readOnly) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	rep := ro.r.replica()
+	if rep == nil {
+		return ro.r.primary.SendBatch(ctx, b)
+	}
+	return rep.db.SendBatch(ctx, b)
+}
+
+// row querying a replica when it's scanned.
+type row struct {
+	r     *Router
+	query func(db pgxs.PGX) pgx.Row
+}
+
+func (r *row) Scan(dest ...any) error {
+	return r.r.read(func(db pgxs.PGX) error {
+		return r.query(db).Scan(dest...)
+	})
+}
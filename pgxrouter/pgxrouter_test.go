@@ -0,0 +1,168 @@
+package pgxrouter_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools/pgxfake"
+	"github.com/henvic/pgtools/pgxrouter"
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/jackc/pgx/v5"
+)
+
+// database answering queries with its name.
+func database(name string) *pgxfake.DB {
+	db := pgxfake.New()
+	db.On(`.`, pgxfake.Response{
+		Columns: []string{"name"},
+		Rows:    [][]any{{name}},
+		Tag:     "SELECT 1",
+	})
+	return db
+}
+
+// queried returns the name of the database the query was sent to.
+func queried(t *testing.T, db pgxs.PGX, sql string) string {
+	t.Helper()
+	var name string
+	if err := db.QueryRow(context.Background(), sql).Scan(&name); err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	return name
+}
+
+func TestIsReadOnly(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT id FROM users", true},
+		{"  select id from users where name = 'insert into'", true},
+		{"SHOW search_path", true},
+		{"INSERT INTO users (id) VALUES ($1)", false},
+		{"SELECT id FROM users FOR UPDATE", false},
+		{"SELECT id FROM users FOR NO KEY UPDATE SKIP LOCKED", false},
+		{"SELECT * INTO users_copy FROM users", false},
+		{"SELECT nextval('users_id_seq')", false},
+		{"SELECT pg_advisory_xact_lock(1)", false},
+		{"WITH deleted AS (DELETE FROM users RETURNING id) SELECT count(*) FROM deleted", false},
+	}
+	for _, tc := range testCases {
+		if got := pgxrouter.IsReadOnly(tc.sql); got != tc.want {
+			t.Errorf("expected IsReadOnly(%q) to be %v, got %v instead", tc.sql, tc.want, got)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+	router := pgxrouter.New(database("primary"), []pgxs.PGX{database("replica1"), database("replica2")}, pgxrouter.Options{})
+	defer router.Close()
+
+	if got := queried(t, router, "SELECT name"); got != "primary" {
+		t.Errorf("expected query to be sent to the primary without AutoRoute, got %q instead", got)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		seen[queried(t, router.ReadOnly(), "SELECT name")] = true
+	}
+	if !seen["replica1"] || !seen["replica2"] || seen["primary"] {
+		t.Errorf("expected queries to be sent to the replicas in turn, got %v instead", seen)
+	}
+}
+
+func TestRouterAutoRoute(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	primary := database("primary")
+	router := pgxrouter.New(primary, []pgxs.PGX{database("replica")}, pgxrouter.Options{AutoRoute: true})
+	defer router.Close()
+
+	if got := queried(t, router, "SELECT name"); got != "replica" {
+		t.Errorf("expected read-only query to be sent to the replica, got %q instead", got)
+	}
+	if got := queried(t, router, "SELECT name FOR UPDATE"); got != "primary" {
+		t.Errorf("expected locking query to be sent to the primary, got %q instead", got)
+	}
+	if _, err := router.Exec(ctx, "UPDATE users SET name = $1", "name"); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if calls := primary.Calls(); len(calls) != 2 || calls[1].Method != "Exec" {
+		t.Errorf("expected Exec to be sent to the primary, got %+v instead", calls)
+	}
+}
+
+func TestRouterFallback(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	down := pgxfake.New()
+	down.On(`.`, pgxfake.Response{Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}})
+	router := pgxrouter.New(database("primary"), []pgxs.PGX{down}, pgxrouter.Options{})
+	defer router.Close()
+
+	if got := queried(t, router.ReadOnly(), "SELECT name"); got != "primary" {
+		t.Errorf("expected query to fall back to the primary, got %q instead", got)
+	}
+	if n := router.Healthy(); n != 0 {
+		t.Errorf("expected no healthy replicas, got %d instead", n)
+	}
+	rows, err := router.ReadOnly().Query(ctx, "SELECT name")
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil || len(names) != 1 || names[0] != "primary" {
+		t.Errorf("expected query to be sent to the primary, got %v, %v instead", names, err)
+	}
+
+	// The replica is healthy again once it passes a health check.
+	down.Reset()
+	down.On(`.`, pgxfake.Response{Tag: "SELECT 1"})
+	router.CheckHealth(ctx)
+	if n := router.Healthy(); n != 1 {
+		t.Errorf("expected 1 healthy replica, got %d instead", n)
+	}
+}
+
+func TestRouterQueryError(t *testing.T) {
+	t.Parallel()
+	replica := pgxfake.New()
+	replica.On(`.`, pgxfake.Response{Err: errors.New("relation does not exist")})
+	router := pgxrouter.New(database("primary"), []pgxs.PGX{replica}, pgxrouter.Options{})
+	defer router.Close()
+
+	var name string
+	if err := router.ReadOnly().QueryRow(context.Background(), "SELECT name").Scan(&name); err == nil {
+		t.Error("expected error, got nil instead")
+	}
+	if n := router.Healthy(); n != 1 {
+		t.Errorf("expected the replica to remain healthy after a query error, got %d healthy instead", n)
+	}
+}
+
+func TestRouterHealthCheck(t *testing.T) {
+	t.Parallel()
+	checked := make(chan struct{}, 1)
+	router := pgxrouter.New(database("primary"), []pgxs.PGX{database("replica")}, pgxrouter.Options{
+		HealthCheckInterval: time.Millisecond,
+		HealthCheck: func(ctx context.Context, db pgxs.PGX) error {
+			select {
+			case checked <- struct{}{}:
+			default:
+			}
+			return errors.New("replication lag too high")
+		},
+	})
+	<-checked
+	router.Close()
+	if n := router.Healthy(); n != 0 {
+		t.Errorf("expected no healthy replicas, got %d instead", n)
+	}
+	if got := queried(t, router.ReadOnly(), "SELECT name"); got != "primary" {
+		t.Errorf("expected query to be sent to the primary, got %q instead", got)
+	}
+}
@@ -0,0 +1,58 @@
+package pgxs_test
+
+import (
+	"context"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/henvic/pgtools/sqltest"
+)
+
+var force = flag.Bool("force", false, "Force cleaning the database before starting")
+
+func checkPostgres(t testing.TB) {
+	if os.Getenv("INTEGRATION_TESTDB") != "true" {
+		t.Skip("Skipping tests that require database connection")
+	}
+}
+
+func TestListen(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("../sqltest/example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_pgxs_",
+	})
+	pool := migration.Setup(ctx, "")
+
+	sub, err := pgxs.Listen(ctx, pool, "media_created", "media_deleted")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	if _, err := pool.Exec(ctx, "SELECT pg_notify('media_deleted', 'example')"); err != nil {
+		t.Fatalf("cannot notify: %v", err)
+	}
+	select {
+	case n := <-sub.C:
+		if n.Channel != "media_deleted" || n.Payload != "example" {
+			t.Errorf("expected notification on media_deleted with payload example, got %+v instead", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expected notification, got none")
+	}
+
+	if err := sub.Close(); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Error("expected channel to be closed")
+	}
+	if n := pool.Stat().AcquiredConns(); n != 0 {
+		t.Errorf("expected connection to be released, got %d acquired connections instead", n)
+	}
+}
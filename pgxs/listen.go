@@ -0,0 +1,122 @@
+package pgxs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Listener interface extending PGX with a method to acquire a dedicated connection, as needed for LISTEN/NOTIFY.
+// It is satisfied by *pgxpool.Pool.
+type Listener interface {
+	PGX
+
+	// Acquire returns a connection from the pool, which must be released with Release once it's no longer needed.
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// Validate if the Listener interface was derived from *pgxpool.Pool correctly.
+var _ Listener = (*pgxpool.Pool)(nil)
+
+// unlistenTimeout is the time to wait for UNLISTEN before the connection is closed instead of returned to the pool.
+const unlistenTimeout = 5 * time.Second
+
+// Subscription to notifications sent with NOTIFY or pg_notify.
+type Subscription struct {
+	// C receives the notifications, and is closed once the subscription stops.
+	C <-chan *pgconn.Notification
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// Listen acquires a dedicated connection from db, subscribes to the channels with LISTEN,
+// and sends the notifications received to the C channel of the subscription.
+//
+// The subscription stops once ctx is canceled, Close is called, or the connection fails.
+// The connection is then released back to the pool, after UNLISTEN.
+//
+// Notifications aren't received while the C channel is full, so read it promptly.
+func Listen(ctx context.Context, db Listener, channels ...string) (*Subscription, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("no channels to listen to")
+	}
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			release(conn)
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c := make(chan *pgconn.Notification)
+	s := &Subscription{
+		C:      c,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go s.run(ctx, conn, c)
+	return s, nil
+}
+
+func (s *Subscription) run(ctx context.Context, conn *pgxpool.Conn, c chan<- *pgconn.Notification) {
+	defer close(s.done)
+	defer close(c)
+	defer release(conn)
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.mu.Lock()
+				s.err = err
+				s.mu.Unlock()
+			}
+			return
+		}
+		select {
+		case c <- n:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// release the connection back to the pool, closing it if it cannot stop listening.
+func release(conn *pgxpool.Conn) {
+	if !conn.Conn().IsClosed() {
+		ctx, cancel := context.WithTimeout(context.Background(), unlistenTimeout)
+		defer cancel()
+		if _, err := conn.Exec(ctx, "UNLISTEN *"); err != nil {
+			_ = conn.Hijack().Close(ctx)
+			return
+		}
+	}
+	conn.Release()
+}
+
+// Err returns the error that stopped the subscription, if any.
+// It returns nil if the subscription is still running, or if it was stopped by Close or by canceling its context.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close stops the subscription, waiting for the connection to be released.
+// It returns the error that stopped the subscription before, if any.
+func (s *Subscription) Close() error {
+	s.cancel()
+	<-s.done
+	return s.Err()
+}
@@ -1,4 +1,6 @@
-package postgres
+// Package pgxs contains interfaces for using pgx in the business logic of applications,
+// so that packages don't depend on the concrete *pgxpool.Pool or *pgx.Conn types.
+package pgxs
 
 import (
 	"context"
@@ -10,9 +12,10 @@ import (
 
 // PGX limited interface with high-level API for pgx methods safe to be used in high-level business logic packages.
 // It is satisfied by implementations *pgx.Conn and *pgxpool.Pool (and you should probably use the second one usually).
+// pgx.Tx doesn't satisfy it, as it has no BeginTx method.
 //
 // Caveat: It doesn't expose a method to acquire a *pgx.Conn or handle notifications,
-// so it's not compatible with LISTEN/NOTIFY.
+// so it's not compatible with LISTEN/NOTIFY. Use the Listener interface and the Listen function for that.
 //
 // Reference: https://pkg.go.dev/github.com/jackc/pgx/v5
 type PGX interface {
@@ -0,0 +1,136 @@
+// Package pgxslog logs the statements executed with pgx using log/slog.
+//
+// Install the Tracer on the configuration of a connection or pool:
+//
+//	config.ConnConfig.Tracer = pgxslog.NewTracer(pgxslog.Options{
+//		Logger:        logger,
+//		SlowThreshold: 500 * time.Millisecond,
+//		SampleRate:    0.01,
+//	})
+//
+// Failed statements are logged with the error level, and statements taking at least SlowThreshold
+// with the warning level. Other statements are logged with Level, subject to sampling.
+package pgxslog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Redacted replaces the arguments of the statements, unless LogArgs is set.
+const Redacted = "[REDACTED]"
+
+// Options for logging.
+type Options struct {
+	// Logger to use. If nil, slog.Default() is used.
+	Logger *slog.Logger
+
+	// Level of the statements that aren't slow and didn't fail. The default is slog.LevelInfo.
+	Level slog.Level
+
+	// SlowThreshold for logging statements with the warning level, regardless of sampling.
+	// If zero, statements aren't considered slow.
+	SlowThreshold time.Duration
+
+	// SampleRate is the fraction of the statements that aren't slow and didn't fail to log, between 0 and 1.
+	// If zero, all of them are logged.
+	SampleRate float64
+
+	// LogArgs logs the arguments of the statements, instead of replacing each one with Redacted.
+	LogArgs bool
+
+	// Redact the argument at the given position of the statement when LogArgs is set,
+	// returning the value to log, such as Redacted for sensitive data. If nil, arguments are logged as is.
+	Redact func(sql string, n int, arg any) any
+}
+
+// Tracer logging the statements executed on a connection.
+// It implements pgx.QueryTracer.
+type Tracer struct {
+	o Options
+}
+
+// Validate if Tracer implements the pgx.QueryTracer interface.
+var _ pgx.QueryTracer = (*Tracer)(nil)
+
+// NewTracer to install on the configuration of a connection or pool.
+func NewTracer(o Options) *Tracer {
+	if o.Logger == nil {
+		o.Logger = slog.Default()
+	}
+	return &Tracer{o: o}
+}
+
+type startKey struct{}
+
+// start of a statement, kept in the context between TraceQueryStart and TraceQueryEnd.
+type start struct {
+	time time.Time
+	sql  string
+	args []any
+}
+
+// TraceQueryStart records when the statement started.
+func (t *Tracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, startKey{}, start{time: time.Now(), sql: data.SQL, args: data.Args})
+}
+
+// TraceQueryEnd logs the statement.
+func (t *Tracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	s, ok := ctx.Value(startKey{}).(start)
+	if !ok {
+		return
+	}
+	duration := time.Since(s.time)
+	level, msg := t.o.Level, "query"
+	failed := data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows)
+	switch {
+	case failed:
+		level, msg = slog.LevelError, "query failed"
+	case t.o.SlowThreshold > 0 && duration >= t.o.SlowThreshold:
+		level, msg = slog.LevelWarn, "slow query"
+	case t.o.SampleRate > 0 && rand.Float64() >= t.o.SampleRate:
+		return
+	}
+	if !t.o.Logger.Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("sql", s.sql),
+		slog.Any("args", t.args(s.sql, s.args)),
+		slog.Duration("duration", duration),
+		slog.Int64("rows", data.CommandTag.RowsAffected()),
+	}
+	if conn != nil {
+		attrs = append(attrs, slog.String("database", conn.Config().Database))
+	}
+	if failed {
+		attrs = append(attrs, slog.Any("error", data.Err))
+		var pgErr *pgconn.PgError
+		if errors.As(data.Err, &pgErr) {
+			attrs = append(attrs, slog.String("sqlstate", pgErr.Code))
+		}
+	}
+	t.o.Logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// args to log, redacted.
+func (t *Tracer) args(sql string, args []any) []any {
+	logged := make([]any, 0, len(args))
+	for n, arg := range args {
+		switch {
+		case !t.o.LogArgs:
+			arg = Redacted
+		case t.o.Redact != nil:
+			arg = t.o.Redact(sql, n, arg)
+		}
+		logged = append(logged, arg)
+	}
+	return logged
+}
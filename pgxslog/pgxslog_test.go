@@ -0,0 +1,167 @@
+package pgxslog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools/pgxslog"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// trace the statement with the tracer, returning the decoded log records.
+func trace(t *testing.T, o pgxslog.Options, sql string, args []any, tag string, err error) []map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	o.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	tracer := pgxslog.NewTracer(o)
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: sql, Args: args})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag(tag), Err: err})
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var r map[string]any
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("cannot decode log record: %v", err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc  string
+		o     pgxslog.Options
+		sql   string
+		args  []any
+		tag   string
+		err   error
+		want  map[string]any
+		empty bool
+	}{
+		{
+			desc: "query",
+			o:    pgxslog.Options{Level: slog.LevelDebug},
+			sql:  "UPDATE users SET password = $1 WHERE id = $2",
+			args: []any{"secret", 1},
+			tag:  "UPDATE 1",
+			want: map[string]any{
+				"level": "DEBUG",
+				"msg":   "query",
+				"sql":   "UPDATE users SET password = $1 WHERE id = $2",
+				"args":  []any{pgxslog.Redacted, pgxslog.Redacted},
+				"rows":  float64(1),
+			},
+		},
+		{
+			desc: "args",
+			o: pgxslog.Options{
+				LogArgs: true,
+				Redact: func(sql string, n int, arg any) any {
+					if n == 0 {
+						return pgxslog.Redacted
+					}
+					return arg
+				},
+			},
+			sql:  "UPDATE users SET password = $1 WHERE id = $2",
+			args: []any{"secret", 1},
+			tag:  "UPDATE 1",
+			want: map[string]any{
+				"level": "INFO",
+				"msg":   "query",
+				"sql":   "UPDATE users SET password = $1 WHERE id = $2",
+				"args":  []any{pgxslog.Redacted, float64(1)},
+				"rows":  float64(1),
+			},
+		},
+		{
+			desc: "failed",
+			o:    pgxslog.Options{SampleRate: 0.000001},
+			sql:  "INSERT INTO users (id) VALUES ($1)",
+			args: []any{1},
+			err:  &pgconn.PgError{Severity: "ERROR", Code: "23505", Message: "duplicate key value violates unique constraint"},
+			want: map[string]any{
+				"level":    "ERROR",
+				"msg":      "query failed",
+				"sql":      "INSERT INTO users (id) VALUES ($1)",
+				"args":     []any{pgxslog.Redacted},
+				"rows":     float64(0),
+				"error":    "ERROR: duplicate key value violates unique constraint (SQLSTATE 23505)",
+				"sqlstate": "23505",
+			},
+		},
+		{
+			desc: "slow",
+			o:    pgxslog.Options{SlowThreshold: time.Nanosecond, SampleRate: 0.000001},
+			sql:  "SELECT 1",
+			tag:  "SELECT 1",
+			want: map[string]any{
+				"level": "WARN",
+				"msg":   "slow query",
+				"sql":   "SELECT 1",
+				"args":  []any{},
+				"rows":  float64(1),
+			},
+		},
+		{
+			desc:  "disabled level",
+			o:     pgxslog.Options{Level: slog.LevelDebug - 1},
+			sql:   "SELECT name FROM users WHERE id = $1",
+			args:  []any{1},
+			err:   pgx.ErrNoRows,
+			empty: true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			records := trace(t, tc.o, tc.sql, tc.args, tc.tag, tc.err)
+			if tc.empty {
+				if len(records) != 0 {
+					t.Errorf("expected no log records, got %v instead", records)
+				}
+				return
+			}
+			if len(records) != 1 {
+				t.Fatalf("expected 1 log record, got %v instead", records)
+			}
+			got := records[0]
+			if _, ok := got["duration"]; !ok {
+				t.Error("expected duration to be logged")
+			}
+			delete(got, "time")
+			delete(got, "duration")
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected log record to be %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTracerSampling(t *testing.T) {
+	t.Parallel()
+	var logged int
+	for i := 0; i < 100; i++ {
+		logged += len(trace(t, pgxslog.Options{SampleRate: 0.000001}, "SELECT 1", nil, "SELECT 1", nil))
+	}
+	if logged > 1 {
+		t.Errorf("expected sampling to skip the statements, got %d log records instead", logged)
+	}
+	// Failed statements are logged regardless of sampling.
+	if records := trace(t, pgxslog.Options{SampleRate: 0.000001}, "SELECT 1", nil, "", errors.New("canceled")); len(records) != 1 {
+		t.Errorf("expected failed statement to be logged, got %v instead", records)
+	}
+}
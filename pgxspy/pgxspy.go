@@ -0,0 +1,323 @@
+// Package pgxspy records the calls made through the pgxs.PGX interface, delegating them to
+// another implementation, such as a *pgxpool.Pool, so that they can be checked in tests or
+// inspected while debugging, as in:
+//
+//	spy := pgxspy.New(pool)
+//	s := &Service{Postgres: spy}
+//	// ...
+//	spy.AssertQuery(t, regexp.MustCompile(`^SELECT .* FROM users`))
+//
+// For production debugging builds, set MaxCalls to keep only the most recent calls.
+package pgxspy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Call made through the spy.
+type Call struct {
+	// Method called, such as Exec, Query, Begin, or Commit.
+	Method string
+
+	// SQL of the statement, or the table name for CopyFrom.
+	SQL string
+
+	// Args of the statement.
+	Args []any
+
+	// Duration of the call. For Query, it lasts until the rows are closed,
+	// and for QueryRow, until the row is scanned.
+	Duration time.Duration
+
+	// Err returned by the call, including errors reading the rows of Query.
+	Err error
+}
+
+// TB is the subset of testing.TB used by the assertion helpers.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Spy implementing the PGX interface by delegating the calls to another implementation and recording them.
+// It's safe for concurrent use.
+type Spy struct {
+	// MaxCalls to keep, discarding the oldest ones. If zero, all calls are kept.
+	MaxCalls int
+
+	db    pgxs.PGX
+	mu    sync.Mutex
+	calls []*Call
+}
+
+// Validate if Spy implements the PGX interface.
+var _ pgxs.PGX = (*Spy)(nil)
+
+// New spy delegating the calls to db.
+func New(db pgxs.PGX) *Spy {
+	return &Spy{db: db}
+}
+
+// start recording a call, returning a function to finish it with its error.
+func (s *Spy) start(method, sql string, args []any) func(err error) {
+	c := &Call{Method: method, SQL: sql, Args: args}
+	s.mu.Lock()
+	s.calls = append(s.calls, c)
+	if s.MaxCalls > 0 && len(s.calls) > s.MaxCalls {
+		s.calls = append(s.calls[:0:0], s.calls[len(s.calls)-s.MaxCalls:]...)
+	}
+	s.mu.Unlock()
+	start := time.Now()
+	return func(err error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		c.Duration, c.Err = time.Since(start), err
+	}
+}
+
+// Calls recorded, in the order they were made.
+func (s *Spy) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := make([]Call, 0, len(s.calls))
+	for _, c := range s.calls {
+		calls = append(calls, *c)
+	}
+	return calls
+}
+
+// Reset the recorded calls.
+func (s *Spy) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+// AssertQuery checks that a recorded call has SQL matching the regular expression.
+// If none does, t.Errorf is called with the recorded calls.
+func (s *Spy) AssertQuery(t TB, re *regexp.Regexp) {
+	t.Helper()
+	calls := s.Calls()
+	for _, c := range calls {
+		if re.MatchString(c.SQL) {
+			return
+		}
+	}
+	t.Errorf("expected a query matching %q, got %d calls instead:%s", re, len(calls), formatCalls(calls))
+}
+
+// AssertArgs checks that a recorded call has SQL matching the regular expression and the given arguments.
+// If none does, t.Errorf is called with the recorded calls.
+func (s *Spy) AssertArgs(t TB, re *regexp.Regexp, args ...any) {
+	t.Helper()
+	if args == nil {
+		args = []any{}
+	}
+	calls := s.Calls()
+	for _, c := range calls {
+		got := c.Args
+		if got == nil {
+			got = []any{}
+		}
+		if re.MatchString(c.SQL) && reflect.DeepEqual(got, args) {
+			return
+		}
+	}
+	t.Errorf("expected a query matching %q with args %v, got %d calls instead:%s", re, args, len(calls), formatCalls(calls))
+}
+
+// formatCalls for an assertion failure.
+func formatCalls(calls []Call) string {
+	var sb strings.Builder
+	for _, c := range calls {
+		sb.WriteString("\n\t" + c.Method + " " + c.SQL)
+		if len(c.Args) != 0 {
+			fmt.Fprintf(&sb, " %v", c.Args)
+		}
+	}
+	return sb.String()
+}
+
+// Begin a transaction recording its calls.
+func (s *Spy) Begin(ctx context.Context) (pgx.Tx, error) {
+	finish := s.start("Begin", "BEGIN", nil)
+	tx, err := s.db.Begin(ctx)
+	finish(err)
+	if err != nil {
+		return nil, err
+	}
+	return &spyTx{Tx: tx, spy: s}, nil
+}
+
+// BeginTx starts a transaction recording its calls.
+func (s *Spy) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	finish := s.start("BeginTx", "BEGIN", nil)
+	tx, err := s.db.BeginTx(ctx, txOptions)
+	finish(err)
+	if err != nil {
+		return nil, err
+	}
+	return &spyTx{Tx: tx, spy: s}, nil
+}
+
+func (s *Spy) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	finish := s.start("CopyFrom", tableName.Sanitize(), nil)
+	n, err := s.db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	finish(err)
+	return n, err
+}
+
+func (s *Spy) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	finish := s.start("Exec", sql, arguments)
+	tag, err := s.db.Exec(ctx, sql, arguments...)
+	finish(err)
+	return tag, err
+}
+
+// Query is recorded when the rows are closed, either explicitly or by reading all of them.
+func (s *Spy) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	finish := s.start("Query", sql, args)
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		finish(err)
+		return rows, err
+	}
+	return &spyRows{Rows: rows, finish: finish}, nil
+}
+
+// QueryRow is recorded when the row is scanned.
+func (s *Spy) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	finish := s.start("QueryRow", sql, args)
+	return &spyRow{row: s.db.QueryRow(ctx, sql, args...), finish: finish}
+}
+
+// SendBatch is recorded with the number of queries of the batch when the results are closed,
+// as the statements of a batch can't be read with pgx v5.3.
+func (s *Spy) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	finish := s.start("SendBatch", "", []any{b.Len()})
+	return &spyBatchResults{BatchResults: s.db.SendBatch(ctx, b), finish: finish}
+}
+
+// spyTx records the calls made in a transaction.
+type spyTx struct {
+	pgx.Tx
+	spy *Spy
+}
+
+func (t *spyTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	finish := t.spy.start("Begin", "SAVEPOINT", nil)
+	tx, err := t.Tx.Begin(ctx)
+	finish(err)
+	if err != nil {
+		return nil, err
+	}
+	return &spyTx{Tx: tx, spy: t.spy}, nil
+}
+
+func (t *spyTx) Commit(ctx context.Context) error {
+	finish := t.spy.start("Commit", "COMMIT", nil)
+	err := t.Tx.Commit(ctx)
+	finish(err)
+	return err
+}
+
+func (t *spyTx) Rollback(ctx context.Context) error {
+	finish := t.spy.start("Rollback", "ROLLBACK", nil)
+	err := t.Tx.Rollback(ctx)
+	finish(err)
+	return err
+}
+
+func (t *spyTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	finish := t.spy.start("CopyFrom", tableName.Sanitize(), nil)
+	n, err := t.Tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	finish(err)
+	return n, err
+}
+
+func (t *spyTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	finish := t.spy.start("SendBatch", "", []any{b.Len()})
+	return &spyBatchResults{BatchResults: t.Tx.SendBatch(ctx, b), finish: finish}
+}
+
+func (t *spyTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	finish := t.spy.start("Exec", sql, arguments)
+	tag, err := t.Tx.Exec(ctx, sql, arguments...)
+	finish(err)
+	return tag, err
+}
+
+func (t *spyTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	finish := t.spy.start("Query", sql, args)
+	rows, err := t.Tx.Query(ctx, sql, args...)
+	if err != nil {
+		finish(err)
+		return rows, err
+	}
+	return &spyRows{Rows: rows, finish: finish}, nil
+}
+
+func (t *spyTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	finish := t.spy.start("QueryRow", sql, args)
+	return &spyRow{row: t.Tx.QueryRow(ctx, sql, args...), finish: finish}
+}
+
+// spyRows finishes the call when the rows are closed.
+type spyRows struct {
+	pgx.Rows
+	finish func(err error)
+	once   sync.Once
+}
+
+func (r *spyRows) Close() {
+	r.Rows.Close()
+	r.once.Do(func() {
+		r.finish(r.Rows.Err())
+	})
+}
+
+func (r *spyRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	// Rows are closed automatically once they're read.
+	r.Close()
+	return false
+}
+
+// spyRow finishes the call when the row is scanned.
+type spyRow struct {
+	row    pgx.Row
+	finish func(err error)
+}
+
+func (r *spyRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	r.finish(err)
+	return err
+}
+
+// spyBatchResults finishes the call when the batch is closed.
+type spyBatchResults struct {
+	pgx.BatchResults
+	finish func(err error)
+	once   sync.Once
+}
+
+func (b *spyBatchResults) Close() error {
+	err := b.BatchResults.Close()
+	b.once.Do(func() {
+		b.finish(err)
+	})
+	return err
+}
@@ -0,0 +1,140 @@
+package pgxspy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/henvic/pgtools/pgxfake"
+	"github.com/henvic/pgtools/pgxspy"
+	"github.com/jackc/pgx/v5"
+)
+
+// errorTB records the errors of the assertion helpers.
+type errorTB struct {
+	errors []string
+}
+
+func (t *errorTB) Helper() {}
+
+func (t *errorTB) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestSpy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	db.On(`SELECT id FROM users`, pgxfake.Response{
+		Columns: []string{"id"},
+		Rows:    [][]any{{"1"}, {"2"}},
+	})
+	db.On(`SELECT count`, pgxfake.Response{
+		Columns: []string{"count"},
+		Rows:    [][]any{{int64(2)}},
+	})
+	db.On(`DELETE FROM users`, pgxfake.Response{Err: errors.New("permission denied")})
+	spy := pgxspy.New(db)
+
+	rows, err := spy.Query(ctx, "SELECT id FROM users WHERE active = $1", true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	if want := []string{"1", "2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected ids to be %v, got %v instead", want, ids)
+	}
+	var count int
+	if err := spy.QueryRow(ctx, "SELECT count(*) FROM users").Scan(&count); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if _, err := spy.Exec(ctx, "DELETE FROM users WHERE id = $1", "1"); err == nil {
+		t.Error("expected error, got nil instead")
+	}
+
+	calls := spy.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 calls, got %d instead: %+v", len(calls), calls)
+	}
+	if calls[0].Method != "Query" || !reflect.DeepEqual(calls[0].Args, []any{true}) || calls[0].Err != nil {
+		t.Errorf("unexpected Query call: %+v", calls[0])
+	}
+	if calls[1].Method != "QueryRow" || calls[1].Err != nil {
+		t.Errorf("unexpected QueryRow call: %+v", calls[1])
+	}
+	if calls[2].Method != "Exec" || calls[2].Err == nil || calls[2].Err.Error() != "permission denied" {
+		t.Errorf("unexpected Exec call: %+v", calls[2])
+	}
+
+	spy.AssertQuery(t, regexp.MustCompile(`^SELECT count`))
+	spy.AssertArgs(t, regexp.MustCompile(`^DELETE FROM users`), "1")
+
+	var tb errorTB
+	spy.AssertQuery(&tb, regexp.MustCompile(`^UPDATE`))
+	spy.AssertArgs(&tb, regexp.MustCompile(`^DELETE FROM users`), "2")
+	if len(tb.errors) != 2 {
+		t.Fatalf("expected 2 errors, got %d instead: %v", len(tb.errors), tb.errors)
+	}
+	if !strings.Contains(tb.errors[1], "Exec DELETE FROM users WHERE id = $1 [1]") {
+		t.Errorf("expected error to list the calls, got %q instead", tb.errors[1])
+	}
+
+	spy.Reset()
+	if calls := spy.Calls(); len(calls) != 0 {
+		t.Errorf("expected no calls after Reset, got %+v instead", calls)
+	}
+}
+
+func TestSpyTx(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	db.On(`INSERT INTO users`, pgxfake.Response{Tag: "INSERT 0 1"})
+	spy := pgxspy.New(db)
+
+	tx, err := spy.Begin(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO users (name) VALUES ($1)", "Alice"); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	var methods []string
+	for _, c := range spy.Calls() {
+		methods = append(methods, c.Method)
+	}
+	if want := []string{"Begin", "Exec", "Commit"}; !reflect.DeepEqual(methods, want) {
+		t.Errorf("expected methods to be %v, got %v instead", want, methods)
+	}
+}
+
+func TestSpyMaxCalls(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	db.On(`.`, pgxfake.Response{Tag: "SELECT 1"})
+	spy := pgxspy.New(db)
+	spy.MaxCalls = 2
+	for i := 0; i < 5; i++ {
+		if _, err := spy.Exec(ctx, fmt.Sprintf("SELECT %d", i)); err != nil {
+			t.Errorf("expected no error, got %v instead", err)
+		}
+	}
+	var got []string
+	for _, c := range spy.Calls() {
+		got = append(got, c.SQL)
+	}
+	if want := []string{"SELECT 3", "SELECT 4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected calls to be %v, got %v instead", want, got)
+	}
+}
@@ -0,0 +1,241 @@
+// Package pgxtimeout implements the pgxs.PGX interface by delegating the calls to another implementation,
+// such as a *pgxpool.Pool, with a default timeout, protecting services from runaway queries, as in:
+//
+//	db := pgxtimeout.New(pool, pgxtimeout.Options{
+//		Timeout:          5 * time.Second,
+//		StatementTimeout: 5 * time.Second,
+//	})
+//	s := &Service{Postgres: db}
+//
+// The timeout of a call isn't applied if its context already has a sooner deadline.
+package pgxtimeout
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Options for the timeouts.
+type Options struct {
+	// Timeout of each call, including the calls made inside transactions.
+	// For Query, QueryRow, and SendBatch, it lasts until the rows are closed, the row is scanned, or the results are closed.
+	// If zero, no timeout is applied.
+	Timeout time.Duration
+
+	// StatementTimeout set with SET LOCAL statement_timeout at the start of each transaction,
+	// so that PostgreSQL cancels the statements taking longer even if the client stops responding.
+	// If zero, it isn't set.
+	StatementTimeout time.Duration
+}
+
+// DB applying the timeouts to the calls to another implementation of the PGX interface.
+type DB struct {
+	db pgxs.PGX
+	o  Options
+}
+
+// Validate if DB implements the PGX interface.
+var _ pgxs.PGX = (*DB)(nil)
+
+// New DB applying the timeouts to the calls to db.
+func New(db pgxs.PGX, o Options) *DB {
+	return &DB{db: db, o: o}
+}
+
+// withTimeout returns a copy of the context with the timeout, unless it has a sooner deadline.
+func (o Options) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= o.Timeout {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, o.Timeout)
+}
+
+// Begin a transaction, applying the timeout to the begin command and to the calls made inside the transaction.
+func (db *DB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return db.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// BeginTx starts a transaction, applying the timeout to the begin command and to the calls made inside the transaction.
+// If StatementTimeout is set, it's set for the transaction.
+func (db *DB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	tctx, cancel := db.o.withTimeout(ctx)
+	defer cancel()
+	tx, err := db.db.BeginTx(tctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+	if db.o.StatementTimeout > 0 {
+		sql := fmt.Sprintf("SET LOCAL statement_timeout = %d", db.o.StatementTimeout.Milliseconds())
+		if _, err := tx.Exec(tctx, sql); err != nil {
+			_ = tx.Rollback(ctx)
+			return nil, fmt.Errorf("cannot set statement_timeout: %w", err)
+		}
+	}
+	return &timeoutTx{Tx: tx, o: db.o}, nil
+}
+
+func (db *DB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return copyFrom(ctx, db.o, db.db, tableName, columnNames, rowSrc)
+}
+
+func (db *DB) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return exec(ctx, db.o, db.db, sql, arguments...)
+}
+
+// Query applies the timeout until the rows are closed, either explicitly or by reading all of them.
+func (db *DB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return query(ctx, db.o, db.db, sql, args...)
+}
+
+// QueryRow applies the timeout until the row is scanned.
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return queryRow(ctx, db.o, db.db, sql, args...)
+}
+
+// SendBatch applies the timeout until the results are closed.
+func (db *DB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return sendBatch(ctx, db.o, db.db, b)
+}
+
+// querier is the subset of PGX shared by DB and transactions.
+type querier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func copyFrom(ctx context.Context, o Options, q querier, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	return q.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func exec(ctx context.Context, o Options, q querier, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	return q.Exec(ctx, sql, arguments...)
+}
+
+func query(ctx context.Context, o Options, q querier, sql string, args ...any) (pgx.Rows, error) {
+	ctx, cancel := o.withTimeout(ctx)
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		cancel()
+		return rows, err
+	}
+	return &timeoutRows{Rows: rows, cancel: cancel}, nil
+}
+
+func queryRow(ctx context.Context, o Options, q querier, sql string, args ...any) pgx.Row {
+	ctx, cancel := o.withTimeout(ctx)
+	return &timeoutRow{row: q.QueryRow(ctx, sql, args...), cancel: cancel}
+}
+
+func sendBatch(ctx context.Context, o Options, q querier, b *pgx.Batch) pgx.BatchResults {
+	ctx, cancel := o.withTimeout(ctx)
+	return &timeoutBatchResults{BatchResults: q.SendBatch(ctx, b), cancel: cancel}
+}
+
+// timeoutTx applying the timeout to the calls made in a transaction.
+type timeoutTx struct {
+	pgx.Tx
+	o Options
+}
+
+func (t *timeoutTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	ctx, cancel := t.o.withTimeout(ctx)
+	defer cancel()
+	sp, err := t.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutTx{Tx: sp, o: t.o}, nil
+}
+
+func (t *timeoutTx) Commit(ctx context.Context) error {
+	ctx, cancel := t.o.withTimeout(ctx)
+	defer cancel()
+	return t.Tx.Commit(ctx)
+}
+
+func (t *timeoutTx) Rollback(ctx context.Context) error {
+	ctx, cancel := t.o.withTimeout(ctx)
+	defer cancel()
+	return t.Tx.Rollback(ctx)
+}
+
+func (t *timeoutTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return copyFrom(ctx, t.o, t.Tx, tableName, columnNames, rowSrc)
+}
+
+func (t *timeoutTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return sendBatch(ctx, t.o, t.Tx, b)
+}
+
+func (t *timeoutTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return exec(ctx, t.o, t.Tx, sql, arguments...)
+}
+
+func (t *timeoutTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return query(ctx, t.o, t.Tx, sql, args...)
+}
+
+func (t *timeoutTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return queryRow(ctx, t.o, t.Tx, sql, args...)
+}
+
+// timeoutRows releasing the timeout when the rows are closed.
+type timeoutRows struct {
+	pgx.Rows
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (r *timeoutRows) Close() {
+	r.Rows.Close()
+	r.once.Do(r.cancel)
+}
+
+func (r *timeoutRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	// Rows are closed automatically once they're read.
+	r.Close()
+	return false
+}
+
+// timeoutRow releasing the timeout when the row is scanned.
+type timeoutRow struct {
+	row    pgx.Row
+	cancel context.CancelFunc
+}
+
+func (r *timeoutRow) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.row.Scan(dest...)
+}
+
+// timeoutBatchResults releasing the timeout when the results are closed.
+type timeoutBatchResults struct {
+	pgx.BatchResults
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (b *timeoutBatchResults) Close() error {
+	err := b.BatchResults.Close()
+	b.once.Do(b.cancel)
+	return err
+}
@@ -0,0 +1,191 @@
+package pgxtimeout_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools/pgxfake"
+	"github.com/henvic/pgtools/pgxtimeout"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// recorder database saving the context of the last call.
+type recorder struct {
+	*pgxfake.DB
+	ctx context.Context
+}
+
+func (r *recorder) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	r.ctx = ctx
+	return r.DB.Exec(ctx, sql, arguments...)
+}
+
+func (r *recorder) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	r.ctx = ctx
+	return r.DB.Query(ctx, sql, args...)
+}
+
+func (r *recorder) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	r.ctx = ctx
+	return r.DB.QueryRow(ctx, sql, args...)
+}
+
+func newRecorder() *recorder {
+	db := pgxfake.New()
+	db.On(`SELECT`, pgxfake.Response{
+		Columns: []string{"id"},
+		Rows:    [][]any{{"1"}, {"2"}},
+		Tag:     "SELECT 2",
+	})
+	db.On(`.`, pgxfake.Response{Tag: "UPDATE 1"})
+	return &recorder{DB: db}
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc     string
+		timeout  time.Duration
+		deadline time.Duration
+		want     time.Duration
+	}{
+		{
+			desc:    "timeout",
+			timeout: time.Hour,
+			want:    time.Hour,
+		},
+		{
+			desc:     "later deadline",
+			timeout:  time.Hour,
+			deadline: 2 * time.Hour,
+			want:     time.Hour,
+		},
+		{
+			desc:     "sooner deadline",
+			timeout:  time.Hour,
+			deadline: time.Minute,
+			want:     time.Minute,
+		},
+		{
+			desc:     "no timeout",
+			deadline: time.Minute,
+			want:     time.Minute,
+		},
+		{
+			desc: "no timeout or deadline",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			if tc.deadline != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.deadline)
+				defer cancel()
+			}
+			rec := newRecorder()
+			db := pgxtimeout.New(rec, pgxtimeout.Options{Timeout: tc.timeout})
+			start := time.Now()
+			if _, err := db.Exec(ctx, "UPDATE users SET name = $1", "name"); err != nil {
+				t.Fatalf("expected no error, got %v instead", err)
+			}
+			deadline, ok := rec.ctx.Deadline()
+			if ok != (tc.want != 0) {
+				t.Fatalf("expected deadline set to be %v, got %v instead", tc.want != 0, ok)
+			}
+			// Allow for the time passed between creating the contexts.
+			if got := deadline.Sub(start); ok && (got < tc.want-time.Second || got > tc.want+time.Second) {
+				t.Errorf("expected deadline to be in %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTimeoutCanceled(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rec := newRecorder()
+	db := pgxtimeout.New(rec, pgxtimeout.Options{Timeout: time.Hour})
+
+	if _, err := db.Exec(ctx, "UPDATE users SET name = $1", "name"); err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	if err := rec.ctx.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected Exec context to be canceled once it returns, got %v instead", err)
+	}
+
+	rows, err := db.Query(ctx, "SELECT id FROM users")
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	if err := rec.ctx.Err(); err != nil {
+		t.Errorf("expected Query context to remain active while reading rows, got %v instead", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil || len(ids) != 2 {
+		t.Errorf("expected 2 rows, got %v, %v instead", ids, err)
+	}
+	if err := rec.ctx.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected Query context to be canceled once the rows are closed, got %v instead", err)
+	}
+
+	var id string
+	row := db.QueryRow(ctx, "SELECT id FROM users")
+	if err := rec.ctx.Err(); err != nil {
+		t.Errorf("expected QueryRow context to remain active until scanning, got %v instead", err)
+	}
+	if err := row.Scan(&id); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if err := rec.ctx.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected QueryRow context to be canceled once scanned, got %v instead", err)
+	}
+}
+
+func TestStatementTimeout(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rec := newRecorder()
+	db := pgxtimeout.New(rec, pgxtimeout.Options{
+		Timeout:          time.Hour,
+		StatementTimeout: 1500 * time.Millisecond,
+	})
+	err := pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE users SET name = $1", "name")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	calls := rec.Calls()
+	if len(calls) != 4 {
+		t.Fatalf("expected 4 calls, got %+v instead", calls)
+	}
+	if want := "SET LOCAL statement_timeout = 1500"; calls[1].Method != "Exec" || calls[1].SQL != want {
+		t.Errorf("expected statement_timeout to be set with %q, got %+v instead", want, calls[1])
+	}
+	if calls[3].Method != "Commit" {
+		t.Errorf("expected transaction to be committed, got %+v instead", calls[3])
+	}
+}
+
+func TestStatementTimeoutError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fake := pgxfake.New()
+	errSet := errors.New("cannot set parameter")
+	fake.On(`statement_timeout`, pgxfake.Response{Err: errSet})
+	db := pgxtimeout.New(fake, pgxtimeout.Options{StatementTimeout: time.Second})
+	if _, err := db.Begin(ctx); !errors.Is(err, errSet) {
+		t.Errorf("expected error to be %v, got %v instead", errSet, err)
+	}
+	calls := fake.Calls()
+	if len(calls) != 3 || calls[2].Method != "Rollback" {
+		t.Errorf("expected transaction to be rolled back, got %+v instead", calls)
+	}
+}
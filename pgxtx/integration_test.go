@@ -0,0 +1,70 @@
+package pgxtx_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/henvic/pgtools/pgxtx"
+	"github.com/henvic/pgtools/sqltest"
+	"github.com/jackc/pgx/v5"
+)
+
+var force = flag.Bool("force", false, "Force cleaning the database before starting")
+
+func checkPostgres(t testing.TB) {
+	if os.Getenv("INTEGRATION_TESTDB") != "true" {
+		t.Skip("Skipping tests that require database connection")
+	}
+}
+
+func TestWithTxSavepoint(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("../sqltest/example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_pgxtx_",
+	})
+	pool := migration.Setup(ctx, "")
+
+	insert := func(ctx context.Context, id string) error {
+		_, err := pgxtx.From(ctx, pool).Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ($1, 'name', 'photo', 'url')", id)
+		return err
+	}
+	errRollback := errors.New("rollback")
+	err := pgxtx.WithTx(ctx, pool, func(ctx context.Context, tx pgx.Tx) error {
+		if err := insert(ctx, "kept"); err != nil {
+			return err
+		}
+		err := pgxtx.WithTx(ctx, pool, func(ctx context.Context, tx pgx.Tx) error {
+			if err := insert(ctx, "rolled_back"); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Errorf("expected error to be %v, got %v instead", errRollback, err)
+		}
+		// The transaction remains usable after the savepoint is rolled back.
+		return insert(ctx, "after")
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+
+	rows, err := pool.Query(ctx, "SELECT id FROM media ORDER BY id")
+	if err != nil {
+		t.Fatalf("cannot query media: %v", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("cannot read media: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "after" || ids[1] != "kept" {
+		t.Errorf("expected media to be [after kept], got %v instead", ids)
+	}
+}
@@ -0,0 +1,88 @@
+// Package pgxtx runs functions in transactions that nest, so that composable service methods can each declare
+// their transactional boundaries without knowing whether a transaction is already open, as in:
+//
+//	func (s *Service) CreateUser(ctx context.Context, u User) error {
+//		return pgxtx.WithTx(ctx, s.Postgres, func(ctx context.Context, tx pgx.Tx) error {
+//			if _, err := tx.Exec(ctx, "INSERT INTO users (id, name) VALUES ($1, $2)", u.ID, u.Name); err != nil {
+//				return err
+//			}
+//			return s.AddToGroup(ctx, u.ID, "everyone") // Calls WithTx too.
+//		})
+//	}
+//
+// The transaction is carried by the context passed to the function, and a WithTx call with that context
+// runs in a savepoint of it instead of starting a new transaction. If the function of a nested call fails,
+// only the changes made since its savepoint are rolled back.
+package pgxtx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/henvic/pgtools/pgxs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// txKey is the key of the transaction on the database db in the context.
+// As context keys are compared with ==, db must be comparable, as pointers are.
+type txKey struct {
+	db pgxs.PGX
+}
+
+// isComparable reports whether db can be used in a txKey without making context lookups panic,
+// which isn't the case of a struct value with a slice or a map, for example.
+func isComparable(db pgxs.PGX) bool {
+	return reflect.ValueOf(db).Comparable()
+}
+
+// WithTx calls fn with a transaction on db, committing it if fn succeeds, and rolling it back otherwise.
+// If the context carries a transaction on db started by WithTx, fn runs in a savepoint of it instead.
+//
+// The transaction is carried by the context keyed on db, so db must be comparable, such as a pointer.
+// Otherwise, an error is returned without calling fn.
+func WithTx(ctx context.Context, db pgxs.PGX, fn func(ctx context.Context, tx pgx.Tx) error) error {
+	return WithTxOptions(ctx, db, pgx.TxOptions{}, fn)
+}
+
+// WithTxOptions is like WithTx, but starts the transaction with txOptions.
+// The options are ignored for savepoints, as they only apply to the outermost transaction.
+func WithTxOptions(ctx context.Context, db pgxs.PGX, txOptions pgx.TxOptions, fn func(ctx context.Context, tx pgx.Tx) error) error {
+	if !isComparable(db) {
+		return fmt.Errorf("cannot carry transaction on %T in the context: use a comparable type, such as a pointer", db)
+	}
+	key := txKey{db}
+	run := func(tx pgx.Tx) error {
+		return fn(context.WithValue(ctx, key, tx), tx)
+	}
+	if tx, ok := ctx.Value(key).(pgx.Tx); ok {
+		return pgx.BeginFunc(ctx, tx, run)
+	}
+	return pgx.BeginTxFunc(ctx, db, txOptions, run)
+}
+
+// Querier is the subset of the PGX interface implemented by both databases and transactions.
+type Querier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// Validate if transactions implement the Querier interface.
+var _ Querier = (pgx.Tx)(nil)
+
+// From returns the transaction on db carried by the context, or db if there's none,
+// so that methods can run their statements in the transaction of their caller, if any.
+// If db isn't comparable, WithTx cannot carry a transaction on it, and db is returned.
+func From(ctx context.Context, db pgxs.PGX) Querier {
+	if !isComparable(db) {
+		return db
+	}
+	if tx, ok := ctx.Value(txKey{db}).(pgx.Tx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,112 @@
+package pgxtx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/henvic/pgtools/pgxfake"
+	"github.com/henvic/pgtools/pgxtx"
+	"github.com/jackc/pgx/v5"
+)
+
+// methods called on the fake database.
+func methods(db *pgxfake.DB) string {
+	var m []string
+	for _, c := range db.Calls() {
+		m = append(m, c.Method)
+	}
+	return fmt.Sprint(m)
+}
+
+func TestWithTx(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := pgxfake.New()
+	db.On(`INSERT`, pgxfake.Response{Tag: "INSERT 0 1"})
+
+	errNested := errors.New("nested failure")
+	err := pgxtx.WithTx(ctx, db, func(ctx context.Context, outer pgx.Tx) error {
+		if q := pgxtx.From(ctx, db); q != outer {
+			t.Errorf("expected From to return the transaction, got %v instead", q)
+		}
+		if _, err := outer.Exec(ctx, "INSERT INTO users (id) VALUES (1)"); err != nil {
+			return err
+		}
+		err := pgxtx.WithTx(ctx, db, func(ctx context.Context, inner pgx.Tx) error {
+			if inner == outer {
+				t.Error("expected nested call to run in a savepoint")
+			}
+			if q := pgxtx.From(ctx, db); q != inner {
+				t.Errorf("expected From to return the savepoint, got %v instead", q)
+			}
+			return errNested
+		})
+		if !errors.Is(err, errNested) {
+			t.Errorf("expected error to be %v, got %v instead", errNested, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if want, got := "[Begin Exec Begin Rollback Commit]", methods(db); got != want {
+		t.Errorf("expected calls to be %v, got %v instead", want, got)
+	}
+	if q := pgxtx.From(ctx, db); q != db {
+		t.Errorf("expected From to return the database without a transaction, got %v instead", q)
+	}
+}
+
+func TestWithTxDatabases(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db1, db2 := pgxfake.New(), pgxfake.New()
+	err := pgxtx.WithTx(ctx, db1, func(ctx context.Context, tx1 pgx.Tx) error {
+		return pgxtx.WithTx(ctx, db2, func(ctx context.Context, tx2 pgx.Tx) error {
+			if pgxtx.From(ctx, db1) != tx1 || pgxtx.From(ctx, db2) != tx2 {
+				t.Error("expected each database to have its own transaction")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	for _, db := range []*pgxfake.DB{db1, db2} {
+		if want, got := "[Begin Commit]", methods(db); got != want {
+			t.Errorf("expected calls to be %v, got %v instead", want, got)
+		}
+	}
+}
+
+// sliceDB is a database that isn't comparable, as it has a slice.
+type sliceDB struct {
+	*pgxfake.DB
+	tags []string
+}
+
+func TestWithTxNotComparable(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fake := pgxfake.New()
+	db := sliceDB{DB: fake, tags: []string{"primary"}}
+	err := pgxtx.WithTx(ctx, db, func(ctx context.Context, tx pgx.Tx) error {
+		t.Error("expected fn not to be called")
+		return nil
+	})
+	if err == nil {
+		t.Error("expected error, got nil instead")
+	}
+	// Looking up a database that isn't comparable in a context carrying a transaction must not panic.
+	err = pgxtx.WithTx(ctx, fake, func(ctx context.Context, tx pgx.Tx) error {
+		if q := pgxtx.From(ctx, db); q.(sliceDB).DB != fake {
+			t.Errorf("expected From to return the database, got %v instead", q)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+}
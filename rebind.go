@@ -0,0 +1,169 @@
+package pgtools
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rebind concatenates query fragments, separated by a space, renumbering the positional
+// parameters of each fragment so they follow the ones of the previous fragments.
+// This way, fragments built independently, each one starting at $1, can be composed, as in:
+//
+//	where, args := pgtools.Where(filter)
+//	sql, err := pgtools.Rebind("SELECT "+pgtools.Wildcard(Post{})+" FROM posts", where, "LIMIT $1")
+//	// ...
+//	rows, err := db.Query(ctx, sql, append(args, limit)...)
+//
+// A parameter used more than once in the same fragment keeps referencing the same argument,
+// and the arguments of a fragment follow the ones of the previous fragments up to its highest
+// parameter. Parameters inside string constants, quoted identifiers, and comments aren't changed.
+//
+// Empty fragments are skipped.
+func Rebind(fragments ...string) (string, error) {
+	var (
+		b      strings.Builder
+		offset int
+	)
+	for i, f := range fragments {
+		if strings.TrimSpace(f) == "" {
+			continue
+		}
+		if b.Len() != 0 {
+			b.WriteString(" ")
+		}
+		n, err := rebind(&b, f, offset)
+		if err != nil {
+			return "", fmt.Errorf("cannot rebind fragment %d: %w", i, err)
+		}
+		offset += n
+	}
+	return b.String(), nil
+}
+
+var (
+	errUnterminatedString     = errors.New("unterminated quoted string")
+	errUnterminatedIdentifier = errors.New("unterminated quoted identifier")
+	errUnterminatedComment    = errors.New("unterminated comment")
+)
+
+// rebind writes the fragment s adding offset to its positional parameters,
+// and returns the highest parameter of the fragment.
+func rebind(b *strings.Builder, s string, offset int) (max int, err error) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		var end int
+		switch {
+		case c == '\'':
+			escapes := i > 0 && (s[i-1] == 'E' || s[i-1] == 'e') && (i == 1 || !isIdentifierByte(s[i-2]))
+			if end = quoteEnd(s, i, '\'', escapes); end == -1 {
+				return 0, errUnterminatedString
+			}
+		case c == '"':
+			if end = quoteEnd(s, i, '"', false); end == -1 {
+				return 0, errUnterminatedIdentifier
+			}
+		case c == '-' && strings.HasPrefix(s[i:], "--"):
+			if end = strings.IndexByte(s[i:], '\n'); end == -1 {
+				end = len(s)
+			} else {
+				end += i + 1
+			}
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			if end = commentEnd(s, i); end == -1 {
+				return 0, errUnterminatedComment
+			}
+		case c == '$' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9' && (i == 0 || !isIdentifierByte(s[i-1])):
+			end = i + 1
+			for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+				end++
+			}
+			n, err := strconv.Atoi(s[i+1 : end])
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("invalid parameter %s", s[i:end])
+			}
+			if n > max {
+				max = n
+			}
+			b.WriteString("$")
+			b.WriteString(strconv.Itoa(n + offset))
+			i = end
+			continue
+		case c == '$' && (i == 0 || !isIdentifierByte(s[i-1])):
+			tag, ok := dollarQuoteTag(s[i:])
+			if !ok {
+				end = i + 1
+				break
+			}
+			closing := strings.Index(s[i+len(tag):], tag)
+			if closing == -1 {
+				return 0, fmt.Errorf("unterminated dollar-quoted string %s", tag)
+			}
+			end = i + len(tag) + closing + len(tag)
+		default:
+			end = i + 1
+		}
+		b.WriteString(s[i:end])
+		i = end
+	}
+	return max, nil
+}
+
+// quoteEnd returns the index after the quote closing the one at s[start], or -1 if it isn't closed.
+// A doubled quote is part of the quoted text. If escapes is set, a backslash escapes the next character.
+func quoteEnd(s string, start int, quote byte, escapes bool) int {
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case escapes && s[i] == '\\':
+			i++
+		case s[i] == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// commentEnd returns the index after the end of the block comment starting at s[start],
+// or -1 if it isn't closed. Block comments can be nested.
+func commentEnd(s string, start int) int {
+	depth := 0
+	for i := start; i+1 < len(s); i++ {
+		switch {
+		case s[i] == '/' && s[i+1] == '*':
+			depth++
+			i++
+		case s[i] == '*' && s[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// dollarQuoteTag returns the tag of a dollar-quoted string constant at the start of s, such as $$ or $body$.
+func dollarQuoteTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1], true
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= 0x80:
+		case c >= '0' && c <= '9' && i > 1:
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// isIdentifierByte reports whether c can be part of an unquoted identifier or keyword.
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
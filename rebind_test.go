@@ -0,0 +1,143 @@
+package pgtools_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/henvic/pgtools"
+)
+
+func ExampleRebind() {
+	where, args := pgtools.Where(PostFilter{Author: "henvic", Categories: []string{"go"}})
+	sql, err := pgtools.Rebind("SELECT "+pgtools.Wildcard(Post{})+" FROM posts", where, "LIMIT $1 OFFSET $2")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(sql)
+	fmt.Println(len(append(args, 10, 20)))
+	// Output:
+	// SELECT "id","title","message" FROM posts WHERE "author" = $1 AND "category" = ANY($2) LIMIT $3 OFFSET $4
+	// 4
+}
+
+func TestRebind(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc      string
+		fragments []string
+		want      string
+		wantErr   string
+	}{
+		{
+			desc: "none",
+			want: "",
+		},
+		{
+			desc:      "single",
+			fragments: []string{"SELECT $1, $2"},
+			want:      "SELECT $1, $2",
+		},
+		{
+			desc:      "empty fragments",
+			fragments: []string{"", "SELECT 1", " ", "WHERE a = $1"},
+			want:      "SELECT 1 WHERE a = $1",
+		},
+		{
+			desc:      "renumber",
+			fragments: []string{"WHERE a = $1 AND b = $2", "AND c = $1", "LIMIT $1 OFFSET $2"},
+			want:      "WHERE a = $1 AND b = $2 AND c = $3 LIMIT $4 OFFSET $5",
+		},
+		{
+			desc:      "repeated",
+			fragments: []string{"WHERE a = $1", "AND (b = $1 OR c = $1) AND d = $2"},
+			want:      "WHERE a = $1 AND (b = $2 OR c = $2) AND d = $3",
+		},
+		{
+			desc:      "gap",
+			fragments: []string{"WHERE a = $2", "AND b = $1"},
+			want:      "WHERE a = $2 AND b = $3",
+		},
+		{
+			desc:      "no parameters",
+			fragments: []string{"SELECT 1", "WHERE a = $1"},
+			want:      "SELECT 1 WHERE a = $1",
+		},
+		{
+			desc:      "cast",
+			fragments: []string{"WHERE a = $1", "AND b = $1::int"},
+			want:      "WHERE a = $1 AND b = $2::int",
+		},
+		{
+			desc:      "string",
+			fragments: []string{"WHERE a = $1", "AND b = 'it''s $1' AND c = $1"},
+			want:      "WHERE a = $1 AND b = 'it''s $1' AND c = $2",
+		},
+		{
+			desc:      "escape string",
+			fragments: []string{"WHERE a = $1", `AND b = E'\'$1' AND c = $1`},
+			want:      `WHERE a = $1 AND b = E'\'$1' AND c = $2`,
+		},
+		{
+			desc:      "quoted identifier",
+			fragments: []string{"WHERE a = $1", `AND "b$1""" = $1`},
+			want:      `WHERE a = $1 AND "b$1""" = $2`,
+		},
+		{
+			desc:      "identifier",
+			fragments: []string{"WHERE a = $1", "AND b$1 = $1"},
+			want:      "WHERE a = $1 AND b$1 = $2",
+		},
+		{
+			desc:      "comments",
+			fragments: []string{"WHERE a = $1 -- $1\n", "/* $1 /* $2 */ */ AND b = $1"},
+			want:      "WHERE a = $1 -- $1\n /* $1 /* $2 */ */ AND b = $2",
+		},
+		{
+			desc:      "dollar-quoted string",
+			fragments: []string{"WHERE a = $1", "AND b = $$ $1 $$ AND c = $tag$ $1 $$ $tag$ AND d = $1"},
+			want:      "WHERE a = $1 AND b = $$ $1 $$ AND c = $tag$ $1 $$ $tag$ AND d = $2",
+		},
+		{
+			desc:      "unterminated string",
+			fragments: []string{"SELECT 1", "WHERE a = 'b"},
+			wantErr:   "cannot rebind fragment 1: unterminated quoted string",
+		},
+		{
+			desc:      "unterminated quoted identifier",
+			fragments: []string{`WHERE "a = $1`},
+			wantErr:   "cannot rebind fragment 0: unterminated quoted identifier",
+		},
+		{
+			desc:      "unterminated comment",
+			fragments: []string{"WHERE a = $1 /* /* */"},
+			wantErr:   "cannot rebind fragment 0: unterminated comment",
+		},
+		{
+			desc:      "unterminated dollar-quoted string",
+			fragments: []string{"WHERE a = $x$ $1"},
+			wantErr:   "cannot rebind fragment 0: unterminated dollar-quoted string $x$",
+		},
+		{
+			desc:      "invalid parameter",
+			fragments: []string{"WHERE a = $0"},
+			wantErr:   "cannot rebind fragment 0: invalid parameter $0",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := pgtools.Rebind(tc.fragments...)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("expected error %q, got %v instead", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.want != got {
+				t.Errorf("expected statement to be %q, got %q instead", tc.want, got)
+			}
+		})
+	}
+}
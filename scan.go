@@ -0,0 +1,101 @@
+package pgtools
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CollectRows iterates through rows, scanning each one into a value of type T,
+// and returns the collected values. It's a shortcut for using pgx.CollectRows with RowToStruct.
+func CollectRows[T any](rows pgx.Rows) ([]T, error) {
+	return pgx.CollectRows(rows, RowToStruct[T])
+}
+
+// CollectOneRow scans the first row of rows into a value of type T, and closes it.
+// If no rows are found, it returns an error where errors.Is(pgx.ErrNoRows) is true.
+// It's a shortcut for using pgx.CollectOneRow with RowToStruct.
+func CollectOneRow[T any](rows pgx.Rows) (T, error) {
+	return pgx.CollectOneRow(rows, RowToStruct[T])
+}
+
+// RowToStruct is a pgx.RowToFunc that scans a row into a value of type T,
+// which must be a struct or a pointer to a struct.
+//
+// Columns are mapped to the struct fields using the same rules as Fields,
+// so it's symmetric with queries written using Wildcard:
+// fields with the "json" option are unmarshaled from JSON, and columns of
+// nested structs are matched by their dot-aliased names.
+// Pointers to nested structs are allocated as needed.
+// Other fields, such as the slices of array columns, are scanned directly by pgx.
+//
+// Every column in the row must be mapped to a field.
+func RowToStruct[T any](row pgx.CollectableRow) (T, error) {
+	var value T
+	rv := reflect.ValueOf(&value).Elem()
+	if rv.Kind() == reflect.Ptr {
+		rv.Set(reflect.New(rv.Type().Elem()))
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return value, fmt.Errorf("cannot scan into %v: not a struct", rv.Type())
+	}
+	m := getTypeMapping(rv.Type())
+
+	fds := row.FieldDescriptions()
+	dest := make([]any, len(fds))
+	type jsonField struct {
+		src  *[]byte
+		dest reflect.Value
+	}
+	var jsonFields []jsonField
+	for i, fd := range fds {
+		c, ok := m.byName[fd.Name]
+		if !ok {
+			return value, fmt.Errorf("cannot find field for column %q in %v", fd.Name, rv.Type())
+		}
+		f, err := fieldByIndexAlloc(rv, c.Index)
+		if err != nil {
+			return value, fmt.Errorf("cannot scan column %q: %w", fd.Name, err)
+		}
+		if !c.HasOption("json") {
+			dest[i] = f.Addr().Interface()
+			continue
+		}
+		var b []byte
+		dest[i] = &b
+		jsonFields = append(jsonFields, jsonField{src: &b, dest: f})
+	}
+	if err := row.Scan(dest...); err != nil {
+		return value, err
+	}
+	for _, jf := range jsonFields {
+		if *jf.src == nil {
+			continue // NULL
+		}
+		if err := json.Unmarshal(*jf.src, jf.dest.Addr().Interface()); err != nil {
+			return value, fmt.Errorf("cannot unmarshal JSON into %v: %w", jf.dest.Type(), err)
+		}
+	}
+	return value, nil
+}
+
+// fieldByIndexAlloc is like reflect.Value.FieldByIndex, but allocates
+// nil pointers to structs found on the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot allocate pointer to unexported struct %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
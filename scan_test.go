@@ -0,0 +1,215 @@
+package pgtools_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/henvic/pgtools"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeRows implements pgx.Rows returning rows from memory.
+// Values are assigned to the scan destinations as is.
+type fakeRows struct {
+	columns []string
+	rows    [][]any
+
+	current int
+	closed  bool
+	err     error
+}
+
+func (r *fakeRows) Close() {
+	r.closed = true
+}
+
+func (r *fakeRows) Err() error {
+	return r.err
+}
+
+func (r *fakeRows) CommandTag() pgconn.CommandTag {
+	return pgconn.CommandTag{}
+}
+
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription {
+	fds := make([]pgconn.FieldDescription, 0, len(r.columns))
+	for _, c := range r.columns {
+		fds = append(fds, pgconn.FieldDescription{Name: c})
+	}
+	return fds
+}
+
+func (r *fakeRows) Next() bool {
+	if r.closed || r.current >= len(r.rows) {
+		r.Close()
+		return false
+	}
+	r.current++
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	row := r.rows[r.current-1]
+	if len(dest) != len(row) {
+		return fmt.Errorf("number of field descriptions must equal number of destinations, got %d and %d", len(row), len(dest))
+	}
+	for i, d := range dest {
+		if row[i] == nil {
+			continue
+		}
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(row[i]))
+	}
+	return nil
+}
+
+func (r *fakeRows) Values() ([]any, error) {
+	return r.rows[r.current-1], nil
+}
+
+func (r *fakeRows) RawValues() [][]byte {
+	return nil
+}
+
+func (r *fakeRows) Conn() *pgx.Conn {
+	return nil
+}
+
+type scanNested struct {
+	ID    string
+	Theme *Theme
+}
+
+func TestCollectRows(t *testing.T) {
+	t.Parallel()
+	rows := &fakeRows{
+		columns: []string{"username", "email", "id", "theme"},
+		rows: [][]any{
+			{"henvic", "henvic@example.com", "hv", []byte(`{"PrimaryColor":"blue"}`)},
+			{"other", "other@example.com", "o", nil},
+		},
+	}
+	got, err := pgtools.CollectRows[User](rows)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []User{
+		{
+			Username: "henvic",
+			Email:    "henvic@example.com",
+			Alias:    "hv",
+			Theme:    Theme{PrimaryColor: "blue"},
+		},
+		{
+			Username: "other",
+			Email:    "other@example.com",
+			Alias:    "o",
+		},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected %+v, got %+v instead", want, got)
+	}
+}
+
+func TestCollectOneRow(t *testing.T) {
+	t.Parallel()
+	rows := &fakeRows{
+		columns: []string{"id", "theme.primary_color", "theme.text_uppercase"},
+		rows: [][]any{
+			{"x", "red", true},
+		},
+	}
+	got, err := pgtools.CollectOneRow[*scanNested](rows)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &scanNested{
+		ID: "x",
+		Theme: &Theme{
+			PrimaryColor:  "red",
+			TextUppercase: true,
+		},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected %+v, got %+v instead", want, got)
+	}
+}
+
+func TestCollectOneRowNoRows(t *testing.T) {
+	t.Parallel()
+	rows := &fakeRows{
+		columns: []string{"number"},
+	}
+	if _, err := pgtools.CollectOneRow[numericMock](rows); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected error to be pgx.ErrNoRows, got %v instead", err)
+	}
+}
+
+func TestRowToStructErrors(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc string
+		f    func(rows pgx.Rows) error
+		rows *fakeRows
+		want string
+	}{
+		{
+			desc: "not a struct",
+			f: func(rows pgx.Rows) error {
+				_, err := pgtools.CollectRows[int](rows)
+				return err
+			},
+			rows: &fakeRows{
+				columns: []string{"number"},
+				rows:    [][]any{{1}},
+			},
+			want: "cannot scan into int: not a struct",
+		},
+		{
+			desc: "unknown column",
+			f: func(rows pgx.Rows) error {
+				_, err := pgtools.CollectRows[numericMock](rows)
+				return err
+			},
+			rows: &fakeRows{
+				columns: []string{"number", "unknown"},
+				rows:    [][]any{{1, "x"}},
+			},
+			want: `cannot find field for column "unknown" in pgtools_test.numericMock`,
+		},
+		{
+			desc: "invalid JSON",
+			f: func(rows pgx.Rows) error {
+				_, err := pgtools.CollectRows[User](rows)
+				return err
+			},
+			rows: &fakeRows{
+				columns: []string{"theme"},
+				rows:    [][]any{{[]byte(`{`)}},
+			},
+			want: "cannot unmarshal JSON into pgtools_test.Theme: unexpected end of JSON input",
+		},
+		{
+			desc: "unexported embedded pointer",
+			f: func(rows pgx.Rows) error {
+				_, err := pgtools.CollectRows[pointerEmbedMock](rows)
+				return err
+			},
+			rows: &fakeRows{
+				columns: []string{"id", "number"},
+				rows:    [][]any{{"x", 1}},
+			},
+			want: `cannot scan column "number": cannot allocate pointer to unexported struct pgtools_test.numericMock`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := tc.f(tc.rows); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected error to contain %q, got %v instead", tc.want, err)
+			}
+		})
+	}
+}
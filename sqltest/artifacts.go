@@ -0,0 +1,103 @@
+package sqltest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// dumpOnFailure writes a dump of the database of a failed test to a directory named after the test
+// in the DumpOnFailure directory, and logs its path.
+//
+// pg_dump is used if it's available, with a fallback to a dump of the schema and COPY of each table
+// to a CSV file, as when pg_dump isn't installed or its version doesn't match the server's.
+func (m *Migration) dumpOnFailure(ctx context.Context) {
+	m.t.Helper()
+	if m.Options.DumpOnFailure == "" || !m.t.Failed() || m.pool == nil {
+		return
+	}
+	dir := filepath.Join(m.Options.DumpOnFailure, SQLTestName(m.t))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		m.t.Errorf("cannot dump database: %v", err)
+		return
+	}
+	if err := m.pgDump(ctx, filepath.Join(dir, "dump.sql")); err == nil {
+		m.t.Logf("database dumped to %s", dir)
+		return
+	} else if !errors.Is(err, exec.ErrNotFound) {
+		m.t.Logf("cannot dump database with pg_dump, using COPY instead: %v", err)
+	}
+	if err := m.copyDump(ctx, dir); err != nil {
+		m.t.Errorf("cannot dump database: %v", err)
+		return
+	}
+	m.t.Logf("database dumped to %s", dir)
+}
+
+// pgDump the database to the file, returning exec.ErrNotFound if pg_dump isn't installed.
+func (m *Migration) pgDump(ctx context.Context, file string) error {
+	bin, err := exec.LookPath("pg_dump")
+	if err != nil {
+		return exec.ErrNotFound
+	}
+	args := []string{"--dbname=" + m.ConnString(), "--file=" + file, "--no-owner"}
+	if m.schema != "" {
+		args = append(args, "--schema="+m.schema)
+	}
+	if out, err := exec.CommandContext(ctx, bin, args...).CombinedOutput(); err != nil { // #nosec G204
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// copyDump writes the schema of the database to schema.sql, and the rows of each table to a CSV file named
+// after the table in the directory.
+func (m *Migration) copyDump(ctx context.Context, dir string) error {
+	schema, err := dumpSchema(ctx, m.pool, m.schemaVersionTable(), m.schema != "")
+	if err != nil {
+		return fmt.Errorf("cannot dump schema: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "schema.sql"), []byte(schema), 0o644); err != nil { // #nosec G306
+		return err
+	}
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	rows, err := conn.Query(ctx, `SELECT c.oid::regclass::text FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND NOT c.relispartition AND `+userObject("c.oid", m.schema != "")+`
+		ORDER BY 1`)
+	if err != nil {
+		return fmt.Errorf("cannot query tables: %w", err)
+	}
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("cannot query tables: %w", err)
+	}
+	for _, table := range tables {
+		if err := copyTable(ctx, conn.Conn(), table, filepath.Join(dir, strings.ReplaceAll(table, `"`, "")+".csv")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyTable rows to a CSV file.
+func copyTable(ctx context.Context, conn *pgx.Conn, table, file string) error {
+	f, err := os.Create(file) // #nosec G304
+	if err != nil {
+		return err
+	}
+	if _, err := conn.PgConn().CopyTo(ctx, f, "COPY "+table+" TO STDOUT WITH (FORMAT csv, HEADER)"); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot copy %s: %w", table, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,177 @@
+package sqltest
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/henvic/pgtools/introspect"
+)
+
+// AssertRows checks that the query returns the wanted rows, in order, with the values of their columns.
+// If they differ, t.Error is called with a diff. If the query fails, t.Fatal is called.
+//
+// Values are compared by their text representation, so that an int can be used to match an integer column
+// regardless of its size, and a string can be used to match types such as uuid and numeric.
+// Use nil for NULL.
+func AssertRows(t TB, db introspect.Querier, sql string, want [][]any, args ...any) {
+	t.Helper()
+	rows, err := db.Query(context.Background(), sql, args...)
+	if err != nil {
+		t.Fatalf("cannot query rows: %v", err)
+	}
+	defer rows.Close()
+	var got [][]any
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			t.Fatalf("cannot read row: %v", err)
+		}
+		got = append(got, values)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("cannot query rows: %v", err)
+	}
+	if w, g := formatRows(want), formatRows(got); w != g {
+		t.Errorf("rows mismatch (-want +got):\n%s", diff(w, g))
+	}
+}
+
+// AssertCount checks that the table has n rows.
+// If it doesn't, t.Error is called. If the query fails, t.Fatal is called.
+func AssertCount(t TB, db introspect.Querier, table string, n int) {
+	t.Helper()
+	var got int
+	rows, err := db.Query(context.Background(), "SELECT count(*) FROM "+quoteTable(table))
+	if err == nil {
+		for rows.Next() {
+			err = rows.Scan(&got)
+		}
+		rows.Close()
+		if err == nil {
+			err = rows.Err()
+		}
+	}
+	if err != nil {
+		t.Fatalf("cannot count rows of %s: %v", table, err)
+	}
+	if got != n {
+		t.Errorf("expected %s to have %d rows, got %d instead", table, n, got)
+	}
+}
+
+// AssertStructSchema checks that the columns mapped from the fields of a given Go struct match the columns of the table,
+// as in:
+//
+//	sqltest.AssertStructSchema(t, pool, &User{}, "users")
+//
+// Unlike Migration.ValidateSchema, it also reports columns of the table that aren't mapped from any field,
+// and mismatches between the nullability of the columns and the types of the fields.
+// If they don't match, t.Error is called with every problem found, and how to fix it.
+//
+// See introspect.Validate and introspect.Strict for details.
+func AssertStructSchema(t TB, db introspect.Querier, v any, table string) {
+	t.Helper()
+	assertStructSchema(context.Background(), t, db, v, table, introspect.Strict())
+}
+
+// assertStructSchema calls t.Error if the Go struct doesn't match the table.
+func assertStructSchema(ctx context.Context, t TB, db introspect.Querier, v any, table string, opts ...introspect.Option) {
+	t.Helper()
+	if err := introspect.Validate(ctx, db, v, table, opts...); err != nil {
+		t.Error(err)
+	}
+}
+
+// AssertGoldenQuery compares the results of the query with the contents of the golden file.
+// If they differ, t.Error is called with a diff. If something fails, t.Fatal is called.
+// Use the UpdateGolden option to write the golden file instead, as with AssertSchema.
+//
+// The results are written with a header with the names of the columns followed by a line per row,
+// with the values of the columns separated by " | " in a canonical format: timestamps in UTC, and
+// json and jsonb values with sorted keys. Rows are sorted, so that the results don't depend on the
+// query plan. Use AssertRows to check the order of the rows.
+func (m *Migration) AssertGoldenQuery(ctx context.Context, golden, sql string, args ...any) {
+	m.t.Helper()
+	rows, err := m.querier().Query(ctx, sql, args...)
+	if err != nil {
+		m.t.Fatalf("cannot query rows: %v", err)
+	}
+	defer rows.Close()
+	var lines []string
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			m.t.Fatalf("cannot read row: %v", err)
+		}
+		lines = append(lines, formatRows([][]any{values}))
+	}
+	if err := rows.Err(); err != nil {
+		m.t.Fatalf("cannot query rows: %v", err)
+	}
+	sort.Strings(lines)
+
+	var sb strings.Builder
+	for n, f := range rows.FieldDescriptions() {
+		if n > 0 {
+			sb.WriteString(" | ")
+		}
+		sb.WriteString(f.Name)
+	}
+	sb.WriteString("\n")
+	for _, l := range lines {
+		sb.WriteString(l)
+	}
+	fmt.Fprintf(&sb, "(%d rows)\n", len(lines))
+	m.golden(golden, []byte(sb.String()))
+}
+
+// formatRows with a line per row, and the values of its columns separated by " | ".
+func formatRows(rows [][]any) string {
+	var sb strings.Builder
+	for _, row := range rows {
+		for n, v := range row {
+			if n > 0 {
+				sb.WriteString(" | ")
+			}
+			sb.WriteString(formatValue(v))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// formatValue to its text representation.
+func formatValue(v any) string {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if dv, err := valuer.Value(); err == nil {
+			v = dv
+		}
+	}
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case [16]byte:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16])
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case map[string]any, []any:
+		// Keys of JSON objects are sorted by encoding/json.
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+		return fmt.Sprint(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
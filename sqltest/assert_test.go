@@ -0,0 +1,49 @@
+package sqltest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestFormatRows(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc string
+		rows [][]any
+		want string
+	}{
+		{
+			desc: "empty",
+		},
+		{
+			desc: "values",
+			rows: [][]any{
+				{1, "name", nil, true},
+				{int32(2), []byte("bytes"), 1.5, false},
+			},
+			want: "1 | name | NULL | true\n2 | bytes | 1.5 | false\n",
+		},
+		{
+			desc: "types",
+			rows: [][]any{
+				{
+					[16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8},
+					time.Date(2001, 2, 3, 4, 5, 6, 0, time.FixedZone("BRT", -3*60*60)),
+					pgtype.Numeric{},
+				},
+			},
+			want: "6ba7b810-9dad-11d1-80b4-00c04fd430c8 | 2001-02-03T07:05:06Z | NULL\n",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			if got := formatRows(tc.rows); got != tc.want {
+				t.Errorf("expected rows to be %q, got %q instead", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package sqltest
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Bench sets up a database with the migration files for a benchmark using the default options and the
+// PostgreSQL environment variables, and resets the timer of the benchmark, so that the setup isn't measured.
+// If something fails, b.Fatal is called.
+//
+// It returns a pgx pool to connect to the database, and a function to truncate its tables with the timer stopped,
+// to call between iterations writing data to the database, as in:
+//
+//	pool, reset := sqltest.Bench(b, os.DirFS("migrations"))
+//	for i := 0; i < b.N; i++ {
+//		reset()
+//		// Code writing to the database.
+//	}
+func Bench(b *testing.B, files fs.FS) (pool *pgxpool.Pool, reset func()) {
+	b.Helper()
+	m := New(b, Options{
+		Files: files,
+		// Names of benchmarks don't start with the required DatabasePrefix, unlike the names of tests.
+		TemporaryDatabasePrefix: DatabasePrefix + "_",
+	})
+	pool = m.Setup(context.Background(), "")
+	reset = func() {
+		b.Helper()
+		b.StopTimer()
+		defer b.StartTimer()
+		if err := truncateAll(context.Background(), pool, m.schemaVersionTable(), false); err != nil {
+			b.Fatalf("cannot reset database: %v", err)
+		}
+	}
+	b.ResetTimer()
+	return pool, reset
+}
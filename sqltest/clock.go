@@ -0,0 +1,78 @@
+package sqltest
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// clockSchema where the functions of the fake clock are created.
+const clockSchema = "sqltest_clock"
+
+// clockSearchPath puts the clock schema before pg_catalog, so that its functions shadow the built-in ones.
+const clockSearchPath = `"$user", public, ` + clockSchema + `, pg_catalog`
+
+// clockSQL creates the functions of the fake clock, which return the time set with SetTime,
+// or the time of the server if it's not set.
+const clockSQL = `CREATE SCHEMA IF NOT EXISTS ` + clockSchema + `;
+CREATE TABLE IF NOT EXISTS ` + clockSchema + `.fake_time (t timestamptz NOT NULL);
+CREATE OR REPLACE FUNCTION ` + clockSchema + `.now() RETURNS timestamptz LANGUAGE sql STABLE
+	AS $$ SELECT coalesce((SELECT t FROM ` + clockSchema + `.fake_time LIMIT 1), pg_catalog.now()) $$;
+CREATE OR REPLACE FUNCTION ` + clockSchema + `.transaction_timestamp() RETURNS timestamptz LANGUAGE sql STABLE
+	AS $$ SELECT coalesce((SELECT t FROM ` + clockSchema + `.fake_time LIMIT 1), pg_catalog.transaction_timestamp()) $$;
+CREATE OR REPLACE FUNCTION ` + clockSchema + `.statement_timestamp() RETURNS timestamptz LANGUAGE sql STABLE
+	AS $$ SELECT coalesce((SELECT t FROM ` + clockSchema + `.fake_time LIMIT 1), pg_catalog.statement_timestamp()) $$;
+CREATE OR REPLACE FUNCTION ` + clockSchema + `.clock_timestamp() RETURNS timestamptz LANGUAGE sql VOLATILE
+	AS $$ SELECT coalesce((SELECT t FROM ` + clockSchema + `.fake_time LIMIT 1), pg_catalog.clock_timestamp()) $$;`
+
+// installClock creates the functions of the fake clock on the database, when the FakeClock option is set.
+// It must be called before the migrations, so that column defaults such as DEFAULT now() use them.
+func (m *Migration) installClock(ctx context.Context, conn *pgx.Conn) error {
+	if !m.Options.FakeClock {
+		return nil
+	}
+	_, err := conn.Exec(ctx, clockSQL)
+	return err
+}
+
+// SetTime of the fake clock returned by now() and similar functions on the database until it's changed again.
+// It requires the FakeClock option.
+func (m *Migration) SetTime(ctx context.Context, t time.Time) {
+	m.t.Helper()
+	if err := m.setTime(ctx, &t); err != nil {
+		m.t.Fatalf("cannot set time: %v", err)
+	}
+}
+
+// ResetTime of the fake clock to the time of the server.
+func (m *Migration) ResetTime(ctx context.Context) {
+	m.t.Helper()
+	if err := m.setTime(ctx, nil); err != nil {
+		m.t.Fatalf("cannot reset time: %v", err)
+	}
+}
+
+func (m *Migration) setTime(ctx context.Context, t *time.Time) error {
+	m.t.Helper()
+	if !m.Options.FakeClock {
+		m.t.Fatal("the fake clock requires the FakeClock option")
+	}
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Rolling back a transaction that was committed is a no-op.
+		_ = tx.Rollback(ctx)
+	}()
+	if _, err := tx.Exec(ctx, "DELETE FROM "+clockSchema+".fake_time"); err != nil {
+		return err
+	}
+	if t != nil {
+		if _, err := tx.Exec(ctx, "INSERT INTO "+clockSchema+".fake_time (t) VALUES ($1)", *t); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
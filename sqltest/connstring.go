@@ -0,0 +1,110 @@
+package sqltest
+
+import (
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DatabaseName returns the name of the database used by the test.
+// It's only available after Setup or SetupTx.
+func (m *Migration) DatabaseName() string {
+	return m.database
+}
+
+// ConnString returns the connection string passed to Setup or SetupTx, changed to connect to
+// the database used by the test, so that it can be used by other clients and tools such as psql.
+// With SchemaIsolation, it also sets the search_path to the schema of the test.
+// It's only available after Setup or SetupTx.
+//
+// Parameters missing from the connection string, such as the host, are still read from
+// the PostgreSQL environment variables, as with libpq.
+func (m *Migration) ConnString() string {
+	params := map[string]string{
+		"dbname": m.database,
+	}
+	if m.schema != "" {
+		params["options"] = "-csearch_path=" + quoteIdentifier(m.schema)
+	}
+	s := m.connString
+	if strings.HasPrefix(s, "postgres://") || strings.HasPrefix(s, "postgresql://") {
+		u, err := url.Parse(s)
+		if err == nil {
+			u.Path = "/" + params["dbname"]
+			if options, ok := params["options"]; ok {
+				q := u.Query()
+				q.Set("options", options)
+				u.RawQuery = q.Encode()
+			}
+			return u.String()
+		}
+	}
+	// Later parameters override earlier ones on the keyword/value format.
+	for _, k := range []string{"dbname", "options"} {
+		if v, ok := params[k]; ok {
+			s += " " + k + "='" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// shellQuote a string for use as a single argument in a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// passwordParam matches the password parameter of a connection string in the keyword/value format.
+var passwordParam = regexp.MustCompile(`(^|\s+)password\s*=\s*('(\\.|[^'\\])*'|\S*)`)
+
+// redactPassword removes the password from a connection string, so that it can be logged.
+// Clients such as psql can still read it from the PGPASSWORD environment variable or the password file.
+func redactPassword(connString string) string {
+	if strings.HasPrefix(connString, "postgres://") || strings.HasPrefix(connString, "postgresql://") {
+		u, err := url.Parse(connString)
+		if err != nil {
+			return ""
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.User(u.User.Username())
+		}
+		if q := u.Query(); q.Has("password") {
+			q.Del("password")
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	return strings.TrimSpace(passwordParam.ReplaceAllString(connString, ""))
+}
+
+// logKept logs how to connect to the database left behind by the test with psql or another client.
+// The password is omitted from the connection string.
+func (m *Migration) logKept(msg string) {
+	m.t.Helper()
+	dsn := redactPassword(m.ConnString())
+	m.log(msg, slog.String("database", m.database), slog.String("schema", m.schema), slog.String("dsn", dsn))
+	m.t.Logf("connect to it with: psql %s", shellQuote(dsn))
+	m.t.Logf("DSN: %s", dsn)
+}
+
+// parseConfig of a connection, applying the SimpleProtocol option.
+func (m *Migration) parseConfig(connString string) (*pgx.ConnConfig, error) {
+	config, err := pgx.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	m.setProtocol(config)
+	return config, nil
+}
+
+// setProtocol of the connection according to the SimpleProtocol option.
+func (m *Migration) setProtocol(config *pgx.ConnConfig) {
+	if !m.Options.SimpleProtocol {
+		return
+	}
+	config.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	config.StatementCacheCapacity = 0
+	config.DescriptionCacheCapacity = 0
+}
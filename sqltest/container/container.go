@@ -0,0 +1,166 @@
+// Package container starts a disposable PostgreSQL server in a Docker container for tests
+// using the sqltest package, so that they can run without any local PostgreSQL setup.
+//
+// Use it on the TestMain function of your package:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(container.Run(m, container.Options{}))
+//	}
+//
+// If a PostgreSQL server is reachable with the PostgreSQL environment variables, such as PGHOST
+// and PGPORT, it's used instead, unless the Always option is set.
+// Otherwise, a container is started with testcontainers-go, the environment variables are set to connect to it,
+// and it's terminated once the tests are over.
+package container
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// DefaultImage of the PostgreSQL container.
+const DefaultImage = "postgres:15-alpine"
+
+// Options for starting the PostgreSQL container.
+type Options struct {
+	// Image of the container. If empty, DefaultImage is used.
+	Image string
+
+	// Always start a container, even if a PostgreSQL server is already reachable.
+	Always bool
+
+	// StartupTimeout is the maximum time to wait for the server to accept connections.
+	// If zero, one minute is used.
+	StartupTimeout time.Duration
+}
+
+const (
+	user     = "postgres"
+	password = "postgres"
+	database = "postgres"
+)
+
+// Container running PostgreSQL.
+type Container struct {
+	container testcontainers.Container
+	host      string
+	port      string
+}
+
+// ConnString of the PostgreSQL server.
+func (c *Container) ConnString() string {
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", user, password, net.JoinHostPort(c.host, c.port), database)
+}
+
+// Terminate the container.
+func (c *Container) Terminate(ctx context.Context) error {
+	return c.container.Terminate(ctx)
+}
+
+// setenv sets the PostgreSQL environment variables to connect to the container.
+func (c *Container) setenv() error {
+	for k, v := range map[string]string{
+		"PGHOST":     c.host,
+		"PGPORT":     c.port,
+		"PGUSER":     user,
+		"PGPASSWORD": password,
+		"PGDATABASE": database,
+		"PGSSLMODE":  "disable",
+	} {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start a PostgreSQL container.
+func Start(ctx context.Context, opts Options) (*Container, error) {
+	image := opts.Image
+	if image == "" {
+		image = DefaultImage
+	}
+	timeout := opts.StartupTimeout
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+	req := testcontainers.ContainerRequest{
+		Image:        image,
+		ExposedPorts: []string{"5432/tcp"},
+		Env: map[string]string{
+			"POSTGRES_USER":     user,
+			"POSTGRES_PASSWORD": password,
+			"POSTGRES_DB":       database,
+		},
+		// The server is restarted once after the database is initialized, so wait for the second message.
+		WaitingFor: wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).
+			WithStartupTimeout(timeout),
+	}
+	pc, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot start PostgreSQL container: %w", err)
+	}
+	c := &Container{container: pc}
+	if c.host, err = pc.Host(ctx); err != nil {
+		_ = pc.Terminate(ctx)
+		return nil, err
+	}
+	port, err := pc.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		_ = pc.Terminate(ctx)
+		return nil, err
+	}
+	c.port = port.Port()
+	return c, nil
+}
+
+// Run the tests, starting a PostgreSQL container beforehand if no server is reachable,
+// and returns the exit code to be passed to os.Exit.
+func Run(m *testing.M, opts Options) int {
+	ctx := context.Background()
+	if !opts.Always && reachable(ctx) {
+		return m.Run()
+	}
+	c, err := Start(ctx, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer func() {
+		if err := c.Terminate(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot terminate PostgreSQL container: %v\n", err)
+		}
+	}()
+	if err := c.setenv(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return m.Run()
+}
+
+// reachable reports whether a PostgreSQL server is reachable with the PostgreSQL environment variables.
+func reachable(ctx context.Context) bool {
+	config, err := pgx.ParseConfig("")
+	if err != nil {
+		return false
+	}
+	config.ConnectTimeout = 2 * time.Second
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close(ctx)
+	return true
+}
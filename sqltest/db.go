@@ -0,0 +1,50 @@
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// SetupDB of the migrations is similar to Setup, but returns a *sql.DB for use with the database/sql package,
+// using the pgx driver.
+// If something fails, t.Fatal is called.
+//
+// The *sql.DB is closed during teardown.
+func (m *Migration) SetupDB(ctx context.Context, connString string) *sql.DB {
+	if m.t == nil {
+		panic("migration must be initialized with sqltest.New()")
+	}
+	m.t.Helper()
+	pool := m.Setup(ctx, connString)
+	m.db = stdlib.OpenDB(*pool.Config().ConnConfig)
+	return m.db
+}
+
+// Quick sets up a database with the migration files using the default options and the
+// PostgreSQL environment variables, and returns a pgx pool to connect to it.
+// If something fails, t.Fatal is called.
+func Quick(t TB, files fs.FS) *pgxpool.Pool {
+	t.Helper()
+	return QuickOptions(t, files, Options{})
+}
+
+// QuickOptions is similar to Quick, but uses the given options, as in:
+//
+//	pool := sqltest.QuickOptions(t, os.DirFS("migrations"), sqltest.Options{Template: true})
+//
+// The Files option is set to files.
+func QuickOptions(t TB, files fs.FS, opts Options) *pgxpool.Pool {
+	t.Helper()
+	opts.Files = files
+	return New(t, opts).Setup(context.Background(), "")
+}
+
+// QuickDB is similar to Quick, but returns a *sql.DB for use with the database/sql package.
+func QuickDB(t TB, files fs.FS) *sql.DB {
+	t.Helper()
+	return New(t, Options{Files: files}).SetupDB(context.Background(), "")
+}
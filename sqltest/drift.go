@@ -0,0 +1,81 @@
+package sqltest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/tern/v2/migrate"
+)
+
+// checksumTableSuffix appended to the schema version table to name the migration checksums table.
+const checksumTableSuffix = "_checksums"
+
+// checksumTableDDL creates the migration checksums table.
+const checksumTableDDL = "CREATE TABLE IF NOT EXISTS %s (version int4 PRIMARY KEY, name text NOT NULL, checksum text NOT NULL)"
+
+// checksumTable where the checksums of the applied migrations are recorded with the UseExisting option,
+// next to the schema version table.
+func (m *Migration) checksumTable() string {
+	return m.schemaVersionTable() + checksumTableSuffix
+}
+
+// migrationChecksum of the content of a migration.
+func migrationChecksum(mig *migrate.Migration) string {
+	sum := sha256.Sum256([]byte(mig.UpSQL + "\x00" + mig.DownSQL))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkDrift of the migrations applied to an existing database, returning an error if any of them
+// was changed since it was applied, as editing a migration that was already applied doesn't change the database.
+func (m *Migration) checkDrift(ctx context.Context, conn *pgx.Conn, migrator *migrate.Migrator) error {
+	if _, err := conn.Exec(ctx, fmt.Sprintf(checksumTableDDL, m.checksumTable())); err != nil {
+		return fmt.Errorf("cannot create migration checksums table: %w", err)
+	}
+	rows, err := conn.Query(ctx, fmt.Sprintf("SELECT version, checksum FROM %s WHERE version <= (SELECT version FROM %s) ORDER BY version", m.checksumTable(), m.schemaVersionTable()))
+	if err != nil {
+		return fmt.Errorf("cannot get migration checksums: %w", err)
+	}
+	var (
+		version  int32
+		checksum string
+		changed  []string
+	)
+	_, err = pgx.ForEachRow(rows, []any{&version, &checksum}, func() error {
+		if version < 1 || int(version) > len(migrator.Migrations) {
+			return nil
+		}
+		if mig := migrator.Migrations[version-1]; checksum != migrationChecksum(mig) {
+			changed = append(changed, mig.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot get migration checksums: %w", err)
+	}
+	if len(changed) != 0 {
+		return fmt.Errorf("migrations changed after they were applied to the database: %s (add a new migration instead, or try -force)", strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// recordChecksums of the migrations applied to the database.
+func (m *Migration) recordChecksums(ctx context.Context, conn *pgx.Conn, migrator *migrate.Migrator) error {
+	version, err := migrator.GetCurrentVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot get schema version: %w", err)
+	}
+	batch := &pgx.Batch{}
+	batch.Queue(fmt.Sprintf(checksumTableDDL, m.checksumTable()))
+	batch.Queue("DELETE FROM " + m.checksumTable())
+	for _, mig := range migrator.Migrations[:version] {
+		batch.Queue("INSERT INTO "+m.checksumTable()+" (version, name, checksum) VALUES ($1, $2, $3)", mig.Sequence, mig.Name, migrationChecksum(mig))
+	}
+	if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("cannot record migration checksums: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package sqltest
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// Environment variables overriding the options passed to New, so that behavior can be changed
+// for a single run of go test without editing the tests, as in:
+//
+//	PGTOOLS_FORCE=1 PGTOOLS_SKIP_TEARDOWN=1 go test ./...
+//
+// Boolean values are parsed with strconv.ParseBool, so they can also disable an option.
+const (
+	// ForceEnv overrides the Force option.
+	ForceEnv = "PGTOOLS_FORCE"
+
+	// SkipTeardownEnv overrides the SkipTeardown option.
+	SkipTeardownEnv = "PGTOOLS_SKIP_TEARDOWN"
+
+	// KeepOnFailureEnv overrides the KeepOnFailure option.
+	KeepOnFailureEnv = "PGTOOLS_KEEP_ON_FAILURE"
+
+	// UpdateGoldenEnv overrides the UpdateGolden option.
+	UpdateGoldenEnv = "PGTOOLS_UPDATE_GOLDEN"
+
+	// DumpOnFailureEnv overrides the DumpOnFailure option.
+	DumpOnFailureEnv = "PGTOOLS_DUMP_ON_FAILURE"
+
+	// LogsEnv sets the Logger option to write events to the standard error
+	// in the text or json format of the log/slog package.
+	LogsEnv = "PGTOOLS_LOGS"
+)
+
+// applyEnv overrides the options with the values of the environment variables.
+func applyEnv(o *Options) error {
+	for env, p := range map[string]*bool{
+		ForceEnv:         &o.Force,
+		SkipTeardownEnv:  &o.SkipTeardown,
+		KeepOnFailureEnv: &o.KeepOnFailure,
+		UpdateGoldenEnv:  &o.UpdateGolden,
+	} {
+		v, ok := os.LookupEnv(env)
+		if !ok || v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %q", env, v)
+		}
+		*p = b
+	}
+	if v := os.Getenv(DumpOnFailureEnv); v != "" {
+		o.DumpOnFailure = v
+	}
+	switch v := os.Getenv(LogsEnv); v {
+	case "":
+	case "text":
+		o.Logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	case "json":
+		o.Logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	default:
+		return fmt.Errorf("invalid value for %s: %q (use text or json)", LogsEnv, v)
+	}
+	return nil
+}
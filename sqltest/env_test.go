@@ -0,0 +1,60 @@
+package sqltest
+
+import "testing"
+
+func TestNewEnv(t *testing.T) {
+	t.Setenv(ForceEnv, "1")
+	t.Setenv(SkipTeardownEnv, "false")
+	t.Setenv(DumpOnFailureEnv, "artifacts")
+	t.Setenv(LogsEnv, "json")
+	m := New(t, Options{
+		SkipTeardown:  true,
+		KeepOnFailure: true,
+	})
+	if !m.Options.Force {
+		t.Error("expected Force to be set")
+	}
+	if m.Options.SkipTeardown {
+		t.Error("expected SkipTeardown to be unset")
+	}
+	if !m.Options.KeepOnFailure {
+		t.Error("expected KeepOnFailure to be kept")
+	}
+	if m.Options.DumpOnFailure != "artifacts" {
+		t.Errorf("expected DumpOnFailure to be %q, got %q instead", "artifacts", m.Options.DumpOnFailure)
+	}
+	if m.Options.Logger == nil {
+		t.Error("expected Logger to be set")
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	testCases := []struct {
+		desc string
+		env  string
+		v    string
+		want string
+	}{
+		{
+			desc: "bool",
+			env:  ForceEnv,
+			v:    "maybe",
+			want: `invalid value for PGTOOLS_FORCE: "maybe"`,
+		},
+		{
+			desc: "logs",
+			env:  LogsEnv,
+			v:    "xml",
+			want: `invalid value for PGTOOLS_LOGS: "xml" (use text or json)`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv(tc.env, tc.v)
+			var o Options
+			if err := applyEnv(&o); err == nil || err.Error() != tc.want {
+				t.Errorf("expected error to be %q, got %v instead", tc.want, err)
+			}
+		})
+	}
+}
@@ -6,16 +6,16 @@ import (
 	"log"
 	"time"
 
-	"github.com/henvic/pgtools/sqltest/example/internal/postgres"
+	"github.com/henvic/pgtools/pgxs"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Database layer for your application.
 type Database struct {
-	// Postgres connection using the limited postgres.PGX interface,
+	// Postgres connection using the limited pgxs.PGX interface,
 	// instead of *pgxpool.Pool.
 	// Used as a light way of disencouraging unwarranted access to low-level APIs.
-	Postgres postgres.PGX
+	Postgres pgxs.PGX
 }
 
 // Now gets the current time in the database.
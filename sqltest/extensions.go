@@ -0,0 +1,25 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// createExtensions of the Extensions option on the database, before the migrations.
+func (m *Migration) createExtensions(ctx context.Context, conn *pgx.Conn) error {
+	for _, name := range m.Options.Extensions {
+		var available bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_available_extensions WHERE name = $1)", name).Scan(&available); err != nil {
+			return err
+		}
+		if !available {
+			return fmt.Errorf("extension %q isn't installed on the PostgreSQL server", name)
+		}
+		if _, err := conn.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS "+quoteIdentifier(name)); err != nil {
+			return fmt.Errorf("cannot create extension %q: %w", name, err)
+		}
+	}
+	return nil
+}
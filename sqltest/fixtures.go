@@ -0,0 +1,323 @@
+package sqltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFixtures inserts the rows of the fixture files into the database.
+// If something fails, t.Fatal is called.
+//
+// Each YAML (.yml or .yaml), JSON (.json), or CSV (.csv) file contains the rows of the table
+// named after the file, as in users.yml or public.users.json:
+//
+//   - YAML and JSON files contain a list of rows mapping column names to values.
+//     Nested lists and objects are encoded as JSON, for use with json and jsonb columns.
+//   - CSV files contain a header with the column names, and a row per line.
+//     Use \N for NULL values, as in the text format of COPY.
+//
+// The tables are truncated before inserting the rows, and rows are inserted in an order that
+// satisfies the foreign keys between the tables.
+// After that, SQL (.sql) files are executed in lexical order.
+//
+// Everything runs in a single transaction, or in a savepoint of the transaction
+// returned by SetupTx.
+func (m *Migration) LoadFixtures(ctx context.Context, files fs.FS) {
+	m.t.Helper()
+	if err := m.loadFixtures(ctx, files); err != nil {
+		m.t.Fatalf("cannot load fixtures: %v", err)
+	}
+}
+
+// fixture rows of a table.
+type fixture struct {
+	table string
+	rows  []map[string]any
+}
+
+func (m *Migration) loadFixtures(ctx context.Context, files fs.FS) error {
+	var (
+		fixtures []*fixture
+		scripts  []string
+	)
+	err := fs.WalkDir(files, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := path.Ext(name)
+		if ext == ".sql" {
+			b, err := fs.ReadFile(files, name)
+			scripts = append(scripts, string(b))
+			return err
+		}
+		decode, ok := decoders[ext]
+		if !ok {
+			return nil
+		}
+		b, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+		rows, err := decode(b)
+		if err != nil {
+			return fmt.Errorf("cannot decode %s: %w", name, err)
+		}
+		fixtures = append(fixtures, &fixture{
+			table: strings.TrimSuffix(path.Base(name), ext),
+			rows:  rows,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Rolling back a transaction that was committed is a no-op.
+		_ = tx.Rollback(ctx)
+	}()
+
+	if len(fixtures) != 0 {
+		if err := sortFixtures(ctx, tx, fixtures); err != nil {
+			return err
+		}
+		tables := make([]string, 0, len(fixtures))
+		for _, f := range fixtures {
+			tables = append(tables, quoteTable(f.table))
+		}
+		if _, err := tx.Exec(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" RESTART IDENTITY CASCADE"); err != nil {
+			return err
+		}
+	}
+	for _, f := range fixtures {
+		for _, row := range f.rows {
+			if err := insertRow(ctx, tx, f.table, row); err != nil {
+				return err
+			}
+		}
+	}
+	for _, sql := range scripts {
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// insertRow into the table.
+// The simple protocol is used, so values are sent as literals coerced to the data types of the columns.
+func insertRow(ctx context.Context, tx pgx.Tx, table string, row map[string]any) error {
+	columns := make([]string, 0, len(row))
+	for c := range row {
+		columns = append(columns, c)
+	}
+	sort.Strings(columns)
+	var (
+		quoted       = make([]string, 0, len(columns))
+		placeholders = make([]string, 0, len(columns))
+		args         = []any{pgx.QueryExecModeSimpleProtocol}
+	)
+	for n, c := range columns {
+		quoted = append(quoted, quoteIdentifier(c))
+		placeholders = append(placeholders, fmt.Sprintf("$%d", n+1))
+		v := row[c]
+		switch v.(type) {
+		case map[string]any, []any:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			v = string(b)
+		}
+		args = append(args, v)
+	}
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteTable(table), strings.Join(quoted, ","), strings.Join(placeholders, ","))
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("cannot insert into %s: %w", table, err)
+	}
+	return nil
+}
+
+// sortFixtures so that tables are inserted after the tables they reference with foreign keys.
+func sortFixtures(ctx context.Context, tx pgx.Tx, fixtures []*fixture) error {
+	names := make([]string, 0, len(fixtures))
+	for _, f := range fixtures {
+		names = append(names, f.table)
+	}
+	order, err := insertionOrder(ctx, tx, names)
+	if err != nil {
+		return err
+	}
+	sorted := make([]*fixture, 0, len(fixtures))
+	for _, n := range order {
+		sorted = append(sorted, fixtures[n])
+	}
+	copy(fixtures, sorted)
+	return nil
+}
+
+// insertionOrder returns the indexes of the tables in an order where each table comes after
+// the tables it references with foreign keys.
+// Tables with circular references are kept in the given order.
+func insertionOrder(ctx context.Context, tx pgx.Tx, tables []string) ([]int, error) {
+	rows, err := tx.Query(ctx, `SELECT c.conrelid::regclass::text, c.confrelid::regclass::text
+		FROM pg_constraint c
+		WHERE c.contype = 'f' AND c.conrelid <> c.confrelid
+		AND c.conrelid = ANY($1::text[]::regclass[]) AND c.confrelid = ANY($1::text[]::regclass[])`, tables)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query foreign keys: %w", err)
+	}
+	deps, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) ([2]string, error) {
+		var d [2]string
+		err := row.Scan(&d[0], &d[1])
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot query foreign keys: %w", err)
+	}
+
+	// Tables are returned by regclass as they'd be written to be resolved with the search_path,
+	// so they're matched by the name resolved in the same way.
+	var resolved []string
+	if err := tx.QueryRow(ctx, `SELECT array_agg(t::regclass::text ORDER BY n) FROM unnest($1::text[]) WITH ORDINALITY AS u(t, n)`, tables).Scan(&resolved); err != nil {
+		return nil, fmt.Errorf("cannot resolve tables: %w", err)
+	}
+	index := make(map[string]int, len(resolved))
+	for n, r := range resolved {
+		index[r] = n
+	}
+	references := make([][]int, len(tables)) // Tables referenced by each table.
+	for _, d := range deps {
+		from, to := index[d[0]], index[d[1]]
+		references[from] = append(references[from], to)
+	}
+
+	order := make([]int, 0, len(tables))
+	done := make([]bool, len(tables))
+	for len(order) < len(tables) {
+		progress := false
+		for n := range tables {
+			if done[n] || !ready(references[n], done) {
+				continue
+			}
+			done[n], progress = true, true
+			order = append(order, n)
+		}
+		if progress {
+			continue
+		}
+		// Break a cycle by taking the first remaining table.
+		for n := range tables {
+			if !done[n] {
+				done[n] = true
+				order = append(order, n)
+				break
+			}
+		}
+	}
+	return order, nil
+}
+
+// ready reports whether all the referenced tables are done.
+func ready(references []int, done []bool) bool {
+	for _, r := range references {
+		if !done[r] {
+			return false
+		}
+	}
+	return true
+}
+
+// decoders of fixture files by extension.
+var decoders = map[string]func([]byte) ([]map[string]any, error){
+	".json": decodeJSON,
+	".yml":  decodeYAML,
+	".yaml": decodeYAML,
+	".csv":  decodeCSV,
+}
+
+func decodeJSON(b []byte) ([]map[string]any, error) {
+	var rows []map[string]any
+	d := json.NewDecoder(bytes.NewReader(b))
+	// Decode numbers as json.Number to avoid losing precision of large integers.
+	d.UseNumber()
+	if err := d.Decode(&rows); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		for k, v := range row {
+			if n, ok := v.(json.Number); ok {
+				row[k] = n.String()
+			}
+		}
+	}
+	return rows, nil
+}
+
+func decodeYAML(b []byte) ([]map[string]any, error) {
+	var rows []map[string]any
+	if err := yaml.Unmarshal(b, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func decodeCSV(b []byte) ([]map[string]any, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]any
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(header))
+		for n, c := range header {
+			if record[n] == `\N` {
+				row[c] = nil
+				continue
+			}
+			row[c] = record[n]
+		}
+		rows = append(rows, row)
+	}
+}
+
+// quoteIdentifier for use in a SQL statement.
+func quoteIdentifier(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// quoteTable name, which might be qualified with a schema.
+func quoteTable(s string) string {
+	parts := strings.Split(s, ".")
+	for n, p := range parts {
+		parts[n] = quoteIdentifier(p)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,274 @@
+package sqltest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/tern/v2/migrate"
+)
+
+// Format of the migration files.
+type Format int
+
+const (
+	// TernFormat is the format of tern migration files, such as 001_create_users.sql,
+	// where the SQL to undo the migration comes after a ---- create above / drop below ---- line.
+	TernFormat Format = iota
+
+	// GolangMigrateFormat is the format of golang-migrate migration files,
+	// such as 001_create_users.up.sql and 001_create_users.down.sql.
+	//
+	// Migrations are applied in the order of their versions, which don't need to be sequential,
+	// as in 20230102150405_create_users.up.sql.
+	// The target version of SetupVersion and MigrateTo is the position of the migration instead.
+	GolangMigrateFormat
+
+	// GooseFormat is the format of goose SQL migration files, such as 20230102150405_create_users.sql,
+	// with -- +goose Up and -- +goose Down annotations marking the SQL to apply and undo the migration.
+	//
+	// Migrations are applied in the order of their versions, and the target version of SetupVersion
+	// and MigrateTo is the position of the migration, as with GolangMigrateFormat.
+	// Go migrations and the -- +goose NO TRANSACTION annotation aren't supported.
+	GooseFormat
+
+	// PlainSQLFormat executes every .sql file in lexical order of their paths, including files in subdirectories,
+	// without tracking the schema version, for schemas managed outside of a migration tool.
+	//
+	// SetupVersion and MigrateTo aren't supported.
+	PlainSQLFormat
+)
+
+// source migration loaded from files in a format other than tern's.
+type source struct {
+	version uint64
+	name    string
+	up      string
+	down    string
+}
+
+// loaders of the migration files by format, except for TernFormat.
+var loaders = map[Format]func(fs.FS) ([]*source, error){
+	GolangMigrateFormat: loadGolangMigrate,
+	GooseFormat:         loadGoose,
+}
+
+// loadMigrations from the files into the migrator.
+func (m *Migration) loadMigrations(migrator *migrate.Migrator) error {
+	if m.Options.Format == TernFormat {
+		return migrator.LoadMigrations(m.Options.Files)
+	}
+	load, ok := loaders[m.Options.Format]
+	if !ok {
+		return fmt.Errorf("unknown migration format %d", m.Options.Format)
+	}
+	sources, err := load(m.Options.Files)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return migrate.NoMigrationsFoundError{}
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].version < sources[j].version
+	})
+	for _, s := range sources {
+		migrator.AppendMigration(s.name, s.up, s.down)
+	}
+	return nil
+}
+
+// versionByName returns the version of the migration with the given name, with or without its extension.
+func (m *Migration) versionByName(name string) (int32, error) {
+	if m.Options.Format == PlainSQLFormat {
+		return 0, errors.New("SetupVersionName isn't supported with PlainSQLFormat")
+	}
+	// Not using migrate.NewMigrator, as it creates the schema version table.
+	migrator := &migrate.Migrator{Data: map[string]any{}}
+	if err := m.loadMigrations(migrator); err != nil {
+		return 0, fmt.Errorf("cannot load migrations: %w", err)
+	}
+	for n, mig := range migrator.Migrations {
+		if trimMigrationExt(mig.Name) == trimMigrationExt(name) {
+			return int32(n + 1), nil
+		}
+	}
+	return 0, fmt.Errorf("migration %q not found", name)
+}
+
+// trimMigrationExt removes the extension of a migration file name, such as .sql or .up.sql.
+func trimMigrationExt(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(name, ".up.sql"), ".sql")
+}
+
+var golangMigratePattern = regexp.MustCompile(`\A(\d+)_(.*)\.(up|down)\.sql\z`)
+
+// loadGolangMigrate loads the migrations in the golang-migrate format.
+func loadGolangMigrate(files fs.FS) ([]*source, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+	versions := map[uint64]*source{}
+	var sources []*source
+	for _, e := range entries {
+		matches := golangMigratePattern.FindStringSubmatch(e.Name())
+		if e.IsDir() || matches == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %q: %w", e.Name(), err)
+		}
+		b, err := fs.ReadFile(files, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		s, ok := versions[version]
+		if !ok {
+			s = &source{version: version, name: matches[1] + "_" + matches[2]}
+			versions[version] = s
+			sources = append(sources, s)
+		}
+		if s.name != matches[1]+"_"+matches[2] {
+			return nil, fmt.Errorf("duplicate migration %d", version)
+		}
+		if matches[3] == "up" {
+			s.up = string(b)
+		} else {
+			s.down = string(b)
+		}
+	}
+	for _, s := range sources {
+		if s.up == "" {
+			return nil, fmt.Errorf("missing up migration %s", s.name)
+		}
+	}
+	return sources, nil
+}
+
+var goosePattern = regexp.MustCompile(`\A(\d+)_.+\.sql\z`)
+
+// loadGoose loads the migrations in the goose format.
+func loadGoose(files fs.FS) ([]*source, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+	versions := map[uint64]string{}
+	var sources []*source
+	for _, e := range entries {
+		matches := goosePattern.FindStringSubmatch(e.Name())
+		if e.IsDir() || matches == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %q: %w", e.Name(), err)
+		}
+		if name, ok := versions[version]; ok {
+			return nil, fmt.Errorf("duplicate migration %d: %s and %s", version, name, e.Name())
+		}
+		versions[version] = e.Name()
+		b, err := fs.ReadFile(files, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		s, err := parseGoose(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		s.version = version
+		s.name = strings.TrimSuffix(e.Name(), ".sql")
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
+// parseGoose splits a goose migration file into its up and down sections.
+// Other annotations, such as -- +goose StatementBegin, are removed.
+func parseGoose(sql string) (*source, error) {
+	var (
+		up, down strings.Builder
+		section  *strings.Builder
+		hasUp    bool
+	)
+	for _, line := range strings.SplitAfter(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "-- +goose ") {
+			if section != nil {
+				section.WriteString(line)
+			}
+			continue
+		}
+		switch strings.TrimSpace(strings.TrimPrefix(trimmed, "-- +goose ")) {
+		case "Up":
+			section, hasUp = &up, true
+		case "Down":
+			section = &down
+		case "NO TRANSACTION":
+			return nil, errors.New("-- +goose NO TRANSACTION is not supported")
+		}
+	}
+	if !hasUp {
+		return nil, errors.New("missing -- +goose Up annotation")
+	}
+	return &source{
+		up:   up.String(),
+		down: down.String(),
+	}, nil
+}
+
+// execFilesTx executes the files like execFiles, in a single transaction if the SingleTransaction option is set.
+func (m *Migration) execFilesTx(ctx context.Context, conn *pgx.Conn, targetVersion *int32) error {
+	if !m.Options.SingleTransaction {
+		return m.execFiles(ctx, conn, targetVersion)
+	}
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	defer func() {
+		// Rolling back a transaction that was committed is a no-op.
+		_ = tx.Rollback(ctx)
+	}()
+	if err := m.execFiles(ctx, conn, targetVersion); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// execFiles executes the SQL files of a PlainSQLFormat schema.
+func (m *Migration) execFiles(ctx context.Context, conn *pgx.Conn, targetVersion *int32) error {
+	if targetVersion != nil {
+		return errors.New("cannot migrate to a target version with PlainSQLFormat")
+	}
+	var n int
+	err := fs.WalkDir(m.Options.Files, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != ".sql" {
+			return err
+		}
+		b, err := fs.ReadFile(m.Options.Files, name)
+		if err != nil {
+			return err
+		}
+		n++
+		m.startStep(int32(n), name, "up", string(b))
+		if _, err := conn.Exec(ctx, string(b)); err != nil {
+			return fmt.Errorf("cannot execute %s: %w", name, m.stepError(ctx, err))
+		}
+		return nil
+	})
+	m.finishStep(err)
+	if err == nil && n == 0 {
+		return migrate.NoMigrationsFoundError{}
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package sqltest
+
+import (
+	"os"
+	"testing"
+)
+
+func TestVersionByName(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc    string
+		options Options
+		name    string
+		want    int32
+		wantErr string
+	}{
+		{
+			desc:    "tern",
+			options: Options{Files: os.DirFS("example/testdata/migrations")},
+			name:    "002_settings.sql",
+			want:    2,
+		},
+		{
+			desc:    "without extension",
+			options: Options{Files: os.DirFS("example/testdata/migrations")},
+			name:    "003_posts",
+			want:    3,
+		},
+		{
+			desc:    "golang-migrate",
+			options: Options{Files: os.DirFS("testdata/golang-migrate"), Format: GolangMigrateFormat},
+			name:    "20230215093000_create_books.up.sql",
+			want:    2,
+		},
+		{
+			desc:    "not found",
+			options: Options{Files: os.DirFS("example/testdata/migrations")},
+			name:    "004_comments.sql",
+			wantErr: `migration "004_comments.sql" not found`,
+		},
+		{
+			desc:    "plain SQL",
+			options: Options{Files: os.DirFS("testdata/schema"), Format: PlainSQLFormat},
+			name:    "01_authors.sql",
+			wantErr: "SetupVersionName isn't supported with PlainSQLFormat",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			m := New(t, tc.options)
+			got, err := m.versionByName(tc.name)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("expected error to be %q, got %v instead", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v instead", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected version to be %d, got %d instead", tc.want, got)
+			}
+		})
+	}
+}
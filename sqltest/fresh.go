@@ -0,0 +1,47 @@
+package sqltest
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Fresh database for a subtest, cloned from a template database with the migrations applied,
+// so that each subtest gets its own database in milliseconds and can run in parallel, as in:
+//
+//	migration.Setup(ctx, "")
+//	for _, tc := range testCases {
+//		tc := tc
+//		t.Run(tc.desc, func(t *testing.T) {
+//			t.Parallel()
+//			pool := migration.Fresh(t)
+//			// ...
+//		})
+//	}
+//
+// The template database is created the first time it's needed, as with the Template option,
+// and migrated to the same version as the database of the migration. The Fixtures and Seed options
+// are applied to each database, which is dropped during the cleanup of t.
+// If something fails, t.Fatal is called.
+//
+// Unlike Snapshot and Restore, it requires permission to create databases, so it can't be used
+// with SchemaIsolation or TransactionIsolation.
+func (m *Migration) Fresh(t TB) *pgxpool.Pool {
+	if m.t == nil {
+		panic("migration must be initialized with sqltest.New()")
+	}
+	t.Helper()
+	if m.pool == nil {
+		t.Fatal("Fresh requires Setup to be called first")
+	}
+	if m.Options.Isolation != DatabaseIsolation {
+		t.Fatal("Fresh requires the DatabaseIsolation option")
+	}
+	o := m.Options
+	o.Template = true
+	o.UseExisting = false
+	o.SharedDatabases = 0
+	o.Dir = "" // Already applied to Files.
+	fresh := New(t, o)
+	return fresh.setupVersion(context.Background(), m.connString, m.targetVersion)
+}
@@ -0,0 +1,124 @@
+package sqltest
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// golden compares got with the contents of the golden file, or writes it if the UpdateGolden option is set.
+func (m *Migration) golden(name string, got []byte) {
+	m.t.Helper()
+	if m.Options.UpdateGolden {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			m.t.Fatalf("cannot create golden file directory: %v", err)
+		}
+		if err := os.WriteFile(name, got, 0o644); err != nil { // #nosec G306
+			m.t.Fatalf("cannot write golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		m.t.Fatalf("golden file %s not found: use the UpdateGolden option to create it", name)
+	}
+	if err != nil {
+		m.t.Fatalf("cannot read golden file: %v", err)
+	}
+	if !bytes.Equal(want, got) {
+		m.t.Errorf("mismatch with golden file %s (-want +got):\n%s", name, diff(string(want), string(got)))
+	}
+}
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diff returns the lines removed from a prefixed with "-", and the lines added to b prefixed with "+",
+// along with a few unchanged lines around them.
+func diff(a, b string) string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// Skip the common prefix and suffix, as diffs are usually small compared to the whole file.
+	var prefix, suffix int
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	xs, ys := x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+
+	// Longest common subsequence of the remaining lines.
+	lcs := make([][]int, len(xs)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(ys)+1)
+	}
+	for i := len(xs) - 1; i >= 0; i-- {
+		for j := len(ys) - 1; j >= 0; j-- {
+			if xs[i] == ys[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	lines := make([]line, 0, len(x)+len(y))
+	for _, s := range x[:prefix] {
+		lines = append(lines, line{' ', s})
+	}
+	i, j := 0, 0
+	for i < len(xs) || j < len(ys) {
+		switch {
+		case i < len(xs) && j < len(ys) && xs[i] == ys[j]:
+			lines = append(lines, line{' ', xs[i]})
+			i++
+			j++
+		case j == len(ys) || (i < len(xs) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', xs[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', ys[j]})
+			j++
+		}
+	}
+	for _, s := range x[len(x)-suffix:] {
+		lines = append(lines, line{' ', s})
+	}
+
+	// Only show unchanged lines close to a change.
+	show := make([]bool, len(lines))
+	for n, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for k := n - diffContext; k <= n+diffContext; k++ {
+			if k >= 0 && k < len(lines) {
+				show[k] = true
+			}
+		}
+	}
+	var sb strings.Builder
+	last := -1 // Last line written.
+	for n, l := range lines {
+		if !show[n] {
+			continue
+		}
+		if last != -1 && last != n-1 {
+			sb.WriteString("...\n")
+		}
+		sb.WriteByte(l.op)
+		sb.WriteString(" " + l.text + "\n")
+		last = n
+	}
+	return sb.String()
+}
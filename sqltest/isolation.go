@@ -0,0 +1,176 @@
+package sqltest
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Isolation mode of the tests.
+type Isolation int
+
+const (
+	// DatabaseIsolation creates a temporary database for each test, and drops it at teardown.
+	// Use it with Setup.
+	DatabaseIsolation Isolation = iota
+
+	// TransactionIsolation runs each test inside a transaction on a database shared by the tests,
+	// and rolls it back at teardown. Use it with SetupTx.
+	//
+	// It's much faster than creating a database for each test, but data written by a test
+	// might still block concurrent tests until it's rolled back, as in a unique constraint violation.
+	TransactionIsolation
+
+	// SchemaIsolation creates a temporary schema for each test in the database of the connection,
+	// and drops it at teardown. Use it with Setup.
+	//
+	// The search_path of the connections is set to the schema, so that the migrations create the tables
+	// and other objects in it. Use it where you're not allowed to create databases, such as with
+	// PostgreSQL servers managed by CI services. The Template and SharedDatabases options are ignored.
+	SchemaIsolation
+)
+
+// SetupTx of the migrations for use with the TransactionIsolation option.
+// This function returns a transaction that is rolled back during teardown,
+// instead of creating and dropping a database for the test.
+// If something fails, t.Fatal is called.
+//
+// The transaction runs on a database shared by the tests, which is created from a template database
+// with the migrations applied only once. See the Template option for details.
+// The UseExisting option is ignored.
+func (m *Migration) SetupTx(ctx context.Context, connString string) pgx.Tx {
+	if m.t == nil {
+		panic("migration must be initialized with sqltest.New()")
+	}
+
+	m.t.Helper()
+	if m.Options.Isolation != TransactionIsolation {
+		m.t.Fatal("SetupTx requires the TransactionIsolation option")
+	}
+	if err := m.setupTx(ctx, connString); err != nil {
+		m.t.Fatal(err)
+	}
+	m.startRecording()
+	return m.tx
+}
+
+// setupTx connects to the shared database and begins the transaction of the test,
+// returning errors instead of calling t.Fatal.
+func (m *Migration) setupTx(ctx context.Context, connString string) (err error) {
+	m.log("setup PostgreSQL transaction")
+	m.connString = connString
+	ctx, cancel := m.setupContext(ctx)
+	defer func() {
+		err = m.setupError(ctx, err)
+		cancel()
+	}()
+
+	config, err := m.parseConfig(connString)
+	if err != nil {
+		return err
+	}
+	if err := m.waitReady(ctx, connString); err != nil {
+		return err
+	}
+	if m.conn, err = pgx.ConnectConfig(ctx, config); err != nil {
+		return err
+	}
+	if !m.Options.SkipTeardown {
+		m.t.Cleanup(func() {
+			m.Teardown(context.Background())
+		})
+	}
+
+	if m.database, err = m.setupShared(ctx, connString); err != nil {
+		return fmt.Errorf("cannot create shared database: %w", err)
+	}
+	config.Database = m.database
+	config.Tracer = m.tracer()
+	m.setTimeouts(config)
+	if m.Options.FakeClock {
+		config.RuntimeParams["search_path"] = clockSearchPath
+	}
+	if m.txConn, err = pgx.ConnectConfig(ctx, config); err != nil {
+		return fmt.Errorf("cannot connect to database: %w", err)
+	}
+	if m.tx, err = m.txConn.Begin(ctx); err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	if m.Options.Fixtures != nil {
+		if err := m.loadFixtures(ctx, m.Options.Fixtures); err != nil {
+			return fmt.Errorf("cannot load fixtures: %w", err)
+		}
+	}
+	return nil
+}
+
+// setupShared creates the database shared by the tests using transaction isolation
+// by cloning the template database if it doesn't exist yet, and returns its name.
+//
+// The shared database isn't the template database itself, as a template database can't be cloned
+// while there are connections to it.
+func (m *Migration) setupShared(ctx context.Context, connString string) (string, error) {
+	template, err := m.setupTemplate(ctx, connString, nil)
+	if err != nil {
+		return "", err
+	}
+	sum, err := m.checksum(nil)
+	if err != nil {
+		return "", err
+	}
+	name := DatabasePrefix + "_shared_" + hex.EncodeToString(sum[:8])
+	err = once(name, func() error {
+		return m.createShared(ctx, name, template, lockKey(sum, 1))
+	})
+	return name, err
+}
+
+// createShared database from the template, unless it already exists.
+func (m *Migration) createShared(ctx context.Context, name, template string, lock int64) error {
+	return withLock(ctx, m.conn, lock, func() error {
+		return m.createSharedLocked(ctx, name, template)
+	})
+}
+
+// createSharedLocked creates the shared database while holding its advisory lock.
+func (m *Migration) createSharedLocked(ctx context.Context, name, template string) error {
+	if m.Options.Force {
+		if _, err := m.conn.Exec(ctx, fmt.Sprintf(`DROP DATABASE IF EXISTS "%s";`, name)); err != nil {
+			return err
+		}
+	}
+	var exists bool
+	if err := m.conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists); err != nil || exists {
+		return err
+	}
+	m.log("creating shared database", slog.String("database", name))
+	_, err := m.conn.Exec(ctx, fmt.Sprintf(`CREATE DATABASE "%s" TEMPLATE "%s";`, name, template))
+	return err
+}
+
+// teardownTx rolls back the transaction of a test using transaction isolation.
+func (m *Migration) teardownTx(ctx context.Context) {
+	m.t.Helper()
+	defer m.conn.Close(ctx)
+	if m.txConn == nil {
+		return
+	}
+	defer m.txConn.Close(ctx)
+	if m.tx != nil {
+		if err := m.tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			m.t.Fatalf("cannot rollback transaction: %v", err)
+		}
+	}
+}
+
+// begin a transaction on the database, or a savepoint when using transaction isolation.
+func (m *Migration) begin(ctx context.Context) (pgx.Tx, error) {
+	if m.tx != nil {
+		return m.tx.Begin(ctx)
+	}
+	return m.pool.Begin(ctx)
+}
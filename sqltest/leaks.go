@@ -0,0 +1,94 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LeakMode for connections and transactions left open by the test.
+type LeakMode int
+
+const (
+	// NoLeakCheck disables checking for leaked connections during teardown.
+	NoLeakCheck LeakMode = iota
+
+	// LeakWarn logs leaked connections and open transactions with t.Log.
+	LeakWarn
+
+	// LeakStrict fails the test with t.Error when leaked connections or open transactions are found.
+	LeakStrict
+)
+
+// leakGracePeriod is how long to wait for the server to end the sessions of closed connections
+// before considering them leaked.
+const leakGracePeriod = time.Second
+
+// session connected to the database of the test.
+type session struct {
+	pid   int32
+	state string
+	app   string
+	query string
+}
+
+func (s session) String() string {
+	return fmt.Sprintf("pid %d (%s, application_name %q): %s", s.pid, s.state, s.app, s.query)
+}
+
+// closeAndCheckLeaks closes the pool, and reports connections to the database of the test left open,
+// according to the Leaks option. Leaked connections are terminated, so that the database can be dropped.
+//
+// The pool isn't closed if connections acquired from it weren't released, as closing it would
+// wait for them forever.
+func (m *Migration) closeAndCheckLeaks(ctx context.Context) {
+	m.t.Helper()
+	report := m.t.Logf
+	if m.Options.Leaks == LeakStrict {
+		report = m.t.Errorf
+	}
+	if n := m.pool.Stat().AcquiredConns(); n > 0 {
+		report("leak: %d connections acquired from the pool weren't released, as when rows aren't closed", n)
+	} else {
+		m.pool.Close()
+	}
+
+	var (
+		sessions []session
+		err      error
+	)
+	for deadline := time.Now().Add(leakGracePeriod); ; time.Sleep(50 * time.Millisecond) {
+		if sessions, err = m.sessions(ctx); err != nil {
+			m.t.Errorf("cannot check for leaked connections: %v", err)
+			return
+		}
+		if len(sessions) == 0 || time.Now().After(deadline) {
+			break
+		}
+	}
+	for _, s := range sessions {
+		report("leak: connection left open by %v", s)
+	}
+	if len(sessions) != 0 {
+		if _, err := m.conn.Exec(ctx, "SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()", m.database); err != nil {
+			m.t.Errorf("cannot terminate leaked connections: %v", err)
+		}
+	}
+}
+
+// sessions connected to the database of the test.
+func (m *Migration) sessions(ctx context.Context) ([]session, error) {
+	rows, err := m.conn.Query(ctx, `SELECT pid, coalesce(state, ''), application_name, query
+		FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()
+		ORDER BY pid`, m.database)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (session, error) {
+		var s session
+		err := row.Scan(&s.pid, &s.state, &s.app, &s.query)
+		return s, err
+	})
+}
@@ -0,0 +1,212 @@
+package sqltest
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// LintMode for the migration files.
+type LintMode int
+
+const (
+	// NoLint disables linting the migration files.
+	NoLint LintMode = iota
+
+	// LintWarn logs risky statements found in the migration files with t.Log.
+	LintWarn
+
+	// LintStrict fails the test with t.Error when risky statements are found in the migration files.
+	LintStrict
+)
+
+// lintIssue found in a migration file.
+type lintIssue struct {
+	file    string
+	line    int
+	message string
+}
+
+func (i lintIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s", i.file, i.line, i.message)
+}
+
+// lint the migration files, reporting the issues found according to the Lint option.
+func (m *Migration) lint() error {
+	m.t.Helper()
+	if m.Options.Lint == NoLint {
+		return nil
+	}
+	var issues []lintIssue
+	if m.migrator != nil {
+		for _, mig := range m.migrator.Migrations {
+			issues = append(issues, lintUp(mig.Name, mig.UpSQL)...)
+			issues = append(issues, lintDown(mig.Name, mig.DownSQL)...)
+		}
+	} else if m.Options.Format == PlainSQLFormat {
+		err := fs.WalkDir(m.Options.Files, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || path.Ext(name) != ".sql" {
+				return err
+			}
+			b, err := fs.ReadFile(m.Options.Files, name)
+			issues = append(issues, lintUp(name, string(b))...)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("cannot lint migrations: %w", err)
+		}
+	}
+	for _, issue := range issues {
+		if m.Options.Lint == LintStrict {
+			m.t.Errorf("lint: %v", issue)
+		} else {
+			m.t.Logf("lint: %v", issue)
+		}
+	}
+	return nil
+}
+
+var (
+	dropPattern        = regexp.MustCompile(`(?i)^DROP\s+(TABLE|INDEX|VIEW|MATERIALIZED\s+VIEW|SEQUENCE|TYPE|DOMAIN|FUNCTION|PROCEDURE|SCHEMA|TRIGGER|EXTENSION)\s+(IF\s+EXISTS\b)?`)
+	dropTablePattern   = regexp.MustCompile(`(?i)^DROP\s+TABLE\b`)
+	dropColumnPattern  = regexp.MustCompile(`(?i)\bDROP\s+COLUMN\b`)
+	alterTypePattern   = regexp.MustCompile(`(?i)\bALTER\s+COLUMN\s+\S+\s+(SET\s+DATA\s+)?TYPE\b`)
+	usingPattern       = regexp.MustCompile(`(?i)\bUSING\b`)
+	createIndexPattern = regexp.MustCompile(`(?i)^CREATE\s+(UNIQUE\s+)?INDEX\s+(CONCURRENTLY\b)?.*?\bON\s+(ONLY\s+)?([\w."]+)`)
+	createTablePattern = regexp.MustCompile(`(?i)^CREATE\s+(UNLOGGED\s+)?TABLE\s+(IF\s+NOT\s+EXISTS\s+)?([\w."]+)`)
+)
+
+// lintUp migration for risky statements.
+func lintUp(file, sql string) []lintIssue {
+	var (
+		issues  []lintIssue
+		created = map[string]bool{} // Tables created by the migration.
+	)
+	for _, s := range splitStatements(sql) {
+		report := func(message string) {
+			issues = append(issues, lintIssue{file: file, line: s.line, message: message})
+		}
+		if matches := createTablePattern.FindStringSubmatch(s.sql); matches != nil {
+			created[normalizeName(matches[3])] = true
+		}
+		if dropTablePattern.MatchString(s.sql) {
+			report("DROP TABLE loses data, and breaks code still using the table")
+		}
+		if matches := dropPattern.FindStringSubmatch(s.sql); matches != nil && matches[2] == "" {
+			report(fmt.Sprintf("DROP %s without IF EXISTS", strings.ToUpper(matches[1])))
+		}
+		if dropColumnPattern.MatchString(s.sql) {
+			report("DROP COLUMN loses data, and breaks code still using the column")
+		}
+		if alterTypePattern.MatchString(s.sql) && !usingPattern.MatchString(s.sql) {
+			report("changing the type of a column without USING might fail or rewrite the table")
+		}
+		// Creating an index on a table created by the same migration doesn't lock anything in use.
+		if matches := createIndexPattern.FindStringSubmatch(s.sql); matches != nil && matches[2] == "" && !created[normalizeName(matches[4])] {
+			report("CREATE INDEX without CONCURRENTLY blocks writes to the table while the index is built")
+		}
+	}
+	return issues
+}
+
+// lintDown migration for DROP statements without IF EXISTS.
+func lintDown(file, sql string) []lintIssue {
+	var issues []lintIssue
+	for _, s := range splitStatements(sql) {
+		if matches := dropPattern.FindStringSubmatch(s.sql); matches != nil && matches[2] == "" {
+			issues = append(issues, lintIssue{
+				file:    file,
+				line:    s.line,
+				message: fmt.Sprintf("DROP %s without IF EXISTS", strings.ToUpper(matches[1])),
+			})
+		}
+	}
+	return issues
+}
+
+// normalizeName of a table for comparison, removing quotes and the public schema.
+func normalizeName(name string) string {
+	name = strings.ReplaceAll(name, `"`, "")
+	return strings.TrimPrefix(name, "public.")
+}
+
+// statement of a SQL script.
+type statement struct {
+	sql  string // Without comments, and with the contents of strings blanked.
+	line int
+}
+
+// splitStatements of a SQL script.
+// Comments are removed and the contents of string constants, including dollar-quoted strings,
+// are replaced by spaces so that they're not mistaken for SQL.
+func splitStatements(sql string) []statement {
+	var (
+		statements []statement
+		sb         strings.Builder
+		line       = 1
+		start      = 0 // Line where the current statement starts.
+	)
+	flush := func() {
+		if s := strings.TrimSpace(sb.String()); s != "" {
+			statements = append(statements, statement{sql: s, line: start})
+		}
+		sb.Reset()
+		start = 0
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		if start == 0 && !strings.ContainsRune(" \t\r\n;", rune(c)) && !strings.HasPrefix(sql[i:], "--") && !strings.HasPrefix(sql[i:], "/*") {
+			start = line
+		}
+		switch {
+		case c == '\n':
+			line++
+			sb.WriteByte(c)
+		case c == ';':
+			flush()
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end == -1 {
+				end = len(sql) - i
+			}
+			i += end - 1
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				end = len(sql) - i - 2
+			}
+			line += strings.Count(sql[i:i+2+end], "\n")
+			i += end + 3
+			sb.WriteByte(' ')
+		case c == '\'':
+			end := strings.IndexByte(sql[i+1:], '\'')
+			if end == -1 {
+				end = len(sql) - i - 1
+			}
+			line += strings.Count(sql[i:i+1+end], "\n")
+			i += end + 1
+			sb.WriteString("''")
+		case c == '$':
+			tag := dollarTag.FindString(sql[i:])
+			if tag == "" {
+				sb.WriteByte(c)
+				continue
+			}
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end == -1 {
+				end = len(sql) - i - len(tag)
+			}
+			line += strings.Count(sql[i:i+len(tag)+end], "\n")
+			i += len(tag) + end + len(tag) - 1
+			sb.WriteString("''")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+var dollarTag = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z_0-9]*)?\$`)
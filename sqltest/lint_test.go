@@ -0,0 +1,70 @@
+package sqltest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintUp(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc string
+		sql  string
+		want []lintIssue
+	}{
+		{
+			desc: "safe",
+			sql: `CREATE TABLE posts (id text PRIMARY KEY);
+CREATE INDEX posts_id ON posts (id);
+CREATE INDEX CONCURRENTLY media_name ON media (name);
+ALTER TABLE media ALTER COLUMN id TYPE bigint USING id::bigint;`,
+		},
+		{
+			desc: "risky",
+			sql: `-- Risky statements.
+ALTER TABLE media DROP COLUMN name;
+ALTER TABLE media ALTER COLUMN id TYPE bigint;
+CREATE UNIQUE INDEX media_name ON public.media (name);
+
+DROP TABLE settings;`,
+			want: []lintIssue{
+				{file: "001_risky.sql", line: 2, message: "DROP COLUMN loses data, and breaks code still using the column"},
+				{file: "001_risky.sql", line: 3, message: "changing the type of a column without USING might fail or rewrite the table"},
+				{file: "001_risky.sql", line: 4, message: "CREATE INDEX without CONCURRENTLY blocks writes to the table while the index is built"},
+				{file: "001_risky.sql", line: 6, message: "DROP TABLE loses data, and breaks code still using the table"},
+				{file: "001_risky.sql", line: 6, message: "DROP TABLE without IF EXISTS"},
+			},
+		},
+		{
+			desc: "strings and comments",
+			sql: `/* DROP TABLE posts; */
+INSERT INTO notes (text) VALUES ('DROP TABLE posts;');
+CREATE FUNCTION f() RETURNS void AS $$
+BEGIN
+	DROP TABLE IF EXISTS posts;
+END;
+$$ LANGUAGE plpgsql;
+DROP INDEX IF EXISTS media_name;`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			if got := lintUp("001_"+tc.desc+".sql", tc.sql); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected issues to be %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLintDown(t *testing.T) {
+	t.Parallel()
+	got := lintDown("002_settings.sql", "DROP TABLE IF EXISTS settings;\nDROP TYPE status_type;")
+	want := []lintIssue{
+		{file: "002_settings.sql", line: 2, message: "DROP TYPE without IF EXISTS"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected issues to be %v, got %v instead", want, got)
+	}
+}
@@ -0,0 +1,31 @@
+package sqltest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// withLock calls fn holding a session advisory lock with the given key on the connection,
+// serializing it with other tests and test binaries, such as those of other packages
+// run concurrently by go test ./...
+func withLock(ctx context.Context, conn *pgx.Conn, key int64, fn func() error) (err error) {
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		return fmt.Errorf("cannot acquire lock: %w", err)
+	}
+	defer func() {
+		if _, unlockErr := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", key); unlockErr != nil && err == nil {
+			err = fmt.Errorf("cannot release lock: %w", unlockErr)
+		}
+	}()
+	return fn()
+}
+
+// databaseLockKey returns the key of the advisory lock for creating or dropping the database with the given name.
+func databaseLockKey(name string) int64 {
+	sum := sha256.Sum256([]byte("database " + name))
+	return int64(binary.BigEndian.Uint64(sum[:8]))
+}
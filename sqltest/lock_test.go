@@ -0,0 +1,53 @@
+package sqltest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestWithLock(t *testing.T) {
+	if os.Getenv("INTEGRATION_TESTDB") != "true" {
+		t.Skip("Skipping tests that require database connection")
+	}
+	t.Parallel()
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, "")
+	if err != nil {
+		t.Fatalf("connection error: %v", err)
+	}
+	defer conn.Close(ctx)
+	other, err := pgx.Connect(ctx, "")
+	if err != nil {
+		t.Fatalf("connection error: %v", err)
+	}
+	defer other.Close(ctx)
+
+	key := databaseLockKey("test_internal_testwithlock")
+	tryLock := func() bool {
+		var locked bool
+		if err := other.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+			t.Fatalf("cannot try lock: %v", err)
+		}
+		if locked {
+			if _, err := other.Exec(ctx, "SELECT pg_advisory_unlock($1)", key); err != nil {
+				t.Fatalf("cannot unlock: %v", err)
+			}
+		}
+		return locked
+	}
+	err = withLock(ctx, conn, key, func() error {
+		if tryLock() {
+			t.Error("expected lock to be held by another session")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+	if !tryLock() {
+		t.Error("expected lock to be released")
+	}
+}
@@ -0,0 +1,84 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// log an event with the Logger option, or with t.Log as the message followed by its attributes.
+// The name of the test is added to the attributes of events logged with the Logger option.
+func (m *Migration) log(msg string, args ...any) {
+	m.t.Helper()
+	if m.Options.Logger != nil {
+		m.Options.Logger.Info(msg, append([]any{slog.String("test", m.t.Name())}, args...)...)
+		return
+	}
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, msg, 0)
+	r.Add(args...)
+	var sb strings.Builder
+	sb.WriteString(msg)
+	r.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&sb, " %s=%v", a.Key, a.Value)
+		return true
+	})
+	m.t.Log(sb.String())
+}
+
+// migrationStep being executed, to log how long it took once it's done.
+type migrationStep struct {
+	sequence  int32
+	name      string
+	direction string
+	sql       string
+	start     time.Time
+}
+
+// startStep of a migration, finishing the previous one.
+func (m *Migration) startStep(sequence int32, name, direction, sql string) {
+	m.t.Helper()
+	m.finishStep(nil)
+	if m.Options.Logger == nil {
+		m.t.Logf("executing %s %s\n", name, direction)
+	}
+	m.step = &migrationStep{sequence: sequence, name: name, direction: direction, sql: sql, start: time.Now()}
+	if m.Options.OnStart != nil {
+		m.Options.OnStart(sequence, name, direction, sql)
+	}
+}
+
+// stepError adds the migration being executed to an error caused by the context being done,
+// as when the SetupTimeout option is exceeded.
+func (m *Migration) stepError(ctx context.Context, err error) error {
+	if m.step == nil || ctx.Err() == nil {
+		return err
+	}
+	return fmt.Errorf("%w (migration %s %s was running for %v)", err, m.step.name, m.step.direction, time.Since(m.step.start).Round(time.Millisecond))
+}
+
+// finishStep of a migration, logging it with the Logger option.
+// If err isn't nil, the migration failed, and the OnFinish option isn't called.
+func (m *Migration) finishStep(err error) {
+	if m.step == nil {
+		return
+	}
+	s := m.step
+	m.step = nil
+	if m.Options.Logger != nil {
+		attrs := []any{
+			slog.String("test", m.t.Name()),
+			slog.String("name", s.name),
+			slog.String("direction", s.direction),
+			slog.Duration("duration", time.Since(s.start)),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.Any("error", err))
+		}
+		m.Options.Logger.Info("migration", attrs...)
+	}
+	if err == nil && m.Options.OnFinish != nil {
+		m.Options.OnFinish(s.sequence, s.name, s.direction, s.sql)
+	}
+}
@@ -0,0 +1,70 @@
+package sqltest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MatrixEnv is the environment variable listing the connection strings of the PostgreSQL servers
+// Matrix runs tests against, separated by semicolons, as in:
+//
+//	SQLTEST_MATRIX="postgres://localhost:5413/test;postgres://localhost:5417/test"
+const MatrixEnv = "SQLTEST_MATRIX"
+
+// Matrix runs fn as a subtest for each PostgreSQL server listed on the SQLTEST_MATRIX environment variable,
+// named after the version of the server, as in postgres13.10, so that compatibility with multiple versions
+// can be checked in one test suite. If it isn't set, fn is called once with an empty connection string,
+// so that the PostgreSQL environment variables are used.
+//
+// Use the connection string passed to fn with Setup, as in:
+//
+//	sqltest.Matrix(t, func(t *testing.T, connString string) {
+//		migration := sqltest.New(t, sqltest.Options{Files: os.DirFS("migrations")})
+//		pool := migration.Setup(context.Background(), connString)
+//		// ...
+//	})
+//
+// To run disposable servers with different versions, see the sqltest/container package.
+func Matrix(t *testing.T, fn func(t *testing.T, connString string)) {
+	t.Helper()
+	endpoints := []string{""}
+	if env := os.Getenv(MatrixEnv); env != "" {
+		endpoints = endpoints[:0]
+		for _, e := range strings.Split(env, ";") {
+			if e = strings.TrimSpace(e); e != "" {
+				endpoints = append(endpoints, e)
+			}
+		}
+	}
+	for n, connString := range endpoints {
+		connString := connString
+		name, err := serverVersion(context.Background(), connString)
+		if err != nil {
+			t.Errorf("cannot get version of PostgreSQL server #%d: %v", n+1, err)
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			fn(t, connString)
+		})
+	}
+}
+
+// serverVersion returns the name of the PostgreSQL server with its version, as in postgres15.2.
+func serverVersion(ctx context.Context, connString string) (string, error) {
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close(ctx)
+	// Versions might be followed by details of the distribution, as in 15.2 (Debian 15.2-1.pgdg110+1).
+	version := strings.Fields(conn.PgConn().ParameterStatus("server_version"))
+	if len(version) == 0 {
+		return "", errors.New("unknown server version")
+	}
+	return "postgres" + version[0], nil
+}
@@ -0,0 +1,92 @@
+package sqltest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// MergeFiles returns a filesystem merging the files of multiple sources, for use with the Files option,
+// as in a core schema shared by multiple services followed by the migrations of a service:
+//
+//	Files: sqltest.MergeFiles(core.Migrations, os.DirFS("migrations"))
+//
+// Directories existing in more than one source are merged. Reading a directory fails if a file exists
+// in more than one source, or if migration files of different sources have the same sequence number,
+// as in 003_users.sql and 003_posts.sql.
+func MergeFiles(files ...fs.FS) fs.FS {
+	return mergedFS(files)
+}
+
+// mergedFS is a filesystem merging multiple sources.
+type mergedFS []fs.FS
+
+// Open the file from the first source where it exists.
+func (m mergedFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, files := range m {
+		f, err := files.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return f, err
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+var sequencePattern = regexp.MustCompile(`\A(\d+)_`)
+
+// ReadDir merges the entries of the directory in all sources, sorted by name.
+func (m mergedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var (
+		entries  []fs.DirEntry
+		found    bool
+		sources  = map[string]int{}    // Source of each entry by name.
+		sequence = map[uint64]string{} // Migration file of each sequence number of the top-level directory.
+	)
+	for n, files := range m {
+		dir, err := fs.ReadDir(files, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, e := range dir {
+			if prev, ok := sources[e.Name()]; ok {
+				if e.IsDir() {
+					continue
+				}
+				return nil, fmt.Errorf("file %s exists in sources %d and %d", e.Name(), prev, n)
+			}
+			sources[e.Name()] = n
+			entries = append(entries, e)
+
+			matches := sequencePattern.FindStringSubmatch(e.Name())
+			if name != "." || e.IsDir() || matches == nil {
+				continue
+			}
+			seq, err := strconv.ParseUint(matches[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			if other, ok := sequence[seq]; ok && sources[other] != n {
+				return nil, fmt.Errorf("migrations %s and %s of sources %d and %d have the same sequence number", other, e.Name(), sources[other], n)
+			}
+			sequence[seq] = e.Name()
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
@@ -0,0 +1,77 @@
+package sqltest
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMergeFiles(t *testing.T) {
+	t.Parallel()
+	core := fstest.MapFS{
+		"001_users.sql":      {Data: []byte("CREATE TABLE users (id text);")},
+		"002_settings.sql":   {Data: []byte("CREATE TABLE settings (id text);")},
+		"shared/columns.sql": {Data: []byte("id text")},
+	}
+	testCases := []struct {
+		desc    string
+		files   fs.FS
+		want    []string
+		wantErr string
+	}{
+		{
+			desc: "merged",
+			files: MergeFiles(core, fstest.MapFS{
+				"003_posts.sql":    {Data: []byte("CREATE TABLE posts (id text);")},
+				"shared/names.sql": {Data: []byte("name text")},
+			}),
+			want: []string{
+				".",
+				"001_users.sql",
+				"002_settings.sql",
+				"003_posts.sql",
+				"shared",
+				"shared/columns.sql",
+				"shared/names.sql",
+			},
+		},
+		{
+			desc: "sequence",
+			files: MergeFiles(core, fstest.MapFS{
+				"002_posts.sql": {Data: []byte("CREATE TABLE posts (id text);")},
+			}),
+			wantErr: "migrations 002_settings.sql and 002_posts.sql of sources 0 and 1 have the same sequence number",
+		},
+		{
+			desc: "duplicate",
+			files: MergeFiles(core, fstest.MapFS{
+				"shared/columns.sql": {Data: []byte("name text")},
+			}),
+			wantErr: "file columns.sql exists in sources 0 and 1",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			var got []string
+			err := fs.WalkDir(tc.files, ".", func(name string, d fs.DirEntry, err error) error {
+				got = append(got, name)
+				return err
+			})
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("expected error to be %q, got %v instead", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v instead", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected files to be %q, got %q instead", tc.want, got)
+			}
+		})
+	}
+}
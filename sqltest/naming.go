@@ -0,0 +1,61 @@
+package sqltest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+)
+
+// Naming of the temporary database or schema created for a test.
+type Naming int
+
+const (
+	// TestNaming names the temporary database after the test, prefixed by TemporaryDatabasePrefix.
+	// Tests with the same name in different packages collide if they run at the same time.
+	TestNaming Naming = iota
+
+	// PackageNaming appends a short hash of the directory of the package being tested to the name,
+	// so that tests with the same name in different packages don't collide.
+	PackageNaming
+
+	// RandomNaming appends a random suffix to the name, so that the same test can run
+	// concurrently, as with go test -count. The Force option can't drop databases left behind
+	// with random names, so consider using it with CleanupOrphans.
+	RandomNaming
+)
+
+// maxIdentifierLength of PostgreSQL. Longer names are truncated.
+const maxIdentifierLength = 63
+
+// temporaryName returns the name of the temporary database or schema of the test.
+func (m *Migration) temporaryName() (string, error) {
+	m.t.Helper()
+	name := m.Options.TemporaryDatabasePrefix + SQLTestName(m.t)
+	var suffix string
+	switch m.Options.Naming {
+	case PackageNaming:
+		// Tests run in the directory of the package being tested.
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256([]byte(wd))
+		suffix = "_" + hex.EncodeToString(sum[:4])
+	case RandomNaming:
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		suffix = "_" + hex.EncodeToString(b)
+	default:
+		return name, nil
+	}
+	// Truncate the name instead of the suffix when it's too long.
+	if len(name)+len(suffix) > maxIdentifierLength {
+		name = name[:maxIdentifierLength-len(suffix)]
+	}
+	m.log("naming temporary database", slog.String("test", m.t.Name()), slog.String("database", name+suffix))
+	return name + suffix, nil
+}
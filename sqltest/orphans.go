@@ -0,0 +1,94 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// orphanComment prefixes the comment set on temporary databases to record when they were created,
+// so that they can be told apart from other databases by CleanupOrphans.
+const orphanComment = "sqltest created_at="
+
+// markTemporary database with its creation time.
+func markTemporary(ctx context.Context, conn *pgx.Conn, name string) error {
+	comment := orphanComment + time.Now().UTC().Format(time.RFC3339)
+	_, err := conn.Exec(ctx, fmt.Sprintf(`COMMENT ON DATABASE "%s" IS '%s';`, name, comment))
+	return err
+}
+
+// CleanupOrphans drops temporary databases created by tests longer than olderThan ago that were left behind,
+// as when a test run crashes or is interrupted, or by the KeepOnFailure option.
+// It returns the names of the dropped databases.
+//
+// Only databases created by Setup starting with DatabasePrefix and not in use are dropped.
+// Template and shared databases are kept.
+func CleanupOrphans(ctx context.Context, connString string, olderThan time.Duration) ([]string, error) {
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	rows, err := conn.Query(ctx, `SELECT d.datname, shobj_description(d.oid, 'pg_database')
+		FROM pg_database d
+		WHERE NOT d.datistemplate AND starts_with(d.datname, $1)
+		AND starts_with(shobj_description(d.oid, 'pg_database'), $2)
+		AND NOT EXISTS (SELECT 1 FROM pg_stat_activity a WHERE a.datid = d.oid)
+		ORDER BY d.datname`, DatabasePrefix, orphanComment)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list databases: %w", err)
+	}
+	candidates, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) ([2]string, error) {
+		var c [2]string
+		err := row.Scan(&c[0], &c[1])
+		return c, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list databases: %w", err)
+	}
+
+	var dropped []string
+	for _, c := range candidates {
+		created, err := time.Parse(time.RFC3339, strings.TrimPrefix(c[1], orphanComment))
+		if err != nil || time.Since(created) < olderThan {
+			continue
+		}
+		err = withLock(ctx, conn, databaseLockKey(c[0]), func() error {
+			_, err := conn.Exec(ctx, fmt.Sprintf(`DROP DATABASE IF EXISTS "%s";`, c[0]))
+			return err
+		})
+		if err != nil {
+			return dropped, fmt.Errorf("cannot drop database %q: %w", c[0], err)
+		}
+		dropped = append(dropped, c[0])
+	}
+	return dropped, nil
+}
+
+// cleanupOrphansOnce guards against dropping orphaned databases more than once per process,
+// as tests running in parallel would otherwise race to drop the same databases.
+var cleanupOrphansOnce sync.Once
+
+// cleanupOrphans drops orphaned databases when the CleanupOrphans option is set.
+// Errors are only logged, as they don't affect the test.
+func (m *Migration) cleanupOrphans(ctx context.Context, connString string) {
+	m.t.Helper()
+	if m.Options.CleanupOrphans <= 0 {
+		return
+	}
+	cleanupOrphansOnce.Do(func() {
+		dropped, err := CleanupOrphans(ctx, connString, m.Options.CleanupOrphans)
+		if err != nil {
+			m.t.Logf("cannot clean up orphaned databases: %v", err)
+		}
+		for _, name := range dropped {
+			m.log("dropped orphaned database", slog.String("database", name))
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package sqltest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// restoreDump restores the files of the PgDump option on the database.
+//
+// A new connection is used, as pg_dump files change settings of the session, such as the search_path.
+func (m *Migration) restoreDump(ctx context.Context, config *pgx.ConnConfig) error {
+	if m.Options.PgDump == nil {
+		return nil
+	}
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	return fs.WalkDir(m.Options.PgDump, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != ".sql" {
+			return err
+		}
+		f, err := m.Options.PgDump.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		m.log("restoring pg_dump file", slog.String("name", name))
+		if err := restoreDumpFile(ctx, conn, f); err != nil {
+			return fmt.Errorf("cannot restore %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+var copyFromStdinPattern = regexp.MustCompile(`(?i)^COPY\s.+\sFROM\s+stdin;\s*$`)
+
+// restoreDumpFile in the plain format of pg_dump.
+//
+// SQL statements are executed as they are, except for COPY ... FROM stdin statements, which are executed with
+// the data lines following them until the \. line, and psql meta-commands, such as \connect, which are ignored.
+func restoreDumpFile(ctx context.Context, conn *pgx.Conn, r io.Reader) error {
+	var (
+		br  = bufio.NewReader(r)
+		sql strings.Builder
+	)
+	flush := func() error {
+		defer sql.Reset()
+		if strings.TrimSpace(sql.String()) == "" {
+			return nil
+		}
+		_, err := conn.Exec(ctx, sql.String())
+		return err
+	}
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		switch trimmed := strings.TrimSpace(line); {
+		case copyFromStdinPattern.MatchString(trimmed):
+			if err := flush(); err != nil {
+				return err
+			}
+			if err := copyFromDump(ctx, conn, strings.TrimSuffix(trimmed, ";"), br); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, `\`):
+			// Ignore psql meta-commands.
+		default:
+			sql.WriteString(line)
+		}
+		if errors.Is(err, io.EOF) {
+			return flush()
+		}
+	}
+}
+
+// copyFromDump executes the COPY ... FROM stdin statement with the data lines read from the dump,
+// which end with a \. line.
+func copyFromDump(ctx context.Context, conn *pgx.Conn, sql string, br *bufio.Reader) error {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.PgConn().CopyFrom(ctx, pr, sql)
+		// Unblock writing the remaining data lines if COPY fails.
+		pr.CloseWithError(err)
+		done <- err
+	}()
+	var readErr error
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			readErr = err
+			break
+		}
+		if strings.TrimRight(line, "\r\n") == `\.` {
+			break
+		}
+		if errors.Is(err, io.EOF) {
+			readErr = errors.New(`missing \. at the end of the COPY data`)
+			break
+		}
+		if _, err := io.WriteString(pw, line); err != nil {
+			break
+		}
+	}
+	if readErr != nil {
+		pw.CloseWithError(readErr)
+	} else {
+		pw.Close()
+	}
+	if err := <-done; err != nil {
+		return fmt.Errorf("cannot execute %s: %w", sql, err)
+	}
+	return readErr
+}
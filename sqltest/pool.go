@@ -0,0 +1,85 @@
+package sqltest
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// checkout a database from the databases shared by the tests with the SharedDatabases option,
+// creating it from the template database if it doesn't exist yet, and returns its name.
+//
+// A database is checked out by holding an advisory lock on m.conn until teardown,
+// so a database isn't used by two tests at the same time, even on different processes.
+// It's reset with TRUNCATE before being used.
+func (m *Migration) checkout(ctx context.Context, connString string) (string, error) {
+	template, err := m.setupTemplate(ctx, connString, nil)
+	if err != nil {
+		return "", err
+	}
+	sum, err := m.checksum(nil)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for n := 0; n < m.Options.SharedDatabases; n++ {
+			// The first keys are used by the template and transaction isolation databases.
+			lock := lockKey(sum, int64(2+n))
+			var ok bool
+			if err := m.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", lock).Scan(&ok); err != nil {
+				return "", fmt.Errorf("cannot acquire lock: %w", err)
+			}
+			if !ok {
+				continue
+			}
+			name := DatabasePrefix + "_pool_" + hex.EncodeToString(sum[:8]) + "_" + strconv.Itoa(n)
+			err := once(name, func() error {
+				return m.createShared(ctx, name, template, lock)
+			})
+			if err == nil {
+				err = m.resetDB(ctx, connString, name)
+			}
+			if err != nil {
+				_, _ = m.conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lock)
+				return "", err
+			}
+			// The shared databases are clones of the template database, so they're already migrated.
+			m.template, m.shared = template, true
+			return name, nil
+		}
+		// Wait for a database to be returned.
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// resetDB removes the data written to a shared database by a previous test.
+func (m *Migration) resetDB(ctx context.Context, connString, name string) error {
+	config, err := m.parseConfig(connString)
+	if err != nil {
+		return err
+	}
+	config.Database = name
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	if err := truncateAll(ctx, conn, m.schemaVersionTable(), false); err != nil {
+		return fmt.Errorf("cannot reset database: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "DROP SCHEMA IF EXISTS "+snapshotSchema+" CASCADE"); err != nil {
+		return err
+	}
+	if m.Options.FakeClock {
+		_, err = conn.Exec(ctx, "DELETE FROM "+clockSchema+".fake_time")
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+package sqltest
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Query executed by the code under test, recorded with the RecordQueries option.
+type Query struct {
+	SQL  string
+	Args []any
+}
+
+// recorder of the queries executed on the connections of the test.
+// It implements pgx.QueryTracer.
+type recorder struct {
+	mu      sync.Mutex
+	enabled bool // Queries executed during setup, such as migrations, aren't recorded.
+	queries []Query
+}
+
+// TraceQueryStart records the query.
+func (r *recorder) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.enabled {
+		r.queries = append(r.queries, Query{SQL: data.SQL, Args: data.Args})
+	}
+	return ctx
+}
+
+// TraceQueryEnd is a no-op.
+func (r *recorder) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {}
+
+// tracer to install on the connections of the test, if the RecordQueries or Tracer options are set.
+func (m *Migration) tracer() pgx.QueryTracer {
+	if !m.Options.RecordQueries {
+		return m.Options.Tracer
+	}
+	if m.recorder == nil {
+		m.recorder = &recorder{}
+	}
+	if m.Options.Tracer == nil {
+		return m.recorder
+	}
+	return queryTracers{m.recorder, m.Options.Tracer}
+}
+
+// queryTracers calling each of the tracers in order.
+type queryTracers []pgx.QueryTracer
+
+// TraceQueryStart calls TraceQueryStart of each tracer, passing the context returned by one to the next.
+func (tracers queryTracers) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	for _, t := range tracers {
+		ctx = t.TraceQueryStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+// TraceQueryEnd calls TraceQueryEnd of each tracer.
+func (tracers queryTracers) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	for _, t := range tracers {
+		t.TraceQueryEnd(ctx, conn, data)
+	}
+}
+
+// startRecording the queries, once the setup is done.
+func (m *Migration) startRecording() {
+	if m.recorder == nil {
+		return
+	}
+	m.recorder.mu.Lock()
+	m.recorder.enabled = true
+	m.recorder.mu.Unlock()
+}
+
+// Queries returns the queries executed since Setup or SetupTx returned, or since ResetQueries was called,
+// in the order they were executed. It requires the RecordQueries option.
+func (m *Migration) Queries() []Query {
+	m.t.Helper()
+	if m.recorder == nil {
+		m.t.Fatal("Queries requires the RecordQueries option")
+	}
+	m.recorder.mu.Lock()
+	defer m.recorder.mu.Unlock()
+	return append([]Query(nil), m.recorder.queries...)
+}
+
+// ResetQueries forgets the recorded queries.
+func (m *Migration) ResetQueries() {
+	m.t.Helper()
+	if m.recorder == nil {
+		m.t.Fatal("ResetQueries requires the RecordQueries option")
+	}
+	m.recorder.mu.Lock()
+	defer m.recorder.mu.Unlock()
+	m.recorder.queries = nil
+}
+
+// AssertQuery checks that a recorded query matches the regular expression.
+// If none does, t.Error is called with the recorded queries.
+func (m *Migration) AssertQuery(re *regexp.Regexp) {
+	m.t.Helper()
+	queries := m.Queries()
+	for _, q := range queries {
+		if re.MatchString(q.SQL) {
+			return
+		}
+	}
+	var sb strings.Builder
+	for _, q := range queries {
+		sb.WriteString("\n\t" + q.SQL)
+	}
+	m.t.Errorf("expected a query matching %q, got %d queries instead:%s", re, len(queries), sb.String())
+}
@@ -0,0 +1,88 @@
+package sqltest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// minReadyBackoff is the time waited before retrying to connect for the first time.
+	minReadyBackoff = 100 * time.Millisecond
+
+	// maxReadyBackoff is the maximum time waited between retries.
+	maxReadyBackoff = 2 * time.Second
+)
+
+// waitReady retries connecting to the database with exponential backoff until it succeeds
+// or the WaitReady duration elapses, returning the last error.
+func (m *Migration) waitReady(ctx context.Context, connString string) error {
+	if m.Options.WaitReady <= 0 {
+		return nil
+	}
+	config, err := m.parseConfig(connString)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.Options.WaitReady)
+	defer cancel()
+	backoff := minReadyBackoff
+	for {
+		if err = ping(ctx, config); err == nil {
+			return nil
+		}
+		m.log("waiting for PostgreSQL", slog.Duration("backoff", backoff), slog.Any("error", err))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not ready after %v: %w", m.Options.WaitReady, err)
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxReadyBackoff {
+			backoff = maxReadyBackoff
+		}
+	}
+}
+
+// setupContext returns a context limited by the SetupTimeout option.
+func (m *Migration) setupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.Options.SetupTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, m.Options.SetupTimeout)
+}
+
+// setupError returns err, or an error wrapping context.DeadlineExceeded if the setup failed
+// because the SetupTimeout option was exceeded.
+func (m *Migration) setupError(ctx context.Context, err error) error {
+	if err == nil || m.Options.SetupTimeout <= 0 || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+	}
+	return fmt.Errorf("setup timed out after %v: %w", m.Options.SetupTimeout, err)
+}
+
+// setTimeouts of the connection according to the StatementTimeout and LockTimeout options.
+func (m *Migration) setTimeouts(config *pgx.ConnConfig) {
+	if m.Options.StatementTimeout > 0 {
+		config.RuntimeParams["statement_timeout"] = fmt.Sprintf("%dms", m.Options.StatementTimeout.Milliseconds())
+	}
+	if m.Options.LockTimeout > 0 {
+		config.RuntimeParams["lock_timeout"] = fmt.Sprintf("%dms", m.Options.LockTimeout.Milliseconds())
+	}
+}
+
+// ping the database with a new connection.
+func ping(ctx context.Context, config *pgx.ConnConfig) error {
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	return conn.Ping(ctx)
+}
@@ -0,0 +1,30 @@
+package sqltest
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitReadyTimeout(t *testing.T) {
+	t.Parallel()
+	m := New(t, Options{
+		WaitReady: 300 * time.Millisecond,
+	})
+	start := time.Now()
+	// Nothing is expected to listen on port 1.
+	if err := m.waitReady(context.Background(), "host=127.0.0.1 port=1 connect_timeout=1"); err == nil {
+		t.Error("expected error, got nil instead")
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("expected to retry for at least 300ms, got %v instead", elapsed)
+	}
+}
+
+func TestWaitReadyDisabled(t *testing.T) {
+	t.Parallel()
+	m := New(t, Options{})
+	if err := m.waitReady(context.Background(), "host=127.0.0.1 port=1 connect_timeout=1"); err != nil {
+		t.Errorf("expected no error, got %v instead", err)
+	}
+}
@@ -0,0 +1,99 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SetupRole creates a temporary role with the given privileges on the database of the test, and returns a pgx pool
+// whose connections use it, so that row-level security policies and privileges can be tested.
+// If something fails, t.Fatal is called.
+//
+// Each grant is the part of a GRANT statement before TO, as in:
+//
+//	pool := m.SetupRole(ctx, "reader", "SELECT ON posts", "USAGE ON SEQUENCE posts_id_seq")
+//
+// The role is named after the database or schema of the test followed by name, and the connections of the pool
+// switch to it with SET ROLE, so the user of the connection must be allowed to create roles.
+// The pool is closed and the role is dropped during teardown.
+// TransactionIsolation isn't supported.
+func (m *Migration) SetupRole(ctx context.Context, name string, grants ...string) *pgxpool.Pool {
+	m.t.Helper()
+	if m.pool == nil {
+		m.t.Fatal("SetupRole requires a database set up with Setup or SetupVersion")
+	}
+	role := m.database
+	if m.schema != "" {
+		role = m.schema
+	}
+	role += "_" + name
+	if len(role) > maxIdentifierLength {
+		role = role[:maxIdentifierLength]
+	}
+	if err := m.createRole(ctx, role, grants); err != nil {
+		m.t.Fatalf("cannot create role: %v", err)
+	}
+
+	config := m.pool.Config()
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, "SET ROLE "+quoteIdentifier(role))
+		return err
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		m.t.Fatalf("cannot connect to database: %v", err)
+	}
+	if !m.Options.SkipTeardown {
+		// Cleanup functions are called in the reverse order they're registered,
+		// so the role is dropped before the database.
+		m.t.Cleanup(func() {
+			pool.Close()
+			if err := m.dropRole(context.Background(), role); err != nil {
+				m.t.Errorf("cannot drop role: %v", err)
+			}
+		})
+	}
+	return pool
+}
+
+// createRole with the privileges, and grant it to the current user, so that it can be used with SET ROLE.
+func (m *Migration) createRole(ctx context.Context, role string, grants []string) error {
+	if m.Options.Force {
+		if err := m.dropRole(ctx, role); err != nil {
+			return err
+		}
+	}
+	m.log("creating role", slog.String("role", role))
+	quoted := quoteIdentifier(role)
+	statements := []string{
+		"CREATE ROLE " + quoted + " NOLOGIN",
+		"GRANT " + quoted + " TO CURRENT_USER",
+	}
+	if m.schema != "" {
+		statements = append(statements, "GRANT USAGE ON SCHEMA "+quoteIdentifier(m.schema)+" TO "+quoted)
+	}
+	for _, g := range grants {
+		statements = append(statements, "GRANT "+g+" TO "+quoted)
+	}
+	for _, sql := range statements {
+		if _, err := m.pool.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("%s: %w", sql, err)
+		}
+	}
+	return nil
+}
+
+// dropRole and its privileges on the database, if it exists.
+func (m *Migration) dropRole(ctx context.Context, role string) error {
+	var exists bool
+	if err := m.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1)", role).Scan(&exists); err != nil || !exists {
+		return err
+	}
+	quoted := quoteIdentifier(role)
+	_, err := m.pool.Exec(ctx, "DROP OWNED BY "+quoted+"; DROP ROLE "+quoted)
+	return err
+}
@@ -0,0 +1,223 @@
+package sqltest
+
+import (
+	"context"
+	"strings"
+
+	"github.com/henvic/pgtools/introspect"
+	"github.com/jackc/pgx/v5"
+)
+
+// AssertSchema compares the schema of the database with the golden file,
+// to catch accidental changes to the schema introduced by edited migrations.
+// If they're different, t.Error is called with a diff.
+//
+// The schema is dumped as SQL statements similar to the output of pg_dump --schema-only,
+// but in a stable order and without server-specific details, such as owners, versions, and settings.
+// Use the UpdateGolden option to write the golden file instead, as in:
+//
+//	var update = flag.Bool("update", false, "update golden files")
+//
+//	migration := sqltest.New(t, sqltest.Options{
+//		Files:        os.DirFS("migrations"),
+//		UpdateGolden: *update,
+//	})
+//	migration.Setup(ctx, "")
+//	migration.AssertSchema(ctx, "testdata/schema.golden.sql")
+func (m *Migration) AssertSchema(ctx context.Context, golden string) {
+	m.t.Helper()
+	schema, err := dumpSchema(ctx, m.querier(), m.schemaVersionTable(), m.schema != "")
+	if err != nil {
+		m.t.Fatalf("cannot dump schema: %v", err)
+	}
+	m.golden(golden, []byte(schema))
+}
+
+// querier returns the transaction of a test using transaction isolation, or the pool otherwise.
+func (m *Migration) querier() introspect.Querier {
+	if m.tx != nil {
+		return m.tx
+	}
+	return m.pool
+}
+
+// userObject returns a filter of the objects identified by oid in the namespace n to the ones created by the user,
+// excluding system schemas and objects created by extensions.
+// If scoped is set, only objects in the schemas of the search_path are included, as with SchemaIsolation.
+func userObject(oid string, scoped bool) string {
+	filter := `n.nspname NOT IN ('pg_catalog', 'information_schema', '` + snapshotSchema + `', '` + clockSchema + `')
+	AND n.nspname NOT LIKE 'pg_toast%' AND n.nspname NOT LIKE 'pg_temp%'
+	AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = ` + oid + ` AND d.deptype = 'e')`
+	if scoped {
+		filter += `
+	AND n.nspname = ANY(current_schemas(false))`
+	}
+	return filter
+}
+
+// notVersionTable filters out the schema version table passed as $1 and its migration checksums table.
+func notVersionTable(oid string) string {
+	return oid + ` NOT IN (coalesce(to_regclass($1), 0), coalesce(to_regclass($1 || '` + checksumTableSuffix + `'), 0))`
+}
+
+// schemaQueries return the SQL statements to dump each kind of object in the schema.
+func schemaQueries(scoped bool) []string {
+	return []string{
+		// Extensions.
+		`SELECT format('CREATE EXTENSION %I;', extname) FROM pg_extension WHERE extname <> 'plpgsql' ORDER BY 1`,
+
+		// Schemas.
+		`SELECT format('CREATE SCHEMA %I;', n.nspname) FROM pg_namespace n
+		WHERE n.nspname <> 'public' AND ` + userObject("n.oid", scoped) + ` ORDER BY 1`,
+
+		// Enums.
+		`SELECT format('CREATE TYPE %s AS ENUM (%s);', t.oid::regtype, string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder))
+		FROM pg_type t JOIN pg_enum e ON e.enumtypid = t.oid JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE ` + userObject("t.oid", scoped) + ` GROUP BY t.oid ORDER BY 1`,
+
+		// Composite types.
+		`SELECT format('CREATE TYPE %s AS (%s);', t.oid::regtype,
+			(SELECT string_agg(quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod), ', ' ORDER BY a.attnum)
+			FROM pg_attribute a WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped))
+		FROM pg_type t JOIN pg_class c ON c.oid = t.typrelid JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE t.typtype = 'c' AND c.relkind = 'c' AND ` + userObject("t.oid", scoped) + ` ORDER BY 1`,
+
+		// Domains.
+		`SELECT format('CREATE DOMAIN %s AS %s%s%s%s;', t.oid::regtype, format_type(t.typbasetype, t.typtypmod),
+			CASE WHEN t.typnotnull THEN ' NOT NULL' ELSE '' END,
+			coalesce(' DEFAULT ' || t.typdefault, ''),
+			coalesce((SELECT string_agg(format(' CONSTRAINT %I %s', con.conname, pg_get_constraintdef(con.oid)), '' ORDER BY con.conname)
+			FROM pg_constraint con WHERE con.contypid = t.oid), ''))
+		FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE t.typtype = 'd' AND ` + userObject("t.oid", scoped) + ` ORDER BY 1`,
+
+		// Sequences not owned by serial or identity columns.
+		`SELECT format('CREATE SEQUENCE %s AS %s START %s INCREMENT %s;', c.oid::regclass, format_type(s.seqtypid, NULL), s.seqstart, s.seqincrement)
+		FROM pg_sequence s JOIN pg_class c ON c.oid = s.seqrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = c.oid AND d.deptype IN ('a', 'i'))
+		AND ` + userObject("c.oid", scoped) + ` ORDER BY 1`,
+
+		// Tables, except for the schema version table.
+		`SELECT format(E'CREATE TABLE %s (\n%s\n)%s;', c.oid::regclass,
+			coalesce((SELECT string_agg(E'\t' || quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod)
+				|| CASE a.attidentity WHEN 'a' THEN ' GENERATED ALWAYS AS IDENTITY' WHEN 'd' THEN ' GENERATED BY DEFAULT AS IDENTITY' ELSE '' END
+				|| CASE WHEN a.attgenerated = 's' THEN ' GENERATED ALWAYS AS (' || pg_get_expr(ad.adbin, ad.adrelid) || ') STORED'
+					WHEN ad.adbin IS NOT NULL THEN ' DEFAULT ' || pg_get_expr(ad.adbin, ad.adrelid) ELSE '' END
+				|| CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END, E',\n' ORDER BY a.attnum)
+			FROM pg_attribute a LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+			WHERE a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped), ''),
+			CASE WHEN c.relkind = 'p' THEN ' PARTITION BY ' || pg_get_partkeydef(c.oid) ELSE '' END)
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND ` + notVersionTable("c.oid") + `
+		AND ` + userObject("c.oid", scoped) + ` ORDER BY c.oid::regclass::text`,
+
+		// Partitions.
+		`SELECT format('ALTER TABLE ONLY %s ATTACH PARTITION %s %s;', i.inhparent::regclass, c.oid::regclass, pg_get_expr(c.relpartbound, c.oid))
+		FROM pg_class c JOIN pg_inherits i ON i.inhrelid = c.oid JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND c.relispartition AND ` + userObject("c.oid", scoped) + ` ORDER BY 1`,
+
+		// Constraints, except for NOT NULL constraints (already part of the tables) and constraints inherited by partitions.
+		`SELECT format('ALTER TABLE %s ADD CONSTRAINT %I %s;', con.conrelid::regclass, con.conname, pg_get_constraintdef(con.oid))
+		FROM pg_constraint con JOIN pg_class c ON c.oid = con.conrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE con.contype <> 'n' AND con.conparentid = 0 AND ` + notVersionTable("c.oid") + `
+		AND ` + userObject("c.oid", scoped) + ` ORDER BY 1`,
+
+		// Indexes, except for the ones created by constraints and partitions.
+		`SELECT pg_get_indexdef(i.indexrelid) || ';'
+		FROM pg_index i JOIN pg_class c ON c.oid = i.indrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x'))
+		AND NOT EXISTS (SELECT 1 FROM pg_inherits inh WHERE inh.inhrelid = i.indexrelid)
+		AND ` + notVersionTable("c.oid") + `
+		AND ` + userObject("c.oid", scoped) + ` ORDER BY 1`,
+
+		// Views and materialized views.
+		`SELECT format(E'CREATE %sVIEW %s AS\n%s', CASE WHEN c.relkind = 'm' THEN 'MATERIALIZED ' ELSE '' END, c.oid::regclass, pg_get_viewdef(c.oid))
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('v', 'm') AND ` + userObject("c.oid", scoped) + ` ORDER BY c.oid::regclass::text`,
+
+		// Functions and procedures.
+		`SELECT pg_get_functiondef(p.oid) || ';'
+		FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE p.prokind IN ('f', 'p') AND ` + userObject("p.oid", scoped) + ` ORDER BY p.oid::regprocedure::text`,
+
+		// Triggers.
+		`SELECT pg_get_triggerdef(t.oid) || ';'
+		FROM pg_trigger t JOIN pg_class c ON c.oid = t.tgrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE NOT t.tgisinternal AND ` + userObject("c.oid", scoped) + ` ORDER BY 1`,
+	}
+}
+
+// DumpSchema of the database as SQL statements, in a stable order suitable for comparing databases.
+// Tables, constraints, and indexes of the SchemaVersionTable are omitted.
+//
+// It's not meant to restore a database, as objects might be out of order regarding their dependencies.
+func DumpSchema(ctx context.Context, db introspect.Querier) (string, error) {
+	return dumpSchema(ctx, db, SchemaVersionTable, false)
+}
+
+// dumpSchema of the database omitting the schema version table,
+// or only of the schemas of the search_path if scoped is set.
+func dumpSchema(ctx context.Context, db introspect.Querier, versionTable string, scoped bool) (string, error) {
+	var all []string
+	for _, query := range schemaQueries(scoped) {
+		var args []any
+		if strings.Contains(query, "$1") {
+			args = append(args, versionTable)
+		}
+		rows, err := db.Query(ctx, query, args...)
+		if err != nil {
+			return "", err
+		}
+		statements, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return "", err
+		}
+		all = append(all, statements...)
+	}
+	if len(all) == 0 {
+		return "", nil
+	}
+	return strings.Join(all, "\n\n") + "\n", nil
+}
+
+// AssertRoundTrip checks that every down migration reverses its up migration.
+// If the schema after undoing a migration differs from the schema before applying it, t.Error is called with a diff.
+// If something fails, t.Fatal is called.
+//
+// It migrates the database up from scratch one migration at a time, dumping the schema after each one,
+// then down one migration at a time comparing the schemas, and up to the latest migration again.
+// Data written to the database is lost.
+func (m *Migration) AssertRoundTrip(ctx context.Context) {
+	m.t.Helper()
+	if m.migrator == nil {
+		m.t.Fatal("AssertRoundTrip requires a database set up with Setup or SetupVersion")
+	}
+	latest := int32(len(m.migrator.Migrations))
+	migrateTo := func(version int32) string {
+		m.t.Helper()
+		err := m.migrator.MigrateTo(ctx, version)
+		m.finishStep(err)
+		if err != nil {
+			m.t.Fatalf("cannot migrate database to version %d: %v", version, err)
+		}
+		schema, err := dumpSchema(ctx, m.pool, m.schemaVersionTable(), m.schema != "")
+		if err != nil {
+			m.t.Fatalf("cannot dump schema: %v", err)
+		}
+		return schema
+	}
+
+	schemas := make([]string, latest+1)
+	for v := int32(0); v <= latest; v++ {
+		schemas[v] = migrateTo(v)
+	}
+	for v := latest; v > 0; v-- {
+		if got := migrateTo(v - 1); got != schemas[v-1] {
+			m.t.Errorf("down migration %s doesn't reverse its up migration (-before +after):\n%s",
+				m.migrator.Migrations[v-1].Name, diff(schemas[v-1], got))
+		}
+	}
+	if got := migrateTo(latest); got != schemas[latest] {
+		m.t.Errorf("schema differs after migrating up again (-before +after):\n%s", diff(schemas[latest], got))
+	}
+}
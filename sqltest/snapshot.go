@@ -0,0 +1,213 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// snapshotSchema where the data of the tables is copied to by Snapshot.
+const snapshotSchema = "sqltest_snapshot"
+
+// snapshot of the data of the database.
+type snapshot struct {
+	tables    []snapshotTable
+	sequences []snapshotSequence
+}
+
+type snapshotTable struct {
+	name    string // Qualified and quoted, as returned by regclass.
+	copy    string // Table on the snapshot schema holding the rows.
+	columns []string
+}
+
+type snapshotSequence struct {
+	name   string
+	value  int64
+	called bool
+}
+
+// Snapshot the data of the database, so that it can be reset to this state with Restore.
+// If something fails, t.Fatal is called.
+//
+// This is useful for table-driven subtests that modify data, as in:
+//
+//	migration.Snapshot(ctx)
+//	for _, tc := range testCases {
+//		t.Run(tc.desc, func(t *testing.T) {
+//			defer migration.Restore(ctx)
+//			// ...
+//		})
+//	}
+//
+// The rows of each table are copied to tables on the sqltest_snapshot schema (or the test schema name followed by _snapshot
+// with SchemaIsolation), and the value of each sequence is saved.
+// Changes to the schema itself are not tracked, and tables created after the snapshot are not restored.
+// Calling Snapshot again replaces the previous snapshot.
+func (m *Migration) Snapshot(ctx context.Context) {
+	m.t.Helper()
+	if err := m.takeSnapshot(ctx); err != nil {
+		m.t.Fatalf("cannot snapshot database: %v", err)
+	}
+}
+
+// Restore the data of the database to the state saved by the last call to Snapshot.
+// If something fails, t.Fatal is called.
+func (m *Migration) Restore(ctx context.Context) {
+	m.t.Helper()
+	if m.snapshot == nil {
+		m.t.Fatal("cannot restore database: no snapshot")
+	}
+	if err := m.restoreSnapshot(ctx); err != nil {
+		m.t.Fatalf("cannot restore database: %v", err)
+	}
+}
+
+func (m *Migration) takeSnapshot(ctx context.Context) error {
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Rolling back a transaction that was committed is a no-op.
+		_ = tx.Rollback(ctx)
+	}()
+
+	schema := m.snapshotSchema()
+	if _, err := tx.Exec(ctx, "DROP SCHEMA IF EXISTS "+quoteIdentifier(schema)+" CASCADE"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "CREATE SCHEMA "+quoteIdentifier(schema)); err != nil {
+		return err
+	}
+	s := &snapshot{}
+	if s.tables, err = snapshotTables(ctx, tx, schema, m.schema != ""); err != nil {
+		return err
+	}
+	for _, t := range s.tables {
+		sql := fmt.Sprintf("CREATE TABLE %s.%s AS SELECT %s FROM %s", quoteIdentifier(schema), t.copy, strings.Join(t.columns, ","), t.name)
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("cannot copy %s: %w", t.name, err)
+		}
+	}
+	if s.sequences, err = snapshotSequences(ctx, tx, schema, m.schema != ""); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	m.snapshot = s
+	return nil
+}
+
+// snapshotSchema returns the name of the schema where the data of the tables is copied to by Snapshot.
+func (m *Migration) snapshotSchema() string {
+	if m.schema != "" {
+		return m.schema + "_snapshot"
+	}
+	return snapshotSchema
+}
+
+// snapshotTables returns the tables of the database in an order that satisfies their foreign keys.
+// Partitions are skipped, as their rows are copied through the partitioned table.
+// If scoped is set, only the tables in the schemas of the search_path are returned, as with SchemaIsolation.
+func snapshotTables(ctx context.Context, tx pgx.Tx, schema string, scoped bool) ([]snapshotTable, error) {
+	rows, err := tx.Query(ctx, `SELECT c.oid::regclass::text, 't' || c.oid,
+		array(SELECT quote_ident(a.attname) FROM pg_attribute a
+			WHERE a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped AND a.attgenerated = ''
+			ORDER BY a.attnum)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND NOT c.relispartition
+		AND n.nspname NOT IN ('pg_catalog', 'information_schema', $1) AND n.nspname NOT LIKE 'pg_toast%'
+		AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')
+		AND (NOT $2 OR n.nspname = ANY(current_schemas(false)))
+		ORDER BY n.nspname, c.relname`, schema, scoped)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query tables: %w", err)
+	}
+	tables, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (snapshotTable, error) {
+		var t snapshotTable
+		err := row.Scan(&t.name, &t.copy, &t.columns)
+		return t, err
+	})
+	if err != nil || len(tables) == 0 {
+		return nil, err
+	}
+	names := make([]string, 0, len(tables))
+	for _, t := range tables {
+		names = append(names, t.name)
+	}
+	order, err := insertionOrder(ctx, tx, names)
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]snapshotTable, 0, len(tables))
+	for _, n := range order {
+		sorted = append(sorted, tables[n])
+	}
+	return sorted, nil
+}
+
+// snapshotSequences returns the current state of the sequences of the database.
+func snapshotSequences(ctx context.Context, tx pgx.Tx, schema string, scoped bool) ([]snapshotSequence, error) {
+	rows, err := tx.Query(ctx, `SELECT c.oid::regclass::text
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind = 'S'
+		AND n.nspname NOT IN ('pg_catalog', 'information_schema', $1) AND n.nspname NOT LIKE 'pg_toast%'
+		AND (NOT $2 OR n.nspname = ANY(current_schemas(false)))`, schema, scoped)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query sequences: %w", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("cannot query sequences: %w", err)
+	}
+	sequences := make([]snapshotSequence, 0, len(names))
+	for _, name := range names {
+		s := snapshotSequence{name: name}
+		if err := tx.QueryRow(ctx, "SELECT last_value, is_called FROM "+name).Scan(&s.value, &s.called); err != nil {
+			return nil, fmt.Errorf("cannot read sequence %s: %w", name, err)
+		}
+		sequences = append(sequences, s)
+	}
+	return sequences, nil
+}
+
+func (m *Migration) restoreSnapshot(ctx context.Context) error {
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Rolling back a transaction that was committed is a no-op.
+		_ = tx.Rollback(ctx)
+	}()
+
+	if len(m.snapshot.tables) != 0 {
+		names := make([]string, 0, len(m.snapshot.tables))
+		for _, t := range m.snapshot.tables {
+			names = append(names, t.name)
+		}
+		if _, err := tx.Exec(ctx, "TRUNCATE "+strings.Join(names, ", ")+" CASCADE"); err != nil {
+			return err
+		}
+	}
+	for _, t := range m.snapshot.tables {
+		columns := strings.Join(t.columns, ",")
+		// OVERRIDING SYSTEM VALUE is required to write to GENERATED ALWAYS identity columns.
+		sql := fmt.Sprintf("INSERT INTO %s (%s) OVERRIDING SYSTEM VALUE SELECT %s FROM %s.%s", t.name, columns, columns, quoteIdentifier(m.snapshotSchema()), t.copy)
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("cannot restore %s: %w", t.name, err)
+		}
+	}
+	for _, s := range m.snapshot.sequences {
+		if _, err := tx.Exec(ctx, "SELECT setval($1::regclass, $2, $3)", s.name, s.value, s.called); err != nil {
+			return fmt.Errorf("cannot restore sequence %s: %w", s.name, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
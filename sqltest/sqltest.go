@@ -3,10 +3,14 @@ package sqltest
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
+	"regexp"
 	"strings"
-	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -19,11 +23,41 @@ var (
 	DatabasePrefix = "test"
 
 	// SchemaVersionTable where tern saves the version of the current migration in PostgreSQL.
+	// It can be set for each migration with the SchemaVersionTable option.
 	SchemaVersionTable = "schema_version"
 )
 
+// TB is the subset of testing.TB used by this package, so that it can be used with
+// test frameworks and harnesses that don't provide a testing.TB, such as Ginkgo with GinkgoT().
+// *testing.T and *testing.B implement it.
+type TB interface {
+	Cleanup(func())
+	Error(args ...any)
+	Errorf(format string, args ...any)
+	Failed() bool
+	Fatal(args ...any)
+	Fatalf(format string, args ...any)
+	Helper()
+	Log(args ...any)
+	Logf(format string, args ...any)
+	Name() string
+}
+
 // New migration to use with a test.
-func New(t testing.TB, o Options) *Migration {
+// The options can be overridden with environment variables, such as PGTOOLS_FORCE.
+// See ForceEnv and the other environment variables for details.
+func New(t TB, o Options) *Migration {
+	t.Helper()
+	if err := applyEnv(&o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Dir != "" && o.Files != nil {
+		sub, err := fs.Sub(o.Files, o.Dir)
+		if err != nil {
+			t.Fatalf("cannot use migrations directory: %v", err)
+		}
+		o.Files = sub
+	}
 	return &Migration{
 		Options: o,
 		t:       t,
@@ -37,13 +71,33 @@ type Options struct {
 
 	// SkipTeardown stops the Teardown function being registered with testing cleanup.
 	// You can use this to debug migration after running a specific test.
+	// How to connect to the temporary database with psql is logged once the test is over.
 	//
 	// Be aware that you'll encounter errors if you try to run a migration multiple times without
 	// a proper cleaning up. To forcefully clean up the database, you can use the force option.
 	SkipTeardown bool
 
+	// KeepOnFailure keeps the temporary database or schema when the test fails, instead of dropping it
+	// during teardown, and logs how to connect to it with psql, so you can inspect it.
+	// The database is still dropped when the test passes.
+	// Ignored if using TransactionIsolation or SharedDatabases.
+	KeepOnFailure bool
+
+	// CleanupOrphans drops temporary databases left behind by tests created longer than this ago
+	// the first time Setup is called by the process. See the CleanupOrphans function.
+	CleanupOrphans time.Duration
+
+	// DumpOnFailure is a directory where to write a dump of the database of a test that failed during teardown,
+	// so that failures can be diagnosed once the tests are over, as in CI artifacts.
+	// The dump is written to a subdirectory named after the test, with pg_dump if it's installed,
+	// or with the schema and a CSV file with the rows of each table otherwise.
+	// Ignored if using TransactionIsolation.
+	DumpOnFailure string
+
 	// UseExisting database from connection instead of creating a temporary one.
 	// If set, the database isn't dropped during teardown / test cleanup.
+	// Checksums of the applied migrations are recorded in a table next to the schema version table,
+	// and setup fails if a migration was changed after it was applied, unless the Force option is set.
 	UseExisting bool
 
 	// TemporaryDatabasePrefix for namespacing the temporary database name created for the test function.
@@ -51,9 +105,149 @@ type Options struct {
 	// Ignore if using UseExisting.
 	TemporaryDatabasePrefix string
 
+	// Naming of the temporary database. By default, TestNaming is used.
+	// Use PackageNaming or RandomNaming to avoid naming clashes between tests instead of TemporaryDatabasePrefix.
+	Naming Naming
+
 	// Files to use in the migration.
 	// e.g., os.DirFS("migrations/")
 	Files fs.FS
+
+	// Dir of Files where the migrations are, as when they're embedded with //go:embed db/migrations/*.sql.
+	// It's applied by New with fs.Sub.
+	Dir string
+
+	// Extensions to create on the database before the migrations, such as pgcrypto or pg_trgm.
+	// They must be installed on the PostgreSQL server.
+	// With SchemaIsolation, extensions already created on the database by another test are reused,
+	// and their objects might not be on the search_path.
+	Extensions []string
+
+	// PgDump files in the plain format of pg_dump to restore before the migrations, for tests that need
+	// realistic data or legacy schemas not managed by migrations. The .sql files are restored in lexical order.
+	// If Files is nil, the database is set up with the dump files only.
+	// e.g., os.DirFS("testdata/dump")
+	PgDump fs.FS
+
+	// Format of the migration files. By default, TernFormat is used.
+	Format Format
+
+	// SingleTransaction runs all the migrations in a single transaction, instead of one transaction per migration,
+	// so that a failing migration leaves the database without any migration applied rather than half-migrated.
+	// Tern migrations that disable transactions with ---- tern: disable-tx ---- are run as usual.
+	SingleTransaction bool
+
+	// SchemaVersionTable where tern saves the version of the current migration in PostgreSQL.
+	// If empty, the package-level SchemaVersionTable is used.
+	SchemaVersionTable string
+
+	// Lint the migration files for risky statements, such as DROP COLUMN or CREATE INDEX without CONCURRENTLY.
+	// By default, NoLint is used.
+	Lint LintMode
+
+	// UpdateGolden files used by assertions such as AssertSchema with the current results,
+	// instead of comparing them. You might want to set it with a flag, as in go test -update.
+	UpdateGolden bool
+
+	// Template migrates a template database once, and creates the temporary database
+	// of each test by cloning it with CREATE DATABASE ... TEMPLATE instead of running the migrations.
+	//
+	// The template database is named after a checksum of the migration files and the target version,
+	// and it's kept to be reused by subsequent test runs, so changing a migration creates a new one.
+	// Use the Force option to recreate it.
+	// Ignored if using UseExisting.
+	Template bool
+
+	// Isolation mode of the test. By default, DatabaseIsolation is used.
+	Isolation Isolation
+
+	// SharedDatabases is the number of pre-migrated databases shared by the tests.
+	// If set, Setup checks out one of them instead of creating a temporary database for the test,
+	// and returns it during teardown. Tests wait for a database to be returned if all of them are in use.
+	//
+	// The databases are created from a template database (see the Template option), kept to be reused by
+	// subsequent test runs, and reset with TRUNCATE before being used by another test.
+	// Tests must not change the schema, and SetupVersion isn't supported.
+	// Ignored if using UseExisting.
+	SharedDatabases int
+
+	// Fixtures to load after the migrations.
+	// e.g., os.DirFS("testdata/fixtures")
+	//
+	// See LoadFixtures for details.
+	Fixtures fs.FS
+
+	// OnStart is called before each migration is executed, as with the OnStart field of the tern migrator.
+	OnStart func(sequence int32, name, direction, sql string)
+
+	// OnFinish is called after each migration is executed successfully.
+	// Use it with OnStart to measure how long each migration takes, as in tests of the migration process.
+	OnFinish func(sequence int32, name, direction, sql string)
+
+	// BeforeMigrate is called with the connection used to run the migrations before running them,
+	// as to create roles the migrations depend on.
+	// With the Template option, it's only called when the template database is created.
+	BeforeMigrate func(ctx context.Context, conn *pgx.Conn) error
+
+	// AfterMigrate is called with the connection used to run the migrations after running them,
+	// as to run ANALYZE or sanity checks before the test.
+	// With the Template option, it's only called when the template database is created.
+	AfterMigrate func(ctx context.Context, conn *pgx.Conn) error
+
+	// Seed the database after the migrations and fixtures are applied, before Setup returns.
+	// It's not called by SetupTx.
+	Seed func(ctx context.Context, pool *pgxpool.Pool) error
+
+	// WaitReady is how long to retry connecting to the database with exponential backoff
+	// before giving up, for when PostgreSQL might still be starting, as with docker-compose in CI.
+	// By default, the first connection error fails the test.
+	WaitReady time.Duration
+
+	// SimpleProtocol executes queries with the simple protocol, and disables caching prepared statements,
+	// for when PostgreSQL is only reachable through PgBouncer in transaction pooling mode.
+	// The Template, SharedDatabases, and TransactionIsolation options rely on session-level advisory locks,
+	// which don't work in transaction pooling mode.
+	SimpleProtocol bool
+
+	// StatementTimeout sets the statement_timeout of the connections of the test, including the ones used to
+	// run the migrations, so that hanging queries fail fast instead of stalling the tests until the go test timeout.
+	StatementTimeout time.Duration
+
+	// SetupTimeout limits how long creating and migrating the database of the test might take,
+	// so that a hanging migration fails the test with the migration that was running,
+	// instead of stalling the tests until the go test timeout.
+	// SetupE returns an error wrapping context.DeadlineExceeded instead.
+	SetupTimeout time.Duration
+
+	// LockTimeout sets the lock_timeout of the connections of the test, so that queries waiting for locks held
+	// by other connections fail fast, as in a deadlock with a connection left open by the code under test.
+	LockTimeout time.Duration
+
+	// RecordQueries executed by the code under test on the connections returned by Setup, SetupDB, and SetupTx,
+	// for use with Queries and AssertQuery.
+	RecordQueries bool
+
+	// Tracer installed on the connections returned by Setup, SetupDB, and SetupTx, such as pgxotel.NewTracer,
+	// to trace the queries executed by the code under test. If it's used with RecordQueries, only its pgx.QueryTracer methods are called.
+	Tracer pgx.QueryTracer
+
+	// Leaks checks for connections to the database of the test left open, such as connections that weren't closed
+	// or sessions idle in a transaction, during teardown. By default, NoLeakCheck is used.
+	// Ignored if using UseExisting, TransactionIsolation, or SchemaIsolation.
+	Leaks LeakMode
+
+	// FakeClock replaces now(), transaction_timestamp(), statement_timestamp(), and clock_timestamp()
+	// with functions returning the time set with SetTime, so that time-dependent queries can be tested deterministically.
+	//
+	// The functions are created on the sqltest_clock schema before the migrations, so that column defaults
+	// such as DEFAULT now() use them, and the schema is put on the search_path before pg_catalog.
+	// SQL keywords such as CURRENT_TIMESTAMP and CURRENT_DATE aren't replaced.
+	// SchemaIsolation isn't supported.
+	FakeClock bool
+
+	// Logger for structured events, such as creating a database, executing each migration with its duration,
+	// and teardown. If nil, the events are logged with t.Log.
+	Logger *slog.Logger
 }
 
 // Migration simplifies avlidadting the migration process, and setting up a test database
@@ -61,19 +255,37 @@ type Options struct {
 type Migration struct {
 	Options Options
 
-	t        testing.TB
+	t        TB
 	migrator *migrate.Migrator
 
-	pool     *pgxpool.Pool
-	conn     *pgx.Conn
-	database string
+	pool       *pgxpool.Pool
+	conn       *pgx.Conn
+	connString string
+	database   string
+	template   string
+	shared     bool   // Database checked out from the SharedDatabases.
+	schema     string // Used with SchemaIsolation.
+
+	// Used with TransactionIsolation.
+	txConn *pgx.Conn
+	tx     pgx.Tx
+
+	snapshot *snapshot
+	recorder *recorder      // Used with RecordQueries.
+	step     *migrationStep // Migration being executed.
+	tornDown bool           // Teardown was called.
+
+	targetVersion *int32 // Passed to SetupVersion, used by Fresh.
+
+	// Used with SetupDB.
+	db *sql.DB
 }
 
 // Setup the migration.
 // This function returns a pgx pool that can be used to connect to the database.
 // If something fails, t.Fatal is called.
 //
-// It register the Teardown function with testing.TB to clean up the database once the
+// It register the Teardown function with TB to clean up the database once the
 // tests are over by default, but this can be disabled by setting the SkipTeardown option.
 //
 // If the UseExisting option is set, a temporary database is used for running the tests.
@@ -92,12 +304,39 @@ func (m *Migration) Setup(ctx context.Context, connString string) *pgxpool.Pool
 	return m.setupVersion(ctx, connString, nil)
 }
 
+// SetupE is similar to Setup, but returns an error instead of calling t.Fatal if something fails,
+// for use where failing the test isn't appropriate, as in tooling built on top of this package.
+// The Teardown function is still registered with testing cleanup, unless the SkipTeardown option is set.
+// Issues found by the Lint option are still reported with t.
+func (m *Migration) SetupE(ctx context.Context, connString string) (*pgxpool.Pool, error) {
+	if m.t == nil {
+		panic("migration must be initialized with sqltest.New()")
+	}
+	m.t.Helper()
+	return m.setup(ctx, connString, nil)
+}
+
 // SetupVersion of the migrations is similar to the Setup version,
 // but migrates to the given target version.
 func (m *Migration) SetupVersion(ctx context.Context, connString string, targetVersion int32) *pgxpool.Pool {
 	return m.setupVersion(ctx, connString, &targetVersion)
 }
 
+// SetupVersionName is similar to SetupVersion, but migrates to the migration with the given name,
+// such as 003_posts.sql, so that tests pinned to a version of the schema don't break when migrations are renumbered.
+// The extension of the file name is optional.
+func (m *Migration) SetupVersionName(ctx context.Context, connString, name string) *pgxpool.Pool {
+	if m.t == nil {
+		panic("migration must be initialized with sqltest.New()")
+	}
+	m.t.Helper()
+	version, err := m.versionByName(name)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return m.setupVersion(ctx, connString, &version)
+}
+
 // setupVersion is only used to avoid receiving targetVersion as a pointer in the exported function.
 // If targetVersion isn't passed, it migrates to the latest migration, which is only known after
 // migrate.NewMigrator is called.
@@ -107,137 +346,469 @@ func (m *Migration) setupVersion(ctx context.Context, connString string, targetV
 	}
 
 	m.t.Helper()
-	m.t.Log("setup PostgreSQL database")
+	pool, err := m.setup(ctx, connString, targetVersion)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return pool
+}
+
+// setup the database and migrate it to the target version, returning errors instead of calling t.Fatal.
+func (m *Migration) setup(ctx context.Context, connString string, targetVersion *int32) (_ *pgxpool.Pool, err error) {
+	m.t.Helper()
+	if m.Options.Isolation == TransactionIsolation {
+		return nil, errors.New("use SetupTx with the TransactionIsolation option")
+	}
+	if m.Options.FakeClock && m.Options.Isolation == SchemaIsolation {
+		return nil, errors.New("the FakeClock option isn't supported with SchemaIsolation")
+	}
+	m.log("setup PostgreSQL database")
+	m.connString, m.targetVersion = connString, targetVersion
+	start := time.Now()
+
+	// If something fails before Teardown is registered, undo what was set up so far.
+	var created, registered bool
+	defer func() {
+		if err != nil && !registered {
+			if cerr := m.abortSetup(created); cerr != nil {
+				err = fmt.Errorf("%w (cannot clean up: %v)", err, cerr)
+			}
+		}
+	}()
+	ctx, cancel := m.setupContext(ctx)
+	defer func() {
+		err = m.setupError(ctx, err)
+		cancel()
+	}()
 
 	// Similarly to how it's done in the application code, pgxpool is used to create a pool
 	// of connections to the database that is safe to be used concurrently.
 	poolConfig, err := pgxpool.ParseConfig(connString)
 	if err != nil {
-		m.t.Fatal(err)
+		return nil, err
+	}
+	if err := m.waitReady(ctx, connString); err != nil {
+		return nil, err
 	}
 
+	m.setProtocol(poolConfig.ConnConfig)
+
 	if !m.Options.UseExisting {
-		var err error
-		if m.conn, err = pgx.Connect(ctx, connString); err != nil {
-			m.t.Fatal(err)
+		config, err := m.parseConfig(connString)
+		if err != nil {
+			return nil, err
+		}
+		if m.conn, err = pgx.ConnectConfig(ctx, config); err != nil {
+			return nil, err
+		}
+		m.cleanupOrphans(ctx, connString)
+		if m.database, err = m.temporaryName(); err != nil {
+			return nil, fmt.Errorf("cannot name database: %w", err)
 		}
-		m.database = m.Options.TemporaryDatabasePrefix + SQLTestName(m.t)
 		// Lousy check if database name is invalid.
 		// Ref: https://www.postgresql.org/docs/current/sql-syntax-lexical.html#SQL-SYNTAX-IDENTIFIERS
 		if strings.ContainsAny(m.database, `" `) {
-			m.t.Fatalf("invalid database name")
+			return nil, errors.New("invalid database name")
 		}
 
-		if err := m.cleanDB(ctx, connString); err != nil {
-			m.t.Fatalf("cannot create database: %v", err)
+		switch {
+		case m.Options.Isolation == SchemaIsolation:
+			m.schema, m.database = m.database, ""
+			if err := m.createSchema(ctx); err != nil {
+				return nil, fmt.Errorf("cannot create schema: %w", err)
+			}
+			created = true
+			poolConfig.ConnConfig.RuntimeParams["search_path"] = quoteIdentifier(m.schema)
+		case m.Options.SharedDatabases > 0:
+			if targetVersion != nil {
+				return nil, errors.New("SetupVersion isn't supported with the SharedDatabases option")
+			}
+			if m.database, err = m.checkout(ctx, connString); err != nil {
+				return nil, fmt.Errorf("cannot check out shared database: %w", err)
+			}
+		case m.Options.Template:
+			if m.template, err = m.setupTemplate(ctx, connString, targetVersion); err != nil {
+				return nil, fmt.Errorf("cannot create template database: %w", err)
+			}
+			fallthrough
+		default:
+			if err := m.cleanDB(ctx, connString); err != nil {
+				return nil, fmt.Errorf("cannot create database: %w", err)
+			}
+			created = true
 		}
 
-		poolConfig.ConnConfig.Database = m.database
+		if m.schema == "" {
+			poolConfig.ConnConfig.Database = m.database
+		}
+	}
+	poolConfig.ConnConfig.Tracer = m.tracer()
+	m.setTimeouts(poolConfig.ConnConfig)
+	if m.Options.FakeClock {
+		poolConfig.ConnConfig.RuntimeParams["search_path"] = clockSearchPath
 	}
 	m.pool, err = pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		m.t.Fatalf("cannot connect to database: %v", err)
+		return nil, fmt.Errorf("cannot connect to database: %w", err)
 	}
 
 	poolConn, err := m.pool.Acquire(ctx)
 	if err != nil {
-		m.t.Fatalf("cannot acquire PostgreSQL connection: %v", err)
+		return nil, fmt.Errorf("cannot acquire PostgreSQL connection: %w", err)
 	}
 	defer poolConn.Release()
 
 	if err := poolConn.QueryRow(ctx, "SELECT current_database();").Scan(&m.database); err != nil {
-		m.t.Fatalf("cannot get database name: %v", err)
+		return nil, fmt.Errorf("cannot get database name: %w", err)
 	}
 
 	// Enforce database name to start with "test" to mitigate risk of modifying wrong database by mistake.
 	if !strings.HasPrefix(m.database, DatabasePrefix) {
-		m.t.Fatalf(`refusing to run integration tests: database name is %q (%q prefix is required)`, m.database, DatabasePrefix)
+		return nil, fmt.Errorf(`refusing to run integration tests: database name is %q (%q prefix is required)`, m.database, DatabasePrefix)
 	}
 
-	if !m.Options.SkipTeardown {
+	switch {
+	case !m.Options.SkipTeardown:
+		registered = true
 		m.t.Cleanup(func() {
 			m.Teardown(context.Background())
 		})
+	case !m.Options.UseExisting:
+		m.t.Cleanup(func() {
+			if !m.tornDown && !m.shared {
+				m.logKept("keeping PostgreSQL database (SkipTeardown)")
+			}
+		})
+	}
+	// A database cloned from a template already has the extensions, the fake clock, and the dump.
+	if m.template == "" {
+		if err := m.createExtensions(ctx, poolConn.Conn()); err != nil {
+			return nil, err
+		}
+		if err := m.installClock(ctx, poolConn.Conn()); err != nil {
+			return nil, fmt.Errorf("cannot install fake clock: %w", err)
+		}
+		if err := m.restoreDump(ctx, poolConfig.ConnConfig.Copy()); err != nil {
+			return nil, fmt.Errorf("cannot restore pg_dump: %w", err)
+		}
+		if err := m.hook(ctx, "BeforeMigrate", m.Options.BeforeMigrate, poolConn.Conn()); err != nil {
+			return nil, err
+		}
 	}
 	if err := m.migrate(ctx, poolConn, targetVersion); err != nil {
-		m.t.Fatal(err)
+		return nil, err
+	}
+	if m.template == "" {
+		if err := m.hook(ctx, "AfterMigrate", m.Options.AfterMigrate, poolConn.Conn()); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.lint(); err != nil {
+		return nil, err
+	}
+	if m.Options.Fixtures != nil {
+		if err := m.loadFixtures(ctx, m.Options.Fixtures); err != nil {
+			return nil, fmt.Errorf("cannot load fixtures: %w", err)
+		}
+	}
+	if m.Options.Seed != nil {
+		m.log("seed PostgreSQL database")
+		if err := m.Options.Seed(ctx, m.pool); err != nil {
+			return nil, fmt.Errorf("cannot seed database: %w", err)
+		}
 	}
-	return m.pool
+	m.log("PostgreSQL database ready", slog.String("database", m.database), slog.Duration("duration", time.Since(start)))
+	m.startRecording()
+	return m.pool, nil
 }
 
 // migrate database using tern.
+// If the database was cloned from a template, it's already migrated.
 func (m *Migration) migrate(ctx context.Context, poolConn *pgxpool.Conn, targetVersion *int32) (err error) {
-	m.migrator, err = migrate.NewMigrator(ctx, poolConn.Conn(), SchemaVersionTable)
+	if m.Options.Files == nil && m.Options.PgDump != nil {
+		return nil
+	}
+	if m.Options.Format == PlainSQLFormat {
+		if m.template != "" {
+			return nil
+		}
+		return m.execFilesTx(ctx, poolConn.Conn(), targetVersion)
+	}
+	if m.migrator, err = m.newMigrator(ctx, poolConn.Conn()); err != nil {
+		return err
+	}
+	if m.template != "" {
+		return nil
+	}
+	if !m.Options.UseExisting {
+		return m.migrateTx(ctx, poolConn.Conn(), m.migrator, targetVersion)
+	}
+	// An existing database outlives the migration files, so check they weren't changed since they were applied.
+	if !m.Options.Force {
+		if err := m.checkDrift(ctx, poolConn.Conn(), m.migrator); err != nil {
+			return err
+		}
+	}
+	if err := m.migrateTx(ctx, poolConn.Conn(), m.migrator, targetVersion); err != nil {
+		return err
+	}
+	return m.recordChecksums(ctx, poolConn.Conn(), m.migrator)
+}
+
+// hook calls fn with the connection, if it's set.
+func (m *Migration) hook(ctx context.Context, name string, fn func(ctx context.Context, conn *pgx.Conn) error, conn *pgx.Conn) error {
+	if fn == nil {
+		return nil
+	}
+	if err := fn(ctx, conn); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// schemaVersionTable returns the name of the table where the version of the current migration is saved.
+func (m *Migration) schemaVersionTable() string {
+	if m.Options.SchemaVersionTable != "" {
+		return m.Options.SchemaVersionTable
+	}
+	return SchemaVersionTable
+}
+
+// newMigrator creates a tern migrator for the connection and loads the migrations.
+func (m *Migration) newMigrator(ctx context.Context, conn *pgx.Conn) (*migrate.Migrator, error) {
+	migrator, err := migrate.NewMigrator(ctx, conn, m.schemaVersionTable())
 	if err != nil {
-		return fmt.Errorf("cannot run migration: %w", err)
+		return nil, fmt.Errorf("cannot run migration: %w", err)
 	}
 
-	m.migrator.OnStart = func(sequence int32, name, direction, sql string) {
-		m.t.Logf("executing %s %s\n", name, direction)
+	migrator.OnStart = func(sequence int32, name, direction, sql string) {
+		m.startStep(sequence, name, direction, sql)
 	}
 
 	// Test the migration scripts and prepare database for integration tests.
-	if err := m.migrator.LoadMigrations(m.Options.Files); err != nil {
-		return fmt.Errorf("cannot load migrations: %w", err)
+	if err := m.loadMigrations(migrator); err != nil {
+		return nil, fmt.Errorf("cannot load migrations: %w", err)
 	}
+	return migrator, nil
+}
 
+// migrateTo the latest or target version of the database, after undoing existing migrations.
+func (m *Migration) migrateTo(ctx context.Context, migrator *migrate.Migrator, targetVersion *int32) error {
 	// Check if the database seems to be in a reliable state.
 	// If the database current version is ahead of existing migrations, refuse to overwrite it.
 	if !m.Options.Force {
-		switch version, err := m.migrator.GetCurrentVersion(ctx); {
+		switch version, err := migrator.GetCurrentVersion(ctx); {
 		case err != nil:
 			return fmt.Errorf("cannot get schema version: %w", err)
-		case int(version) > len(m.migrator.Migrations):
-			return fmt.Errorf("database is dirty (current version is ahead of existing migrations), please fix %q table manually or try -force", SchemaVersionTable)
+		case int(version) > len(migrator.Migrations):
+			return fmt.Errorf("database is dirty (current version is ahead of existing migrations), please fix %q table manually or try -force", m.schemaVersionTable())
 		}
 	}
 
 	// Undo database migrations.
-	if err := m.migrator.MigrateTo(ctx, 0); err != nil {
+	if err := migrator.MigrateTo(ctx, 0); err != nil {
+		err = m.stepError(ctx, err)
+		m.finishStep(err)
 		return fmt.Errorf("cannot undo database migrations: %v", err)
 	}
 
 	// Migrate to the latest or target version of the database.
-	tv := int32(len(m.migrator.Migrations))
+	tv := int32(len(migrator.Migrations))
 	if targetVersion != nil {
 		tv = *targetVersion
 	}
-	if err := m.migrator.MigrateTo(ctx, tv); err != nil {
+	err := migrator.MigrateTo(ctx, tv)
+	if err != nil {
+		err = m.stepError(ctx, err)
+	}
+	m.finishStep(err)
+	if err != nil {
 		return fmt.Errorf("cannot apply migrations: %v", err)
 	}
 	return nil
 }
 
+// disableTxPattern of tern migrations that can't run inside a transaction.
+var disableTxPattern = regexp.MustCompile(`(?m)^---- tern: disable-tx ----$`)
+
+// migrateTx migrates the database of the connection like migrateTo, but runs all the migrations in a single
+// transaction when the SingleTransaction option is set, unless a migration disables transactions.
+func (m *Migration) migrateTx(ctx context.Context, conn *pgx.Conn, migrator *migrate.Migrator, targetVersion *int32) error {
+	if !m.Options.SingleTransaction {
+		return m.migrateTo(ctx, migrator, targetVersion)
+	}
+	for _, mig := range migrator.Migrations {
+		if disableTxPattern.MatchString(mig.UpSQL) || disableTxPattern.MatchString(mig.DownSQL) {
+			m.log("cannot run migrations in a single transaction: migration disables transactions", slog.String("migration", mig.Name))
+			return m.migrateTo(ctx, migrator, targetVersion)
+		}
+	}
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	defer func() {
+		// Rolling back a transaction that was committed is a no-op.
+		_ = tx.Rollback(ctx)
+	}()
+	// The migrations must not begin and commit their own transactions inside the transaction.
+	txMigrator, err := migrate.NewMigratorEx(ctx, conn, m.schemaVersionTable(), &migrate.MigratorOptions{DisableTx: true})
+	if err != nil {
+		return fmt.Errorf("cannot run migration: %w", err)
+	}
+	txMigrator.Migrations = migrator.Migrations
+	txMigrator.OnStart = migrator.OnStart
+	if err := m.migrateTo(ctx, txMigrator, targetVersion); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
 // MigrateTo migrates to targetVersion.
 //
 // You probably only need this if you need to test code against an older version of your database,
 // or if you are testing a migration process.
 func (m *Migration) MigrateTo(ctx context.Context, targetVersion int32) {
 	m.t.Helper()
-	if err := m.migrator.MigrateTo(ctx, targetVersion); err != nil {
+	if m.Options.Format == PlainSQLFormat {
+		m.t.Fatal("MigrateTo isn't supported with PlainSQLFormat")
+	}
+	if m.migrator == nil {
+		m.t.Fatal("MigrateTo requires a database set up with Setup or SetupVersion")
+	}
+	err := m.migrator.MigrateTo(ctx, targetVersion)
+	m.finishStep(err)
+	if err != nil {
 		m.t.Fatalf("cannot migrate database to version %d: %v", targetVersion, err)
 	}
 }
 
+// MigrateDown undoes the given number of migrations, stopping at version 0.
+func (m *Migration) MigrateDown(ctx context.Context, steps int) {
+	m.t.Helper()
+	if m.migrator == nil {
+		m.t.Fatal("MigrateDown requires a database set up with Setup or SetupVersion")
+	}
+	version, err := m.migrator.GetCurrentVersion(ctx)
+	if err != nil {
+		m.t.Fatalf("cannot get schema version: %v", err)
+	}
+	target := int64(version) - int64(steps)
+	if target < 0 {
+		target = 0
+	}
+	m.MigrateTo(ctx, int32(target))
+}
+
+// Reset the database by undoing all migrations, and migrating to the latest version again.
+// Data written to the database is lost.
+func (m *Migration) Reset(ctx context.Context) {
+	m.t.Helper()
+	if m.migrator == nil {
+		m.t.Fatal("Reset requires a database set up with Setup or SetupVersion")
+	}
+	m.MigrateTo(ctx, 0)
+	m.MigrateTo(ctx, int32(len(m.migrator.Migrations)))
+}
+
+// ValidateSchema checks if every column mapped from the fields of a given Go struct exists in the table
+// with a compatible data type, to catch drift between your structs and migrations.
+// If not, t.Error is called with the differences found.
+//
+// See introspect.Validate for details, and AssertStructSchema for a stricter check.
+func (m *Migration) ValidateSchema(ctx context.Context, v any, table string) {
+	m.t.Helper()
+	assertStructSchema(ctx, m.t, m.querier(), v, table)
+}
+
+// abortSetup closes the connections of a setup that failed before Teardown was registered,
+// and drops the temporary database or schema if it was created.
+// With the SkipTeardown option, they're kept, so Teardown can still be called.
+func (m *Migration) abortSetup(created bool) error {
+	if m.Options.SkipTeardown {
+		return nil
+	}
+	ctx := context.Background()
+	if m.pool != nil {
+		m.pool.Close()
+	}
+	if m.conn == nil {
+		return nil
+	}
+	// Closing the connection returns the shared database by releasing its advisory lock.
+	defer m.conn.Close(ctx)
+	if !created {
+		return nil
+	}
+	if m.schema != "" {
+		return m.dropSchema(ctx)
+	}
+	return withLock(ctx, m.conn, databaseLockKey(m.database), func() error {
+		return m.dropDB(ctx)
+	})
+}
+
 // Teardown database after running the tests.
 //
 // This function is registered by Setup to be called automatically by the testing package
 // during testing cleanup. Use the SkipTeardown option to disable this.
 func (m *Migration) Teardown(ctx context.Context) {
 	m.t.Helper()
-	m.t.Log("teardown PostgreSQL database")
-	m.pool.Close()
+	m.tornDown = true
+	if m.Options.Isolation == TransactionIsolation {
+		m.log("teardown PostgreSQL transaction", slog.String("database", m.database))
+		m.teardownTx(ctx)
+		return
+	}
+	m.log("teardown PostgreSQL database", slog.String("database", m.database))
+	m.dumpOnFailure(ctx)
+	if m.db != nil {
+		if err := m.db.Close(); err != nil {
+			m.t.Errorf("cannot close database: %v", err)
+		}
+	}
+	if m.Options.Leaks != NoLeakCheck && !m.Options.UseExisting && m.schema == "" {
+		m.closeAndCheckLeaks(ctx)
+	} else {
+		m.pool.Close()
+	}
 
 	if !m.Options.UseExisting {
+		// Closing the connection returns the shared database by releasing its advisory lock.
 		defer m.conn.Close(ctx)
-		if err := m.dropDB(ctx); err != nil {
+		if m.Options.KeepOnFailure && m.t.Failed() && !m.shared {
+			m.logKept("keeping PostgreSQL database of failed test")
+			return
+		}
+		if m.schema != "" {
+			if err := m.dropSchema(ctx); err != nil {
+				m.t.Fatalf("cannot drop schema: %v", err)
+			}
+			return
+		}
+		if m.shared {
+			return
+		}
+		err := withLock(ctx, m.conn, databaseLockKey(m.database), func() error {
+			return m.dropDB(ctx)
+		})
+		if err != nil {
 			m.t.Fatalf("cannot drop database: %v", err)
 		}
 	}
 }
 
 // cleanDB creates a temporary database when CleanDB is used.
+// Creating and dropping the database is serialized with other tests using the same name
+// with an advisory lock.
 func (m *Migration) cleanDB(ctx context.Context, connString string) error {
+	return withLock(ctx, m.conn, databaseLockKey(m.database), func() error {
+		return m.createDB(ctx)
+	})
+}
+
+// createDB creates the temporary database, dropping it first if the Force option is set.
+func (m *Migration) createDB(ctx context.Context) error {
 	// If force is set to true, drop database if it exists.
 	if m.Options.Force {
 		if err := m.dropDB(ctx); err != nil {
@@ -246,7 +817,31 @@ func (m *Migration) cleanDB(ctx context.Context, connString string) error {
 	}
 
 	// Create new database.
-	_, err := m.conn.Exec(ctx, fmt.Sprintf(`CREATE DATABASE "%s";`, m.database))
+	m.log("creating database", slog.String("database", m.database), slog.String("template", m.template))
+	sql := fmt.Sprintf(`CREATE DATABASE "%s";`, m.database)
+	if m.template != "" {
+		sql = fmt.Sprintf(`CREATE DATABASE "%s" TEMPLATE "%s";`, m.database, m.template)
+	}
+	if _, err := m.conn.Exec(ctx, sql); err != nil {
+		return err
+	}
+	return markTemporary(ctx, m.conn, m.database)
+}
+
+// createSchema creates a temporary schema when SchemaIsolation is used.
+func (m *Migration) createSchema(ctx context.Context) error {
+	if m.Options.Force {
+		if err := m.dropSchema(ctx); err != nil {
+			return err
+		}
+	}
+	_, err := m.conn.Exec(ctx, fmt.Sprintf(`CREATE SCHEMA "%s";`, m.schema))
+	return err
+}
+
+// dropSchema drops the created temporary schema, and its snapshot.
+func (m *Migration) dropSchema(ctx context.Context) error {
+	_, err := m.conn.Exec(ctx, fmt.Sprintf(`DROP SCHEMA IF EXISTS "%s" CASCADE; DROP SCHEMA IF EXISTS "%s" CASCADE;`, m.schema, m.snapshotSchema()))
 	return err
 }
 
@@ -258,6 +853,6 @@ func (m *Migration) dropDB(ctx context.Context) error {
 
 // SQLTestName normalizes a test name to a database name.
 // It lowercases the test name and converts / to underscore.
-func SQLTestName(t testing.TB) string {
+func SQLTestName(t TB) string {
 	return strings.ToLower(strings.ReplaceAll(t.Name(), "/", "_"))
 }
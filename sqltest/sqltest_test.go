@@ -3,72 +3,1745 @@ package sqltest_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
+	"github.com/henvic/pgtools/pgxotel"
 	"github.com/henvic/pgtools/sqltest"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
-func TestMain(m *testing.M) {
+var force = flag.Bool("force", false, "Force cleaning the database before starting")
+
+func checkPostgres(t testing.TB) {
 	if os.Getenv("INTEGRATION_TESTDB") != "true" {
-		log.Printf("Skipping tests that require database connection")
+		t.Skip("Skipping tests that require database connection")
+	}
+}
+
+func TestNow(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force: *force,
+		Files: os.DirFS("example/testdata/migrations"),
+
+		// If we don't use a prefix, the test will be flaky when testing multi packages
+		// because there is another TestNow function in example/example_test.go.
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	conn := migration.Setup(ctx, "") // Using environment variables instead of connString to configure tests.
+	var tt time.Time
+	if err := conn.QueryRow(ctx, "SELECT NOW();").Scan(&tt); err != nil {
+		t.Errorf("cannot execute query: %v", err)
+	}
+	if tt.IsZero() {
+		t.Error("time returned by pgx is zero")
+	}
+}
+
+func TestPrefixedDatabase(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_must_have_prefix_",
+	})
+	conn := migration.Setup(ctx, "") // Using environment variables instead of connString to configure tests.
+	var got string
+	if err := conn.QueryRow(ctx, "SELECT current_database();").Scan(&got); err != nil {
+		t.Errorf("cannot get database name: %v", err)
+	}
+	if want := "test_must_have_prefix_testprefixeddatabase"; want != got {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestSchemaVersionTable(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		SchemaVersionTable:      "custom_schema_version",
+	})
+	conn := migration.Setup(ctx, "")
+	var version int32
+	if err := conn.QueryRow(ctx, "SELECT version FROM custom_schema_version").Scan(&version); err != nil {
+		t.Errorf("cannot query schema version: %v", err)
+	}
+	if version != 3 {
+		t.Errorf("expected schema version to be 3, got %d instead", version)
+	}
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", sqltest.SchemaVersionTable).Scan(&exists); err != nil {
+		t.Errorf("cannot query schema version table: %v", err)
+	}
+	if exists {
+		t.Error("expected default schema version table to not exist")
+	}
+}
+
+func TestSetupVersionName(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	conn := migration.SetupVersionName(ctx, "", "002_settings")
+	var version int32
+	if err := conn.QueryRow(ctx, "SELECT version FROM "+sqltest.SchemaVersionTable).Scan(&version); err != nil {
+		t.Errorf("cannot query schema version: %v", err)
+	}
+	if version != 2 {
+		t.Errorf("expected schema version to be 2, got %d instead", version)
+	}
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('posts') IS NOT NULL").Scan(&exists); err != nil {
+		t.Errorf("cannot query posts table: %v", err)
+	}
+	if exists {
+		t.Error("expected posts table to not exist")
+	}
+}
+
+// failedTB reports the test as failed, without failing it.
+type failedTB struct {
+	testing.TB
+}
+
+func (failedTB) Failed() bool {
+	return true
+}
+
+func TestKeepOnFailure(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	for _, failed := range []bool{false, true} {
+		failed := failed
+		t.Run(fmt.Sprintf("failed=%v", failed), func(t *testing.T) {
+			t.Parallel()
+			var tb testing.TB = t
+			if failed {
+				tb = failedTB{t}
+			}
+			migration := sqltest.New(tb, sqltest.Options{
+				Force:                   *force,
+				Files:                   os.DirFS("example/testdata/migrations"),
+				TemporaryDatabasePrefix: "test_internal_",
+				SkipTeardown:            true,
+				KeepOnFailure:           true,
+			})
+			migration.Setup(ctx, "")
+			database := migration.DatabaseName()
+			migration.Teardown(ctx)
+
+			conn, err := pgx.Connect(ctx, "")
+			if err != nil {
+				t.Fatalf("connection error: %v", err)
+			}
+			defer conn.Close(ctx)
+			defer conn.Exec(ctx, fmt.Sprintf(`DROP DATABASE IF EXISTS "%s";`, database))
+			var exists bool
+			if err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)", database).Scan(&exists); err != nil {
+				t.Fatalf("cannot query database: %v", err)
+			}
+			if exists != failed {
+				t.Errorf("expected database to exist to be %v, got %v instead", failed, exists)
+			}
+		})
+	}
+}
+
+func TestSkipTeardownLogsConnection(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	var buf bytes.Buffer
+	t.Run("kept", func(t *testing.T) {
+		migration := sqltest.New(t, sqltest.Options{
+			Force:                   *force,
+			Files:                   os.DirFS("example/testdata/migrations"),
+			TemporaryDatabasePrefix: "test_internal_",
+			SkipTeardown:            true,
+			Logger:                  slog.New(slog.NewJSONHandler(&buf, nil)),
+		})
+		pool := migration.Setup(ctx, "")
+		t.Cleanup(pool.Close)
+	})
+
+	var dsn string
+	d := json.NewDecoder(&buf)
+	for d.More() {
+		var e struct {
+			Msg string `json:"msg"`
+			DSN string `json:"dsn"`
+		}
+		if err := d.Decode(&e); err != nil {
+			t.Fatalf("cannot decode event: %v", err)
+		}
+		if e.Msg == "keeping PostgreSQL database (SkipTeardown)" {
+			dsn = e.DSN
+		}
+	}
+	if dsn == "" {
+		t.Fatal("expected DSN of the kept database to be logged")
+	}
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("cannot connect to kept database: %v", err)
+	}
+	var database string
+	if err := conn.QueryRow(ctx, "SELECT current_database()").Scan(&database); err != nil {
+		t.Errorf("cannot get database name: %v", err)
+	}
+	conn.Close(ctx)
+
+	if conn, err = pgx.Connect(ctx, ""); err != nil {
+		t.Fatalf("connection error: %v", err)
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, fmt.Sprintf(`DROP DATABASE IF EXISTS "%s";`, database)); err != nil {
+		t.Errorf("cannot drop database: %v", err)
+	}
+}
+
+func TestDumpOnFailure(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	migration := sqltest.New(failedTB{t}, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		SkipTeardown:            true,
+		DumpOnFailure:           dir,
+	})
+	pool := migration.Setup(ctx, "")
+	if _, err := pool.Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ('dumped', 'name', 'photo', 'url')"); err != nil {
+		t.Errorf("cannot insert media: %v", err)
+	}
+	migration.Teardown(ctx)
+
+	// The dump is written with pg_dump if it's installed, or with COPY otherwise.
+	files := []string{
+		filepath.Join(dir, "testdumponfailure", "dump.sql"),
+		filepath.Join(dir, "testdumponfailure", "media.csv"),
+	}
+	var found bool
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		found = true
+		if !bytes.Contains(b, []byte("dumped")) {
+			t.Errorf("expected %s to contain the inserted row", f)
+		}
+	}
+	if !found {
+		t.Errorf("expected database to be dumped to %s", dir)
+	}
+}
+
+func TestCleanupOrphans(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(failedTB{t}, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		SkipTeardown:            true,
+		KeepOnFailure:           true,
+	})
+	migration.Setup(ctx, "")
+	database := migration.DatabaseName()
+	migration.Teardown(ctx)
+
+	conn, err := pgx.Connect(ctx, "")
+	if err != nil {
+		t.Fatalf("connection error: %v", err)
+	}
+	defer conn.Close(ctx)
+	defer conn.Exec(ctx, fmt.Sprintf(`DROP DATABASE IF EXISTS "%s";`, database))
+
+	// Only databases created longer than olderThan ago are dropped.
+	dropped, err := sqltest.CleanupOrphans(ctx, "", time.Hour)
+	if err != nil {
+		t.Fatalf("cannot clean up orphans: %v", err)
+	}
+	for _, d := range dropped {
+		if d == database {
+			t.Errorf("expected database %q to be kept", database)
+		}
+	}
+
+	if _, err := conn.Exec(ctx, fmt.Sprintf(`COMMENT ON DATABASE "%s" IS 'sqltest created_at=2006-01-02T15:04:05Z';`, database)); err != nil {
+		t.Fatalf("cannot comment on database: %v", err)
+	}
+	if dropped, err = sqltest.CleanupOrphans(ctx, "", time.Hour); err != nil {
+		t.Fatalf("cannot clean up orphans: %v", err)
+	}
+	var found bool
+	for _, d := range dropped {
+		found = found || d == database
+	}
+	if !found {
+		t.Errorf("expected database %q to be dropped, got %q instead", database, dropped)
+	}
+}
+
+func TestNaming(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	testCases := []struct {
+		desc   string
+		naming sqltest.Naming
+		want   *regexp.Regexp
+	}{
+		{
+			desc:   "test",
+			naming: sqltest.TestNaming,
+			want:   regexp.MustCompile(`^test_internal_testnaming_test$`),
+		},
+		{
+			desc:   "package",
+			naming: sqltest.PackageNaming,
+			want:   regexp.MustCompile(`^test_internal_testnaming_package_[0-9a-f]{8}$`),
+		},
+		{
+			desc:   "random",
+			naming: sqltest.RandomNaming,
+			want:   regexp.MustCompile(`^test_internal_testnaming_random_[0-9a-f]{8}$`),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			migration := sqltest.New(t, sqltest.Options{
+				Force:                   *force,
+				Files:                   os.DirFS("example/testdata/migrations"),
+				TemporaryDatabasePrefix: "test_internal_",
+				Naming:                  tc.naming,
+			})
+			migration.Setup(ctx, "")
+			if got := migration.DatabaseName(); !tc.want.MatchString(got) {
+				t.Errorf("expected database name to match %v, got %q instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRecordQueries(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		RecordQueries:           true,
+	})
+	pool := migration.Setup(ctx, "")
+	if queries := migration.Queries(); len(queries) != 0 {
+		t.Errorf("expected no queries recorded during setup, got %v instead", queries)
+	}
+	if _, err := pool.Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ($1, 'name', 'photo', 'url')", "recorded"); err != nil {
+		t.Errorf("cannot insert media: %v", err)
+	}
+	want := []sqltest.Query{
+		{
+			SQL:  "INSERT INTO media (id, name, source, url) VALUES ($1, 'name', 'photo', 'url')",
+			Args: []any{"recorded"},
+		},
+	}
+	if queries := migration.Queries(); !reflect.DeepEqual(queries, want) {
+		t.Errorf("expected queries to be %v, got %v instead", want, queries)
+	}
+	migration.AssertQuery(regexp.MustCompile(`^INSERT INTO media\b`))
+
+	migration.ResetQueries()
+	if queries := migration.Queries(); len(queries) != 0 {
+		t.Errorf("expected no queries after reset, got %v instead", queries)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		RecordQueries:           true,
+		Tracer: pgxotel.NewTracer(pgxotel.Options{
+			TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+		}),
+	})
+	pool := migration.Setup(ctx, "")
+	setup := len(recorder.Ended())
+	if _, err := pool.Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ($1, 'name', 'photo', 'url')", "traced"); err != nil {
+		t.Errorf("cannot insert media: %v", err)
+	}
+	spans := recorder.Ended()[setup:]
+	if len(spans) != 1 || spans[0].Name() != "INSERT" {
+		t.Errorf("expected an INSERT span, got %v instead", spans)
+	}
+	migration.AssertQuery(regexp.MustCompile(`^INSERT INTO media\b`))
+}
+
+// errorTB records errors instead of failing the test.
+type errorTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *errorTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *errorTB) Error(args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprint(args...))
+}
+
+func TestLeaks(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	tb := &errorTB{TB: t}
+	migration := sqltest.New(tb, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		SkipTeardown:            true,
+		Leaks:                   sqltest.LeakStrict,
+	})
+	migration.Setup(ctx, "")
+	conn, err := pgx.Connect(ctx, migration.ConnString())
+	if err != nil {
+		t.Fatalf("connection error: %v", err)
+	}
+	defer conn.Close(ctx)
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		t.Fatalf("cannot begin transaction: %v", err)
+	}
+	if _, err := tx.Exec(ctx, "SELECT count(*) FROM media"); err != nil {
+		t.Errorf("cannot query media: %v", err)
+	}
+	migration.Teardown(ctx)
+
+	want := "leak: connection left open by pid"
+	if len(tb.errors) != 1 || !strings.HasPrefix(tb.errors[0], want) || !strings.Contains(tb.errors[0], "idle in transaction") {
+		t.Errorf("expected a leaked connection idle in transaction, got %q instead", tb.errors)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		FakeClock:               true,
+	})
+	pool := migration.Setup(ctx, "")
+	want := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	migration.SetTime(ctx, want)
+	if _, err := pool.Exec(ctx, "INSERT INTO posts (id, name, message) VALUES ('clock', 'name', 'message')"); err != nil {
+		t.Errorf("cannot insert post: %v", err)
+	}
+	var now, createdAt time.Time
+	if err := pool.QueryRow(ctx, "SELECT now(), created_at FROM posts WHERE id = 'clock'").Scan(&now, &createdAt); err != nil {
+		t.Fatalf("cannot query time: %v", err)
+	}
+	if !now.Equal(want) {
+		t.Errorf("expected now() to be %v, got %v instead", want, now)
+	}
+	if !createdAt.Equal(want) {
+		t.Errorf("expected created_at to be %v, got %v instead", want, createdAt)
+	}
+
+	migration.ResetTime(ctx)
+	if err := pool.QueryRow(ctx, "SELECT now()").Scan(&now); err != nil {
+		t.Fatalf("cannot query time: %v", err)
+	}
+	if time.Since(now) > time.Minute {
+		t.Errorf("expected now() to be the time of the server, got %v instead", now)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		StatementTimeout:        100 * time.Millisecond,
+		LockTimeout:             50 * time.Millisecond,
+	})
+	pool := migration.Setup(ctx, "")
+	var statementTimeout, lockTimeout string
+	if err := pool.QueryRow(ctx, "SELECT current_setting('statement_timeout'), current_setting('lock_timeout')").Scan(&statementTimeout, &lockTimeout); err != nil {
+		t.Fatalf("cannot query timeouts: %v", err)
+	}
+	if statementTimeout != "100ms" {
+		t.Errorf("expected statement_timeout to be 100ms, got %q instead", statementTimeout)
+	}
+	if lockTimeout != "50ms" {
+		t.Errorf("expected lock_timeout to be 50ms, got %q instead", lockTimeout)
+	}
+	want := "canceling statement due to statement timeout"
+	if _, err := pool.Exec(ctx, "SELECT pg_sleep(1)"); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error to contain %q, got %v instead", want, err)
+	}
+}
+
+func TestPgDump(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	testCases := []struct {
+		desc     string
+		files    fs.FS
+		template bool
+	}{
+		{
+			desc: "dump",
+		},
+		{
+			desc:  "migrations",
+			files: os.DirFS("example/testdata/migrations"),
+		},
+		{
+			desc:     "template",
+			files:    os.DirFS("example/testdata/migrations"),
+			template: true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			migration := sqltest.New(t, sqltest.Options{
+				Force:                   *force,
+				Files:                   tc.files,
+				PgDump:                  os.DirFS("testdata/pgdump"),
+				Template:                tc.template,
+				TemporaryDatabasePrefix: "test_internal_",
+			})
+			pool := migration.Setup(ctx, "")
+			// The search_path set by the dump doesn't leak to the connections of the test.
+			var name, bio string
+			if err := pool.QueryRow(ctx, "SELECT authors_name(2), bio FROM authors WHERE id = 2").Scan(&name, &bio); err != nil {
+				t.Fatalf("cannot query authors: %v", err)
+			}
+			if name != "Clarice Lispector" {
+				t.Errorf("expected name to be %q, got %q instead", "Clarice Lispector", name)
+			}
+			if want := "Born in Chechelnyk.\nRaised in Recife."; bio != want {
+				t.Errorf("expected bio to be %q, got %q instead", want, bio)
+			}
+			var migrated bool
+			if err := pool.QueryRow(ctx, "SELECT to_regclass('posts') IS NOT NULL").Scan(&migrated); err != nil {
+				t.Fatalf("cannot query posts table: %v", err)
+			}
+			if want := tc.files != nil; migrated != want {
+				t.Errorf("expected migrated to be %v, got %v instead", want, migrated)
+			}
+		})
+	}
+}
+
+func TestAssertRows(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	pool := migration.Setup(ctx, "")
+	if _, err := pool.Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ('a', 'first', 'photo', 'url'), ('b', 'second', 'video', 'url')"); err != nil {
+		t.Fatalf("cannot insert media: %v", err)
+	}
+	sqltest.AssertRows(t, pool, "SELECT id, name, length(url) FROM media WHERE source = ANY($1) ORDER BY id", [][]any{
+		{"a", "first", 3},
+		{"b", "second", 3},
+	}, []string{"photo", "video"})
+	sqltest.AssertCount(t, pool, "media", 2)
+
+	tb := &errorTB{TB: t}
+	sqltest.AssertRows(tb, pool, "SELECT id FROM media ORDER BY id", [][]any{{"a"}, {"c"}})
+	sqltest.AssertCount(tb, pool, "public.media", 3)
+	want := []string{
+		"rows mismatch (-want +got):\n  a\n- c\n+ b\n  \n",
+		"expected public.media to have 3 rows, got 2 instead",
+	}
+	if !reflect.DeepEqual(tb.errors, want) {
+		t.Errorf("expected errors to be %q, got %q instead", want, tb.errors)
+	}
+}
+
+func TestAssertStructSchema(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	pool := migration.Setup(ctx, "")
+
+	type Media struct {
+		ID         string `db:"id,pk"`
+		Name       string
+		Source     string
+		URL        string `db:"url"`
+		CreatedAt  time.Time
+		ModifiedAt time.Time
+	}
+	sqltest.AssertStructSchema(t, pool, &Media{}, "media")
+
+	type drifted struct {
+		ID        string `db:"id,pk"`
+		Name      *string
+		Source    string
+		CreatedAt time.Time
+		Deleted   bool
+	}
+	tb := &errorTB{TB: t}
+	sqltest.AssertStructSchema(tb, pool, &drifted{}, "media")
+	want := []string{
+		"sqltest_test.drifted doesn't match table \"media\":" +
+			"\n\t- column \"deleted\" is missing" +
+			"\n\t- column \"name\" is NOT NULL without a default value, but *string can hold NULL: use a non-pointer type, or drop NOT NULL from the column" +
+			"\n\t- column \"url\" of type text isn't mapped from any field" +
+			"\n\t- column \"modified_at\" of type timestamp with time zone isn't mapped from any field",
+	}
+	if !reflect.DeepEqual(tb.errors, want) {
+		t.Errorf("expected errors to be %q, got %q instead", want, tb.errors)
+	}
+}
+
+func TestAssertGoldenQuery(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	golden := filepath.Join(t.TempDir(), "media.golden")
+	for _, update := range []bool{true, false} {
+		migration := sqltest.New(t, sqltest.Options{
+			Force:                   *force,
+			Files:                   os.DirFS("example/testdata/migrations"),
+			TemporaryDatabasePrefix: "test_internal_",
+			UpdateGolden:            update,
+			SkipTeardown:            true,
+		})
+		pool := migration.Setup(ctx, "")
+		if _, err := pool.Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ('b', 'second', 'video', 'url'), ('a', 'first', 'photo', 'url')"); err != nil {
+			t.Fatalf("cannot insert media: %v", err)
+		}
+		migration.AssertGoldenQuery(ctx, golden, `SELECT id, name, jsonb_build_object('z', 1, 'a', source) AS info,
+			'2001-02-03 04:05:06-03'::timestamptz AS ts FROM media WHERE url = $1`, "url")
+		migration.Teardown(ctx)
+	}
+	got, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("cannot read golden file: %v", err)
+	}
+	want := `id | name | info | ts
+a | first | {"a":"photo","z":1} | 2001-02-03T07:05:06Z
+b | second | {"a":"video","z":1} | 2001-02-03T07:05:06Z
+(2 rows)
+`
+	if string(got) != want {
+		t.Errorf("expected golden file to be %q, got %q instead", want, got)
+	}
+}
+
+func BenchmarkBench(b *testing.B) {
+	pool, reset := sqltest.Bench(b, os.DirFS("example/testdata/migrations"))
+	ctx := context.Background()
+	for i := 0; i < b.N; i++ {
+		reset()
+		if _, err := pool.Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ('bench', 'name', 'photo', 'url')"); err != nil {
+			b.Fatalf("cannot insert media: %v", err)
+		}
+	}
+}
+
+func TestTruncateAll(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	pool := migration.Setup(ctx, "")
+	if _, err := pool.Exec(ctx, `INSERT INTO media (id, name, source, url) VALUES ('a', 'name', 'photo', 'url');
+		INSERT INTO posts (id, name, message) VALUES ('a', 'name', 'message')`); err != nil {
+		t.Fatalf("cannot insert rows: %v", err)
+	}
+	migration.TruncateAll(ctx, "posts")
+	sqltest.AssertCount(t, pool, "media", 0)
+	sqltest.AssertCount(t, pool, "posts", 1)
+	sqltest.AssertCount(t, pool, sqltest.SchemaVersionTable, 1)
+
+	migration.TruncateAll(ctx)
+	sqltest.AssertCount(t, pool, "posts", 0)
+}
+
+func TestSimpleProtocol(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		SimpleProtocol:          true,
+	})
+	pool := migration.Setup(ctx, "")
+	if mode := pool.Config().ConnConfig.DefaultQueryExecMode; mode != pgx.QueryExecModeSimpleProtocol {
+		t.Errorf("expected query exec mode to be %v, got %v instead", pgx.QueryExecModeSimpleProtocol, mode)
+	}
+	if _, err := pool.Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ($1, 'name', 'photo', 'url')", "simple"); err != nil {
+		t.Errorf("cannot insert media: %v", err)
+	}
+	var prepared int
+	if err := pool.QueryRow(ctx, "SELECT count(*) FROM pg_prepared_statements").Scan(&prepared); err != nil {
+		t.Fatalf("cannot query prepared statements: %v", err)
+	}
+	if prepared != 0 {
+		t.Errorf("expected no prepared statements, got %d instead", prepared)
+	}
+}
+
+func TestSetupRole(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	pool := migration.Setup(ctx, "")
+	if _, err := pool.Exec(ctx, `INSERT INTO posts (id, name, message) VALUES ('public', 'name', 'message'), ('private', 'name', 'message');
+		ALTER TABLE posts ENABLE ROW LEVEL SECURITY;
+		CREATE POLICY public_posts ON posts USING (id = 'public')`); err != nil {
+		t.Fatalf("cannot set up row-level security: %v", err)
+	}
+	reader := migration.SetupRole(ctx, "reader", "SELECT ON posts")
+	var user string
+	if err := reader.QueryRow(ctx, "SELECT current_user").Scan(&user); err != nil {
+		t.Fatalf("cannot query current user: %v", err)
+	}
+	if want := "test_internal_testsetuprole_reader"; user != want {
+		t.Errorf("expected current user to be %q, got %q instead", want, user)
+	}
+	sqltest.AssertRows(t, reader, "SELECT id FROM posts", [][]any{{"public"}})
+	want := "permission denied"
+	if _, err := reader.Exec(ctx, "DELETE FROM posts"); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error to contain %q, got %v instead", want, err)
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		Extensions:              []string{"pgcrypto"},
+	})
+	pool := migration.Setup(ctx, "")
+	sqltest.AssertRows(t, pool, "SELECT extname FROM pg_extension WHERE extname = 'pgcrypto'", [][]any{{"pgcrypto"}})
+}
+
+var checkExtensionsNotInstalled = flag.Bool("check_extensions_not_installed", false, "if true, TestExtensionsNotInstalled should fail.")
+
+func TestExtensionsNotInstalled(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	if *checkExtensionsNotInstalled {
+		migration := sqltest.New(t, sqltest.Options{
+			Files:                   os.DirFS("example/testdata/migrations"),
+			TemporaryDatabasePrefix: "test_internal_",
+			Extensions:              []string{"not_installed"},
+		})
+		migration.Setup(context.Background(), "")
 		return
 	}
-	os.Exit(m.Run())
+	args := []string{
+		"-test.v",
+		"-test.run=TestExtensionsNotInstalled",
+		"-check_extensions_not_installed",
+	}
+	out, err := exec.Command(os.Args[0], args...).CombinedOutput()
+	if err == nil {
+		t.Error("expected command to fail")
+	}
+	want := []byte(`extension "not_installed" isn't installed on the PostgreSQL server`)
+	if !bytes.Contains(out, want) {
+		t.Errorf("got %q, wanted %q", out, want)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	var versions []string
+	sqltest.Matrix(t, func(t *testing.T, connString string) {
+		migration := sqltest.New(t, sqltest.Options{
+			Force:                   *force,
+			Files:                   os.DirFS("example/testdata/migrations"),
+			TemporaryDatabasePrefix: "test_internal_",
+		})
+		pool := migration.Setup(context.Background(), connString)
+		sqltest.AssertCount(t, pool, "media", 0)
+		versions = append(versions, t.Name())
+	})
+	if len(versions) == 0 {
+		t.Error("expected at least one version")
+	}
+	for _, v := range versions {
+		if !strings.HasPrefix(v, "TestMatrix/postgres") {
+			t.Errorf("expected subtest name to start with TestMatrix/postgres, got %q instead", v)
+		}
+	}
+}
+
+var checkSetupTimeout = flag.Bool("check_setup_timeout", false, "if true, TestSetupTimeout should fail.")
+
+func TestSetupTimeout(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	if *checkSetupTimeout {
+		migration := sqltest.New(t, sqltest.Options{
+			Force:                   true,
+			Files:                   os.DirFS("testdata/slow"),
+			TemporaryDatabasePrefix: "test_internal_",
+			SetupTimeout:            time.Second,
+		})
+		migration.Setup(context.Background(), "")
+		return
+	}
+	start := time.Now()
+	out, err := exec.Command(os.Args[0], "-test.v", "-test.run=TestSetupTimeout", "-check_setup_timeout").CombinedOutput()
+	if err == nil {
+		t.Error("expected command to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 8*time.Second {
+		t.Errorf("expected setup to time out after 1s, took %v instead", elapsed)
+	}
+	for _, want := range []string{"migration 001_slow.sql up was running for", "setup timed out after 1s"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("got %q, wanted %q", out, want)
+		}
+	}
+}
+
+func TestSetupETimeout(t *testing.T) {
+	t.Parallel()
+	tb := &errorTB{TB: t}
+	migration := sqltest.New(tb, sqltest.Options{
+		TemporaryDatabasePrefix: "test_internal_",
+		SetupTimeout:            300 * time.Millisecond,
+		WaitReady:               time.Minute,
+	})
+	// Nothing is expected to listen on port 1.
+	_, err := migration.SetupE(context.Background(), "host=127.0.0.1 port=1 connect_timeout=1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v instead", err)
+	}
+	if want := "setup timed out after 300ms: "; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("expected error to start with %q, got %v instead", want, err)
+	}
+	if len(tb.errors) != 0 {
+		t.Errorf("expected no errors to be reported, got %q instead", tb.errors)
+	}
+}
+
+func TestMigrateHooks(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	var calls []string
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		BeforeMigrate: func(ctx context.Context, conn *pgx.Conn) error {
+			var exists bool
+			err := conn.QueryRow(ctx, "SELECT to_regclass('posts') IS NOT NULL").Scan(&exists)
+			calls = append(calls, fmt.Sprintf("before posts=%v", exists))
+			return err
+		},
+		AfterMigrate: func(ctx context.Context, conn *pgx.Conn) error {
+			var exists bool
+			err := conn.QueryRow(ctx, "SELECT to_regclass('posts') IS NOT NULL").Scan(&exists)
+			calls = append(calls, fmt.Sprintf("after posts=%v", exists))
+			return err
+		},
+	})
+	migration.Setup(ctx, "")
+	want := []string{"before posts=false", "after posts=true"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls to be %q, got %q instead", want, calls)
+	}
+}
+
+func TestOnStartOnFinish(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	var calls []string
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		OnStart: func(sequence int32, name, direction, sql string) {
+			calls = append(calls, fmt.Sprintf("start %d %s %s", sequence, name, direction))
+		},
+		OnFinish: func(sequence int32, name, direction, sql string) {
+			calls = append(calls, fmt.Sprintf("finish %d %s %s", sequence, name, direction))
+		},
+	})
+	migration.Setup(ctx, "")
+	migration.MigrateDown(ctx, 1)
+	want := []string{
+		"start 1 001_media.sql up",
+		"finish 1 001_media.sql up",
+		"start 2 002_settings.sql up",
+		"finish 2 002_settings.sql up",
+		"start 3 003_posts.sql up",
+		"finish 3 003_posts.sql up",
+		"start 3 003_posts.sql down",
+		"finish 3 003_posts.sql down",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls to be %q, got %q instead", want, calls)
+	}
+}
+
+func TestConnString(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	testCases := []struct {
+		desc       string
+		connString string
+		isolation  sqltest.Isolation
+	}{
+		{
+			desc: "environment",
+		},
+		{
+			desc:       "keyword",
+			connString: "application_name=sqltest",
+		},
+		{
+			desc:      "schema",
+			isolation: sqltest.SchemaIsolation,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			migration := sqltest.New(t, sqltest.Options{
+				Force:                   *force,
+				Files:                   os.DirFS("example/testdata/migrations"),
+				Isolation:               tc.isolation,
+				TemporaryDatabasePrefix: "test_internal_",
+			})
+			migration.Setup(ctx, tc.connString)
+			conn, err := pgx.Connect(ctx, migration.ConnString())
+			if err != nil {
+				t.Fatalf("cannot connect to database: %v", err)
+			}
+			defer conn.Close(ctx)
+			var database string
+			if err := conn.QueryRow(ctx, "SELECT current_database()").Scan(&database); err != nil {
+				t.Errorf("cannot get database name: %v", err)
+			}
+			if want := migration.DatabaseName(); database != want {
+				t.Errorf("expected database to be %q, got %q instead", want, database)
+			}
+			// The posts table is only visible if connected to the database or schema of the test.
+			if _, err := conn.Exec(ctx, "SELECT * FROM posts"); err != nil {
+				t.Errorf("cannot query posts: %v", err)
+			}
+		})
+	}
+}
+
+func TestMigrateDownAndReset(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	conn := migration.Setup(ctx, "")
+	version := func() int32 {
+		var v int32
+		if err := conn.QueryRow(ctx, "SELECT version FROM "+sqltest.SchemaVersionTable).Scan(&v); err != nil {
+			t.Errorf("cannot query schema version: %v", err)
+		}
+		return v
+	}
+	if _, err := conn.Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ('reset', 'name', 'photo', 'url')"); err != nil {
+		t.Errorf("cannot insert media: %v", err)
+	}
+
+	migration.MigrateDown(ctx, 2)
+	if v := version(); v != 1 {
+		t.Errorf("expected schema version to be 1, got %d instead", v)
+	}
+	migration.MigrateDown(ctx, 5)
+	if v := version(); v != 0 {
+		t.Errorf("expected schema version to be 0, got %d instead", v)
+	}
+
+	migration.MigrateTo(ctx, 1)
+	if _, err := conn.Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ('reset', 'name', 'photo', 'url')"); err != nil {
+		t.Errorf("cannot insert media: %v", err)
+	}
+	migration.Reset(ctx)
+	if v := version(); v != 3 {
+		t.Errorf("expected schema version to be 3, got %d instead", v)
+	}
+	var media int
+	if err := conn.QueryRow(ctx, "SELECT count(*) FROM media").Scan(&media); err != nil {
+		t.Errorf("cannot query media: %v", err)
+	}
+	if media != 0 {
+		t.Errorf("expected no media after reset, got %d instead", media)
+	}
+}
+
+func TestSingleTransactionDisableTx(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	var buf bytes.Buffer
+	migration := sqltest.New(t, sqltest.Options{
+		Force: *force,
+		Files: fstest.MapFS{
+			"001_authors.sql": {Data: []byte("---- tern: disable-tx ----\nCREATE TABLE authors (id int);\n")},
+		},
+		TemporaryDatabasePrefix: "test_internal_",
+		SingleTransaction:       true,
+		Logger:                  slog.New(slog.NewJSONHandler(&buf, nil)),
+	})
+	migration.Setup(ctx, "")
+	want := `"msg":"cannot run migrations in a single transaction: migration disables transactions","test":"` + t.Name() + `","migration":"001_authors.sql"`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected events to contain %s, got %s instead", want, buf.String())
+	}
+}
+
+func TestLogger(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	var buf bytes.Buffer
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		SkipTeardown:            true,
+		Logger:                  slog.New(slog.NewJSONHandler(&buf, nil)),
+	})
+	migration.Setup(ctx, "")
+	migration.Teardown(ctx)
+
+	type event struct {
+		Msg       string `json:"msg"`
+		Test      string `json:"test"`
+		Name      string `json:"name"`
+		Direction string `json:"direction"`
+		Duration  int64  `json:"duration"`
+	}
+	var (
+		messages   []string
+		migrations []string
+	)
+	d := json.NewDecoder(&buf)
+	for d.More() {
+		var e event
+		if err := d.Decode(&e); err != nil {
+			t.Fatalf("cannot decode event: %v", err)
+		}
+		if e.Test != t.Name() {
+			t.Errorf("expected test to be %q, got %q instead", t.Name(), e.Test)
+		}
+		if e.Msg == "migration" {
+			if e.Duration <= 0 {
+				t.Errorf("expected duration of migration %s to be positive, got %d instead", e.Name, e.Duration)
+			}
+			migrations = append(migrations, e.Name+" "+e.Direction)
+			continue
+		}
+		messages = append(messages, e.Msg)
+	}
+	wantMessages := []string{
+		"setup PostgreSQL database",
+		"creating database",
+		"PostgreSQL database ready",
+		"teardown PostgreSQL database",
+	}
+	if !reflect.DeepEqual(messages, wantMessages) {
+		t.Errorf("expected events to be %q, got %q instead", wantMessages, messages)
+	}
+	wantMigrations := []string{
+		"001_media.sql up",
+		"002_settings.sql up",
+		"003_posts.sql up",
+	}
+	if !reflect.DeepEqual(migrations, wantMigrations) {
+		t.Errorf("expected migrations to be %q, got %q instead", wantMigrations, migrations)
+	}
 }
 
-var force = flag.Bool("force", false, "Force cleaning the database before starting")
+func TestTemplate(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			migration := sqltest.New(t, sqltest.Options{
+				Force:                   *force && name == "first",
+				Files:                   os.DirFS("example/testdata/migrations"),
+				TemporaryDatabasePrefix: "test_internal_",
+				Template:                true,
+			})
+			conn := migration.Setup(ctx, "")
+			var posts int
+			if err := conn.QueryRow(ctx, "SELECT count(*) FROM posts").Scan(&posts); err != nil {
+				t.Errorf("cannot query cloned database: %v", err)
+			}
+			var templates int
+			if err := conn.QueryRow(ctx, "SELECT count(*) FROM pg_database WHERE datname LIKE 'test_template_%' AND datistemplate").Scan(&templates); err != nil {
+				t.Errorf("cannot query template database: %v", err)
+			}
+			if templates == 0 {
+				t.Error("template database not found")
+			}
+		})
+	}
+}
 
-func TestNow(t *testing.T) {
+func TestTransactionIsolation(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	// Each subtest inserts the same row, which is only possible if the previous transaction was rolled back.
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			migration := sqltest.New(t, sqltest.Options{
+				Force:     *force && name == "first",
+				Files:     os.DirFS("example/testdata/migrations"),
+				Isolation: sqltest.TransactionIsolation,
+			})
+			tx := migration.SetupTx(ctx, "")
+			if _, err := tx.Exec(ctx, "INSERT INTO posts (id, name, message) VALUES ('isolation', 'name', 'message')"); err != nil {
+				t.Errorf("cannot insert post: %v", err)
+			}
+			var posts int
+			if err := tx.QueryRow(ctx, "SELECT count(*) FROM posts WHERE id = 'isolation'").Scan(&posts); err != nil {
+				t.Errorf("cannot query posts: %v", err)
+			}
+			if posts != 1 {
+				t.Errorf("expected 1 post, got %d instead", posts)
+			}
+		})
+	}
+}
+
+func TestSharedDatabases(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	// There are more subtests than databases, and each one inserts the same row,
+	// which is only possible if the database was reset after being used by another subtest.
+	for n := 0; n < 4; n++ {
+		t.Run(fmt.Sprintf("test%d", n), func(t *testing.T) {
+			t.Parallel()
+			migration := sqltest.New(t, sqltest.Options{
+				Files:           os.DirFS("example/testdata/migrations"),
+				SharedDatabases: 2,
+			})
+			conn := migration.Setup(ctx, "")
+			if _, err := conn.Exec(ctx, "INSERT INTO posts (id, name, message) VALUES ('shared', 'name', 'message')"); err != nil {
+				t.Errorf("cannot insert post: %v", err)
+			}
+			var posts int
+			if err := conn.QueryRow(ctx, "SELECT count(*) FROM posts").Scan(&posts); err != nil {
+				t.Errorf("cannot query posts: %v", err)
+			}
+			if posts != 1 {
+				t.Errorf("expected 1 post, got %d instead", posts)
+			}
+		})
+	}
+}
+
+func TestSchemaIsolation(t *testing.T) {
+	checkPostgres(t)
 	t.Parallel()
 	ctx := context.Background()
 	migration := sqltest.New(t, sqltest.Options{
-		Force: *force,
-		Files: os.DirFS("example/testdata/migrations"),
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		Isolation:               sqltest.SchemaIsolation,
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	conn := migration.Setup(ctx, "")
+	var schema string
+	if err := conn.QueryRow(ctx, "SELECT current_schema()").Scan(&schema); err != nil {
+		t.Errorf("cannot get schema name: %v", err)
+	}
+	if want := "test_internal_testschemaisolation"; schema != want {
+		t.Errorf("expected schema to be %q, got %q instead", want, schema)
+	}
+	var tables int
+	if err := conn.QueryRow(ctx, "SELECT count(*) FROM pg_tables WHERE schemaname = $1", schema).Scan(&tables); err != nil {
+		t.Errorf("cannot query tables: %v", err)
+	}
+	if tables != 4 { // Including the schema version table.
+		t.Errorf("expected 4 tables on schema, got %d instead", tables)
+	}
+}
 
-		// If we don't use a prefix, the test will be flaky when testing multi packages
-		// because there is another TestNow function in example/example_test.go.
+func TestFixtures(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		Fixtures:                os.DirFS("testdata/fixtures"),
 		TemporaryDatabasePrefix: "test_internal_",
 	})
-	conn := migration.Setup(ctx, "") // Using environment variables instead of connString to configure tests.
-	var tt time.Time
-	if err := conn.QueryRow(ctx, "SELECT NOW();").Scan(&tt); err != nil {
-		t.Errorf("cannot execute query: %v", err)
+	conn := migration.Setup(ctx, "")
+	var messages []string
+	rows, err := conn.Query(ctx, "SELECT message FROM posts ORDER BY id")
+	if err != nil {
+		t.Fatalf("cannot query posts: %v", err)
 	}
-	if tt.IsZero() {
-		t.Error("time returned by pgx is zero")
+	if messages, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+		t.Fatalf("cannot query posts: %v", err)
+	}
+	if want := []string{"Hello, world!", "Updated!"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("got messages %q, want %q", messages, want)
+	}
+	var color string
+	if err := conn.QueryRow(ctx, "SELECT style->>'color' FROM settings WHERE id = 'theme'").Scan(&color); err != nil {
+		t.Errorf("cannot query settings: %v", err)
+	}
+	if color != "blue" {
+		t.Errorf("got color %q, want %q", color, "blue")
+	}
+	var media int
+	if err := conn.QueryRow(ctx, "SELECT count(*) FROM media").Scan(&media); err != nil {
+		t.Errorf("cannot query media: %v", err)
+	}
+	if media != 2 {
+		t.Errorf("got %d media, want 2", media)
+	}
+
+	// Loading fixtures again truncates the tables first.
+	migration.LoadFixtures(ctx, os.DirFS("testdata/fixtures"))
+	if err := conn.QueryRow(ctx, "SELECT count(*) FROM media").Scan(&media); err != nil {
+		t.Errorf("cannot query media: %v", err)
+	}
+	if media != 2 {
+		t.Errorf("got %d media after reloading fixtures, want 2", media)
 	}
 }
 
-func TestPrefixedDatabase(t *testing.T) {
+func TestSeed(t *testing.T) {
+	checkPostgres(t)
 	t.Parallel()
 	ctx := context.Background()
 	migration := sqltest.New(t, sqltest.Options{
 		Force:                   *force,
 		Files:                   os.DirFS("example/testdata/migrations"),
-		TemporaryDatabasePrefix: "test_must_have_prefix_",
+		TemporaryDatabasePrefix: "test_internal_",
+		Seed: func(ctx context.Context, pool *pgxpool.Pool) error {
+			_, err := pool.Exec(ctx, "INSERT INTO posts (id, name, message) VALUES ('seed', 'Seed', 'Seeded')")
+			return err
+		},
 	})
-	conn := migration.Setup(ctx, "") // Using environment variables instead of connString to configure tests.
+	conn := migration.Setup(ctx, "")
+	var message string
+	if err := conn.QueryRow(ctx, "SELECT message FROM posts WHERE id = 'seed'").Scan(&message); err != nil {
+		t.Errorf("cannot query seeded post: %v", err)
+	}
+	if message != "Seeded" {
+		t.Errorf("got message %q, want %q", message, "Seeded")
+	}
+}
+
+var checkMigrationInvalidPath = flag.Bool("check_migration_invalid_path", false, "if true, TestMigrationInvalidPath should fail.")
+
+func TestFresh(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	migration.Setup(ctx, "")
+	for _, desc := range []string{"a", "b"} {
+		t.Run(desc, func(t *testing.T) {
+			t.Parallel()
+			pool := migration.Fresh(t)
+			if _, err := pool.Exec(ctx, "INSERT INTO media (id, name, source, url) VALUES ('fresh', 'name', 'photo', 'url')"); err != nil {
+				t.Errorf("cannot insert media: %v", err)
+			}
+			sqltest.AssertCount(t, pool, "media", 1)
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		Fixtures:                os.DirFS("testdata/fixtures"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	conn := migration.Setup(ctx, "")
+	migration.Snapshot(ctx)
+	testCases := []struct {
+		desc string
+		sql  string
+	}{
+		{
+			desc: "insert",
+			sql:  "INSERT INTO posts (id, name, message) VALUES ('snapshot', 'name', 'message')",
+		},
+		{
+			desc: "delete",
+			sql:  "DELETE FROM posts",
+		},
+		{
+			desc: "update",
+			sql:  "UPDATE media SET name = 'changed'",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if _, err := conn.Exec(ctx, tc.sql); err != nil {
+				t.Fatalf("cannot modify data: %v", err)
+			}
+			migration.Restore(ctx)
+			var posts, changed int
+			if err := conn.QueryRow(ctx, "SELECT count(*) FROM posts").Scan(&posts); err != nil {
+				t.Errorf("cannot query posts: %v", err)
+			}
+			if posts != 2 {
+				t.Errorf("expected 2 posts, got %d instead", posts)
+			}
+			if err := conn.QueryRow(ctx, "SELECT count(*) FROM media WHERE name = 'changed'").Scan(&changed); err != nil {
+				t.Errorf("cannot query media: %v", err)
+			}
+			if changed != 0 {
+				t.Errorf("expected no changed media, got %d instead", changed)
+			}
+		})
+	}
+}
+
+func TestGolangMigrateFormat(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("testdata/golang-migrate"),
+		Format:                  sqltest.GolangMigrateFormat,
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	conn := migration.Setup(ctx, "")
+	if _, err := conn.Exec(ctx, "INSERT INTO authors (id, name) VALUES ('a', 'Author')"); err != nil {
+		t.Errorf("cannot insert author: %v", err)
+	}
+	if _, err := conn.Exec(ctx, "INSERT INTO books (id, author_id, title) VALUES ('b', 'a', 'Book')"); err != nil {
+		t.Errorf("cannot insert book: %v", err)
+	}
+
+	// Migrating down to the first migration drops the books table.
+	migration.MigrateTo(ctx, 1)
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('books') IS NOT NULL").Scan(&exists); err != nil {
+		t.Errorf("cannot query books table: %v", err)
+	}
+	if exists {
+		t.Error("expected books table to be dropped")
+	}
+}
+
+func TestGooseFormat(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("testdata/goose"),
+		Format:                  sqltest.GooseFormat,
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	conn := migration.Setup(ctx, "")
+	if _, err := conn.Exec(ctx, "INSERT INTO authors (id, name) VALUES ('a', 'Author')"); err != nil {
+		t.Errorf("cannot insert author: %v", err)
+	}
+	if _, err := conn.Exec(ctx, "INSERT INTO books (id, author_id, title) VALUES ('b', 'a', 'Book')"); err != nil {
+		t.Errorf("cannot insert book: %v", err)
+	}
+	var title string
+	if err := conn.QueryRow(ctx, "SELECT book_title('b')").Scan(&title); err != nil {
+		t.Errorf("cannot query book title: %v", err)
+	}
+	if title != "Book" {
+		t.Errorf("expected title to be %q, got %q instead", "Book", title)
+	}
+
+	// Migrating down to the first migration drops the books table.
+	migration.MigrateTo(ctx, 1)
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('books') IS NOT NULL").Scan(&exists); err != nil {
+		t.Errorf("cannot query books table: %v", err)
+	}
+	if exists {
+		t.Error("expected books table to be dropped")
+	}
+}
+
+func TestPlainSQLFormat(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("testdata/schema"),
+		Format:                  sqltest.PlainSQLFormat,
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	conn := migration.Setup(ctx, "")
+	if _, err := conn.Exec(ctx, "INSERT INTO authors (id, name) VALUES ('a', 'Author')"); err != nil {
+		t.Errorf("cannot insert author: %v", err)
+	}
+	if _, err := conn.Exec(ctx, "INSERT INTO books (id, author_id, title) VALUES ('b', 'a', 'Book')"); err != nil {
+		t.Errorf("cannot insert book: %v", err)
+	}
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", sqltest.SchemaVersionTable).Scan(&exists); err != nil {
+		t.Errorf("cannot query schema version table: %v", err)
+	}
+	if exists {
+		t.Error("expected schema version table to not exist")
+	}
+}
+
+func TestSetupDB(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	db := migration.SetupDB(ctx, "")
+	if _, err := db.ExecContext(ctx, "INSERT INTO posts (id, name, message) VALUES ($1, $2, $3)", "db", "name", "message"); err != nil {
+		t.Errorf("cannot insert post: %v", err)
+	}
+	var posts int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM posts").Scan(&posts); err != nil {
+		t.Errorf("cannot query posts: %v", err)
+	}
+	if posts != 1 {
+		t.Errorf("expected 1 post, got %d instead", posts)
+	}
+}
+
+func TestQuickDB(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	db := sqltest.QuickDB(t, os.DirFS("example/testdata/migrations"))
 	var got string
-	if err := conn.QueryRow(ctx, "SELECT current_database();").Scan(&got); err != nil {
+	if err := db.QueryRow("SELECT current_database()").Scan(&got); err != nil {
 		t.Errorf("cannot get database name: %v", err)
 	}
-	if want := "test_must_have_prefix_testprefixeddatabase"; want != got {
-		t.Errorf("got %q, wanted %q", got, want)
+	if want := "testquickdb"; got != want {
+		t.Errorf("expected database to be %q, got %q instead", want, got)
 	}
 }
 
-var checkMigrationInvalidPath = flag.Bool("check_migration_invalid_path", false, "if true, TestMigrationInvalidPath should fail.")
+func TestQuickOptions(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	pool := sqltest.QuickOptions(t, os.DirFS("example/testdata/migrations"), sqltest.Options{
+		Force:                   *force,
+		TemporaryDatabasePrefix: "test_internal_",
+		Fixtures:                os.DirFS("testdata/fixtures"),
+	})
+	var got string
+	if err := pool.QueryRow(context.Background(), "SELECT current_database()").Scan(&got); err != nil {
+		t.Errorf("cannot get database name: %v", err)
+	}
+	if want := "test_internal_testquickoptions"; got != want {
+		t.Errorf("expected database to be %q, got %q instead", want, got)
+	}
+	sqltest.AssertCount(t, pool, "media", 2)
+}
+
+// harnessTB implements sqltest.TB, but not testing.TB, as with test frameworks other than testing.
+type harnessTB struct {
+	sqltest.TB
+}
+
+func TestHarnessTB(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	pool := sqltest.QuickOptions(harnessTB{t}, os.DirFS("example/testdata/migrations"), sqltest.Options{
+		Force:                   *force,
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	sqltest.AssertCount(harnessTB{t}, pool, "media", 0)
+}
+
+func TestDir(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	pool := sqltest.QuickOptions(t, os.DirFS("example"), sqltest.Options{
+		Force:                   *force,
+		TemporaryDatabasePrefix: "test_internal_",
+		Dir:                     "testdata/migrations",
+	})
+	sqltest.AssertCount(t, pool, "media", 0)
+}
+
+func TestSingleTransaction(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	testCases := []struct {
+		desc              string
+		singleTransaction bool
+		want              bool
+	}{
+		{
+			desc: "per-migration",
+			want: true,
+		},
+		{
+			desc:              "single",
+			singleTransaction: true,
+			want:              false,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			migration := sqltest.New(t, sqltest.Options{
+				Force:                   *force,
+				Files:                   os.DirFS("testdata/broken"),
+				TemporaryDatabasePrefix: "test_internal_",
+				SkipTeardown:            true,
+				SingleTransaction:       tc.singleTransaction,
+			})
+			if _, err := migration.SetupE(ctx, ""); err == nil {
+				t.Fatal("expected error, got nil instead")
+			}
+			defer migration.Teardown(ctx)
+
+			conn, err := pgx.Connect(ctx, migration.ConnString())
+			if err != nil {
+				t.Fatalf("connection error: %v", err)
+			}
+			defer conn.Close(ctx)
+			var got bool
+			if err := conn.QueryRow(ctx, "SELECT to_regclass('authors') IS NOT NULL").Scan(&got); err != nil {
+				t.Fatalf("cannot query table: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected authors table to exist to be %v, got %v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSetupE(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	pool, err := migration.SetupE(ctx, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+	sqltest.AssertCount(t, pool, "media", 0)
+}
+
+func TestSetupEError(t *testing.T) {
+	t.Parallel()
+	migration := sqltest.New(t, sqltest.Options{
+		Files:     os.DirFS("example/testdata/migrations"),
+		Isolation: sqltest.TransactionIsolation,
+	})
+	pool, err := migration.SetupE(context.Background(), "")
+	if pool != nil {
+		t.Error("expected pool to be nil")
+	}
+	if want := "use SetupTx with the TransactionIsolation option"; err == nil || err.Error() != want {
+		t.Errorf("expected error to be %q, got %v instead", want, err)
+	}
+}
+
+func TestSetupECleanup(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	// The database is created, but fails the check for the "test" prefix.
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "internal_",
+	})
+	if _, err := migration.SetupE(ctx, ""); err == nil || !strings.Contains(err.Error(), "refusing to run integration tests") {
+		t.Fatalf("expected database name error, got %v instead", err)
+	}
+	conn, err := pgx.Connect(ctx, "")
+	if err != nil {
+		t.Fatalf("connection error: %v", err)
+	}
+	defer conn.Close(ctx)
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", "internal_"+sqltest.SQLTestName(t)).Scan(&exists); err != nil {
+		t.Fatalf("cannot query database: %v", err)
+	}
+	if exists {
+		t.Error("expected temporary database to be dropped once setup failed")
+	}
+}
+
+func TestAssertSchema(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+		UpdateGolden:            true,
+	})
+	migration.Setup(ctx, "")
+	golden := filepath.Join(t.TempDir(), "schema.golden.sql")
+	migration.AssertSchema(ctx, golden)
+	b, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("cannot read golden file: %v", err)
+	}
+	for _, want := range []string{
+		"CREATE TYPE media_type AS ENUM ('photo', 'illustration', 'sketch');",
+		"CREATE TABLE posts (\n\tid text NOT NULL,\n\tname text NOT NULL,\n\tmessage text NOT NULL,\n",
+		"ALTER TABLE posts ADD CONSTRAINT posts_pkey PRIMARY KEY (id);",
+		"CREATE INDEX media_name ON public.media USING btree (name text_pattern_ops);",
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("expected schema to contain %q, got:\n%s", want, b)
+		}
+	}
+	if strings.Contains(string(b), sqltest.SchemaVersionTable) {
+		t.Errorf("expected schema to not contain the schema version table, got:\n%s", b)
+	}
+
+	// The schema matches the golden file written above.
+	migration.Options.UpdateGolden = false
+	migration.AssertSchema(ctx, golden)
+}
+
+func TestAssertRoundTrip(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	conn := migration.Setup(ctx, "")
+	migration.AssertRoundTrip(ctx)
+	var version int32
+	if err := conn.QueryRow(ctx, "SELECT version FROM "+sqltest.SchemaVersionTable).Scan(&version); err != nil {
+		t.Errorf("cannot query schema version: %v", err)
+	}
+	if version != 3 {
+		t.Errorf("expected schema version to be 3, got %d instead", version)
+	}
+}
 
 func TestMigrationInvalidPath(t *testing.T) {
+	checkPostgres(t)
 	if *checkMigrationInvalidPath {
 		ctx := context.Background()
 		migration := sqltest.New(t, sqltest.Options{
@@ -101,6 +1774,7 @@ func TestMigrationInvalidPath(t *testing.T) {
 var checkMigrationDirty = flag.Bool("check_migration_dirty", false, "if true, TestMigrationDirty should fail.")
 
 func TestMigrationDirty(t *testing.T) {
+	checkPostgres(t)
 	if *checkMigrationDirty {
 		ctx := context.Background()
 		migration := sqltest.New(t, sqltest.Options{
@@ -161,9 +1835,48 @@ func TestMigrationDirty(t *testing.T) {
 	}
 }
 
+func TestChecksumDrift(t *testing.T) {
+	checkPostgres(t)
+	t.Parallel()
+	ctx := context.Background()
+	migration := sqltest.New(t, sqltest.Options{
+		Force:                   *force,
+		Files:                   os.DirFS("example/testdata/migrations"),
+		TemporaryDatabasePrefix: "test_internal_",
+	})
+	migration.Setup(ctx, "")
+
+	files := fstest.MapFS{}
+	for _, name := range []string{"001_media.sql", "002_settings.sql", "003_posts.sql"} {
+		b, err := os.ReadFile(filepath.Join("example/testdata/migrations", name))
+		if err != nil {
+			t.Fatalf("cannot read migration: %v", err)
+		}
+		files[name] = &fstest.MapFile{Data: b}
+	}
+	existing := sqltest.New(t, sqltest.Options{
+		Files:       files,
+		UseExisting: true,
+	})
+	if _, err := existing.SetupE(ctx, migration.ConnString()); err != nil {
+		t.Fatalf("expected no error, got %v instead", err)
+	}
+
+	files["001_media.sql"].Data = append([]byte("-- Edited after it was applied.\n"), files["001_media.sql"].Data...)
+	changed := sqltest.New(t, sqltest.Options{
+		Files:       files,
+		UseExisting: true,
+	})
+	_, err := changed.SetupE(ctx, migration.ConnString())
+	if want := "migrations changed after they were applied to the database: 001_media.sql"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error to contain %q, got %v instead", want, err)
+	}
+}
+
 var checkExistingTemporaryDB = flag.Bool("check_existing_temporary_db", false, "if true, ExistingTemporaryDB should fail.")
 
 func TestExistingTemporaryDB(t *testing.T) {
+	checkPostgres(t)
 	t.Parallel()
 	if *checkExistingTemporaryDB {
 		ctx := context.Background()
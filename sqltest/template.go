@@ -0,0 +1,202 @@
+package sqltest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// templates and other databases created or verified by this process, by name.
+var created sync.Map // map[string]*createOnce
+
+type createOnce struct {
+	once sync.Once
+	err  error
+}
+
+// setupTemplate creates the template database migrated to the target version if it doesn't exist yet,
+// and returns its name.
+func (m *Migration) setupTemplate(ctx context.Context, connString string, targetVersion *int32) (string, error) {
+	sum, err := m.checksum(targetVersion)
+	if err != nil {
+		return "", err
+	}
+	name := DatabasePrefix + "_template_" + hex.EncodeToString(sum[:8])
+	err = once(name, func() error {
+		return m.createTemplate(ctx, connString, name, lockKey(sum, 0), targetVersion)
+	})
+	return name, err
+}
+
+// once calls fn only once per process for the given database name, returning the same error after that.
+func once(name string, fn func() error) error {
+	v, _ := created.LoadOrStore(name, &createOnce{})
+	to := v.(*createOnce)
+	to.once.Do(func() {
+		to.err = fn()
+	})
+	return to.err
+}
+
+// lockKey returns the key of the advisory lock for a database identified by the checksum.
+func lockKey(sum []byte, n int64) int64 {
+	return int64(binary.BigEndian.Uint64(sum[:8])) + n
+}
+
+// checksum of the migration files and target version identifying the template database.
+func (m *Migration) checksum(targetVersion *int32) ([]byte, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", m.schemaVersionTable())
+	if m.Options.Format != TernFormat {
+		fmt.Fprintf(h, "format %d\n", m.Options.Format)
+	}
+	if targetVersion != nil {
+		fmt.Fprintf(h, "%d\n", *targetVersion)
+	}
+	if m.Options.FakeClock {
+		fmt.Fprintf(h, "fake clock\n")
+	}
+	for _, e := range m.Options.Extensions {
+		fmt.Fprintf(h, "extension %q\n", e)
+	}
+	if m.Options.Files != nil {
+		if err := hashFiles(h, m.Options.Files); err != nil {
+			return nil, fmt.Errorf("cannot read migrations: %w", err)
+		}
+	}
+	if m.Options.PgDump != nil {
+		io.WriteString(h, "pg_dump\n")
+		if err := hashFiles(h, m.Options.PgDump); err != nil {
+			return nil, fmt.Errorf("cannot read pg_dump files: %w", err)
+		}
+	}
+	return h.Sum(nil), nil
+}
+
+// hashFiles writes the paths and contents of the files to the hash.
+func hashFiles(h io.Writer, files fs.FS) error {
+	return fs.WalkDir(files, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		f, err := files.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		io.WriteString(h, strconv.Quote(path)+"\n")
+		_, err = io.Copy(h, f)
+		return err
+	})
+}
+
+// createTemplate database, unless it already exists.
+//
+// An advisory lock is used to serialize the creation of the template database by tests
+// of multiple packages running concurrently.
+func (m *Migration) createTemplate(ctx context.Context, connString, name string, lock int64, targetVersion *int32) error {
+	return withLock(ctx, m.conn, lock, func() error {
+		return m.createTemplateLocked(ctx, connString, name, targetVersion)
+	})
+}
+
+// createTemplateLocked creates the template database while holding its advisory lock.
+func (m *Migration) createTemplateLocked(ctx context.Context, connString, name string, targetVersion *int32) error {
+	// A database that isn't marked as a template yet might be left by a process that didn't finish migrating it.
+	var ready bool
+	if err := m.conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1 AND datistemplate)", name).Scan(&ready); err != nil {
+		return err
+	}
+	if ready && !m.Options.Force {
+		return nil
+	}
+	if err := dropTemplate(ctx, m.conn, name); err != nil {
+		return err
+	}
+
+	m.log("creating template database", slog.String("database", name))
+	if _, err := m.conn.Exec(ctx, fmt.Sprintf(`CREATE DATABASE "%s";`, name)); err != nil {
+		return err
+	}
+	if err := m.migrateTemplate(ctx, connString, name, targetVersion); err != nil {
+		// Don't leave a database with partially applied migrations behind.
+		if dropErr := dropTemplate(ctx, m.conn, name); dropErr != nil {
+			m.t.Logf("cannot drop template database: %v", dropErr)
+		}
+		return err
+	}
+	_, err := m.conn.Exec(ctx, fmt.Sprintf(`ALTER DATABASE "%s" WITH IS_TEMPLATE true;`, name))
+	return err
+}
+
+// migrateTemplate database to the target version.
+// The connection is closed once it's done, as a database can't be cloned while it's in use.
+func (m *Migration) migrateTemplate(ctx context.Context, connString, name string, targetVersion *int32) error {
+	config, err := m.parseConfig(connString)
+	if err != nil {
+		return err
+	}
+	config.Database = name
+	if m.Options.FakeClock {
+		config.RuntimeParams["search_path"] = clockSearchPath
+	}
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	if err := m.createExtensions(ctx, conn); err != nil {
+		return err
+	}
+	if err := m.installClock(ctx, conn); err != nil {
+		return fmt.Errorf("cannot install fake clock: %w", err)
+	}
+	if err := m.restoreDump(ctx, config); err != nil {
+		return fmt.Errorf("cannot restore pg_dump: %w", err)
+	}
+	if err := m.hook(ctx, "BeforeMigrate", m.Options.BeforeMigrate, conn); err != nil {
+		return err
+	}
+	if err := m.migrateConn(ctx, conn, targetVersion); err != nil {
+		return err
+	}
+	return m.hook(ctx, "AfterMigrate", m.Options.AfterMigrate, conn)
+}
+
+// migrateConn migrates the database of the connection to the target version.
+func (m *Migration) migrateConn(ctx context.Context, conn *pgx.Conn, targetVersion *int32) error {
+	if m.Options.Files == nil && m.Options.PgDump != nil {
+		return nil
+	}
+	if m.Options.Format == PlainSQLFormat {
+		return m.execFilesTx(ctx, conn, targetVersion)
+	}
+	migrator, err := m.newMigrator(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return m.migrateTx(ctx, conn, migrator, targetVersion)
+}
+
+// dropTemplate database if it exists.
+func dropTemplate(ctx context.Context, conn *pgx.Conn, name string) error {
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists); err != nil || !exists {
+		return err
+	}
+	// Template databases can't be dropped.
+	if _, err := conn.Exec(ctx, fmt.Sprintf(`ALTER DATABASE "%s" WITH IS_TEMPLATE false;`, name)); err != nil {
+		return err
+	}
+	_, err := conn.Exec(ctx, fmt.Sprintf(`DROP DATABASE "%s";`, name))
+	return err
+}
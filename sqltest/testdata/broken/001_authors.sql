@@ -0,0 +1,8 @@
+CREATE TABLE authors (
+	id text PRIMARY KEY,
+	name text NOT NULL
+);
+
+---- create above / drop below ----
+
+DROP TABLE authors;
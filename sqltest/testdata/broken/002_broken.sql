@@ -0,0 +1,8 @@
+CREATE TABLE books (
+	id text PRIMARY KEY,
+	author_id text NOT NULL REFERENCES missing (id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE books;
@@ -0,0 +1 @@
+UPDATE posts SET message = 'Updated!' WHERE id = 'second';
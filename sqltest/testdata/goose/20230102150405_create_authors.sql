@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE authors (
+	id text PRIMARY KEY,
+	name text NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS authors;
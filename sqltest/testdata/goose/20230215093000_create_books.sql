@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE books (
+	id text PRIMARY KEY,
+	author_id text NOT NULL REFERENCES authors(id),
+	title text NOT NULL
+);
+
+-- +goose StatementBegin
+CREATE FUNCTION book_title(book_id text) RETURNS text AS $$
+BEGIN
+	RETURN (SELECT title FROM books WHERE id = book_id);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS book_title;
+DROP TABLE IF EXISTS books;
@@ -0,0 +1,63 @@
+--
+-- PostgreSQL database dump
+--
+
+\restrict sqltest
+
+-- Dumped from database version 15.2
+-- Dumped by pg_dump version 15.2
+
+SET statement_timeout = 0;
+SET lock_timeout = 0;
+SET client_encoding = 'UTF8';
+SET standard_conforming_strings = on;
+SELECT pg_catalog.set_config('search_path', '', false);
+SET check_function_bodies = false;
+SET client_min_messages = warning;
+
+SET default_tablespace = '';
+
+SET default_table_access_method = heap;
+
+--
+-- Name: authors; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.authors (
+    id integer NOT NULL,
+    name text NOT NULL,
+    bio text
+);
+
+--
+-- Name: authors_name(integer); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.authors_name(author integer) RETURNS text
+    LANGUAGE sql STABLE
+    AS $$
+SELECT name FROM public.authors WHERE id = author;
+$$;
+
+--
+-- Data for Name: authors; Type: TABLE DATA; Schema: public; Owner: -
+--
+
+COPY public.authors (id, name, bio) FROM stdin;
+1	Machado de Assis	\N
+2	Clarice Lispector	Born in Chechelnyk.\nRaised in Recife.
+\.
+
+
+--
+-- Name: authors authors_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.authors
+    ADD CONSTRAINT authors_pkey PRIMARY KEY (id);
+
+--
+-- PostgreSQL database dump complete
+--
+
+\unrestrict sqltest
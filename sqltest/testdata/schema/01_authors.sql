@@ -0,0 +1,4 @@
+CREATE TABLE authors (
+	id text PRIMARY KEY,
+	name text NOT NULL
+);
@@ -0,0 +1,5 @@
+CREATE TABLE books (
+	id text PRIMARY KEY,
+	author_id text NOT NULL REFERENCES authors(id),
+	title text NOT NULL
+);
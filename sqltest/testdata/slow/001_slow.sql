@@ -0,0 +1,4 @@
+CREATE TABLE slow (id text PRIMARY KEY);
+SELECT pg_sleep(10);
+---- create above / drop below ----
+DROP TABLE IF EXISTS slow;
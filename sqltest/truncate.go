@@ -0,0 +1,55 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execQuerier is implemented by *pgx.Conn, pgx.Tx, and *pgxpool.Pool.
+type execQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// TruncateAll tables of the database, except for the schema version table and the given tables,
+// such as tables with reference data inserted by the migrations, restarting their sequences.
+// If something fails, t.Fatal is called.
+//
+// Tables referencing the truncated tables with foreign keys are truncated too, even if they're listed as exceptions.
+// It's useful to start subtests from a clean slate without migrating the database again.
+func (m *Migration) TruncateAll(ctx context.Context, except ...string) {
+	m.t.Helper()
+	var db execQuerier = m.pool
+	if m.tx != nil {
+		db = m.tx
+	}
+	if err := truncateAll(ctx, db, m.schemaVersionTable(), m.schema != "", except...); err != nil {
+		m.t.Fatalf("cannot truncate tables: %v", err)
+	}
+}
+
+// truncateAll tables created by the user except the schema version table, its migration checksums table, and the given tables,
+// restarting their sequences.
+// If scoped is set, only tables in the schemas of the search_path are truncated, as with SchemaIsolation.
+func truncateAll(ctx context.Context, db execQuerier, versionTable string, scoped bool, except ...string) error {
+	if except == nil {
+		except = []string{}
+	}
+	var tables *string
+	err := db.QueryRow(ctx, `SELECT string_agg(c.oid::regclass::text, ', ')
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND NOT c.relispartition AND `+notVersionTable("c.oid")+`
+		AND c.oid <> ALL($2::text[]::regclass[])
+		AND `+userObject("c.oid", scoped), versionTable, except).Scan(&tables)
+	if err != nil {
+		return fmt.Errorf("cannot query tables: %w", err)
+	}
+	if tables == nil {
+		return nil
+	}
+	_, err = db.Exec(ctx, "TRUNCATE "+*tables+" RESTART IDENTITY CASCADE")
+	return err
+}
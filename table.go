@@ -0,0 +1,91 @@
+package pgtools
+
+import (
+	"reflect"
+
+	"github.com/henvic/pgtools/internal/structref"
+)
+
+// TableNamer is implemented by structs that define the name of their SQL table.
+type TableNamer interface {
+	TableName() string
+}
+
+// Table is a marker to embed in a struct to set the name of its SQL table
+// with the "table" key in the field's tag, as in:
+//
+//	type User struct {
+//		pgtools.Table `table:"users"`
+//
+//		ID   string `db:"id,pk"`
+//		Name string
+//	}
+type Table struct{}
+
+var tableType = reflect.TypeOf(Table{})
+
+// TableName returns the name of the SQL table for a given Go struct.
+//
+// Builders such as Upsert and Delete use it when called with an empty table name.
+// The table name is resolved, in order of precedence, by:
+//
+//  1. The TableName method, if the struct implements TableNamer.
+//  2. The "table" key in the tag of an embedded Table field.
+//  3. The pluralized snake_case form of the struct type name, as in user_profiles for UserProfile.
+//
+// TableName returns an empty string if v is nil, or if its table name can't be resolved,
+// as for anonymous structs.
+func TableName(v any) string {
+	// A nil pointer is resolved by its type, as calling a value method on it would panic.
+	if rv := reflect.ValueOf(v); rv.Kind() != reflect.Ptr || !rv.IsNil() {
+		if tn, ok := v.(TableNamer); ok {
+			return tn.TableName()
+		}
+	}
+	m := getMapping(v)
+	if m == nil {
+		return ""
+	}
+	if reflect.PtrTo(m.t).Implements(reflect.TypeOf((*TableNamer)(nil)).Elem()) {
+		return reflect.New(m.t).Interface().(TableNamer).TableName()
+	}
+	return m.table
+}
+
+// tableName returns the table name for the struct type rv,
+// as set by an embedded Table field, or derived from the type name.
+func tableName(rv reflect.Type) string {
+	for i := 0; i < rv.NumField(); i++ {
+		if f := rv.Field(i); f.Anonymous && f.Type == tableType {
+			if name := f.Tag.Get("table"); name != "" {
+				return name
+			}
+		}
+	}
+	if rv.Name() == "" {
+		return ""
+	}
+	return structref.Pluralize(structref.ToSnakeCase(rv.Name()))
+}
+
+// resolveTable returns table, or the table name of v if table is empty.
+func resolveTable(table string, v any) string {
+	if table != "" {
+		return table
+	}
+	return TableName(v)
+}
+
+// resolveTypeTable returns table, or the table name of T if table is empty.
+// T can be a struct or a pointer to a struct, and the name is resolved with a new value of the struct,
+// rather than with the zero value of T, which might be a nil pointer.
+func resolveTypeTable[T any](table string) string {
+	if table != "" {
+		return table
+	}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return TableName(reflect.New(t).Interface())
+}
@@ -0,0 +1,165 @@
+package pgtools_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/henvic/pgtools"
+)
+
+type Account struct {
+	pgtools.Table `table:"public.accounts"`
+
+	ID   string `db:"id,pk"`
+	Name string
+}
+
+type Category struct {
+	ID string `db:"id,pk"`
+}
+
+type UserProfile struct {
+	ID string `db:"id,pk"`
+}
+
+type Address struct {
+	ID string `db:"id,pk"`
+}
+
+type Day struct {
+	ID string `db:"id,pk"`
+}
+
+type custom struct {
+	ID string `db:"id,pk"`
+}
+
+func (custom) TableName() string {
+	return "custom_table"
+}
+
+type customPointer struct {
+	ID string `db:"id,pk"`
+}
+
+func (*customPointer) TableName() string {
+	return "custom_pointer_table"
+}
+
+func ExampleTableName() {
+	fmt.Println(pgtools.TableName(Account{}))
+	fmt.Println(pgtools.TableName(UserProfile{}))
+	fmt.Println(pgtools.Delete("", Category{}))
+	// Output:
+	// public.accounts
+	// user_profiles
+	// DELETE FROM categories WHERE "id" = $1
+}
+
+func TestTableName(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc string
+		v    any
+		want string
+	}{
+		{
+			desc: "nil",
+			v:    nil,
+			want: "",
+		},
+		{
+			desc: "anonymous",
+			v:    struct{ ID string }{},
+			want: "",
+		},
+		{
+			desc: "marker",
+			v:    &Account{},
+			want: "public.accounts",
+		},
+		{
+			desc: "method",
+			v:    custom{},
+			want: "custom_table",
+		},
+		{
+			desc: "pointer method",
+			v:    customPointer{},
+			want: "custom_pointer_table",
+		},
+		{
+			desc: "pointer method with pointer",
+			v:    &customPointer{},
+			want: "custom_pointer_table",
+		},
+		{
+			desc: "method with nil pointer",
+			v:    (*custom)(nil),
+			want: "custom_table",
+		},
+		{
+			desc: "pointer method with nil pointer",
+			v:    (*customPointer)(nil),
+			want: "custom_pointer_table",
+		},
+		{
+			desc: "marker with nil pointer",
+			v:    (*Account)(nil),
+			want: "public.accounts",
+		},
+		{
+			desc: "default",
+			v:    Post{},
+			want: "posts",
+		},
+		{
+			desc: "snake case",
+			v:    UserProfile{},
+			want: "user_profiles",
+		},
+		{
+			desc: "es",
+			v:    Address{},
+			want: "addresses",
+		},
+		{
+			desc: "ies",
+			v:    Category{},
+			want: "categories",
+		},
+		{
+			desc: "vowel y",
+			v:    Day{},
+			want: "days",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := pgtools.TableName(tc.v); tc.want != got {
+				t.Errorf("expected table name to be %q, got %q instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildersTableName(t *testing.T) {
+	t.Parallel()
+	if got, want := pgtools.Upsert("", Account{}, "id"), `INSERT INTO public.accounts ("id","name") VALUES ($1,$2) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`; got != want {
+		t.Errorf("expected statement to be %v, got %v instead", want, got)
+	}
+	if got, want := pgtools.Delete("", &custom{}), `DELETE FROM custom_table WHERE "id" = $1`; got != want {
+		t.Errorf("expected statement to be %v, got %v instead", want, got)
+	}
+	if got, want := pgtools.Delete("", (*custom)(nil)), `DELETE FROM custom_table WHERE "id" = $1`; got != want {
+		t.Errorf("expected statement to be %v, got %v instead", want, got)
+	}
+	if got, args := pgtools.Count("", (*custom)(nil)); got != `SELECT count(*) FROM custom_table` || len(args) != 0 {
+		t.Errorf("expected count statement without arguments, got %v %v instead", got, args)
+	}
+	if got := pgtools.Delete("", struct {
+		ID string `db:"id,pk"`
+	}{}); got != "" {
+		t.Errorf("expected no statement for anonymous struct, got %v instead", got)
+	}
+}
@@ -0,0 +1,160 @@
+package pgtools
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/henvic/pgtools/internal/structref"
+	"github.com/jackc/pgx/v5"
+)
+
+// Values returns the values of the fields of a given Go struct in the same order as InsertFields,
+// so they can be used as the arguments of a query built using the columns.
+//
+// Unlike Fields, InsertFields and Values skip fields with the "readonly" option, such as generated columns,
+// and fields with the "generated" option, such as identity columns, matching the columns used by builders such as Upsert.
+//
+// Fields with the "json" option are marshaled to JSON bytes.
+// Nil slices of fields with the "array" option are encoded as empty arrays instead of NULL.
+// If a field is nested inside a nil pointer to a struct, its value is nil.
+//
+// To keep usage simple in the happy path, Values doesn't return an error.
+// If a field can't be marshaled to JSON, its value is replaced by one that
+// fails with the marshaling error once the query is executed.
+//
+// If v is a map[string]any, its values are returned in the same order as the columns
+// returned by InsertFields.
+//
+// Values returns nil if v is nil or a nil pointer.
+func Values(v any) []any {
+	if mv, ok := v.(map[string]any); ok {
+		return mapValues(mv)
+	}
+	m := getMapping(v)
+	if m == nil {
+		return nil
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil
+	}
+	values := make([]any, 0, len(m.insertable))
+	for _, c := range m.insertable {
+		values = append(values, columnValue(rv, c))
+	}
+	return values
+}
+
+// ToNamedArgs returns the values of the fields of a given Go struct as pgx named arguments,
+// so they can be referenced by column name in queries, as in @full_name.
+//
+// Values are obtained with the same rules used by the Values function,
+// and fields with the "readonly" option are skipped too. However, fields with the
+// "generated" option are kept, so a query can reference them, as in WHERE id = @id.
+// Columns of nested structs have a dot in their names, and can't be referenced this way.
+//
+// ToNamedArgs returns nil if v is nil or a nil pointer.
+func ToNamedArgs(v any) pgx.NamedArgs {
+	m := getMapping(v)
+	if m == nil {
+		return nil
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil
+	}
+	args := make(pgx.NamedArgs, len(m.writable))
+	for _, c := range m.writable {
+		args[c.Name] = columnValue(rv, c)
+	}
+	return args
+}
+
+// columnValue returns the value of the struct field mapped to column c.
+func columnValue(rv reflect.Value, c structref.Column) any {
+	f, ok := fieldByIndex(rv, c.Index)
+	if !ok {
+		return nil
+	}
+	if !c.HasOption("json") {
+		if c.HasOption("array") && f.Kind() == reflect.Slice && f.IsNil() {
+			// Encode as an empty array rather than NULL.
+			return reflect.MakeSlice(f.Type(), 0, 0).Interface()
+		}
+		return f.Interface()
+	}
+	b, err := json.Marshal(f.Interface())
+	if err != nil {
+		return invalidValue{err}
+	}
+	return b
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex, but returns false
+// instead of panicking when a nil pointer to a struct is found on the way.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// invalidValue is used as the value of a field that cannot be encoded.
+// It implements driver.Valuer, which pgx uses to encode it, to fail the query with the original error.
+type invalidValue struct {
+	err error
+}
+
+// Value implements driver.Valuer.
+func (iv invalidValue) Value() (driver.Value, error) {
+	return nil, iv.err
+}
+
+// Diff compares two values of the same Go struct type, returning the columns that changed
+// and their new values, so they can be used to build a minimal UPDATE statement or an audit log.
+//
+// Columns and values follow the same rules used by the Values function, in the same order,
+// except that fields with the "generated" option are compared too, as they can be updated.
+// Values are compared with reflect.DeepEqual, except for time.Time values, which are compared
+// with time.Time.Equal.
+//
+// Diff returns nil if old and new aren't of the same type, or if either one is nil or a nil pointer.
+func Diff(old, new any) (columns []string, args []any) {
+	if old == nil || new == nil || reflect.TypeOf(old) != reflect.TypeOf(new) {
+		return nil, nil
+	}
+	m := getMapping(new)
+	if m == nil {
+		return nil, nil
+	}
+	ov, nv := reflect.Indirect(reflect.ValueOf(old)), reflect.Indirect(reflect.ValueOf(new))
+	if !ov.IsValid() || !nv.IsValid() {
+		return nil, nil
+	}
+	for _, c := range m.writable {
+		a, b := columnValue(ov, c), columnValue(nv, c)
+		if equal(a, b) {
+			continue
+		}
+		columns = append(columns, c.Name)
+		args = append(args, b)
+	}
+	return columns, args
+}
+
+func equal(a, b any) bool {
+	if t, ok := a.(time.Time); ok {
+		if u, ok := b.(time.Time); ok {
+			return t.Equal(u)
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,342 @@
+package pgtools_test
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools"
+	"github.com/jackc/pgx/v5"
+)
+
+func ExampleValues() {
+	u := User{
+		Username: "henvic",
+		FullName: "Henrique Vicente",
+		Email:    "henvic@example.com",
+		Alias:    "hv",
+		Theme: Theme{
+			PrimaryColor: "blue",
+		},
+	}
+	for _, v := range pgtools.Values(u) {
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		fmt.Println(v)
+	}
+	// Output:
+	// henvic
+	// Henrique Vicente
+	// henvic@example.com
+	// hv
+	// {"PrimaryColor":"blue","SecondaryColor":"","TextColor":"","TextUppercase":false,"FontFamilyHeadings":"","FontFamilyBody":"","FontFamilyDefault":""}
+}
+
+type pointerEmbedMock struct {
+	ID string
+	*numericMock
+}
+
+type invalidJSONMock struct {
+	ID   string
+	Func func() `db:"func,json"`
+}
+
+func TestValues(t *testing.T) {
+	t.Parallel()
+	var uninitializedPointer *jsonMock
+	testCases := []struct {
+		v    any
+		desc string
+		want []any
+	}{
+		{
+			v:    nil,
+			desc: "nil",
+			want: nil,
+		},
+		{
+			v:    uninitializedPointer,
+			desc: "uninitializedPointer",
+			want: nil,
+		},
+		{
+			v:    emptyEmbed{},
+			desc: "empty",
+			want: []any{},
+		},
+		{
+			v: mock{
+				Automatic: "auto string",
+				Tagged:    "tag string",
+				OneTwo:    "one two",
+				CamelCase: "camel",
+				Ignored:   "ignored",
+			},
+			desc: "mock",
+			want: []any{"auto string", "tag string", "one two", "camel"},
+		},
+		{
+			v: &mockMultiEmbed{
+				A:           "a",
+				mock:        mock{Automatic: "auto"},
+				B:           "b",
+				numericMock: numericMock{Number: 7},
+				C:           "c",
+			},
+			desc: "multiembed",
+			want: []any{"a", "auto", "", "", "", "b", 7, "c"},
+		},
+		{
+			v:    pointerEmbedMock{ID: "x"},
+			desc: "nil embedded pointer",
+			want: []any{"x", nil},
+		},
+		{
+			v:    Document{ID: "x", Body: "text", SearchVector: "'text'"},
+			desc: "readonly",
+			want: []any{"x", "text"},
+		},
+		{
+			v:    Comment{ID: 1, Body: "text"},
+			desc: "generated",
+			want: []any{"text"},
+		},
+		{
+			v: struct {
+				Tags     []string  `db:"tags,array"`
+				Scores   []int     `db:"scores,array"`
+				Nullable *[]string `db:"nullable,array"`
+				Plain    []string
+			}{Scores: []int{1}},
+			desc: "array",
+			want: []any{[]string{}, []int{1}, (*[]string)(nil), []string(nil)},
+		},
+		{
+			v:    pointerEmbedMock{ID: "x", numericMock: &numericMock{Number: 3}},
+			desc: "embedded pointer",
+			want: []any{"x", 3},
+		},
+		{
+			v: themeImplicit{
+				ID:  "x",
+				XYZ: Theme{TextUppercase: true},
+			},
+			desc: "json",
+			want: []any{"x", []byte(`{"PrimaryColor":"","SecondaryColor":"","TextColor":"","TextUppercase":true,"FontFamilyHeadings":"","FontFamilyBody":"","FontFamilyDefault":""}`)},
+		},
+		{
+			v:    map[string]any{"b": "x", "a": 1, "c": nil},
+			desc: "map",
+			want: []any{1, "x", nil},
+		},
+		{
+			v:    map[string]any{"a": 1, "b;": 2},
+			desc: "map with invalid key",
+			want: nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := pgtools.Values(tc.v)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected values to be %#v, got %#v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValuesInvalidJSON(t *testing.T) {
+	t.Parallel()
+	got := pgtools.Values(invalidJSONMock{ID: "x"})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 values, got %d instead", len(got))
+	}
+	valuer, ok := got[1].(driver.Valuer)
+	if !ok {
+		t.Fatalf("expected invalid JSON value to implement driver.Valuer, got %T instead", got[1])
+	}
+	_, err := valuer.Value()
+	if want := "json: unsupported type: func()"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error to contain %q, got %v instead", want, err)
+	}
+}
+
+func ExampleToNamedArgs() {
+	u := User{
+		Username: "henvic",
+		Email:    "henvic@example.com",
+	}
+	args := pgtools.ToNamedArgs(u)
+	fmt.Println(args["username"], args["email"])
+	// Output:
+	// henvic henvic@example.com
+}
+
+func TestToNamedArgs(t *testing.T) {
+	t.Parallel()
+	var uninitializedPointer *jsonMock
+	testCases := []struct {
+		v    any
+		desc string
+		want pgx.NamedArgs
+	}{
+		{
+			v:    nil,
+			desc: "nil",
+			want: nil,
+		},
+		{
+			v:    uninitializedPointer,
+			desc: "uninitializedPointer",
+			want: nil,
+		},
+		{
+			v:    emptyEmbed{},
+			desc: "empty",
+			want: pgx.NamedArgs{},
+		},
+		{
+			v: &mockEmbed{
+				Before: 1,
+				mock: mock{
+					Automatic: "auto string",
+					Ignored:   "ignored",
+				},
+				After: "after",
+			},
+			desc: "embed",
+			want: pgx.NamedArgs{
+				"before":    1,
+				"automatic": "auto string",
+				"tagged":    "",
+				"one_two":   "",
+				"CamelCase": "",
+				"after":     "after",
+			},
+		},
+		{
+			v:    pointerEmbedMock{ID: "x"},
+			desc: "nil embedded pointer",
+			want: pgx.NamedArgs{"id": "x", "number": nil},
+		},
+		{
+			v:    &Document{ID: "x", Body: "text", SearchVector: "'text'"},
+			desc: "readonly",
+			want: pgx.NamedArgs{"id": "x", "body": "text"},
+		},
+		{
+			v:    Comment{ID: 1, Body: "text"},
+			desc: "generated",
+			want: pgx.NamedArgs{"id": int64(1), "body": "text", "created_at": time.Time{}},
+		},
+		{
+			v: themeImplicit{
+				ID:  "x",
+				XYZ: Theme{PrimaryColor: "red"},
+			},
+			desc: "json",
+			want: pgx.NamedArgs{
+				"id":  "x",
+				"xyz": []byte(`{"PrimaryColor":"red","SecondaryColor":"","TextColor":"","TextUppercase":false,"FontFamilyHeadings":"","FontFamilyBody":"","FontFamilyDefault":""}`),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := pgtools.ToNamedArgs(tc.v); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected named arguments to be %#v, got %#v instead", tc.want, got)
+			}
+		})
+	}
+}
+
+func ExampleDiff() {
+	old := User{
+		Username: "henvic",
+		Email:    "henvic@example.com",
+	}
+	updated := old
+	updated.Email = "henrique@example.com"
+	columns, args := pgtools.Diff(old, updated)
+	fmt.Println(columns, args)
+	// Output:
+	// [email] [henrique@example.com]
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	var uninitializedPointer *jsonMock
+	testCases := []struct {
+		desc        string
+		old         any
+		new         any
+		wantColumns []string
+		wantArgs    []any
+	}{
+		{
+			desc: "nil",
+		},
+		{
+			desc: "nil old",
+			new:  mock{},
+		},
+		{
+			desc: "different types",
+			old:  mock{},
+			new:  &mock{},
+		},
+		{
+			desc: "uninitializedPointer",
+			old:  uninitializedPointer,
+			new:  &jsonMock{},
+		},
+		{
+			desc: "unchanged",
+			old:  mock{Automatic: "a"},
+			new:  mock{Automatic: "a"},
+		},
+		{
+			desc:        "changed",
+			old:         &mock{Automatic: "a", Tagged: "b", OneTwo: "c"},
+			new:         &mock{Automatic: "a", Tagged: "x", OneTwo: "y", Ignored: "z"},
+			wantColumns: []string{"tagged", "one_two"},
+			wantArgs:    []any{"x", "y"},
+		},
+		{
+			desc: "readonly",
+			old:  Document{ID: "a", SearchVector: "a"},
+			new:  Document{ID: "a", SearchVector: "b"},
+		},
+		{
+			desc:        "generated",
+			old:         Comment{ID: 1, Body: "a", CreatedAt: now},
+			new:         Comment{ID: 1, Body: "a", CreatedAt: now.Add(time.Second)},
+			wantColumns: []string{"created_at"},
+			wantArgs:    []any{now.Add(time.Second)},
+		},
+		{
+			desc:        "json",
+			old:         jsonMock{ID: "a", CreatedAt: now},
+			new:         jsonMock{ID: "a", CreatedAt: now.UTC(), Theme: Theme{TextUppercase: true}},
+			wantColumns: []string{"theme"},
+			wantArgs:    []any{[]byte(`{"PrimaryColor":"","SecondaryColor":"","TextColor":"","TextUppercase":true,"FontFamilyHeadings":"","FontFamilyBody":"","FontFamilyDefault":""}`)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			columns, args := pgtools.Diff(tc.old, tc.new)
+			if !reflect.DeepEqual(tc.wantColumns, columns) {
+				t.Errorf("expected columns to be %v, got %v instead", tc.wantColumns, columns)
+			}
+			if !reflect.DeepEqual(tc.wantArgs, args) {
+				t.Errorf("expected args to be %#v, got %#v instead", tc.wantArgs, args)
+			}
+		})
+	}
+}
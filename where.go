@@ -0,0 +1,178 @@
+package pgtools
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// operators supported as options of the "db" key of a filter struct field's tag.
+var operators = map[string]string{
+	"ne":    "<>",
+	"gt":    ">",
+	"gte":   ">=",
+	"lt":    "<",
+	"lte":   "<=",
+	"like":  "LIKE",
+	"ilike": "ILIKE",
+}
+
+// Where returns a WHERE clause using the non-zero fields of a given filter struct,
+// and the arguments referenced positionally by it as $1, $2, etc., as in:
+//
+//	WHERE "name" = $1 AND "created_at" >= $2
+//
+// Columns are mapped using the same rules as Fields. However, a column can be used by
+// multiple fields, so conditions such as a range can be expressed.
+//
+// By default, the equal operator is used. Use one of the following options in the "db" key
+// of the struct field's tag to use another operator:
+//
+//	ne     "name" <> $1
+//	gt     "name" > $1
+//	gte    "name" >= $1
+//	lt     "name" < $1
+//	lte    "name" <= $1
+//	like   "name" LIKE $1
+//	ilike  "name" ILIKE $1
+//	in     "name" = ANY($1)
+//
+// For array columns, use the "array" option to match rows where the array contains the value
+// of the field, as in $1 = ANY("tags"), or all the elements of the field if it's a slice or
+// an array, as in "tags" @> $1.
+//
+// As in:
+//
+//	type PostFilter struct {
+//		Author        string    `db:"author"`
+//		Categories    []string  `db:"category,in"`
+//		CreatedAfter  time.Time `db:"created_at,gte"`
+//		CreatedBefore time.Time `db:"created_at,lt"`
+//	}
+//
+// To filter using a zero value, use a pointer.
+// Where returns an empty string if all fields of the filter are zero.
+func Where(filter any) (string, []any) {
+	m := getMapping(filter)
+	if m == nil {
+		return "", nil
+	}
+	rv := reflect.Indirect(reflect.ValueOf(filter))
+	if !rv.IsValid() {
+		return "", nil
+	}
+
+	var (
+		b    strings.Builder
+		args []any
+	)
+	for _, c := range m.filterColumns() {
+		f, ok := fieldByIndex(rv, c.Index)
+		if !ok || f.IsZero() {
+			continue
+		}
+		if len(args) == 0 {
+			b.WriteString("WHERE ")
+		} else {
+			b.WriteString(" AND ")
+		}
+		args = append(args, columnValue(rv, c))
+		if c.HasOption("array") {
+			writeArrayCondition(&b, c.Name, isList(f.Type()), len(args))
+			continue
+		}
+		writeCondition(&b, c.Name, c.Options(), len(args))
+	}
+	return b.String(), args
+}
+
+// writeCondition for the column using the operator set by the options, and the positional parameter n.
+func writeCondition(b *strings.Builder, column string, options []string, n int) {
+	b.WriteString(`"`)
+	b.WriteString(column)
+	b.WriteString(`"`)
+	op := "="
+	for _, o := range options {
+		if o == "in" {
+			b.WriteString(" = ANY($")
+			b.WriteString(strconv.Itoa(n))
+			b.WriteString(")")
+			return
+		}
+		if v, ok := operators[o]; ok {
+			op = v
+		}
+	}
+	b.WriteString(" ")
+	b.WriteString(op)
+	b.WriteString(" $")
+	b.WriteString(strconv.Itoa(n))
+}
+
+// writeArrayCondition for the array column using the positional parameter n,
+// either for a list of elements it contains, or for a single element.
+func writeArrayCondition(b *strings.Builder, column string, list bool, n int) {
+	if list {
+		b.WriteString(`"`)
+		b.WriteString(column)
+		b.WriteString(`" @> $`)
+		b.WriteString(strconv.Itoa(n))
+		return
+	}
+	b.WriteString("$")
+	b.WriteString(strconv.Itoa(n))
+	b.WriteString(` = ANY("`)
+	b.WriteString(column)
+	b.WriteString(`")`)
+}
+
+// isList reports whether t is a slice or an array, except for []byte and [N]byte,
+// which are mapped to single values, such as bytea or uuid.
+func isList(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// Count returns a SELECT count(*) statement for the given table using the WHERE clause
+// built by Where for the filter, and its arguments, as in:
+//
+//	SELECT count(*) FROM posts WHERE "author" = $1
+//
+// This way, a list endpoint can share the same filter between the query of a page and the query
+// of the total count of rows.
+//
+// If table is empty, the table name is resolved with the TableName function.
+func Count(table string, filter any) (string, []any) {
+	if table = resolveTable(table, filter); table == "" {
+		return "", nil
+	}
+	return selectWhere("SELECT count(*) FROM ", table, "", filter)
+}
+
+// Exists returns a SELECT EXISTS statement for the given table using the WHERE clause
+// built by Where for the filter, and its arguments, as in:
+//
+//	SELECT EXISTS(SELECT 1 FROM posts WHERE "author" = $1)
+//
+// If table is empty, the table name is resolved with the TableName function.
+func Exists(table string, filter any) (string, []any) {
+	if table = resolveTable(table, filter); table == "" {
+		return "", nil
+	}
+	return selectWhere("SELECT EXISTS(SELECT 1 FROM ", table, ")", filter)
+}
+
+// selectWhere returns prefix + table, followed by the WHERE clause of the filter, if any, and suffix.
+func selectWhere(prefix, table, suffix string, filter any) (string, []any) {
+	where, args := Where(filter)
+	if where != "" {
+		where = " " + where
+	}
+	return prefix + table + where + suffix, args
+}
@@ -0,0 +1,229 @@
+package pgtools_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/henvic/pgtools"
+)
+
+type PostFilter struct {
+	Author        string    `db:"author"`
+	Categories    []string  `db:"category,in"`
+	CreatedAfter  time.Time `db:"created_at,gte"`
+	CreatedBefore time.Time `db:"created_at,lt"`
+	Draft         *bool     `db:"draft"`
+}
+
+func ExampleWhere() {
+	draft := false
+	where, args := pgtools.Where(PostFilter{
+		Categories:   []string{"go", "postgres"},
+		CreatedAfter: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Draft:        &draft,
+	})
+	fmt.Println("SELECT " + pgtools.Wildcard(Post{}) + " FROM posts " + where)
+	fmt.Println(len(args))
+	// Output:
+	// SELECT "id","title","message" FROM posts WHERE "category" = ANY($1) AND "created_at" >= $2 AND "draft" = $3
+	// 3
+}
+
+func TestWhere(t *testing.T) {
+	t.Parallel()
+	after := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	draft := false
+	testCases := []struct {
+		desc     string
+		filter   any
+		want     string
+		wantArgs []any
+	}{
+		{
+			desc:   "nil",
+			filter: nil,
+			want:   "",
+		},
+		{
+			desc:   "empty",
+			filter: PostFilter{},
+			want:   "",
+		},
+		{
+			desc:     "single",
+			filter:   &PostFilter{Author: "henvic"},
+			want:     `WHERE "author" = $1`,
+			wantArgs: []any{"henvic"},
+		},
+		{
+			desc: "range",
+			filter: PostFilter{
+				Author:        "henvic",
+				CreatedAfter:  after,
+				CreatedBefore: before,
+				Draft:         &draft,
+			},
+			want:     `WHERE "author" = $1 AND "created_at" >= $2 AND "created_at" < $3 AND "draft" = $4`,
+			wantArgs: []any{"henvic", after, before, &draft},
+		},
+		{
+			desc:     "in",
+			filter:   PostFilter{Categories: []string{}},
+			want:     `WHERE "category" = ANY($1)`,
+			wantArgs: []any{[]string{}},
+		},
+		{
+			desc: "array",
+			filter: struct {
+				Tag    string   `db:"tags,array"`
+				AllOf  []string `db:"tags,array"`
+				Scores *[2]int  `db:"scores,array"`
+				UUID   [16]byte `db:"uuids,array"`
+				Empty  []string `db:"empty,array"`
+			}{"go", []string{"go", "sql"}, &[2]int{1, 2}, [16]byte{1}, nil},
+			want:     `WHERE $1 = ANY("tags") AND "tags" @> $2 AND "scores" @> $3 AND $4 = ANY("uuids")`,
+			wantArgs: []any{"go", []string{"go", "sql"}, &[2]int{1, 2}, [16]byte{1}},
+		},
+		{
+			desc: "operators",
+			filter: struct {
+				Name     string `db:"name,ilike"`
+				Pattern  string `db:"pattern,like"`
+				Other    int    `db:"other,ne"`
+				Min      int    `db:"n,gt"`
+				Max      int    `db:"n,lte"`
+				Unknown  int    `db:"unknown,foo"`
+				Readonly int    `db:"readonly,readonly"`
+			}{"a%", "b%", 1, 2, 3, 4, 5},
+			want:     `WHERE "name" ILIKE $1 AND "pattern" LIKE $2 AND "other" <> $3 AND "n" > $4 AND "n" <= $5 AND "unknown" = $6 AND "readonly" = $7`,
+			wantArgs: []any{"a%", "b%", 1, 2, 3, 4, 5},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, args := pgtools.Where(tc.filter)
+			if tc.want != got {
+				t.Errorf("expected clause to be %v, got %v instead", tc.want, got)
+			}
+			if !reflect.DeepEqual(tc.wantArgs, args) {
+				t.Errorf("expected args to be %v, got %v instead", tc.wantArgs, args)
+			}
+		})
+	}
+}
+
+func ExampleCount() {
+	filter := PostFilter{Author: "henvic"}
+	where, args := pgtools.Where(filter)
+	fmt.Println("SELECT " + pgtools.Wildcard(Post{}) + " FROM posts " + where + " LIMIT 10")
+	count, countArgs := pgtools.Count("posts", filter)
+	fmt.Println(count)
+	fmt.Println(args, countArgs)
+	// Output:
+	// SELECT "id","title","message" FROM posts WHERE "author" = $1 LIMIT 10
+	// SELECT count(*) FROM posts WHERE "author" = $1
+	// [henvic] [henvic]
+}
+
+func TestCount(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc     string
+		table    string
+		filter   any
+		want     string
+		wantArgs []any
+	}{
+		{
+			desc:  "nil",
+			table: "posts",
+			want:  "SELECT count(*) FROM posts",
+		},
+		{
+			desc:   "no table",
+			filter: struct{ Name string }{},
+			want:   "",
+		},
+		{
+			desc:   "zero",
+			table:  "posts",
+			filter: PostFilter{},
+			want:   "SELECT count(*) FROM posts",
+		},
+		{
+			desc:     "filter",
+			table:    "public.posts",
+			filter:   &PostFilter{Author: "henvic", Categories: []string{"go"}},
+			want:     `SELECT count(*) FROM public.posts WHERE "author" = $1 AND "category" = ANY($2)`,
+			wantArgs: []any{"henvic", []string{"go"}},
+		},
+		{
+			desc:     "table name",
+			filter:   Account{Name: "Henrique"},
+			want:     `SELECT count(*) FROM public.accounts WHERE "name" = $1`,
+			wantArgs: []any{"Henrique"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, args := pgtools.Count(tc.table, tc.filter)
+			if tc.want != got {
+				t.Errorf("expected statement to be %v, got %v instead", tc.want, got)
+			}
+			if !reflect.DeepEqual(tc.wantArgs, args) {
+				t.Errorf("expected args to be %#v, got %#v instead", tc.wantArgs, args)
+			}
+		})
+	}
+}
+
+func ExampleExists() {
+	sql, args := pgtools.Exists("posts", PostFilter{Author: "henvic"})
+	fmt.Println(sql)
+	fmt.Println(args)
+	// Output:
+	// SELECT EXISTS(SELECT 1 FROM posts WHERE "author" = $1)
+	// [henvic]
+}
+
+func TestExists(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		desc     string
+		table    string
+		filter   any
+		want     string
+		wantArgs []any
+	}{
+		{
+			desc:  "nil",
+			table: "posts",
+			want:  "SELECT EXISTS(SELECT 1 FROM posts)",
+		},
+		{
+			desc: "no table",
+			want: "",
+		},
+		{
+			desc:     "filter",
+			table:    "posts",
+			filter:   PostFilter{Author: "henvic"},
+			want:     `SELECT EXISTS(SELECT 1 FROM posts WHERE "author" = $1)`,
+			wantArgs: []any{"henvic"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, args := pgtools.Exists(tc.table, tc.filter)
+			if tc.want != got {
+				t.Errorf("expected statement to be %v, got %v instead", tc.want, got)
+			}
+			if !reflect.DeepEqual(tc.wantArgs, args) {
+				t.Errorf("expected args to be %#v, got %#v instead", tc.wantArgs, args)
+			}
+		})
+	}
+}